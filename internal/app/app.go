@@ -4,12 +4,28 @@ import (
 	"context"
 	"log"
 
+	"canteen/internal/controller/analytics"
+	"canteen/internal/controller/billing"
+	"canteen/internal/controller/booking"
+	"canteen/internal/controller/calendar"
 	"canteen/internal/controller/card"
+	"canteen/internal/controller/card_manage"
+	"canteen/internal/controller/dashboard"
+	"canteen/internal/controller/device"
+	"canteen/internal/controller/dish"
+	"canteen/internal/controller/meal_period"
+	"canteen/internal/controller/noshow"
+	"canteen/internal/controller/offline"
+	"canteen/internal/controller/recharge"
+	"canteen/internal/controller/setmeal"
+	"canteen/internal/controller/setmeal_template"
 	"canteen/internal/controller/tempDirect"
 	"canteen/internal/controller/user"
-	"canteen/internal/controller/order_record_detail"
 	"canteen/internal/infrastructure/cache"
+	"canteen/internal/infrastructure/clock"
+	"canteen/internal/infrastructure/config"
 	"canteen/internal/infrastructure/database"
+	"canteen/internal/infrastructure/events"
 	"canteen/pkg/utils"
 	"database/sql"
 )
@@ -28,18 +44,41 @@ func (app *Application) Initialize() error {
 	// 初始化Redis连接
 	cache.InitRedis()
 
+	// 多实例部署时通过 Redis 共享刷卡事件
+	if config.GetBool("dashboard.redis_pubsub") {
+		events.Default().EnableRedisRelay(context.Background(), cache.RedisClient())
+	}
+
 	// 初始化数据库连接
 	app.db = database.InitDb()
 
+	// 在任何次数变动之前补齐次数流水期初余额
+	if err := utils.SeedCountLedger(app.db); err != nil {
+		log.Printf("Failed to seed count ledger opening balances: %v", err)
+	}
+
 	// 注入数据库连接到控制器
 	card.SetDB(app.db)
 	tempDirect.SetDB(app.db)
 	user.SetDB(app.db)
-	order_record_detail.SetDB(app.db)
+	analytics.SetDB(app.db)
+	device.SetDB(app.db)
+	offline.SetDB(app.db)
+	meal_period.SetDB(app.db)
+	recharge.SetDB(app.db)
+	card_manage.SetDB(app.db)
+	booking.SetDB(app.db)
+	calendar.SetDB(app.db)
+	setmeal_template.SetDB(app.db)
+	dish.SetDB(app.db)
+	setmeal.SetDB(app.db)
+	billing.SetDB(app.db)
+	dashboard.SetDB(app.db)
+	noshow.SetDB(app.db)
 
 	// 更新每日餐食缓存
 	log.Println("Updating daily meal cache on startup...")
-	if err := utils.UpdateDailyMealCache(context.Background(), app.db, cache.RedisClient()); err != nil {
+	if err := utils.UpdateDailyMealCache(context.Background(), app.db, cache.RedisClient(), clock.Default()); err != nil {
 		log.Printf("Failed to update daily meal cache on startup: %v", err)
 	}
 
@@ -50,9 +89,11 @@ func (app *Application) Initialize() error {
 func (app *Application) StartBackgroundTasks() {
 	// 启动定时任务
 	go utils.DailyLicenseCheck()
-	go utils.DailyExpireOrderRecords(app.db)
-	go utils.WeeklyGenerateSetmeal(app.db)
-	go utils.DailyMealCacheUpdate(app.db, cache.RedisClient())
+	go utils.DailyExpireOrderRecords(app.db, clock.Default())
+	go utils.DailyCountLedgerCheck(app.db, clock.Default())
+	go utils.DailyAllotmentRun(app.db, clock.Default())
+	go utils.WeeklyGenerateSetmeal(app.db, clock.Default())
+	go utils.DailyMealCacheUpdate(app.db, cache.RedisClient(), clock.Default())
 }
 
 // Shutdown 关闭应用
@@ -0,0 +1,160 @@
+package analytics
+
+import (
+	"canteen/internal/infrastructure/clock"
+	"canteen/internal/model"
+	analyticsRepo "canteen/internal/repository/analytics"
+	"canteen/internal/service/analytics"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	db               *sql.DB
+	analyticsService analytics.AnalyticsService
+)
+
+func SetDB(database *sql.DB) {
+	db = database
+
+	// 初始化service
+	analyticsService = analytics.NewAnalyticsService(analyticsRepo.NewAnalyticsRepository(db), clock.Default())
+}
+
+// parseStatsFilter 读取统计公共查询参数：start_date、end_date（yyyy-MM-dd）及可选的 deptId、mealType、window、status
+func parseStatsFilter(c *gin.Context) (model.StatsFilter, error) {
+	filter := model.StatsFilter{
+		StartDate: c.Query("start_date"),
+		EndDate:   c.Query("end_date"),
+		MealType:  c.Query("mealType"),
+		Window:    c.Query("window"),
+		Status:    c.Query("status"),
+	}
+	if deptId := c.Query("deptId"); deptId != "" {
+		id, err := strconv.Atoi(deptId)
+		if err != nil {
+			return filter, &analytics.InvalidFilterError{Message: "无效的部门ID: " + deptId}
+		}
+		filter.DeptId = id
+	}
+	return filter, nil
+}
+
+// respondStats 按统一格式返回统计结果，参数错误返回 400，查询失败返回 500
+func respondStats(c *gin.Context, data interface{}, err error) {
+	var invalid *analytics.InvalidFilterError
+	if errors.As(err, &invalid) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": invalid.Message,
+		})
+		return
+	}
+	if err != nil {
+		log.Printf("查询统计失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  500,
+			"message": "查询统计失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "请求成功",
+		"data":    data,
+	})
+}
+
+// GetAllMealSelectionStatsHandler 按套餐统计订单数处理器
+func GetAllMealSelectionStatsHandler(c *gin.Context) {
+	filter, err := parseStatsFilter(c)
+	if err != nil {
+		respondStats(c, nil, err)
+		return
+	}
+	stats, err := analyticsService.SetmealStats(filter)
+	respondStats(c, stats, err)
+}
+
+// GetCMealSelectionStatsHandler 按套餐统计 C 窗口订单数处理器
+func GetCMealSelectionStatsHandler(c *gin.Context) {
+	filter, err := parseStatsFilter(c)
+	if err != nil {
+		respondStats(c, nil, err)
+		return
+	}
+	filter.Window = "C"
+	stats, err := analyticsService.SetmealStats(filter)
+	respondStats(c, stats, err)
+}
+
+// GetUserDishOrderStatsHandler 按菜品统计点餐次数处理器
+func GetUserDishOrderStatsHandler(c *gin.Context) {
+	filter, err := parseStatsFilter(c)
+	if err != nil {
+		respondStats(c, nil, err)
+		return
+	}
+	stats, err := analyticsService.DishOrderStats(filter)
+	respondStats(c, stats, err)
+}
+
+// GetBasicDishStatsHandler 按套餐描述拆分的菜名统计点餐次数处理器
+func GetBasicDishStatsHandler(c *gin.Context) {
+	filter, err := parseStatsFilter(c)
+	if err != nil {
+		respondStats(c, nil, err)
+		return
+	}
+	stats, err := analyticsService.BasicDishStats(filter)
+	respondStats(c, stats, err)
+}
+
+// GetDishAppearanceStatsHandler 按菜品统计周套餐出现次数处理器
+func GetDishAppearanceStatsHandler(c *gin.Context) {
+	filter, err := parseStatsFilter(c)
+	if err != nil {
+		respondStats(c, nil, err)
+		return
+	}
+	stats, err := analyticsService.DishAppearanceStats(filter)
+	respondStats(c, stats, err)
+}
+
+// GetDishStatsComparisonHandler 菜品出现次数与点餐次数对比处理器
+func GetDishStatsComparisonHandler(c *gin.Context) {
+	filter, err := parseStatsFilter(c)
+	if err != nil {
+		respondStats(c, nil, err)
+		return
+	}
+	comparison, err := analyticsService.DishComparison(filter)
+	respondStats(c, comparison, err)
+}
+
+// GetDemandForecastHandler 预测 date（yyyyMMdd，为空表示下周）所在周各周套餐备餐份数处理器
+func GetDemandForecastHandler(c *gin.Context) {
+	forecast, err := analyticsService.ForecastWeek(c.Query("date"))
+	respondStats(c, forecast, err)
+}
+
+// GetForecastBacktestHandler 备餐预测回测处理器，weeks 为回测的历史周数
+func GetForecastBacktestHandler(c *gin.Context) {
+	weeks := 0
+	if value := c.Query("weeks"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			respondStats(c, nil, &analytics.InvalidFilterError{Message: "无效的回测周数: " + value})
+			return
+		}
+		weeks = n
+	}
+	backtest, err := analyticsService.BacktestForecast(weeks)
+	respondStats(c, backtest, err)
+}
@@ -0,0 +1,67 @@
+package billing
+
+import (
+	billingRepo "canteen/internal/repository/billing"
+	calendarRepo "canteen/internal/repository/calendar"
+	periodRepo "canteen/internal/repository/meal_period"
+	"canteen/internal/service/billing"
+	"canteen/internal/service/meal_period"
+	"database/sql"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	db             *sql.DB
+	billingService billing.BillingService
+)
+
+func SetDB(database *sql.DB) {
+	db = database
+
+	// 初始化service
+	periodService := meal_period.NewMealPeriodService(periodRepo.NewMealPeriodRepository(db), calendarRepo.NewCalendarRepository(db))
+	billingService = billing.NewBillingService(billingRepo.NewBillingRepository(db), periodService)
+}
+
+// GetBillingReportHandler 查询部门结算报表处理器，startDate/endDate 为 yyyyMMdd
+func GetBillingReportHandler(c *gin.Context) {
+	report, err := billingService.GetReport(c.Query("startDate"), c.Query("endDate"))
+	if err != nil {
+		log.Printf("统计结算报表失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "请求成功",
+		"data":    report,
+	})
+}
+
+// ExportBillingReportHandler 导出结算单 Excel 处理器
+func ExportBillingReportHandler(c *gin.Context) {
+	startDate, endDate := c.Query("startDate"), c.Query("endDate")
+	file, err := billingService.ExportReport(startDate, endDate)
+	if err != nil {
+		log.Printf("导出结算单失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "导出失败: " + err.Error(),
+		})
+		return
+	}
+	defer file.Close()
+
+	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	c.Header("Content-Disposition", "attachment; filename=billing-"+startDate+"-"+endDate+".xlsx")
+	if err := file.Write(c.Writer); err != nil {
+		log.Printf("写入结算单失败: %v", err)
+	}
+}
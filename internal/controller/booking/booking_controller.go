@@ -0,0 +1,248 @@
+package booking
+
+import (
+	"canteen/internal/infrastructure/auth"
+	"canteen/internal/infrastructure/clock"
+	"canteen/internal/model"
+	bookingRepo "canteen/internal/repository/booking"
+	calendarRepo "canteen/internal/repository/calendar"
+	dishRepo "canteen/internal/repository/dish"
+	"canteen/internal/service/booking"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	db             *sql.DB
+	bookingService booking.BookingService
+)
+
+func SetDB(database *sql.DB) {
+	db = database
+
+	// 初始化repository
+	bookingRepository := bookingRepo.NewBookingRepository(db)
+
+	// 初始化service
+	bookingService = booking.NewBookingService(bookingRepository, calendarRepo.NewCalendarRepository(db), dishRepo.NewDishRepository(db), clock.Default())
+}
+
+// bookingRequest 报餐、改餐请求
+type bookingRequest struct {
+	OptionId int `json:"optionId"` // 周套餐ID
+}
+
+// GetWeekMenuHandler 获取一周可报餐套餐及本人报餐情况处理器，
+// exclude 为要避开的过敏原、diet 为要求的饮食标签，均以逗号分隔
+func GetWeekMenuHandler(c *gin.Context) {
+	filter := model.MenuFilter{Exclude: splitTags(c.Query("exclude")), Diets: splitTags(c.Query("diet"))}
+	slots, err := bookingService.GetWeekMenu(c.GetInt(auth.ContextUserId), c.Query("date"), filter)
+	if err != nil {
+		respondBookingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "请求成功",
+		"data":    slots,
+	})
+}
+
+// BookHandler 报餐处理器
+func BookHandler(c *gin.Context) {
+	var req bookingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	order, err := bookingService.Book(c.GetInt(auth.ContextUserId), req.OptionId)
+	if err != nil {
+		respondBookingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "报餐成功",
+		"data":    order,
+	})
+}
+
+// ChangeBookingHandler 改餐处理器
+func ChangeBookingHandler(c *gin.Context) {
+	orderId, ok := orderIdParam(c)
+	if !ok {
+		return
+	}
+	var req bookingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	order, err := bookingService.ChangeBooking(c.GetInt(auth.ContextUserId), orderId, req.OptionId)
+	if err != nil {
+		respondBookingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "改餐成功",
+		"data":    order,
+	})
+}
+
+// CancelBookingHandler 取消报餐处理器
+func CancelBookingHandler(c *gin.Context) {
+	orderId, ok := orderIdParam(c)
+	if !ok {
+		return
+	}
+
+	order, err := bookingService.CancelBooking(c.GetInt(auth.ContextUserId), orderId)
+	if err != nil {
+		respondBookingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "取消成功",
+		"data":    order,
+	})
+}
+
+// GetMyOrdersHandler 获取个人报餐记录处理器
+func GetMyOrdersHandler(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "50"))
+
+	history, err := bookingService.GetOrderHistory(c.GetInt(auth.ContextUserId), c.Query("startDate"), c.Query("endDate"), page, pageSize)
+	if err != nil {
+		respondBookingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "请求成功",
+		"data":    history,
+	})
+}
+
+// AdminBookHandler 管理员代员工报餐处理器
+func AdminBookHandler(c *gin.Context) {
+	var req model.AdminBookingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	order, err := bookingService.AdminBook(req)
+	if err != nil {
+		respondBookingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "报餐成功",
+		"data":    order,
+	})
+}
+
+// AdminChangeBookingHandler 管理员代员工改餐处理器
+func AdminChangeBookingHandler(c *gin.Context) {
+	handleAdminOrderChange(c, bookingService.AdminChangeBooking, "改餐成功")
+}
+
+// AdminCancelBookingHandler 管理员代员工取消报餐处理器
+func AdminCancelBookingHandler(c *gin.Context) {
+	handleAdminOrderChange(c, bookingService.AdminCancelBooking, "取消成功")
+}
+
+// handleAdminOrderChange 绑定请求并执行管理员对已有订单的操作
+func handleAdminOrderChange(c *gin.Context, change func(int, model.AdminBookingRequest) (*model.BookingOrder, error), message string) {
+	orderId, ok := orderIdParam(c)
+	if !ok {
+		return
+	}
+	var req model.AdminBookingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	order, err := change(orderId, req)
+	if err != nil {
+		respondBookingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": message,
+		"data":    order,
+	})
+}
+
+func orderIdParam(c *gin.Context) (int, bool) {
+	orderId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "订单ID格式错误",
+		})
+		return 0, false
+	}
+	return orderId, true
+}
+
+// respondBookingError 根据错误类型返回响应
+func respondBookingError(c *gin.Context, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  404,
+			"message": "订单不存在",
+		})
+		return
+	}
+
+	log.Printf("报餐操作失败: %v", err)
+	c.JSON(http.StatusBadRequest, gin.H{
+		"status":  400,
+		"message": err.Error(),
+	})
+}
+
+// splitTags 拆分逗号分隔的标签，忽略空项
+func splitTags(value string) []string {
+	tags := []string{}
+	for _, tag := range strings.Split(value, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
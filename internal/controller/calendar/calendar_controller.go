@@ -0,0 +1,131 @@
+package calendar
+
+import (
+	"canteen/internal/infrastructure/clock"
+	"canteen/internal/model"
+	calendarRepo "canteen/internal/repository/calendar"
+	"canteen/internal/service/calendar"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	db              *sql.DB
+	calendarService calendar.CalendarService
+)
+
+func SetDB(database *sql.DB) {
+	db = database
+
+	// 初始化repository
+	calendarRepository := calendarRepo.NewCalendarRepository(db)
+
+	// 初始化service
+	calendarService = calendar.NewCalendarService(calendarRepository, clock.Default())
+}
+
+// GetCalendarDaysHandler 查询节假日及调休上班日期处理器，start/end 为 yyyy-MM-dd，默认今年
+func GetCalendarDaysHandler(c *gin.Context) {
+	days, err := calendarService.ListDays(c.Query("start"), c.Query("end"))
+	if err != nil {
+		respondCalendarError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "请求成功",
+		"data":    days,
+	})
+}
+
+// SaveCalendarDayHandler 新增或覆盖日期登记处理器
+func SaveCalendarDayHandler(c *gin.Context) {
+	var req model.CalendarDay
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	day, err := calendarService.SaveDay(req)
+	if err != nil {
+		respondCalendarError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "保存成功",
+		"data":    day,
+	})
+}
+
+// DeleteCalendarDayHandler 删除日期登记处理器
+func DeleteCalendarDayHandler(c *gin.Context) {
+	if err := calendarService.DeleteDay(c.Param("date")); err != nil {
+		respondCalendarError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "删除成功",
+	})
+}
+
+// ImportCalendarHandler 上传 Excel 或 ICS 日历导入处理器
+func ImportCalendarHandler(c *gin.Context) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "获取文件失败: " + err.Error(),
+		})
+		return
+	}
+	src, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  500,
+			"message": "打开文件失败: " + err.Error(),
+		})
+		return
+	}
+	defer src.Close()
+
+	result, err := calendarService.Import(src, file.Filename)
+	if err != nil {
+		respondCalendarError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "导入成功",
+		"data":    result,
+	})
+}
+
+// respondCalendarError 根据错误类型返回响应
+func respondCalendarError(c *gin.Context, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  404,
+			"message": "该日期未登记",
+		})
+		return
+	}
+
+	log.Printf("日历操作失败: %v", err)
+	c.JSON(http.StatusBadRequest, gin.H{
+		"status":  400,
+		"message": err.Error(),
+	})
+}
@@ -3,11 +3,20 @@ package card
 import (
 	"canteen/internal/model"
 	"canteen/internal/service/card"
+	"canteen/internal/service/device"
+	"canteen/internal/service/meal_period"
 	"canteen/internal/service/user"
 	userRepo "canteen/internal/repository/user"
 	orderRepo "canteen/internal/repository/order"
 	cardRepo "canteen/internal/repository/card"
+	deviceRepo "canteen/internal/repository/device"
+	txLogRepo "canteen/internal/repository/transaction_log"
+	offlineRepo "canteen/internal/repository/offline"
+	periodRepo "canteen/internal/repository/meal_period"
+	calendarRepo "canteen/internal/repository/calendar"
 	"canteen/internal/infrastructure/cache"
+	"canteen/internal/infrastructure/clock"
+	"canteen/internal/infrastructure/events"
 	"database/sql"
 	"log"
 	"net/http"
@@ -19,6 +28,7 @@ var (
 	db *sql.DB
 	cardService card.CardService
 	userService user.UserService
+	deviceService device.DeviceService
 )
 
 func SetDB(database *sql.DB) {
@@ -28,10 +38,16 @@ func SetDB(database *sql.DB) {
 	userRepository := userRepo.NewUserRepository(db)
 	orderRepository := orderRepo.NewOrderRepository(db)
 	cardRepository := cardRepo.NewCardRepository(db)
+	deviceRepository := deviceRepo.NewDeviceRepository(db)
+	transactionLogRepository := txLogRepo.NewTransactionLogRepository(db)
+	offlineRepository := offlineRepo.NewOfflineRepository(db)
+	mealPeriodRepository := periodRepo.NewMealPeriodRepository(db)
 	
 	// 初始化services
 	userService = user.NewUserService(userRepository)
-	cardService = card.NewCardService(userRepository, orderRepository, cardRepository, cache.RedisClient())
+	deviceService = device.NewDeviceService(deviceRepository)
+	mealPeriodService := meal_period.NewMealPeriodService(mealPeriodRepository, calendarRepo.NewCalendarRepository(db))
+	cardService = card.NewCardService(userRepository, orderRepository, cardRepository, transactionLogRepository, offlineRepository, deviceService, mealPeriodService, cache.RedisClient(), clock.Default(), events.Default())
 }
 
 // ConsumTransactionHandler 核销接口
@@ -74,14 +90,10 @@ func ConsumTransactionHandler(c *gin.Context) {
 // ServerTimeHandler 服务器时间接口
 func ServerTimeHandler(c *gin.Context) {
 	deviceID := c.GetHeader("Device-ID")
-	// 检查设备ID（保留原有逻辑）
-	deviceToRemark := map[string]string{
-		"0180800116": "A",
-		"0127448632": "B",
-		"0158577664": "C",
-	}
-	if deviceToRemark[deviceID] == "" {
-		log.Printf("deviceID=%s", deviceID)
+	// 检查设备是否已登记
+	if _, err := deviceService.ResolveDevice(deviceID); err != nil {
+		c.JSON(http.StatusOK, gin.H{"Status": 0, "Msg": err.Error()})
+		return
 	}
 	
 	serverTime := cardService.GetServerTime()
@@ -101,17 +113,37 @@ func ServerTimeHandler(c *gin.Context) {
 
 // OffLineHandler 离线处理接口
 func OffLineHandler(c *gin.Context) {
+	deviceID := c.GetHeader("Device-ID")
+
 	var req model.OffLineRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"Status": 0, "Msg": "请求参数错误: " + err.Error()})
 		return
 	}
 	
-	err := cardService.ProcessOffLineRequest(req)
+	err := cardService.ProcessOffLineRequest(req, deviceID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"Status": 0, "Msg": "处理离线请求失败: " + err.Error()})
 		return
 	}
 	
 	c.JSON(http.StatusOK, gin.H{"Status": 1, "Msg": "处理成功"})
-}
\ No newline at end of file
+}
+
+// RefundHandler 撤销核销接口（管理员）
+func RefundHandler(c *gin.Context) {
+	var req model.RefundRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"Status": 0, "Msg": "请求参数错误: " + err.Error()})
+		return
+	}
+
+	refundLog, err := cardService.RefundTransaction(req)
+	if err != nil {
+		log.Printf("TAG: 撤销核销失败: %v", err)
+		c.JSON(http.StatusOK, gin.H{"Status": 0, "Msg": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"Status": 1, "Msg": "撤销成功", "Data": refundLog})
+}
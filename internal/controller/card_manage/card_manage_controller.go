@@ -0,0 +1,158 @@
+package card_manage
+
+import (
+	"canteen/internal/model"
+	cardManageRepo "canteen/internal/repository/card_manage"
+	"canteen/internal/service/card_manage"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	db                *sql.DB
+	cardManageService card_manage.CardManageService
+)
+
+func SetDB(database *sql.DB) {
+	db = database
+
+	// 初始化repository
+	cardManageRepository := cardManageRepo.NewCardManageRepository(db)
+
+	// 初始化service
+	cardManageService = card_manage.NewCardManageService(cardManageRepository)
+}
+
+// GetCardsHandler 获取卡片列表处理器
+func GetCardsHandler(c *gin.Context) {
+	userId, _ := strconv.Atoi(c.Query("userId"))
+	cards, err := cardManageService.ListCards(userId, c.Query("status"))
+	if err != nil {
+		log.Printf("查询卡片列表失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  500,
+			"message": "查询卡片列表失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "请求成功",
+		"data":    cards,
+	})
+}
+
+// GetCardHandler 获取卡片详情及操作记录处理器
+func GetCardHandler(c *gin.Context) {
+	cardNo := c.Param("card_no")
+	card, err := cardManageService.GetCard(cardNo)
+	if err != nil {
+		respondCardError(c, err)
+		return
+	}
+	logs, err := cardManageService.GetCardLogs(cardNo)
+	if err != nil {
+		respondCardError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "请求成功",
+		"data": gin.H{
+			"card": card,
+			"logs": logs,
+		},
+	})
+}
+
+// ReportLossHandler 挂失处理器
+func ReportLossHandler(c *gin.Context) {
+	handleStatusChange(c, cardManageService.ReportLoss, "挂失成功")
+}
+
+// FreezeCardHandler 冻结处理器
+func FreezeCardHandler(c *gin.Context) {
+	handleStatusChange(c, cardManageService.Freeze, "冻结成功")
+}
+
+// RestoreCardHandler 解除挂失或冻结处理器
+func RestoreCardHandler(c *gin.Context) {
+	handleStatusChange(c, cardManageService.Restore, "解挂成功")
+}
+
+// RetireCardHandler 注销处理器
+func RetireCardHandler(c *gin.Context) {
+	handleStatusChange(c, cardManageService.Retire, "注销成功")
+}
+
+// ReplaceCardHandler 补卡处理器
+func ReplaceCardHandler(c *gin.Context) {
+	var req model.CardReplaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	card, err := cardManageService.Replace(req)
+	if err != nil {
+		respondCardError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "补卡成功",
+		"data":    card,
+	})
+}
+
+// handleStatusChange 绑定请求并执行卡片状态变更
+func handleStatusChange(c *gin.Context, change func(model.CardStatusRequest) (*model.Card, error), message string) {
+	var req model.CardStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	card, err := change(req)
+	if err != nil {
+		respondCardError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": message,
+		"data":    card,
+	})
+}
+
+// respondCardError 根据错误类型返回响应
+func respondCardError(c *gin.Context, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  404,
+			"message": "卡片不存在",
+		})
+		return
+	}
+
+	log.Printf("卡片操作失败: %v", err)
+	c.JSON(http.StatusBadRequest, gin.H{
+		"status":  400,
+		"message": err.Error(),
+	})
+}
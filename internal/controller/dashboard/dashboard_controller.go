@@ -0,0 +1,122 @@
+package dashboard
+
+import (
+	"canteen/internal/infrastructure/clock"
+	"canteen/internal/infrastructure/events"
+	calendarRepo "canteen/internal/repository/calendar"
+	dashboardRepo "canteen/internal/repository/dashboard"
+	periodRepo "canteen/internal/repository/meal_period"
+	"canteen/internal/service/dashboard"
+	"canteen/internal/service/meal_period"
+	"database/sql"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// snapshotInterval 有新刷卡事件时推送看板的最短间隔
+	snapshotInterval = 3 * time.Second
+	// refreshInterval 无刷卡事件时定期推送看板，同时作为心跳
+	refreshInterval = 15 * time.Second
+)
+
+var (
+	db               *sql.DB
+	dashboardService dashboard.DashboardService
+)
+
+func SetDB(database *sql.DB) {
+	db = database
+
+	// 初始化service
+	periodService := meal_period.NewMealPeriodService(periodRepo.NewMealPeriodRepository(db), calendarRepo.NewCalendarRepository(db))
+	dashboardService = dashboard.NewDashboardService(dashboardRepo.NewDashboardRepository(db), periodService, clock.Default())
+
+	// 累计核销事件
+	swipes, _ := events.Default().Subscribe()
+	go func() {
+		for event := range swipes {
+			dashboardService.Record(event)
+		}
+	}()
+}
+
+// GetSnapshotHandler 查询食堂当前餐次看板处理器
+func GetSnapshotHandler(c *gin.Context) {
+	snapshot, err := dashboardService.Snapshot(c.Query("canteen"))
+	if err != nil {
+		log.Printf("查询实时看板失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "请求成功",
+		"data":    snapshot,
+	})
+}
+
+// StreamHandler 以 Server-Sent Events 推送实时看板：连接后及有刷卡时推送 snapshot 事件，每次刷卡推送 swipe 事件
+func StreamHandler(c *gin.Context) {
+	canteen := c.Query("canteen")
+	snapshot, err := dashboardService.Snapshot(canteen)
+	if err != nil {
+		log.Printf("查询实时看板失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	swipes, cancel := events.Default().Subscribe()
+	defer cancel()
+
+	// 长连接不受服务端写超时限制
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("取消看板连接写超时失败: %v", err)
+	}
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.SSEvent("snapshot", snapshot)
+	c.Writer.Flush()
+
+	ticker := time.NewTicker(snapshotInterval)
+	defer ticker.Stop()
+	dirty, lastPush := false, time.Now()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event, ok := <-swipes:
+			if !ok {
+				return false
+			}
+			if event.Canteen == canteen {
+				c.SSEvent("swipe", event)
+				dirty = true
+			}
+			return true
+		case <-ticker.C:
+			if !dirty && time.Since(lastPush) < refreshInterval {
+				return true
+			}
+			snapshot, err := dashboardService.Snapshot(canteen)
+			if err != nil {
+				log.Printf("刷新实时看板失败: %v", err)
+				return true
+			}
+			c.SSEvent("snapshot", snapshot)
+			dirty, lastPush = false, time.Now()
+			return true
+		}
+	})
+}
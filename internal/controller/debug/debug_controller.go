@@ -0,0 +1,65 @@
+package debug
+
+import (
+	"canteen/internal/infrastructure/clock"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setTimeRequest 设置调试时间请求
+type setTimeRequest struct {
+	Time string `json:"time"` // 格式 2006-01-02 15:04:05
+}
+
+// GetTimeHandler 获取当前业务时间处理器
+func GetTimeHandler(c *gin.Context) {
+	respondClock(c, "请求成功")
+}
+
+// SetTimeHandler 设置业务时间处理器，设置后时间从该时刻继续流逝
+func SetTimeHandler(c *gin.Context) {
+	var req setTimeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	t, err := time.ParseInLocation("2006-01-02 15:04:05", req.Time, time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "时间格式错误，请使用 yyyy-MM-dd HH:mm:ss 格式",
+		})
+		return
+	}
+
+	clock.Default().Set(t)
+	log.Printf("调试: 业务时间已设置为 %s, 操作IP: %s", req.Time, c.ClientIP())
+	respondClock(c, "设置成功")
+}
+
+// ResetTimeHandler 恢复系统时间处理器
+func ResetTimeHandler(c *gin.Context) {
+	clock.Default().Reset()
+	log.Printf("调试: 业务时间已恢复为系统时间, 操作IP: %s", c.ClientIP())
+	respondClock(c, "已恢复系统时间")
+}
+
+func respondClock(c *gin.Context, message string) {
+	clk := clock.Default()
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": message,
+		"data": gin.H{
+			"now":        clk.Now().Format("2006-01-02 15:04:05"),
+			"systemTime": time.Now().Format("2006-01-02 15:04:05"),
+			"offset":     clk.Offset().String(),
+		},
+	})
+}
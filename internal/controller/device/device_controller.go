@@ -0,0 +1,198 @@
+package device
+
+import (
+	"canteen/internal/model"
+	deviceRepo "canteen/internal/repository/device"
+	"canteen/internal/service/device"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	db            *sql.DB
+	deviceService device.DeviceService
+)
+
+func SetDB(database *sql.DB) {
+	db = database
+
+	// 初始化repository
+	deviceRepository := deviceRepo.NewDeviceRepository(db)
+
+	// 初始化service
+	deviceService = device.NewDeviceService(deviceRepository)
+}
+
+// deviceRequest 设备新增/修改请求
+type deviceRequest struct {
+	SerialNo  string   `json:"serialNo"`
+	Window    string   `json:"window"`
+	Canteen   string   `json:"canteen"`
+	MealTypes []string `json:"mealTypes"`
+	Enabled   *bool    `json:"enabled"`
+	Remark    string   `json:"remark"`
+}
+
+func (r *deviceRequest) toDevice() *model.Device {
+	enabled := true
+	if r.Enabled != nil {
+		enabled = *r.Enabled
+	}
+	return &model.Device{
+		SerialNo:  r.SerialNo,
+		Window:    r.Window,
+		Canteen:   r.Canteen,
+		MealTypes: r.MealTypes,
+		Enabled:   enabled,
+		Remark:    r.Remark,
+	}
+}
+
+// GetDevicesHandler 获取设备列表处理器
+func GetDevicesHandler(c *gin.Context) {
+	devices, err := deviceService.ListDevices()
+	if err != nil {
+		log.Printf("查询设备列表失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  500,
+			"message": "查询设备列表失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "请求成功",
+		"data":    devices,
+	})
+}
+
+// GetDeviceHandler 获取设备详情处理器
+func GetDeviceHandler(c *gin.Context) {
+	id, ok := parseDeviceId(c)
+	if !ok {
+		return
+	}
+
+	d, err := deviceService.GetDevice(id)
+	if err != nil {
+		respondDeviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "请求成功",
+		"data":    d,
+	})
+}
+
+// CreateDeviceHandler 新增设备处理器
+func CreateDeviceHandler(c *gin.Context) {
+	var req deviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	d := req.toDevice()
+	if err := deviceService.CreateDevice(d); err != nil {
+		respondDeviceError(c, err)
+		return
+	}
+
+	log.Printf("新增设备: %+v", d)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "新增成功",
+		"data":    d,
+	})
+}
+
+// UpdateDeviceHandler 修改设备处理器
+func UpdateDeviceHandler(c *gin.Context) {
+	id, ok := parseDeviceId(c)
+	if !ok {
+		return
+	}
+
+	var req deviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	d := req.toDevice()
+	d.Id = id
+	if err := deviceService.UpdateDevice(d); err != nil {
+		respondDeviceError(c, err)
+		return
+	}
+
+	log.Printf("修改设备: %+v", d)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "修改成功",
+		"data":    d,
+	})
+}
+
+// DeleteDeviceHandler 删除设备处理器
+func DeleteDeviceHandler(c *gin.Context) {
+	id, ok := parseDeviceId(c)
+	if !ok {
+		return
+	}
+
+	if err := deviceService.DeleteDevice(id); err != nil {
+		respondDeviceError(c, err)
+		return
+	}
+
+	log.Printf("删除设备: id=%d", id)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "删除成功",
+	})
+}
+
+// parseDeviceId 解析路径中的设备ID
+func parseDeviceId(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "设备ID格式错误",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+// respondDeviceError 根据错误类型返回响应
+func respondDeviceError(c *gin.Context, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  404,
+			"message": "设备不存在",
+		})
+		return
+	}
+
+	log.Printf("设备操作失败: %v", err)
+	c.JSON(http.StatusBadRequest, gin.H{
+		"status":  400,
+		"message": err.Error(),
+	})
+}
@@ -0,0 +1,312 @@
+package dish
+
+import (
+	"canteen/internal/model"
+	dishRepo "canteen/internal/repository/dish"
+	"canteen/internal/service/dish"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	db          *sql.DB
+	dishService dish.DishService
+)
+
+func SetDB(database *sql.DB) {
+	db = database
+
+	// 初始化service
+	dishService = dish.NewDishService(dishRepo.NewDishRepository(db))
+}
+
+// dishRequest 菜品新增/修改请求
+type dishRequest struct {
+	Name        string `json:"name"`
+	CategoryId  int    `json:"categoryId"`
+	Code        string `json:"code"`
+	Description string `json:"description"`
+	Image       string `json:"image"`
+	Status      string `json:"status"`
+	Sort        int    `json:"sort"`
+	model.DishTags
+}
+
+func (r *dishRequest) toDish() *model.Dish {
+	return &model.Dish{
+		Name:        r.Name,
+		CategoryId:  r.CategoryId,
+		Code:        r.Code,
+		Description: r.Description,
+		Image:       r.Image,
+		Status:      r.Status,
+		Sort:        r.Sort,
+		DishTags:    r.DishTags,
+	}
+}
+
+// GetDishesHandler 分页查询菜品处理器，可按 keyword、categoryId、status 过滤
+func GetDishesHandler(c *gin.Context) {
+	categoryId, _ := strconv.Atoi(c.Query("categoryId"))
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "50"))
+
+	result, err := dishService.ListDishes(model.DishQuery{
+		Keyword:    c.Query("keyword"),
+		CategoryId: categoryId,
+		Status:     c.Query("status"),
+		Page:       page,
+		PageSize:   pageSize,
+	})
+	if err != nil {
+		log.Printf("查询菜品失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  500,
+			"message": "查询菜品失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "请求成功",
+		"data":    result,
+	})
+}
+
+// GetDishHandler 获取菜品详情处理器
+func GetDishHandler(c *gin.Context) {
+	id, ok := parseId(c, "菜品ID格式错误")
+	if !ok {
+		return
+	}
+
+	d, err := dishService.GetDish(id)
+	if err != nil {
+		respondDishError(c, err, "菜品不存在")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "请求成功",
+		"data":    d,
+	})
+}
+
+// CreateDishHandler 新增菜品处理器
+func CreateDishHandler(c *gin.Context) {
+	var req dishRequest
+	if !bindRequest(c, &req) {
+		return
+	}
+
+	d := req.toDish()
+	if err := dishService.CreateDish(d); err != nil {
+		respondDishError(c, err, "菜品不存在")
+		return
+	}
+
+	log.Printf("新增菜品: %+v", d)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "新增成功",
+		"data":    d,
+	})
+}
+
+// UpdateDishHandler 修改菜品处理器
+func UpdateDishHandler(c *gin.Context) {
+	id, ok := parseId(c, "菜品ID格式错误")
+	if !ok {
+		return
+	}
+	var req dishRequest
+	if !bindRequest(c, &req) {
+		return
+	}
+
+	d := req.toDish()
+	d.Id = id
+	if err := dishService.UpdateDish(d); err != nil {
+		respondDishError(c, err, "菜品不存在")
+		return
+	}
+
+	log.Printf("修改菜品: %+v", d)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "修改成功",
+		"data":    d,
+	})
+}
+
+// DeleteDishHandler 删除菜品处理器
+func DeleteDishHandler(c *gin.Context) {
+	id, ok := parseId(c, "菜品ID格式错误")
+	if !ok {
+		return
+	}
+
+	if err := dishService.DeleteDish(id); err != nil {
+		respondDishError(c, err, "菜品不存在")
+		return
+	}
+
+	log.Printf("删除菜品: id=%d", id)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "删除成功",
+	})
+}
+
+// GetDishCategoriesHandler 获取菜品分类列表处理器
+func GetDishCategoriesHandler(c *gin.Context) {
+	categories, err := dishService.ListCategories()
+	if err != nil {
+		log.Printf("查询菜品分类失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  500,
+			"message": "查询菜品分类失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "请求成功",
+		"data":    categories,
+	})
+}
+
+// GetDishCategoryHandler 获取菜品分类详情处理器
+func GetDishCategoryHandler(c *gin.Context) {
+	id, ok := parseId(c, "分类ID格式错误")
+	if !ok {
+		return
+	}
+
+	category, err := dishService.GetCategory(id)
+	if err != nil {
+		respondDishError(c, err, "分类不存在")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "请求成功",
+		"data":    category,
+	})
+}
+
+// CreateDishCategoryHandler 新增菜品分类处理器
+func CreateDishCategoryHandler(c *gin.Context) {
+	var category model.DishCategory
+	if !bindRequest(c, &category) {
+		return
+	}
+
+	category.Id = 0
+	if err := dishService.CreateCategory(&category); err != nil {
+		respondDishError(c, err, "分类不存在")
+		return
+	}
+
+	log.Printf("新增菜品分类: %+v", category)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "新增成功",
+		"data":    category,
+	})
+}
+
+// UpdateDishCategoryHandler 修改菜品分类处理器
+func UpdateDishCategoryHandler(c *gin.Context) {
+	id, ok := parseId(c, "分类ID格式错误")
+	if !ok {
+		return
+	}
+	var category model.DishCategory
+	if !bindRequest(c, &category) {
+		return
+	}
+
+	category.Id = id
+	if err := dishService.UpdateCategory(&category); err != nil {
+		respondDishError(c, err, "分类不存在")
+		return
+	}
+
+	log.Printf("修改菜品分类: %+v", category)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "修改成功",
+		"data":    category,
+	})
+}
+
+// DeleteDishCategoryHandler 删除菜品分类处理器
+func DeleteDishCategoryHandler(c *gin.Context) {
+	id, ok := parseId(c, "分类ID格式错误")
+	if !ok {
+		return
+	}
+
+	if err := dishService.DeleteCategory(id); err != nil {
+		respondDishError(c, err, "分类不存在")
+		return
+	}
+
+	log.Printf("删除菜品分类: id=%d", id)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "删除成功",
+	})
+}
+
+// bindRequest 绑定 JSON 请求体，失败时返回 400
+func bindRequest(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+// parseId 解析路径中的ID
+func parseId(c *gin.Context, message string) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": message,
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+// respondDishError 根据错误类型返回响应
+func respondDishError(c *gin.Context, err error, notFound string) {
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  404,
+			"message": notFound,
+		})
+		return
+	}
+
+	log.Printf("菜品操作失败: %v", err)
+	c.JSON(http.StatusBadRequest, gin.H{
+		"status":  400,
+		"message": err.Error(),
+	})
+}
@@ -0,0 +1,203 @@
+package meal_period
+
+import (
+	"canteen/internal/model"
+	calendarRepo "canteen/internal/repository/calendar"
+	periodRepo "canteen/internal/repository/meal_period"
+	"canteen/internal/service/meal_period"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	db                *sql.DB
+	mealPeriodService meal_period.MealPeriodService
+)
+
+func SetDB(database *sql.DB) {
+	db = database
+
+	// 初始化repository
+	mealPeriodRepository := periodRepo.NewMealPeriodRepository(db)
+
+	// 初始化service
+	mealPeriodService = meal_period.NewMealPeriodService(mealPeriodRepository, calendarRepo.NewCalendarRepository(db))
+}
+
+// mealPeriodRequest 餐次新增/修改请求
+type mealPeriodRequest struct {
+	Canteen   string `json:"canteen"`
+	Name      string `json:"name"`
+	Code      string `json:"code"`
+	StartTime string `json:"startTime"`
+	EndTime   string `json:"endTime"`
+	Weekdays  []int  `json:"weekdays"`
+	Sort      int    `json:"sort"`
+	Enabled   *bool  `json:"enabled"`
+}
+
+func (r *mealPeriodRequest) toMealPeriod() *model.MealPeriod {
+	enabled := true
+	if r.Enabled != nil {
+		enabled = *r.Enabled
+	}
+	return &model.MealPeriod{
+		Canteen:   r.Canteen,
+		Name:      r.Name,
+		Code:      r.Code,
+		StartTime: r.StartTime,
+		EndTime:   r.EndTime,
+		Weekdays:  r.Weekdays,
+		Sort:      r.Sort,
+		Enabled:   enabled,
+	}
+}
+
+// GetMealPeriodsHandler 获取餐次列表处理器
+func GetMealPeriodsHandler(c *gin.Context) {
+	periods, err := mealPeriodService.ListPeriods(c.Query("canteen"))
+	if err != nil {
+		log.Printf("查询餐次列表失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  500,
+			"message": "查询餐次列表失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "请求成功",
+		"data":    periods,
+	})
+}
+
+// GetMealPeriodHandler 获取餐次详情处理器
+func GetMealPeriodHandler(c *gin.Context) {
+	id, ok := parseMealPeriodId(c)
+	if !ok {
+		return
+	}
+
+	p, err := mealPeriodService.GetPeriod(id)
+	if err != nil {
+		respondMealPeriodError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "请求成功",
+		"data":    p,
+	})
+}
+
+// CreateMealPeriodHandler 新增餐次处理器
+func CreateMealPeriodHandler(c *gin.Context) {
+	var req mealPeriodRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	p := req.toMealPeriod()
+	if err := mealPeriodService.CreatePeriod(p); err != nil {
+		respondMealPeriodError(c, err)
+		return
+	}
+
+	log.Printf("新增餐次: %+v", p)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "新增成功",
+		"data":    p,
+	})
+}
+
+// UpdateMealPeriodHandler 修改餐次处理器
+func UpdateMealPeriodHandler(c *gin.Context) {
+	id, ok := parseMealPeriodId(c)
+	if !ok {
+		return
+	}
+
+	var req mealPeriodRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	p := req.toMealPeriod()
+	p.Id = id
+	if err := mealPeriodService.UpdatePeriod(p); err != nil {
+		respondMealPeriodError(c, err)
+		return
+	}
+
+	log.Printf("修改餐次: %+v", p)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "修改成功",
+		"data":    p,
+	})
+}
+
+// DeleteMealPeriodHandler 删除餐次处理器
+func DeleteMealPeriodHandler(c *gin.Context) {
+	id, ok := parseMealPeriodId(c)
+	if !ok {
+		return
+	}
+
+	if err := mealPeriodService.DeletePeriod(id); err != nil {
+		respondMealPeriodError(c, err)
+		return
+	}
+
+	log.Printf("删除餐次: id=%d", id)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "删除成功",
+	})
+}
+
+// parseMealPeriodId 解析路径中的餐次ID
+func parseMealPeriodId(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "餐次ID格式错误",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+// respondMealPeriodError 根据错误类型返回响应
+func respondMealPeriodError(c *gin.Context, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  404,
+			"message": "餐次不存在",
+		})
+		return
+	}
+
+	log.Printf("餐次操作失败: %v", err)
+	c.JSON(http.StatusBadRequest, gin.H{
+		"status":  400,
+		"message": err.Error(),
+	})
+}
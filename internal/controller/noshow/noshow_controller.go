@@ -0,0 +1,110 @@
+package noshow
+
+import (
+	"canteen/internal/infrastructure/clock"
+	noShowRepo "canteen/internal/repository/noshow"
+	"canteen/internal/service/noshow"
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	db            *sql.DB
+	noShowService noshow.NoShowService
+)
+
+func SetDB(database *sql.DB) {
+	db = database
+
+	// 初始化service
+	noShowService = noshow.NewNoShowService(noShowRepo.NewNoShowRepository(db), clock.Default())
+}
+
+// GetNoShowReportHandler 查询爽约报表处理器，startDate/endDate 为 yyyyMMdd
+func GetNoShowReportHandler(c *gin.Context) {
+	report, err := noShowService.GetReport(c.Query("startDate"), c.Query("endDate"))
+	if err != nil {
+		log.Printf("统计爽约报表失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "请求成功",
+		"data":    report,
+	})
+}
+
+// ExportNoShowReportHandler 导出爽约报表 Excel 处理器
+func ExportNoShowReportHandler(c *gin.Context) {
+	startDate, endDate := c.Query("startDate"), c.Query("endDate")
+	file, err := noShowService.ExportReport(startDate, endDate)
+	if err != nil {
+		log.Printf("导出爽约报表失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "导出失败: " + err.Error(),
+		})
+		return
+	}
+	defer file.Close()
+
+	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	c.Header("Content-Disposition", "attachment; filename=no-show-"+startDate+"-"+endDate+".xlsx")
+	if err := file.Write(c.Writer); err != nil {
+		log.Printf("写入爽约报表失败: %v", err)
+	}
+}
+
+// GetBlocksHandler 查询当前暂停报餐人员处理器
+func GetBlocksHandler(c *gin.Context) {
+	blocks, err := noShowService.GetActiveBlocks()
+	if err != nil {
+		log.Printf("查询暂停报餐记录失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "请求成功",
+		"data":    blocks,
+	})
+}
+
+// LiftBlockHandler 提前解除暂停报餐处理器
+func LiftBlockHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "记录ID格式错误",
+		})
+		return
+	}
+
+	if err := noShowService.LiftBlock(id); err != nil {
+		log.Printf("解除暂停报餐失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "解除成功",
+	})
+}
@@ -0,0 +1,94 @@
+package offline
+
+import (
+	offlineRepo "canteen/internal/repository/offline"
+	"canteen/internal/service/offline"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	db             *sql.DB
+	offlineService offline.OfflineService
+)
+
+func SetDB(database *sql.DB) {
+	db = database
+
+	// 初始化repository
+	offlineRepository := offlineRepo.NewOfflineRepository(db)
+
+	// 初始化service
+	offlineService = offline.NewOfflineService(offlineRepository)
+}
+
+// GetReconciliationsHandler 获取脱机对账列表处理器
+func GetReconciliationsHandler(c *gin.Context) {
+	records, err := offlineService.ListReconciliations(c.Query("status"))
+	if err != nil {
+		log.Printf("查询脱机对账列表失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  500,
+			"message": "查询脱机对账列表失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "请求成功",
+		"data":    records,
+	})
+}
+
+// ResolveReconciliationHandler 处理脱机对账记录处理器
+func ResolveReconciliationHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "记录ID格式错误",
+		})
+		return
+	}
+
+	var req struct {
+		Operator string `json:"operator"`
+		Remark   string `json:"remark"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	record, err := offlineService.ResolveReconciliation(id, req.Operator, req.Remark)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"status":  404,
+				"message": "待对账记录不存在",
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	log.Printf("脱机对账记录已处理: id=%d, 处理人=%s", id, req.Operator)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "处理成功",
+		"data":    record,
+	})
+}
@@ -0,0 +1,311 @@
+package recharge
+
+import (
+	"canteen/internal/infrastructure/clock"
+	"canteen/internal/model"
+	rechargeRepo "canteen/internal/repository/recharge"
+	userRepo "canteen/internal/repository/user"
+	"canteen/internal/service/recharge"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	db              *sql.DB
+	rechargeService recharge.RechargeService
+)
+
+func SetDB(database *sql.DB) {
+	db = database
+
+	// 初始化repository
+	rechargeRepository := rechargeRepo.NewRechargeRepository(db)
+	userRepository := userRepo.NewUserRepository(db)
+
+	// 初始化service
+	rechargeService = recharge.NewRechargeService(rechargeRepository, userRepository, clock.Default())
+}
+
+// RechargeHandler 为单个用户或整个部门充值处理器
+func RechargeHandler(c *gin.Context) {
+	var req model.RechargeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	batch, err := rechargeService.Recharge(req)
+	if err != nil {
+		respondRechargeError(c, err, "用户不存在")
+		return
+	}
+
+	respondBatch(c, batch)
+}
+
+// RechargeSheetHandler 按上传的 Excel 名单充值处理器
+func RechargeSheetHandler(c *gin.Context) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "获取文件失败: " + err.Error(),
+		})
+		return
+	}
+	src, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  500,
+			"message": "打开文件失败: " + err.Error(),
+		})
+		return
+	}
+	defer src.Close()
+
+	amount, _ := strconv.Atoi(c.PostForm("amount"))
+	dryRun, _ := strconv.ParseBool(c.PostForm("dryRun"))
+	req := model.RechargeRequest{
+		Mode:     c.PostForm("mode"),
+		Amount:   amount,
+		Operator: c.PostForm("operator"),
+		Remark:   c.PostForm("remark"),
+		DryRun:   dryRun,
+	}
+
+	batch, err := rechargeService.RechargeSheet(src, req)
+	if err != nil {
+		respondRechargeError(c, err, "用户不存在")
+		return
+	}
+
+	respondBatch(c, batch)
+}
+
+// GetAllotmentRulesHandler 获取月度配额规则列表处理器
+func GetAllotmentRulesHandler(c *gin.Context) {
+	rules, err := rechargeService.ListRules()
+	if err != nil {
+		log.Printf("查询月度配额规则失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  500,
+			"message": "查询月度配额规则失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "请求成功",
+		"data":    rules,
+	})
+}
+
+// GetAllotmentRuleHandler 获取月度配额规则详情处理器
+func GetAllotmentRuleHandler(c *gin.Context) {
+	id, ok := parseRuleId(c)
+	if !ok {
+		return
+	}
+
+	rule, err := rechargeService.GetRule(id)
+	if err != nil {
+		respondRechargeError(c, err, "规则不存在")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "请求成功",
+		"data":    rule,
+	})
+}
+
+// allotmentRuleRequest 月度配额规则新增/修改请求
+type allotmentRuleRequest struct {
+	Name       string `json:"name"`
+	DeptId     int    `json:"deptId"`
+	Mode       string `json:"mode"`
+	Amount     int    `json:"amount"`
+	DayOfMonth int    `json:"dayOfMonth"`
+	Enabled    *bool  `json:"enabled"`
+	Remark     string `json:"remark"`
+}
+
+func (r *allotmentRuleRequest) toRule() model.AllotmentRule {
+	enabled := true
+	if r.Enabled != nil {
+		enabled = *r.Enabled
+	}
+	return model.AllotmentRule{
+		Name:       r.Name,
+		DeptId:     r.DeptId,
+		Mode:       r.Mode,
+		Amount:     r.Amount,
+		DayOfMonth: r.DayOfMonth,
+		Enabled:    enabled,
+		Remark:     r.Remark,
+	}
+}
+
+// CreateAllotmentRuleHandler 新增月度配额规则处理器
+func CreateAllotmentRuleHandler(c *gin.Context) {
+	var req allotmentRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	rule, err := rechargeService.CreateRule(req.toRule())
+	if err != nil {
+		respondRechargeError(c, err, "规则不存在")
+		return
+	}
+
+	log.Printf("新增月度配额规则: %+v", rule)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "新增成功",
+		"data":    rule,
+	})
+}
+
+// UpdateAllotmentRuleHandler 修改月度配额规则处理器
+func UpdateAllotmentRuleHandler(c *gin.Context) {
+	id, ok := parseRuleId(c)
+	if !ok {
+		return
+	}
+
+	var req allotmentRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	rule := req.toRule()
+	rule.Id = id
+	updated, err := rechargeService.UpdateRule(rule)
+	if err != nil {
+		respondRechargeError(c, err, "规则不存在")
+		return
+	}
+
+	log.Printf("修改月度配额规则: %+v", updated)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "修改成功",
+		"data":    updated,
+	})
+}
+
+// DeleteAllotmentRuleHandler 删除月度配额规则处理器
+func DeleteAllotmentRuleHandler(c *gin.Context) {
+	id, ok := parseRuleId(c)
+	if !ok {
+		return
+	}
+
+	if err := rechargeService.DeleteRule(id); err != nil {
+		respondRechargeError(c, err, "规则不存在")
+		return
+	}
+
+	log.Printf("删除月度配额规则: id=%d", id)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "删除成功",
+	})
+}
+
+// RunAllotmentRuleHandler 手动执行月度配额规则处理器
+func RunAllotmentRuleHandler(c *gin.Context) {
+	id, ok := parseRuleId(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Operator string `json:"operator"`
+		DryRun   bool   `json:"dryRun"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+	if req.Operator == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "操作人不能为空",
+		})
+		return
+	}
+
+	batch, err := rechargeService.RunRule(id, req.Operator, req.DryRun)
+	if err != nil {
+		respondRechargeError(c, err, "规则不存在")
+		return
+	}
+
+	respondBatch(c, batch)
+}
+
+// respondBatch 返回逐行充值结果
+func respondBatch(c *gin.Context, batch *model.RechargeBatch) {
+	message := "充值完成"
+	if batch.DryRun {
+		message = "预览成功"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": message,
+		"data":    batch,
+	})
+}
+
+// parseRuleId 解析路径中的规则ID
+func parseRuleId(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "规则ID格式错误",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+// respondRechargeError 根据错误类型返回响应
+func respondRechargeError(c *gin.Context, err error, notFound string) {
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  404,
+			"message": notFound,
+		})
+		return
+	}
+
+	log.Printf("充值操作失败: %v", err)
+	c.JSON(http.StatusBadRequest, gin.H{
+		"status":  400,
+		"message": err.Error(),
+	})
+}
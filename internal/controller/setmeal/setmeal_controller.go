@@ -0,0 +1,161 @@
+package setmeal
+
+import (
+	"canteen/internal/model"
+	dishRepo "canteen/internal/repository/dish"
+	setmealRepo "canteen/internal/repository/setmeal"
+	"canteen/internal/service/setmeal"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	db             *sql.DB
+	setmealService setmeal.SetmealService
+)
+
+func SetDB(database *sql.DB) {
+	db = database
+
+	// 初始化service
+	setmealService = setmeal.NewSetmealService(setmealRepo.NewSetmealRepository(db), dishRepo.NewDishRepository(db))
+}
+
+// GetSetmealHandler 获取套餐及其菜品处理器
+func GetSetmealHandler(c *gin.Context) {
+	id, ok := parseSetmealId(c)
+	if !ok {
+		return
+	}
+
+	meal, err := setmealService.GetSetmeal(id)
+	if err != nil {
+		respondSetmealError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "请求成功",
+		"data":    meal,
+	})
+}
+
+// CreateSetmealHandler 为周套餐新建套餐处理器
+func CreateSetmealHandler(c *gin.Context) {
+	var req model.SetmealRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	meal, err := setmealService.CreateSetmeal(req)
+	if err != nil {
+		respondSetmealError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "新增成功",
+		"data":    meal,
+	})
+}
+
+// UpdateSetmealHandler 修改套餐名称及菜品处理器
+func UpdateSetmealHandler(c *gin.Context) {
+	id, ok := parseSetmealId(c)
+	if !ok {
+		return
+	}
+
+	var req model.SetmealRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	meal, err := setmealService.UpdateSetmeal(id, req)
+	if err != nil {
+		respondSetmealError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "修改成功",
+		"data":    meal,
+	})
+}
+
+// CloneSetmealHandler 复制套餐到另一个周套餐处理器
+func CloneSetmealHandler(c *gin.Context) {
+	id, ok := parseSetmealId(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		WeeklySetmealId int `json:"weeklySetmealId"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	meal, err := setmealService.CloneSetmeal(id, req.WeeklySetmealId)
+	if err != nil {
+		respondSetmealError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "复制成功",
+		"data":    meal,
+	})
+}
+
+// parseSetmealId 解析路径中的套餐ID
+func parseSetmealId(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "套餐ID格式错误",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+// respondSetmealError 根据错误类型返回响应
+func respondSetmealError(c *gin.Context, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  404,
+			"message": "套餐不存在",
+		})
+		return
+	}
+
+	log.Printf("套餐操作失败: %v", err)
+	c.JSON(http.StatusBadRequest, gin.H{
+		"status":  400,
+		"message": err.Error(),
+	})
+}
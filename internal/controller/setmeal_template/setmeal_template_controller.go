@@ -0,0 +1,238 @@
+package setmeal_template
+
+import (
+	"canteen/internal/infrastructure/cache"
+	"canteen/internal/infrastructure/clock"
+	"canteen/internal/model"
+	calendarRepo "canteen/internal/repository/calendar"
+	deviceRepo "canteen/internal/repository/device"
+	mealRepo "canteen/internal/repository/meal"
+	periodRepo "canteen/internal/repository/meal_period"
+	templateRepo "canteen/internal/repository/setmeal_template"
+	"canteen/internal/service/meal"
+	"canteen/internal/service/meal_period"
+	"canteen/internal/service/setmeal_template"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	db                     *sql.DB
+	setmealTemplateService setmeal_template.SetmealTemplateService
+	mealService            meal.MealService
+)
+
+func SetDB(database *sql.DB) {
+	db = database
+
+	// 初始化repository
+	templateRepository := templateRepo.NewSetmealTemplateRepository(db)
+	mealPeriodRepository := periodRepo.NewMealPeriodRepository(db)
+
+	// 初始化service
+	setmealTemplateService = setmeal_template.NewSetmealTemplateService(templateRepository, mealPeriodRepository)
+	mealPeriodService := meal_period.NewMealPeriodService(mealPeriodRepository, calendarRepo.NewCalendarRepository(db))
+	mealService = meal.NewMealService(mealRepo.NewMealRepository(db), deviceRepo.NewDeviceRepository(db), templateRepository, mealPeriodService, cache.RedisClient(), clock.Default())
+}
+
+// setmealTemplateRequest 套餐模板新增/修改请求
+type setmealTemplateRequest struct {
+	MealPeriodId int    `json:"mealPeriodId"`
+	Weekday      int    `json:"weekday"`
+	Window       string `json:"window"`
+	Sort         int    `json:"sort"`
+	Enabled      *bool  `json:"enabled"`
+	Remark       string `json:"remark"`
+}
+
+func (r *setmealTemplateRequest) toTemplate() *model.SetmealTemplate {
+	enabled := true
+	if r.Enabled != nil {
+		enabled = *r.Enabled
+	}
+	return &model.SetmealTemplate{
+		MealPeriodId: r.MealPeriodId,
+		Weekday:      r.Weekday,
+		Window:       r.Window,
+		Sort:         r.Sort,
+		Enabled:      enabled,
+		Remark:       r.Remark,
+	}
+}
+
+// GetSetmealTemplatesHandler 获取套餐模板列表处理器，可按 mealPeriodId 过滤
+func GetSetmealTemplatesHandler(c *gin.Context) {
+	mealPeriodId, _ := strconv.Atoi(c.Query("mealPeriodId"))
+	templates, err := setmealTemplateService.ListTemplates(mealPeriodId)
+	if err != nil {
+		log.Printf("查询套餐模板失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  500,
+			"message": "查询套餐模板失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "请求成功",
+		"data":    templates,
+	})
+}
+
+// GetSetmealTemplateHandler 获取套餐模板详情处理器
+func GetSetmealTemplateHandler(c *gin.Context) {
+	id, ok := parseTemplateId(c)
+	if !ok {
+		return
+	}
+
+	t, err := setmealTemplateService.GetTemplate(id)
+	if err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "请求成功",
+		"data":    t,
+	})
+}
+
+// CreateSetmealTemplateHandler 新增套餐模板处理器
+func CreateSetmealTemplateHandler(c *gin.Context) {
+	var req setmealTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	t := req.toTemplate()
+	if err := setmealTemplateService.CreateTemplate(t); err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+
+	log.Printf("新增套餐模板: %+v", t)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "新增成功",
+		"data":    t,
+	})
+}
+
+// UpdateSetmealTemplateHandler 修改套餐模板处理器
+func UpdateSetmealTemplateHandler(c *gin.Context) {
+	id, ok := parseTemplateId(c)
+	if !ok {
+		return
+	}
+
+	var req setmealTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	t := req.toTemplate()
+	t.Id = id
+	if err := setmealTemplateService.UpdateTemplate(t); err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+
+	log.Printf("修改套餐模板: %+v", t)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "修改成功",
+		"data":    t,
+	})
+}
+
+// DeleteSetmealTemplateHandler 删除套餐模板处理器
+func DeleteSetmealTemplateHandler(c *gin.Context) {
+	id, ok := parseTemplateId(c)
+	if !ok {
+		return
+	}
+
+	if err := setmealTemplateService.DeleteTemplate(id); err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+
+	log.Printf("删除套餐模板: id=%d", id)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "删除成功",
+	})
+}
+
+// GenerateWeekSetmealsHandler 按套餐模板生成指定周的周套餐处理器
+func GenerateWeekSetmealsHandler(c *gin.Context) {
+	var req struct {
+		Date       string `json:"date"`       // 所在周任意一天 yyyyMMdd，为空表示下周
+		CreateUser int    `json:"createUser"` // 操作人用户ID
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	generation, err := mealService.GenerateWeek(req.Date, req.CreateUser)
+	if err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "生成成功",
+		"data":    generation,
+	})
+}
+
+// parseTemplateId 解析路径中的模板ID
+func parseTemplateId(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "模板ID格式错误",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+// respondTemplateError 根据错误类型返回响应
+func respondTemplateError(c *gin.Context, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  404,
+			"message": "模板不存在",
+		})
+		return
+	}
+
+	log.Printf("套餐模板操作失败: %v", err)
+	c.JSON(http.StatusBadRequest, gin.H{
+		"status":  400,
+		"message": err.Error(),
+	})
+}
@@ -2,12 +2,23 @@ package tempDirect
 
 import (
 	"canteen/internal/model"
+	"canteen/internal/service/dish"
 	"canteen/internal/service/meal"
+	"canteen/internal/service/menu"
 	"canteen/internal/service/order"
+	dishRepo "canteen/internal/repository/dish"
 	mealRepo "canteen/internal/repository/meal"
+	menuRepo "canteen/internal/repository/menu"
+	deviceRepo "canteen/internal/repository/device"
+	periodRepo "canteen/internal/repository/meal_period"
+	calendarRepo "canteen/internal/repository/calendar"
+	templateRepo "canteen/internal/repository/setmeal_template"
+	"canteen/internal/service/meal_period"
 	orderRepo "canteen/internal/repository/order"
 	"canteen/internal/infrastructure/cache"
+	"canteen/internal/infrastructure/clock"
 	"database/sql"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
@@ -19,6 +30,8 @@ import (
 var (
 	db *sql.DB
 	mealService meal.MealService
+	dishService dish.DishService
+	menuService menu.MenuService
 	orderService order.OrderService
 )
 
@@ -28,33 +41,14 @@ func SetDB(database *sql.DB) {
 	// 初始化repositories
 	mealRepository := mealRepo.NewMealRepository(db)
 	orderRepository := orderRepo.NewOrderRepository(db)
+	calendarRepository := calendarRepo.NewCalendarRepository(db)
 	
 	// 初始化services
-	mealService = meal.NewMealService(mealRepository, cache.RedisClient())
-	orderService = order.NewOrderService(orderRepository, nil, cache.RedisClient()) // userRepo设为nil，暂时不使用
-}
-
-// InsertMealSQL 插入餐食SQL
-func InsertMealSQL(c *gin.Context) {
-	var req model.CreateMeal
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"Status": 0, "Msg": "请求参数错误: " + err.Error()})
-		return
-	}
-	
-	if len(req.DishIds) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"Status": 0, "Msg": "菜品列表不能为空"})
-		return
-	}
-	
-	// 使用服务层处理
-	dishes, err := mealService.GetDishesByIds(req.DishIds)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"Status": 0, "Msg": "查询失败: " + err.Error()})
-		return
-	}
-	
-	c.JSON(http.StatusOK, gin.H{"Status": 1, "Msg": "查询成功", "Data": dishes})
+	mealPeriodService := meal_period.NewMealPeriodService(periodRepo.NewMealPeriodRepository(db), calendarRepository)
+	mealService = meal.NewMealService(mealRepository, deviceRepo.NewDeviceRepository(db), templateRepo.NewSetmealTemplateRepository(db), mealPeriodService, cache.RedisClient(), clock.Default())
+	dishService = dish.NewDishService(dishRepo.NewDishRepository(db))
+	menuService = menu.NewMenuService(menuRepo.NewMenuRepository(db), clock.Default())
+	orderService = order.NewOrderService(orderRepository, nil, calendarRepository, cache.RedisClient(), clock.Default()) // userRepo设为nil，暂时不使用
 }
 
 // ExportOrdersByDate 按日期导出订单
@@ -95,16 +89,19 @@ func ExportOrdersByMonth(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"status": 0, "msg": "导出失败: " + err.Error()})
 		return
 	}
+	defer file.Close()
 	
 	// 设置响应头
 	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
 	c.Header("Content-Disposition", "attachment; filename=orders-"+date+".xlsx")
 	
 	// 写入响应
-	file.Write(c.Writer)
+	if err := file.Write(c.Writer); err != nil {
+		log.Printf("写入月度报餐记录失败: %v", err)
+	}
 }
 
-// UploadWeekMenuHandler 上传周菜单
+// UploadWeekMenuHandler 上传周菜单，按 日期+餐别+窗口 写入套餐及菜品；dryRun 为 true 时仅校验预览
 func UploadWeekMenuHandler(c *gin.Context) {
 	// 获取上传的文件
 	file, err := c.FormFile("file")
@@ -121,34 +118,55 @@ func UploadWeekMenuHandler(c *gin.Context) {
 	}
 	defer src.Close()
 	
-	// 使用excelize读取文件
-	xlsx, err := excelize.OpenReader(src)
+	dryRun, _ := strconv.ParseBool(c.PostForm("dryRun"))
+	result, err := menuService.ImportWeekMenu(src, model.MenuImportRequest{
+		Date:   c.PostForm("date"),
+		DryRun: dryRun,
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"status": 0, "msg": "解析Excel文件失败: " + err.Error()})
+		log.Printf("导入周菜单失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"status": 0, "msg": err.Error(), "data": result})
 		return
 	}
 	
-	// 获取第一个工作表
-	sheets := xlsx.GetSheetList()
-	if len(sheets) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"status": 0, "msg": "Excel文件中没有工作表"})
-		return
+	msg := "上传成功"
+	if dryRun {
+		msg = "预览成功"
 	}
-	
-	// 读取数据
-	rows, err := xlsx.GetRows(sheets[0])
+	c.JSON(http.StatusOK, gin.H{"status": 1, "msg": msg, "data": result})
+}
+
+// DownloadWeekMenuTemplateHandler 下载 date 所在周（为空时为下周）的周菜单模板
+func DownloadWeekMenuTemplateHandler(c *gin.Context) {
+	date := c.Query("date")
+	file, err := menuService.BuildTemplate(date)
+	writeWeekMenu(c, "weekmenu-template", date, file, err)
+}
+
+// ExportWeekMenuHandler 按导入格式导出 date 所在周（为空时为下周）已发布的周菜单
+func ExportWeekMenuHandler(c *gin.Context) {
+	date := c.Query("date")
+	file, err := menuService.ExportWeek(date)
+	writeWeekMenu(c, "weekmenu", date, file, err)
+}
+
+func writeWeekMenu(c *gin.Context, prefix, date string, file *excelize.File, err error) {
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"status": 0, "msg": "读取工作表数据失败: " + err.Error()})
+		log.Printf("生成周菜单失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"status": 0, "msg": "导出失败: " + err.Error()})
 		return
 	}
-	
-	// 处理数据（简化处理）
-	log.Printf("读取到 %d 行数据", len(rows))
-	
-	// 这里应该解析Excel数据并插入数据库
-	// 为了简化，我们只是记录日志
-	
-	c.JSON(http.StatusOK, gin.H{"status": 1, "msg": "上传成功"})
+	defer file.Close()
+
+	filename := prefix + ".xlsx"
+	if date != "" {
+		filename = prefix + "-" + date + ".xlsx"
+	}
+	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	if err := file.Write(c.Writer); err != nil {
+		log.Printf("写入周菜单失败: %v", err)
+	}
 }
 
 // DateImport 日期导入
@@ -194,16 +212,15 @@ func DishDetail(c *gin.Context) {
 	}
 	
 	// 使用服务层处理
-	dishes, err := mealService.GetDishesByIds([]int16{int16(id)})
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"status": 0, "msg": "查询失败: " + err.Error()})
+	d, err := dishService.GetDish(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"status": 0, "msg": "菜品不存在"})
 		return
 	}
-	
-	if len(dishes) == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"status": 0, "msg": "菜品不存在"})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": 0, "msg": "查询失败: " + err.Error()})
 		return
 	}
 	
-	c.JSON(http.StatusOK, gin.H{"status": 1, "msg": "查询成功", "data": dishes[0]})
+	c.JSON(http.StatusOK, gin.H{"status": 1, "msg": "查询成功", "data": d})
 }
\ No newline at end of file
@@ -1,9 +1,14 @@
 package user
 
 import (
+	"canteen/internal/infrastructure/auth"
+	"canteen/internal/model"
+	ledgerRepo "canteen/internal/repository/ledger"
 	userRepo "canteen/internal/repository/user"
+	"canteen/internal/service/ledger"
 	"canteen/internal/service/user"
 	"database/sql"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
@@ -12,8 +17,9 @@ import (
 )
 
 var (
-	db          *sql.DB
-	userService user.UserService
+	db            *sql.DB
+	userService   user.UserService
+	ledgerService ledger.LedgerService
 )
 
 func SetDB(database *sql.DB) {
@@ -21,9 +27,11 @@ func SetDB(database *sql.DB) {
 
 	// 初始化repository
 	userRepository := userRepo.NewUserRepository(db)
+	ledgerRepository := ledgerRepo.NewLedgerRepository(db)
 
 	// 初始化service
 	userService = user.NewUserService(userRepository)
+	ledgerService = ledger.NewLedgerService(ledgerRepository, userRepository)
 }
 
 // GetUserHandler 获取用户信息处理器
@@ -99,3 +107,187 @@ func GetUserByNickNameHandler(c *gin.Context) {
 		"data":    user,
 	})
 }
+
+// GetCountLedgerHandler 获取用户次数流水处理器
+func GetCountLedgerHandler(c *gin.Context) {
+	userId, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "用户ID格式错误",
+		})
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "50"))
+
+	result, err := ledgerService.GetUserLedger(userId, c.Query("startDate"), c.Query("endDate"), page, pageSize)
+	if err != nil {
+		respondLedgerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "请求成功",
+		"data":    result,
+	})
+}
+
+// adjustCountRequest 手工调整次数请求
+type adjustCountRequest struct {
+	UserId   int    `json:"userId"`
+	Change   int    `json:"change"`
+	Operator string `json:"operator"`
+	Remark   string `json:"remark"`
+}
+
+// AdjustCountHandler 手工调整用户次数处理器（管理员）
+func AdjustCountHandler(c *gin.Context) {
+	var req adjustCountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	entry, err := ledgerService.AdjustCount(req.UserId, req.Change, req.Operator, req.Remark)
+	if err != nil {
+		respondLedgerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "调整成功",
+		"data":    entry,
+	})
+}
+
+// GetCountDriftsHandler 获取次数流水与余额不一致的用户处理器（管理员）
+func GetCountDriftsHandler(c *gin.Context) {
+	drifts, err := ledgerService.ListDrifts()
+	if err != nil {
+		log.Printf("核对次数流水失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  500,
+			"message": "核对次数流水失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "请求成功",
+		"data":    drifts,
+	})
+}
+
+// reconcileCountRequest 次数对账请求
+type reconcileCountRequest struct {
+	Operator string `json:"operator"`
+	Remark   string `json:"remark"`
+}
+
+// ReconcileCountHandler 以当前次数为准校正用户流水处理器（管理员）
+func ReconcileCountHandler(c *gin.Context) {
+	userId, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "用户ID格式错误",
+		})
+		return
+	}
+
+	var req reconcileCountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	entry, err := ledgerService.ReconcileUser(userId, req.Operator, req.Remark)
+	if err != nil {
+		respondLedgerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "对账成功",
+		"data":    entry,
+	})
+}
+
+// respondLedgerError 根据错误类型返回响应
+func respondLedgerError(c *gin.Context, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  404,
+			"message": "用户不存在",
+		})
+		return
+	}
+
+	log.Printf("次数流水操作失败: %v", err)
+	c.JSON(http.StatusBadRequest, gin.H{
+		"status":  400,
+		"message": err.Error(),
+	})
+}
+
+// LoginHandler 员工登录处理器
+func LoginHandler(c *gin.Context) {
+	var req model.Login
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	result, err := userService.Login(req.User, req.Password)
+	if err != nil {
+		if errors.Is(err, user.ErrBadCredentials) {
+			log.Printf("员工登录失败: user=%s, IP=%s", req.User, c.ClientIP())
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"status":  401,
+				"message": err.Error(),
+			})
+			return
+		}
+		log.Printf("员工登录失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "登录成功",
+		"data":    result,
+	})
+}
+
+// GetProfileHandler 获取当前登录员工信息处理器
+func GetProfileHandler(c *gin.Context) {
+	u, err := userService.FindById(c.GetInt(auth.ContextUserId))
+	if err != nil {
+		respondLedgerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  200,
+		"message": "请求成功",
+		"data":    u,
+	})
+}
@@ -0,0 +1,86 @@
+// Package auth 员工登录令牌的签发与校验
+package auth
+
+import (
+	"canteen/internal/infrastructure/config"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ContextUserId 鉴权通过后写入 gin.Context 的当前用户号
+const ContextUserId = "userId"
+
+// defaultTokenTTL 未配置 auth.token_ttl_hours 时的令牌有效期
+const defaultTokenTTL = 72 * time.Hour
+
+var (
+	ErrInvalidToken = errors.New("登录令牌无效")
+	ErrTokenExpired = errors.New("登录已过期，请重新登录")
+)
+
+// IssueToken 签发令牌，格式为 用户号.过期时间戳.签名
+func IssueToken(userId int, now time.Time) (string, time.Time, error) {
+	secret, err := secretKey()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	ttl := defaultTokenTTL
+	if hours := config.GetInt("auth.token_ttl_hours"); hours > 0 {
+		ttl = time.Duration(hours) * time.Hour
+	}
+	expiry := now.Add(ttl)
+
+	payload := fmt.Sprintf("%d.%d", userId, expiry.Unix())
+	return payload + "." + sign(secret, payload), expiry, nil
+}
+
+// ParseToken 校验令牌签名与有效期，返回用户号
+func ParseToken(token string, now time.Time) (int, error) {
+	secret, err := secretKey()
+	if err != nil {
+		return 0, err
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, ErrInvalidToken
+	}
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(sign(secret, payload))) {
+		return 0, ErrInvalidToken
+	}
+
+	userId, err := strconv.Atoi(parts[0])
+	if err != nil || userId <= 0 {
+		return 0, ErrInvalidToken
+	}
+	expiry, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+	if now.Unix() >= expiry {
+		return 0, ErrTokenExpired
+	}
+	return userId, nil
+}
+
+func secretKey() ([]byte, error) {
+	secret := config.GetString("auth.secret")
+	if secret == "" {
+		return nil, errors.New("未配置 auth.secret，无法使用员工登录")
+	}
+	return []byte(secret), nil
+}
+
+func sign(secret []byte, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
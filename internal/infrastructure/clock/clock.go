@@ -0,0 +1,75 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock 时间来源，业务代码通过注入的 Clock 获取当前时间以便测试与调试
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// System 返回系统时钟
+func System() Clock {
+	return systemClock{}
+}
+
+type fixedClock struct {
+	t time.Time
+}
+
+func (c fixedClock) Now() time.Time {
+	return c.t
+}
+
+// Fixed 返回始终停在 t 的时钟
+func Fixed(t time.Time) Clock {
+	return fixedClock{t: t}
+}
+
+// AdjustableClock 在系统时间基础上叠加偏移量的时钟，设置后时间仍正常流逝
+type AdjustableClock struct {
+	mu     sync.RWMutex
+	offset time.Duration
+}
+
+func (c *AdjustableClock) Now() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return time.Now().Add(c.offset)
+}
+
+// Set 将当前时间调整为 t
+func (c *AdjustableClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.offset = time.Until(t)
+}
+
+// Reset 恢复为系统时间
+func (c *AdjustableClock) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.offset = 0
+}
+
+// Offset 返回当前偏移量
+func (c *AdjustableClock) Offset() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.offset
+}
+
+var defaultClock = &AdjustableClock{}
+
+// Default 返回全局时钟，仅调试接口会对其调整
+func Default() *AdjustableClock {
+	return defaultClock
+}
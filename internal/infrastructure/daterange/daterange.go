@@ -0,0 +1,29 @@
+package daterange
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Validate 校验起止日期为 yyyyMMdd、结束日期不早于开始日期且跨度不超过 maxDays 天
+func Validate(startDate, endDate string, maxDays int) error {
+	if startDate == "" || endDate == "" {
+		return errors.New("开始日期和结束日期不能为空")
+	}
+	start, err := time.Parse("20060102", startDate)
+	if err != nil {
+		return fmt.Errorf("开始日期格式错误: %s，请使用 yyyyMMdd 格式", startDate)
+	}
+	end, err := time.Parse("20060102", endDate)
+	if err != nil {
+		return fmt.Errorf("结束日期格式错误: %s，请使用 yyyyMMdd 格式", endDate)
+	}
+	if end.Before(start) {
+		return errors.New("结束日期不能早于开始日期")
+	}
+	if end.Sub(start).Hours()/24 >= float64(maxDays) {
+		return fmt.Errorf("起止日期跨度不能超过 %d 天", maxDays)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package events
+
+import (
+	"canteen/internal/model"
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// swipeChannel 多实例共享刷卡事件的 Redis 频道
+	swipeChannel = "canteen:swipe_events"
+	// subscriberBuffer 每个订阅者的缓冲区大小，写满后丢弃新事件
+	subscriberBuffer = 64
+	// outboxBuffer 待转发到 Redis 的事件缓冲区大小，写满后仅分发给本实例
+	outboxBuffer = 256
+	// relayTimeout 单次发布到 Redis 的超时时间
+	relayTimeout = 2 * time.Second
+)
+
+// Bus 刷卡事件总线，发布不阻塞核销，订阅者处理不及时时丢弃事件
+type Bus struct {
+	mu     sync.RWMutex
+	subs   map[chan model.SwipeEvent]struct{}
+	outbox chan model.SwipeEvent // 启用 Redis 转发时非空，由后台协程发布
+}
+
+func NewBus() *Bus {
+	return &Bus{subs: map[chan model.SwipeEvent]struct{}{}}
+}
+
+var defaultBus = NewBus()
+
+// Default 返回全局事件总线
+func Default() *Bus {
+	return defaultBus
+}
+
+// Publish 发布事件；启用 Redis 转发时交由后台协程发布到频道，由各实例统一分发，
+// 转发队列已满或发布失败时仅分发给本实例
+func (b *Bus) Publish(event model.SwipeEvent) {
+	b.mu.RLock()
+	outbox := b.outbox
+	b.mu.RUnlock()
+
+	if outbox != nil {
+		select {
+		case outbox <- event:
+			return
+		default:
+			log.Printf("刷卡事件转发队列已满，仅分发给本实例")
+		}
+	}
+	b.dispatch(event)
+}
+
+// Subscribe 订阅事件，调用返回的函数取消订阅并关闭通道
+func (b *Bus) Subscribe() (<-chan model.SwipeEvent, func()) {
+	ch := make(chan model.SwipeEvent, subscriberBuffer)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, ch)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// EnableRedisRelay 通过 Redis pub/sub 在多个实例间共享事件，ctx 结束时停止
+func (b *Bus) EnableRedisRelay(ctx context.Context, client *redis.Client) {
+	pubsub := client.Subscribe(ctx, swipeChannel)
+	outbox := make(chan model.SwipeEvent, outboxBuffer)
+	b.mu.Lock()
+	b.outbox = outbox
+	b.mu.Unlock()
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				b.mu.Lock()
+				b.outbox = nil
+				b.mu.Unlock()
+				pubsub.Close()
+				// 未转发的事件分发给本实例
+				for {
+					select {
+					case event := <-outbox:
+						b.dispatch(event)
+					default:
+						return
+					}
+				}
+			case event := <-outbox:
+				b.relay(ctx, client, event)
+			}
+		}
+	}()
+	go func() {
+		for msg := range pubsub.Channel() {
+			var event model.SwipeEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				log.Printf("解析刷卡事件失败: %v", err)
+				continue
+			}
+			b.dispatch(event)
+		}
+	}()
+}
+
+// relay 发布事件到 Redis 频道，失败时仅分发给本实例
+func (b *Bus) relay(ctx context.Context, client *redis.Client, event model.SwipeEvent) {
+	data, err := json.Marshal(event)
+	if err == nil {
+		publishCtx, cancel := context.WithTimeout(ctx, relayTimeout)
+		err = client.Publish(publishCtx, swipeChannel, data).Err()
+		cancel()
+	}
+	if err != nil {
+		log.Printf("发布刷卡事件到 Redis 失败: %v", err)
+		b.dispatch(event)
+	}
+}
+
+func (b *Bus) dispatch(event model.SwipeEvent) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for ch := range b.subs {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
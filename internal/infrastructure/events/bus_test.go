@@ -0,0 +1,76 @@
+package events
+
+import (
+	"canteen/internal/model"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+func TestBusDispatchesToSubscribers(t *testing.T) {
+	bus := NewBus()
+	first, cancelFirst := bus.Subscribe()
+	second, cancelSecond := bus.Subscribe()
+	defer cancelSecond()
+
+	bus.Publish(model.SwipeEvent{Window: "A", Result: model.SwipeResultCollected})
+	for _, ch := range []<-chan model.SwipeEvent{first, second} {
+		if e := <-ch; e.Window != "A" || e.Result != model.SwipeResultCollected {
+			t.Errorf("收到事件 = %+v", e)
+		}
+	}
+
+	// 取消后通道关闭，不再收到事件
+	cancelFirst()
+	cancelFirst()
+	bus.Publish(model.SwipeEvent{Window: "B"})
+	if _, ok := <-first; ok {
+		t.Errorf("取消订阅后通道应关闭")
+	}
+	if e := <-second; e.Window != "B" {
+		t.Errorf("收到事件 = %+v", e)
+	}
+}
+
+func TestBusDropsWhenSubscriberIsFull(t *testing.T) {
+	bus := NewBus()
+	ch, cancel := bus.Subscribe()
+	defer cancel()
+
+	// 订阅者未读取时发布不阻塞，超出缓冲区的事件被丢弃
+	for i := 0; i < subscriberBuffer+10; i++ {
+		bus.Publish(model.SwipeEvent{Name: "张三"})
+	}
+	if len(ch) != subscriberBuffer {
+		t.Errorf("缓冲事件数 = %d, 期望 %d", len(ch), subscriberBuffer)
+	}
+}
+
+func TestBusRelayFailureDoesNotBlockPublish(t *testing.T) {
+	bus := NewBus()
+	ch, cancel := bus.Subscribe()
+	defer cancel()
+
+	// 无法连接的 Redis：发布立即返回，转发失败后回退为本实例分发
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	defer client.Close()
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	bus.EnableRedisRelay(ctx, client)
+
+	start := time.Now()
+	bus.Publish(model.SwipeEvent{Window: "A"})
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("Publish 耗时 %v，不应等待 Redis", elapsed)
+	}
+	select {
+	case e := <-ch:
+		if e.Window != "A" {
+			t.Errorf("收到事件 = %+v", e)
+		}
+	case <-time.After(relayTimeout + time.Second):
+		t.Fatalf("转发失败后未分发给本实例")
+	}
+}
@@ -0,0 +1,44 @@
+package model
+
+// StatsFilter 统计筛选条件，日期为 yyyyMMdd，其余为空表示不限
+type StatsFilter struct {
+	StartDate string
+	EndDate   string
+	DeptId    int    // 下单用户当前所在部门
+	MealType  string // 餐别
+	Window    string // 窗口，如 C
+	Status    string // 订单状态，为空时不含已取消、已作废
+}
+
+// StatItem 统计项，套餐统计时 Id 为 0
+type StatItem struct {
+	Id         int    `json:"id,omitempty"`
+	Name       string `json:"name"`
+	CategoryId int    `json:"categoryId,omitempty"`
+	Count      int    `json:"count"`
+}
+
+// Stats 统计结果，Items 按次数降序
+type Stats struct {
+	StartDate string     `json:"startDate"` // yyyy-MM-dd
+	EndDate   string     `json:"endDate"`
+	Total     int        `json:"total"`
+	Items     []StatItem `json:"items"`
+}
+
+// DishComparisonItem 菜品上菜次数与点餐次数对比
+type DishComparisonItem struct {
+	DishId          int     `json:"dishId"`
+	DishName        string  `json:"dishName"`
+	CategoryId      int     `json:"categoryId"`
+	AppearanceCount int     `json:"appearanceCount"` // 在周套餐中出现的次数
+	OrderCount      int     `json:"orderCount"`      // 点餐次数
+	Ratio           float64 `json:"ratio"`           // 点餐次数/出现次数
+}
+
+// DishComparison 菜品对比结果，Items 按比值降序
+type DishComparison struct {
+	StartDate string               `json:"startDate"`
+	EndDate   string               `json:"endDate"`
+	Items     []DishComparisonItem `json:"items"`
+}
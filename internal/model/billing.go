@@ -0,0 +1,69 @@
+package model
+
+// GuestDeptId 客户（访客）所在部门，刷卡时直接生成临时订单，结算按客户单价
+const GuestDeptId = 219
+
+// 结算用户类型
+const (
+	BillingUserStaff = "员工"
+	BillingUserGuest = "客户"
+)
+
+// BillingCount 某用户某餐别某状态的计费订单数
+type BillingCount struct {
+	UserId   int
+	WorkNo   string
+	Name     string
+	DeptId   int
+	DeptName string
+	MealType string
+	Status   string
+	Count    int
+}
+
+// BillingItem 部门账单中一个餐别、用户类型的结算行
+type BillingItem struct {
+	MealType  string  `json:"mealType"`
+	UserType  string  `json:"userType"`  // 员工 / 客户
+	UnitPrice float64 `json:"unitPrice"` // 单价（元），未配置时为 0
+	Collected int     `json:"collected"` // 已领取
+	Temp      int     `json:"temp"`      // 临时用餐
+	Expired   int     `json:"expired"`   // 已过期（报餐未领取，照常计费）
+	Count     int     `json:"count"`
+	Amount    float64 `json:"amount"`
+}
+
+// DeptBill 部门账单
+type DeptBill struct {
+	DeptId   int           `json:"deptId"`
+	DeptName string        `json:"deptName"`
+	Items    []BillingItem `json:"items"`
+	Count    int           `json:"count"`
+	Amount   float64       `json:"amount"`
+}
+
+// UserBill 人员结算明细
+type UserBill struct {
+	UserId    int     `json:"userId"`
+	WorkNo    string  `json:"workNo"`
+	Name      string  `json:"name"`
+	DeptId    int     `json:"deptId"`
+	DeptName  string  `json:"deptName"`
+	UserType  string  `json:"userType"`
+	Collected int     `json:"collected"`
+	Temp      int     `json:"temp"`
+	Expired   int     `json:"expired"`
+	Count     int     `json:"count"`
+	Amount    float64 `json:"amount"`
+}
+
+// BillingReport 日期范围内的部门结算报表
+type BillingReport struct {
+	StartDate string     `json:"startDate"` // yyyyMMdd
+	EndDate   string     `json:"endDate"`
+	Depts     []DeptBill `json:"depts"`
+	Users     []UserBill `json:"users"`
+	Count     int        `json:"count"`
+	Amount    float64    `json:"amount"`
+	Unpriced  []string   `json:"unpriced"` // 未配置单价的 餐别/用户类型，按 0 元计
+}
@@ -0,0 +1,87 @@
+package model
+
+// SetmealOption 可报餐的周套餐
+type SetmealOption struct {
+	Id          int          `json:"id"`          // 周套餐ID，报餐时提交
+	Date        string       `json:"date"`        // 日期 yyyyMMdd
+	Weekday     string       `json:"weekday"`     // 星期
+	MealType    string       `json:"mealType"`    // 餐别
+	Remark      string       `json:"remark"`      // 套餐+窗口，如 套餐A
+	SetmealId   int          `json:"setmealId"`   // 套餐ID，尚未排菜时为 0
+	SetmealName string       `json:"setmealName"` // 套餐名称
+	Dishes      []string     `json:"dishes"`      // 菜品名称
+	Tags        *SetmealTags `json:"tags"`        // 过敏原、饮食标签及营养汇总，尚未排菜时为 null
+}
+
+// MenuFilter 周菜单筛选条件，为空表示不限
+type MenuFilter struct {
+	Exclude []string // 不含任一这些过敏原
+	Diets   []string // 满足全部这些饮食标签
+}
+
+// Matches 判断套餐标签是否满足筛选条件，尚未排菜的套餐仅在不筛选时保留
+func (f MenuFilter) Matches(tags *SetmealTags) bool {
+	if len(f.Exclude) == 0 && len(f.Diets) == 0 {
+		return true
+	}
+	if tags == nil {
+		return false
+	}
+	for _, a := range f.Exclude {
+		for _, has := range tags.Allergens {
+			if a == has {
+				return false
+			}
+		}
+	}
+	for _, want := range f.Diets {
+		found := false
+		for _, d := range tags.Diets {
+			if d == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// BookingOrder 员工报餐订单
+type BookingOrder struct {
+	Id          int    `json:"id"`
+	UserId      int    `json:"userId"`
+	Date        string `json:"date"`        // 日期 yyyyMMdd
+	Weekday     string `json:"weekday"`     // 星期
+	MealType    string `json:"mealType"`    // 餐别
+	Status      string `json:"status"`      // 订单状态
+	OptionId    int    `json:"optionId"`    // 周套餐ID
+	Remark      string `json:"remark"`      // 套餐+窗口，如 套餐A
+	SetmealName string `json:"setmealName"` // 套餐名称
+	CreateTime  string `json:"createTime"`
+	UpdateTime  string `json:"updateTime"`
+}
+
+// BookingSlot 某日某餐别的可选套餐及本人报餐情况
+type BookingSlot struct {
+	Date         string          `json:"date"`         // 日期 yyyyMMdd
+	Weekday      string          `json:"weekday"`      // 星期
+	MealType     string          `json:"mealType"`     // 餐别
+	Cutoff       string          `json:"cutoff"`       // 报餐、改餐截止时间
+	CancelCutoff string          `json:"cancelCutoff"` // 取消截止时间
+	Bookable     bool            `json:"bookable"`     // 当前是否可报餐或改餐
+	Cancellable  bool            `json:"cancellable"`  // 当前是否可取消本人订单
+	Order        *BookingOrder   `json:"order"`        // 本人订单，未报餐为 null
+	Options      []SetmealOption `json:"options"`
+}
+
+// AdminBookingRequest 管理员代员工报餐、改餐、取消请求
+type AdminBookingRequest struct {
+	UserId   int    `json:"userId"`   // 报餐时必填
+	OptionId int    `json:"optionId"` // 报餐、改餐时必填
+	Override bool   `json:"override"` // 为 true 时忽略截止时间
+	Operator string `json:"operator"`
+	Remark   string `json:"remark"`
+}
@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// 日历日期类型
+const (
+	CalendarHoliday = "节假日" // 停餐，不生成套餐、不可报餐、不扣过期次数
+	CalendarWorkday = "调休"  // 调休上班，按 Weekday 对应星期的餐次供餐
+)
+
+// CalendarDay 节假日及调休上班日期，未登记的日期按餐次配置的适用星期供餐
+type CalendarDay struct {
+	Id         int    `json:"id"`
+	Date       string `json:"date"`    // 日期 yyyy-MM-dd
+	DayType    string `json:"dayType"` // 节假日 / 调休
+	Name       string `json:"name"`    // 名称，如 国庆节
+	Weekday    int    `json:"weekday"` // 调休上班按星期几供餐，1-7 表示周一至周日
+	Remark     string `json:"remark"`
+	CreateTime string `json:"createTime"`
+	UpdateTime string `json:"updateTime"`
+}
+
+// ScheduleOn 返回 day 应按哪个星期的餐次供餐，节假日返回 false；d 为 nil 表示未登记的普通日期
+func (d *CalendarDay) ScheduleOn(day time.Time) (time.Weekday, bool) {
+	if d == nil {
+		return day.Weekday(), true
+	}
+	switch d.DayType {
+	case CalendarHoliday:
+		return day.Weekday(), false
+	case CalendarWorkday:
+		return time.Weekday(d.Weekday % 7), true
+	}
+	return day.Weekday(), true
+}
+
+// CalendarImportResult 日历导入结果
+type CalendarImportResult struct {
+	Total    int           `json:"total"`    // 导入日期数
+	Holidays int           `json:"holidays"` // 节假日数
+	Workdays int           `json:"workdays"` // 调休上班日数
+	Days     []CalendarDay `json:"days"`
+}
@@ -0,0 +1,45 @@
+package model
+
+import "time"
+
+// 刷卡核销结果
+const (
+	SwipeResultCollected   = "已领取"  // 报餐订单核销
+	SwipeResultTemp        = "临时用餐" // 未报餐或客户生成临时订单
+	SwipeResultWrongWindow = "窗口错误" // 报餐套餐不在本窗口
+	SwipeResultRejected    = "拒绝"   // 挂失、重复刷卡、不在就餐时间等规则未通过
+	SwipeResultError       = "系统异常"
+)
+
+// SwipeEvent 一次在线刷卡的核销结果
+type SwipeEvent struct {
+	Time     time.Time `json:"time"`
+	DeviceId string    `json:"deviceId"`
+	Canteen  string    `json:"canteen"`
+	Window   string    `json:"window"`
+	MealType string    `json:"mealType"` // 未解析到餐次时为空
+	Name     string    `json:"name"`
+	Result   string    `json:"result"`
+	Message  string    `json:"message"`
+}
+
+// WindowCounter 看板中一个窗口的计数
+type WindowCounter struct {
+	Window      string `json:"window"`
+	Swipes      int    `json:"swipes"`      // 刷卡次数（含被拒绝）
+	WrongWindow int    `json:"wrongWindow"` // 窗口错误拒绝次数
+	Rejected    int    `json:"rejected"`    // 其他拒绝次数
+	Collected   int    `json:"collected"`   // 已领取订单数
+	Temp        int    `json:"temp"`        // 临时用餐订单数
+	Remaining   int    `json:"remaining"`   // 已报餐未领取订单数
+}
+
+// DashboardSnapshot 食堂当前餐次的实时看板
+type DashboardSnapshot struct {
+	Canteen   string          `json:"canteen"`
+	Date      string          `json:"date"`     // yyyy-MM-dd
+	MealType  string          `json:"mealType"` // 当前不在餐次时段时为空
+	Remaining int             `json:"remaining"`
+	Windows   []WindowCounter `json:"windows"`
+	Recent    []SwipeEvent    `json:"recent"` // 最近的刷卡事件，新的在前
+}
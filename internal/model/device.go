@@ -0,0 +1,23 @@
+package model
+
+// Device 刷卡终端设备
+type Device struct {
+	Id           int      `json:"id"`           // 设备号
+	SerialNo     string   `json:"serialNo"`     // 终端序列号（请求头 Device-ID）
+	Window       string   `json:"window"`       // 所属窗口（A/B/C）
+	Canteen      string   `json:"canteen"`      // 所属食堂
+	MealTypes    []string `json:"mealTypes"`    // 供应餐别（午餐/晚餐）
+	Enabled      bool     `json:"enabled"`      // 是否启用
+	LastSeenTime string   `json:"lastSeenTime"` // 最近一次通讯时间
+	Remark       string   `json:"remark"`       // 备注
+}
+
+// ServesMealType 判断设备是否供应指定餐别
+func (d *Device) ServesMealType(mealType string) bool {
+	for _, t := range d.MealTypes {
+		if t == mealType {
+			return true
+		}
+	}
+	return false
+}
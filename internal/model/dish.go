@@ -0,0 +1,124 @@
+package model
+
+// 菜品状态
+const (
+	DishStatusEnabled  = "启用"
+	DishStatusDisabled = "停用"
+)
+
+// Allergens 可标注的过敏原及忌口成分
+var Allergens = []string{"花生", "坚果", "乳制品", "鸡蛋", "鱼类", "甲壳类", "大豆", "麸质", "芝麻", "猪肉"}
+
+// 饮食标签
+const (
+	DietVegetarian = "素食"
+	DietHalal      = "清真"
+	DietSpicy      = "辣"
+)
+
+// DietTags 可标注的饮食标签
+var DietTags = []string{DietVegetarian, DietHalal, DietSpicy}
+
+// Nutrition 每份营养成分
+type Nutrition struct {
+	EnergyKcal    float64 `json:"energyKcal"`    // 能量（千卡）
+	ProteinG      float64 `json:"proteinG"`      // 蛋白质（克）
+	FatG          float64 `json:"fatG"`          // 脂肪（克）
+	CarbohydrateG float64 `json:"carbohydrateG"` // 碳水化合物（克）
+	SodiumMg      float64 `json:"sodiumMg"`      // 钠（毫克）
+}
+
+// DishTags 菜品的过敏原、饮食标签及营养成分
+type DishTags struct {
+	Allergens []string   `json:"allergens"`
+	Diets     []string   `json:"diets"`
+	Nutrition *Nutrition `json:"nutrition"` // 未填写时为 null
+}
+
+// SetmealTags 套餐按菜品汇总的过敏原、饮食标签及营养成分
+type SetmealTags struct {
+	Allergens         []string   `json:"allergens"`         // 任一菜品含有即列出
+	Diets             []string   `json:"diets"`             // 素食、清真须全部菜品满足，辣为任一菜品
+	Nutrition         *Nutrition `json:"nutrition"`         // 已填写营养成分的菜品合计，均未填写时为 null
+	NutritionComplete bool       `json:"nutritionComplete"` // 是否全部菜品均已填写营养成分
+}
+
+// AggregateTags 汇总套餐各菜品的标签，没有菜品时不标注任何饮食标签
+func AggregateTags(dishes []DishTags) SetmealTags {
+	allergens := map[string]bool{}
+	diets := map[string]int{}
+	result := SetmealTags{NutritionComplete: len(dishes) > 0}
+	for _, d := range dishes {
+		for _, a := range d.Allergens {
+			allergens[a] = true
+		}
+		for _, t := range d.Diets {
+			diets[t]++
+		}
+		if d.Nutrition == nil {
+			result.NutritionComplete = false
+			continue
+		}
+		if result.Nutrition == nil {
+			result.Nutrition = &Nutrition{}
+		}
+		result.Nutrition.EnergyKcal += d.Nutrition.EnergyKcal
+		result.Nutrition.ProteinG += d.Nutrition.ProteinG
+		result.Nutrition.FatG += d.Nutrition.FatG
+		result.Nutrition.CarbohydrateG += d.Nutrition.CarbohydrateG
+		result.Nutrition.SodiumMg += d.Nutrition.SodiumMg
+	}
+
+	result.Allergens = []string{}
+	for _, a := range Allergens {
+		if allergens[a] {
+			result.Allergens = append(result.Allergens, a)
+		}
+	}
+	result.Diets = []string{}
+	for _, t := range DietTags {
+		if (t == DietSpicy && diets[t] > 0) || (len(dishes) > 0 && diets[t] == len(dishes)) {
+			result.Diets = append(result.Diets, t)
+		}
+	}
+	return result
+}
+
+// Dish 菜品，删除为软删除
+type Dish struct {
+	Id           int    `json:"id"`
+	Name         string `json:"name"`
+	CategoryId   int    `json:"categoryId"`   // 0 表示未分类
+	CategoryName string `json:"categoryName"` // 取自分类
+	Code         string `json:"code"`         // 编码，不为空时唯一
+	Description  string `json:"description"`
+	Image        string `json:"image"`
+	Status       string `json:"status"` // 启用 / 停用
+	Sort         int    `json:"sort"`
+	CreateTime   string `json:"createTime"`
+	UpdateTime   string `json:"updateTime"`
+	DishTags
+}
+
+// DishCategory 菜品分类
+type DishCategory struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"` // 唯一，周菜单中作为列名
+	Code string `json:"code"` // 不为空时唯一
+	Sort int    `json:"sort"`
+}
+
+// DishQuery 菜品查询条件，为空表示不限
+type DishQuery struct {
+	Keyword    string // 匹配名称或编码
+	CategoryId int
+	Status     string
+	Page       int
+	PageSize   int
+}
+
+// DishPage 菜品分页查询结果
+type DishPage struct {
+	Total  int    `json:"total"`
+	Dishes []Dish `json:"dishes"`
+}
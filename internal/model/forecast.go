@@ -0,0 +1,70 @@
+package model
+
+// 预测依据
+const (
+	ForecastBasisBooking = "报餐" // 按报餐数扣除历史爽约率并加上临时用餐
+	ForecastBasisHistory = "历史" // 尚无报餐时按历史同期平均用餐数
+)
+
+// SetmealOrderCount 一个周套餐各状态的订单数
+type SetmealOrderCount struct {
+	WeeklySetmealId int
+	Date            string // yyyyMMdd
+	MealType        string
+	Window          string // 窗口，如 A
+	Description     string // 套餐描述（菜品名称）
+	Booked          int    // 已报餐
+	Collected       int    // 已领取
+	Temp            int    // 临时用餐
+	Expired         int    // 已过期，即报餐未领取
+}
+
+// SetmealForecast 一个周套餐的备餐份数预测
+type SetmealForecast struct {
+	WeeklySetmealId int     `json:"weeklySetmealId"`
+	Date            string  `json:"date"` // yyyy-MM-dd
+	Weekday         string  `json:"weekday"`
+	MealType        string  `json:"mealType"`
+	Window          string  `json:"window"`
+	Description     string  `json:"description"`
+	Booked          int     `json:"booked"`         // 报餐数
+	NoShowRate      float64 `json:"noShowRate"`     // 历史爽约率：已过期/报餐
+	WalkIn          float64 `json:"walkIn"`         // 历史平均临时用餐数
+	HistoryAverage  float64 `json:"historyAverage"` // 历史平均实际用餐数（已领取+临时用餐）
+	Samples         int     `json:"samples"`        // 参与平均的历史同期次数
+	Basis           string  `json:"basis"`          // 报餐 / 历史
+	Forecast        int     `json:"forecast"`       // 建议备餐份数
+	Actual          *int    `json:"actual,omitempty"`
+}
+
+// WeekForecast 一周的备餐预测
+type WeekForecast struct {
+	WeekStart    string            `json:"weekStart"` // yyyy-MM-dd
+	WeekEnd      string            `json:"weekEnd"`
+	HistoryWeeks int               `json:"historyWeeks"` // 参考的历史周数
+	Total        int               `json:"total"`
+	Items        []SetmealForecast `json:"items"`
+}
+
+// ForecastAccuracy 预测误差
+type ForecastAccuracy struct {
+	Slots    int     `json:"slots"`    // 周套餐数
+	Forecast int     `json:"forecast"` // 预测份数合计
+	Actual   int     `json:"actual"`   // 实际用餐数合计
+	MAE      float64 `json:"mae"`      // 平均绝对误差（份）
+	WAPE     float64 `json:"wape"`     // 绝对误差合计/实际用餐数合计
+	Bias     int     `json:"bias"`     // 预测合计-实际合计，正数为多备
+}
+
+// BacktestWeek 一周的回测结果
+type BacktestWeek struct {
+	WeekStart string `json:"weekStart"`
+	ForecastAccuracy
+	Items []SetmealForecast `json:"items"`
+}
+
+// ForecastBacktest 过去若干周的回测报告
+type ForecastBacktest struct {
+	ForecastAccuracy
+	Weeks []BacktestWeek `json:"weeks"`
+}
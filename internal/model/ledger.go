@@ -0,0 +1,49 @@
+package model
+
+// 次数流水类型
+const (
+	LedgerTypeOpening   = "期初余额"
+	LedgerTypeSwipe     = "刷卡核销"
+	LedgerTypeExpiry    = "过期扣减"
+	LedgerTypeTopUp     = "充值"
+	LedgerTypeRefund    = "撤销返还"
+	LedgerTypeManual    = "手工调整"
+	LedgerTypeCorrect   = "对账调整"
+	LedgerTypeAllotment = "月度配额"
+)
+
+// 次数流水关联单据类型
+const (
+	LedgerRefOrder  = "order_record"
+	LedgerRefRefund = "consum_refund"
+	LedgerRefDate   = "date"
+	// LedgerRefRecharge 关联充值批次号
+	LedgerRefRecharge = "recharge_batch"
+	// LedgerRefAllotment 关联月度配额规则，单据号为 规则ID-yyyyMM
+	LedgerRefAllotment = "allotment_rule"
+)
+
+// CountLedger 用户用餐次数流水
+type CountLedger struct {
+	Id         int    `json:"id"`
+	UserId     int    `json:"userId"`     // 用户号
+	Change     int    `json:"change"`     // 变动次数，正数为增加
+	Balance    int    `json:"balance"`    // 变动后 sys_user.count
+	ChangeType string `json:"changeType"` // 变动类型
+	RefType    string `json:"refType"`    // 关联单据类型
+	RefId      string `json:"refId"`      // 关联单据号
+	Operator   string `json:"operator"`   // 操作人，系统操作为空
+	Remark     string `json:"remark"`     // 备注
+	CreateTime string `json:"createTime"` // 变动时间
+}
+
+// CountDrift 流水合计与 sys_user.count 不一致的对账记录
+type CountDrift struct {
+	Id        int    `json:"id"`
+	UserId    int    `json:"userId"`    // 用户号
+	NickName  string `json:"nickName"`  // 姓名
+	LedgerSum int    `json:"ledgerSum"` // 流水合计
+	Count     int    `json:"count"`     // sys_user.count
+	Drift     int    `json:"drift"`     // count - 流水合计
+	CheckTime string `json:"checkTime"` // 对账时间
+}
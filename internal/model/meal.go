@@ -5,6 +5,7 @@ type WeekMeal struct {
 	WeekDay    string `json:"weekDay"`
 	MealType   string `json:"mealType"`
 	MealId     int16  `json:"mealId"`
+	Remark     string `json:"remark"` // 套餐+窗口，如 套餐A
 }
 
 type Meal struct {
@@ -12,20 +13,49 @@ type Meal struct {
 	Description string `json:"description"`
 }
 
-type Dish struct {
-	Id   int16  `json:"id"`
-	Name string `json:"name"`
+// 周菜单发布，Excel 中的一行对应某天某餐别一个窗口的套餐
+type WeekMenu struct {
+	Row             int        `json:"row"` // Excel 行号
+	Day             string     `json:"day"` // 日期 yyyyMMdd
+	MealType        string     `json:"meal_type"`
+	Window          string     `json:"window"`
+	Dishes          []MenuDish `json:"dishes"`          // 按列顺序
+	WeeklySetmealId int        `json:"weeklySetmealId"` // 对应的周套餐
+	SetmealId       int        `json:"setmealId"`       // 导入生成的套餐，预览新增时为 0
+	SetmealCode     string     `json:"setmealCode"`     // 导入生成的套餐编码，同一天同一餐别同一窗口唯一
+	Action          string     `json:"action"`          // 新增 / 更新 / 未变化
 }
 
-type CreateMeal struct {
-	Name    string  `json:"name"`
-	DishIds []int16 `json:"dishIds"`
+// MenuDish 周菜单中的菜品，DishId 为 0 表示导入时新建
+type MenuDish struct {
+	CategoryId int    `json:"categoryId"`
+	Category   string `json:"category"`
+	Name       string `json:"name"`
+	DishId     int    `json:"dishId"`
 }
 
-// 周菜单发布
-type WeekMenu struct {
-	Day      string            `json:"day"`
-	MealType string            `json:"meal_type"`
-	Window   string            `json:"window"`
-	Dishes   map[string]string `json:"dishes"`
+// 周菜单导入动作
+const (
+	MenuActionCreate    = "新增"
+	MenuActionUpdate    = "更新"
+	MenuActionUnchanged = "未变化"
+)
+
+// MenuImportRequest 周菜单导入参数
+type MenuImportRequest struct {
+	Date   string // 所在周任意一天 yyyyMMdd，日期列填写星期时必填
+	DryRun bool   // 仅校验并预览，不写入
+}
+
+// MenuImportResult 周菜单导入结果
+type MenuImportResult struct {
+	DryRun    bool       `json:"dryRun"`
+	StartDate string     `json:"startDate"` // 周一 yyyyMMdd
+	EndDate   string     `json:"endDate"`   // 周日 yyyyMMdd
+	Created   int        `json:"created"`
+	Updated   int        `json:"updated"`
+	Unchanged int        `json:"unchanged"`
+	NewDishes []string   `json:"newDishes"` // 自动新建的菜品
+	Menus     []WeekMenu `json:"menus"`
+	Errors    []string   `json:"errors"` // 校验错误，如 第3行D列: 菜品名称过长
 }
\ No newline at end of file
@@ -0,0 +1,54 @@
+package model
+
+import "time"
+
+// MealPeriod 餐次时段（如早餐、午餐、晚餐、夜宵）
+type MealPeriod struct {
+	Id        int    `json:"id"`
+	Canteen   string `json:"canteen"`   // 所属食堂
+	Name      string `json:"name"`      // 餐别名称，对应 order_record.meal_type
+	Code      string `json:"code"`      // 餐别编码，用于缓存键（如 lunch）
+	StartTime string `json:"startTime"` // 开始时间 HH:MM（含）
+	EndTime   string `json:"endTime"`   // 结束时间 HH:MM（不含），最晚 24:00
+	Weekdays  []int  `json:"weekdays"`  // 适用星期，1-7 表示周一至周日
+	Sort      int    `json:"sort"`      // 排序
+	Enabled   bool   `json:"enabled"`   // 是否启用
+}
+
+// AppliesOn 判断时段是否适用于指定星期
+func (p *MealPeriod) AppliesOn(weekday time.Weekday) bool {
+	day := int(weekday)
+	if day == 0 {
+		day = 7
+	}
+	for _, d := range p.Weekdays {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains 判断时刻 t 是否落在该时段内（含星期判断）
+func (p *MealPeriod) Contains(t time.Time) bool {
+	return p.ContainsOn(t, t.Weekday())
+}
+
+// ContainsOn 按 weekday 的适用星期判断时刻 t 是否落在该时段内，用于调休上班日
+func (p *MealPeriod) ContainsOn(t time.Time, weekday time.Weekday) bool {
+	if !p.Enabled || !p.AppliesOn(weekday) {
+		return false
+	}
+	clock := t.Format("15:04")
+	return clock >= p.StartTime && clock < p.EndTime
+}
+
+// EndOn 返回时段在指定日期的结束时刻
+func (p *MealPeriod) EndOn(day time.Time) time.Time {
+	end, err := time.Parse("15:04", p.EndTime)
+	if err != nil {
+		// 24:00 视为当日结束
+		return time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, day.Location())
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), end.Hour(), end.Minute(), 0, 0, day.Location())
+}
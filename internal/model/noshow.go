@@ -0,0 +1,60 @@
+package model
+
+// NoShowThresholds 爽约标记及暂停报餐规则，来自 canteen_config
+type NoShowThresholds struct {
+	MinCount   int     `json:"minCount"`   // 爽约次数不少于该值
+	MinRate    float64 `json:"minRate"`    // 且爽约率不低于该值时标记
+	BlockDays  int     `json:"blockDays"`  // 暂停报餐天数，0 表示不暂停
+	WindowDays int     `json:"windowDays"` // 自动暂停时统计最近的天数
+}
+
+// NoShowUser 用户爽约统计，报餐数为已领取与已过期订单之和
+type NoShowUser struct {
+	UserId       int     `json:"userId"`
+	WorkNo       string  `json:"workNo"`
+	Name         string  `json:"name"`
+	DeptId       int     `json:"deptId"`
+	DeptName     string  `json:"deptName"`
+	Booked       int     `json:"booked"`
+	NoShow       int     `json:"noShow"` // 已过期（报餐未领取）
+	Rate         float64 `json:"rate"`   // 爽约率 noShow / booked
+	Flagged      bool    `json:"flagged"`
+	BlockedUntil string  `json:"blockedUntil,omitempty"` // 当前暂停报餐的截止日期 yyyy-MM-dd
+}
+
+// NoShowDept 部门爽约统计
+type NoShowDept struct {
+	DeptId   int     `json:"deptId"`
+	DeptName string  `json:"deptName"`
+	Users    int     `json:"users"`   // 有报餐的人数
+	Flagged  int     `json:"flagged"` // 被标记的人数
+	Booked   int     `json:"booked"`
+	NoShow   int     `json:"noShow"`
+	Rate     float64 `json:"rate"`
+}
+
+// NoShowReport 日期范围内的爽约报表
+type NoShowReport struct {
+	StartDate  string           `json:"startDate"` // yyyyMMdd
+	EndDate    string           `json:"endDate"`
+	Thresholds NoShowThresholds `json:"thresholds"`
+	Booked     int              `json:"booked"`
+	NoShow     int              `json:"noShow"`
+	Rate       float64          `json:"rate"`
+	Depts      []NoShowDept     `json:"depts"`
+	Users      []NoShowUser     `json:"users"` // 仅含有爽约的用户
+}
+
+// BookingBlock 因爽约暂停报餐记录
+type BookingBlock struct {
+	Id         int    `json:"id"`
+	UserId     int    `json:"userId"`
+	WorkNo     string `json:"workNo"`
+	Name       string `json:"name"`
+	DeptName   string `json:"deptName"`
+	StartDate  string `json:"startDate"` // yyyy-MM-dd
+	EndDate    string `json:"endDate"`   // yyyy-MM-dd，含当天
+	NoShow     int    `json:"noShow"`
+	Reason     string `json:"reason"`
+	CreateTime string `json:"createTime"`
+}
@@ -1,5 +1,15 @@
 package model
 
+// 订单状态
+const (
+	OrderStatusBooked    = "已报餐"
+	OrderStatusCollected = "已领取"
+	OrderStatusTemp      = "临时用餐"
+	OrderStatusExpired   = "已过期"
+	OrderStatusVoided    = "已作废" // 撤销核销后作废的临时订单
+	OrderStatusCancelled = "已取消" // 截止时间前取消或停餐日取消的报餐
+)
+
 type ConsumTransaction struct {
 	Order    string `json:"Order"`
 	CardNo   string `json:"CardNo"`
@@ -34,4 +44,31 @@ type OffLineRequest struct {
 	Time         string `json:"Time"`
 	CardNo       string `json:"CardNo"`
 	Money        string `json:"Money"`
-}
\ No newline at end of file
+}
+// 脱机消费记录状态
+const (
+	OfflineStatusPending   = "待处理"
+	OfflineStatusSettled   = "已核销"
+	OfflineStatusReconcile = "待对账"
+	OfflineStatusFailed    = "处理失败"
+	OfflineStatusResolved  = "已处理"
+)
+
+// OfflineTransaction 终端脱机期间上传的消费记录
+type OfflineTransaction struct {
+	Id           int    `json:"id"`
+	DeviceNo     string `json:"deviceNo"`     // 终端序列号
+	OrderNo      string `json:"orderNo"`      // 终端交易号
+	CardNo       string `json:"cardNo"`       // 卡号
+	NickName     string `json:"nickName"`     // 持卡人
+	TransTime    string `json:"transTime"`    // 终端记录的消费时间
+	Money        string `json:"money"`        // 金额
+	PayType      int    `json:"payType"`      // 支付方式
+	CardMode     int    `json:"cardMode"`     // 卡类型
+	Status       string `json:"status"`       // 处理状态
+	Reason       string `json:"reason"`       // 未通过核销规则的原因
+	Operator     string `json:"operator"`     // 对账处理人
+	HandleRemark string `json:"handleRemark"` // 对账处理说明
+	HandleTime   string `json:"handleTime"`   // 对账处理时间
+	CreateTime   string `json:"createTime"`   // 上传时间
+}
@@ -0,0 +1,77 @@
+package model
+
+// 充值方式
+const (
+	RechargeModeAdd = "add" // 在当前次数上增加
+	RechargeModeSet = "set" // 将次数重置为指定值
+)
+
+// 充值逐行结果状态
+const (
+	RechargeStatusSuccess = "成功"
+	RechargeStatusPreview = "预览"
+	RechargeStatusSkipped = "跳过"
+	RechargeStatusFailed  = "失败"
+)
+
+// RechargeRequest 单个用户或整个部门充值请求
+type RechargeRequest struct {
+	UserId   int    `json:"userId"`   // 按用户号充值
+	CardNo   string `json:"cardNo"`   // 按卡号充值，与 userId 二选一
+	DeptId   int    `json:"deptId"`   // 按部门充值
+	Mode     string `json:"mode"`     // add 增加 / set 重置，默认 add
+	Amount   int    `json:"amount"`   // 次数
+	Operator string `json:"operator"` // 操作人
+	Remark   string `json:"remark"`   // 备注
+	DryRun   bool   `json:"dryRun"`   // 仅预览，不写入
+}
+
+// RechargeItem 充值名单中的一行
+type RechargeItem struct {
+	Row      int    // Excel 行号，从 1 开始
+	UserId   int    // 用户号
+	CardNo   string // 卡号
+	NickName string // 姓名
+	Amount   int    // 次数
+	Error    string // 解析失败原因
+}
+
+// RechargeResult 单个用户的充值结果
+type RechargeResult struct {
+	Row      int    `json:"row"`      // 名单行号，非名单充值时为序号
+	UserId   int    `json:"userId"`   // 用户号
+	NickName string `json:"nickName"` // 姓名
+	CardNo   string `json:"cardNo"`   // 卡号
+	Before   int    `json:"before"`   // 充值前次数
+	Change   int    `json:"change"`   // 变动次数
+	After    int    `json:"after"`    // 充值后次数
+	Status   string `json:"status"`   // 成功 / 预览 / 跳过 / 失败
+	Message  string `json:"message"`  // 跳过或失败原因
+}
+
+// RechargeBatch 一次充值的汇总结果
+type RechargeBatch struct {
+	BatchNo   string           `json:"batchNo"`   // 批次号，记入次数流水的关联单据号
+	DryRun    bool             `json:"dryRun"`    // 是否为预览
+	Mode      string           `json:"mode"`      // 充值方式
+	Total     int              `json:"total"`     // 总行数
+	Succeeded int              `json:"succeeded"` // 成功（或预览可执行）行数
+	Skipped   int              `json:"skipped"`   // 跳过行数
+	Failed    int              `json:"failed"`    // 失败行数
+	Results   []RechargeResult `json:"results"`
+}
+
+// AllotmentRule 月度配额规则，每月指定日自动为部门用户充值或重置次数
+type AllotmentRule struct {
+	Id           int    `json:"id"`
+	Name         string `json:"name"`         // 规则名称
+	DeptId       int    `json:"deptId"`       // 适用部门，0 表示全部用户
+	Mode         string `json:"mode"`         // add 增加 / set 重置
+	Amount       int    `json:"amount"`       // 次数
+	DayOfMonth   int    `json:"dayOfMonth"`   // 每月执行日，1-28
+	Enabled      bool   `json:"enabled"`      // 是否启用
+	LastRunMonth string `json:"lastRunMonth"` // 最近执行月份 yyyyMM
+	Remark       string `json:"remark"`       // 备注
+	CreateTime   string `json:"createTime"`
+	UpdateTime   string `json:"updateTime"`
+}
@@ -0,0 +1,26 @@
+package model
+
+// RefundRequest 撤销核销请求，按终端交易号或订单号定位核销记录
+type RefundRequest struct {
+	Order    string `json:"Order"`    // 终端交易号
+	DeviceNo string `json:"DeviceNo"` // 终端序列号，交易号在多台设备上重复时必填
+	OrderId  int    `json:"OrderId"`  // 订单号，与交易号二选一
+	Operator string `json:"Operator"` // 操作人
+	Reason   string `json:"Reason"`   // 撤销原因
+}
+
+// RefundLog 撤销核销审计记录
+type RefundLog struct {
+	Id            int    `json:"id"`
+	OrderRecordId int    `json:"orderRecordId"` // 订单号
+	UserId        int    `json:"userId"`        // 用户号
+	DeviceNo      string `json:"deviceNo"`      // 原核销终端
+	OrderNo       string `json:"orderNo"`       // 原终端交易号
+	MealType      string `json:"mealType"`      // 餐别
+	FromStatus    string `json:"fromStatus"`    // 撤销前订单状态
+	ToStatus      string `json:"toStatus"`      // 撤销后订单状态
+	Count         int    `json:"count"`         // 撤销后剩余次数
+	Operator      string `json:"operator"`      // 操作人
+	Reason        string `json:"reason"`        // 撤销原因
+	CreateTime    string `json:"createTime"`    // 撤销时间
+}
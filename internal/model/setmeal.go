@@ -0,0 +1,52 @@
+package model
+
+import "strings"
+
+// WeeklySlot 周套餐及其当前关联的套餐
+type WeeklySlot struct {
+	Id         int    `json:"id"`
+	WeekNumber string `json:"weekNumber"` // 日期 yyyyMMdd
+	MealType   string `json:"mealType"`
+	Remark     string `json:"remark"`    // 套餐+窗口，如 套餐A
+	SetmealId  int    `json:"setmealId"` // 尚未排菜时为 0
+}
+
+// Window 返回周套餐的窗口，如 A
+func (s *WeeklySlot) Window() string {
+	return strings.TrimPrefix(s.Remark, "套餐")
+}
+
+// SetmealCode 返回周套餐对应套餐的编码 M<yyyyMMdd>-<餐别>-<窗口>，统计按该格式截取日期和窗口
+func SetmealCode(day, mealType, window string) string {
+	return "M" + day + "-" + mealType + "-" + window
+}
+
+// Setmeal 套餐及按顺序排列的菜品
+type Setmeal struct {
+	Id          int           `json:"id"`
+	Name        string        `json:"name"`
+	Code        string        `json:"code"`
+	Description string        `json:"description"` // 菜品名称以、连接，统计按其分组
+	Status      string        `json:"status"`
+	Dishes      []SetmealDish `json:"dishes"`
+	Tags        SetmealTags   `json:"tags"`
+	CreateTime  string        `json:"createTime"`
+	UpdateTime  string        `json:"updateTime"`
+}
+
+// SetmealDish 套餐中的菜品
+type SetmealDish struct {
+	DishId       int    `json:"dishId"`
+	Name         string `json:"name"`
+	CategoryId   int    `json:"categoryId"`
+	CategoryName string `json:"categoryName"`
+	Sort         int    `json:"sort"`
+	DishTags
+}
+
+// SetmealRequest 套餐新增/修改请求
+type SetmealRequest struct {
+	WeeklySetmealId int    `json:"weeklySetmealId"` // 新增时必填，新套餐关联到该周套餐
+	Name            string `json:"name"`            // 为空时新增按 日期 餐别 套餐窗口 命名，修改保持不变
+	DishIds         []int  `json:"dishIds"`         // 按顺序排列
+}
@@ -0,0 +1,26 @@
+package model
+
+// SetmealTemplate 套餐模板，配置某餐次在某星期生成哪些窗口的周套餐
+type SetmealTemplate struct {
+	Id           int    `json:"id"`
+	MealPeriodId int    `json:"mealPeriodId"`
+	Canteen      string `json:"canteen"`  // 所属食堂，取自餐次
+	MealType     string `json:"mealType"` // 餐别名称，取自餐次
+	Weekday      int    `json:"weekday"`  // 1-7 表示周一至周日
+	Window       string `json:"window"`   // 窗口，生成的周套餐备注为 套餐+窗口
+	Sort         int    `json:"sort"`
+	Enabled      bool   `json:"enabled"`
+	Remark       string `json:"remark"`
+}
+
+// SetmealRemark 返回生成周套餐时使用的备注，如 套餐A
+func (t *SetmealTemplate) SetmealRemark() string {
+	return "套餐" + t.Window
+}
+
+// SetmealGeneration 周套餐生成结果
+type SetmealGeneration struct {
+	StartWeek string     `json:"startWeek"` // 周一 yyyyMMdd
+	EndWeek   string     `json:"endWeek"`   // 周日 yyyyMMdd
+	Setmeals  []WeekMeal `json:"setmeals"`
+}
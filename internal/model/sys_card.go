@@ -0,0 +1,60 @@
+package model
+
+// 卡片状态
+const (
+	CardStatusActive  = "正常"
+	CardStatusLost    = "挂失"
+	CardStatusFrozen  = "冻结"
+	CardStatusRetired = "注销"
+)
+
+// 卡片操作
+const (
+	CardActionIssue   = "发卡"
+	CardActionLoss    = "挂失"
+	CardActionFreeze  = "冻结"
+	CardActionRestore = "解挂"
+	CardActionRetire  = "注销"
+	CardActionReplace = "补卡"
+)
+
+// Card 用户卡片，用户的次数与订单按用户号归属，补卡后由新卡继承
+type Card struct {
+	Id         int    `json:"id"`
+	CardNo     string `json:"cardNo"`     // 卡号
+	UserId     int    `json:"userId"`     // 持卡用户
+	NickName   string `json:"nickName"`   // 持卡人
+	Status     string `json:"status"`     // 正常 / 挂失 / 冻结 / 注销
+	ReplacedBy string `json:"replacedBy"` // 补办的新卡号
+	Remark     string `json:"remark"`     // 最近一次操作说明
+	StatusTime string `json:"statusTime"` // 状态变更时间
+	CreateTime string `json:"createTime"` // 发卡时间
+}
+
+// CardLog 卡片操作记录
+type CardLog struct {
+	Id         int    `json:"id"`
+	CardNo     string `json:"cardNo"`     // 卡号
+	UserId     int    `json:"userId"`     // 持卡用户
+	Action     string `json:"action"`     // 操作
+	FromStatus string `json:"fromStatus"` // 操作前状态，新卡为空
+	ToStatus   string `json:"toStatus"`   // 操作后状态
+	Operator   string `json:"operator"`   // 操作人
+	Remark     string `json:"remark"`     // 说明
+	CreateTime string `json:"createTime"` // 操作时间
+}
+
+// CardStatusRequest 挂失、冻结、解挂、注销请求
+type CardStatusRequest struct {
+	CardNo   string `json:"cardNo"`
+	Operator string `json:"operator"`
+	Remark   string `json:"remark"`
+}
+
+// CardReplaceRequest 补卡请求
+type CardReplaceRequest struct {
+	CardNo    string `json:"cardNo"`    // 原卡号
+	NewCardNo string `json:"newCardNo"` // 新卡号
+	Operator  string `json:"operator"`
+	Remark    string `json:"remark"`
+}
@@ -0,0 +1,14 @@
+package model
+
+// TransactionLog 核销交易流水，按设备号+终端交易号去重
+type TransactionLog struct {
+	Id            int    `json:"id"`
+	DeviceNo      string `json:"deviceNo"`      // 终端序列号
+	OrderNo       string `json:"orderNo"`       // 终端交易号（ConsumTransaction.Order）
+	CardNo        string `json:"cardNo"`        // 卡号
+	UserId        int    `json:"userId"`        // 用户号
+	OrderRecordId int    `json:"orderRecordId"` // 关联订单号
+	MealType      string `json:"mealType"`      // 餐别
+	Response      string `json:"response"`      // 原始核销响应（JSON）
+	CreateTime    string `json:"createTime"`    // 核销时间
+}
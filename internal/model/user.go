@@ -11,6 +11,13 @@ type User struct {
 	NickName string
 }
 
+// LoginResult 员工登录结果
+type LoginResult struct {
+	Token      string  `json:"token"`      // 请求时通过 Authorization: Bearer 携带
+	ExpireTime string  `json:"expireTime"` // 令牌过期时间
+	User       *UserVo `json:"user"`
+}
+
 type UserVo struct {
 	UserId   int    `json:"userId"`
 	NickName string `json:"nickName"`
@@ -0,0 +1,153 @@
+package analytics
+
+import (
+	"canteen/internal/model"
+	"database/sql"
+	"strings"
+)
+
+type AnalyticsRepository interface {
+	// CountSetmealOrders 按套餐描述统计订单数
+	CountSetmealOrders(filter model.StatsFilter) ([]model.StatItem, error)
+	// CountDishOrders 按菜品统计订单数，订单所选套餐中的每个菜品各计一次
+	CountDishOrders(filter model.StatsFilter) ([]model.StatItem, error)
+	// CountDishAppearances 按菜品统计在周套餐中出现的次数，仅按日期、餐别、窗口筛选
+	CountDishAppearances(filter model.StatsFilter) ([]model.StatItem, error)
+	// FindSetmealOrderCounts 查询日期范围内（yyyyMMdd）每个周套餐各状态的订单数，无订单的周套餐计数为 0
+	FindSetmealOrderCounts(startDate, endDate string) ([]model.SetmealOrderCount, error)
+}
+
+type analyticsRepository struct {
+	db *sql.DB
+}
+
+func NewAnalyticsRepository(db *sql.DB) AnalyticsRepository {
+	return &analyticsRepository{db: db}
+}
+
+// orderConditions 订单统计的公共筛选条件，o 为 order_record，ws 为 weekly_setmeal，u 为 sys_user
+func orderConditions(f model.StatsFilter) (string, []interface{}) {
+	where := " WHERE o.week_number BETWEEN ? AND ?"
+	args := []interface{}{f.StartDate, f.EndDate}
+	if f.DeptId != 0 {
+		where += " AND u.dept_id = ?"
+		args = append(args, f.DeptId)
+	}
+	if f.MealType != "" {
+		where += " AND o.meal_type = ?"
+		args = append(args, f.MealType)
+	}
+	if f.Window != "" {
+		where += " AND ws.remark = ?"
+		args = append(args, "套餐"+f.Window)
+	}
+	if f.Status != "" {
+		where += " AND o.status = ?"
+		args = append(args, f.Status)
+	} else {
+		where += " AND o.status NOT IN (?, ?)"
+		args = append(args, model.OrderStatusCancelled, model.OrderStatusVoided)
+	}
+	return where, args
+}
+
+func (r *analyticsRepository) CountSetmealOrders(f model.StatsFilter) ([]model.StatItem, error) {
+	where, args := orderConditions(f)
+	return r.queryItems(`
+		SELECT 0, s.description, 0, COUNT(*) AS count
+		FROM order_record o
+		JOIN weekly_setmeal ws ON ws.id = o.setmeal_id
+		JOIN setmeal s ON s.id = ws.setmeal_id
+		LEFT JOIN sys_user u ON u.user_id = o.user_id`+where+` AND IFNULL(s.description, '') <> ''
+		GROUP BY s.description
+		ORDER BY count DESC, s.description
+	`, args...)
+}
+
+func (r *analyticsRepository) CountDishOrders(f model.StatsFilter) ([]model.StatItem, error) {
+	where, args := orderConditions(f)
+	return r.queryItems(`
+		SELECT d.id, d.name, IFNULL(d.category_id, 0), COUNT(*) AS count
+		FROM order_record o
+		JOIN weekly_setmeal ws ON ws.id = o.setmeal_id
+		JOIN setmeal_dish sd ON sd.setmeal_id = ws.setmeal_id
+		JOIN dish d ON d.id = sd.dish_id
+		LEFT JOIN sys_user u ON u.user_id = o.user_id`+where+`
+		GROUP BY d.id, d.name, d.category_id
+		ORDER BY count DESC, d.name, d.id
+	`, args...)
+}
+
+func (r *analyticsRepository) CountDishAppearances(f model.StatsFilter) ([]model.StatItem, error) {
+	where := " WHERE ws.week_number BETWEEN ? AND ?"
+	args := []interface{}{f.StartDate, f.EndDate}
+	if f.MealType != "" {
+		where += " AND ws.meal_type = ?"
+		args = append(args, f.MealType)
+	}
+	if f.Window != "" {
+		where += " AND ws.remark = ?"
+		args = append(args, "套餐"+f.Window)
+	}
+	return r.queryItems(`
+		SELECT d.id, d.name, IFNULL(d.category_id, 0), COUNT(*) AS count
+		FROM weekly_setmeal ws
+		JOIN setmeal s ON s.id = ws.setmeal_id AND s.is_deleted = 0
+		JOIN setmeal_dish sd ON sd.setmeal_id = s.id
+		JOIN dish d ON d.id = sd.dish_id`+where+`
+		GROUP BY d.id, d.name, d.category_id
+		ORDER BY count DESC, d.name, d.id
+	`, args...)
+}
+
+func (r *analyticsRepository) FindSetmealOrderCounts(startDate, endDate string) ([]model.SetmealOrderCount, error) {
+	rows, err := r.db.Query(`
+		SELECT ws.id, ws.week_number, ws.meal_type, IFNULL(ws.remark, ''), IFNULL(s.description, ''),
+			SUM(CASE WHEN o.status = ? THEN 1 ELSE 0 END),
+			SUM(CASE WHEN o.status = ? THEN 1 ELSE 0 END),
+			SUM(CASE WHEN o.status = ? THEN 1 ELSE 0 END),
+			SUM(CASE WHEN o.status = ? THEN 1 ELSE 0 END)
+		FROM weekly_setmeal ws
+		LEFT JOIN setmeal s ON s.id = ws.setmeal_id AND s.is_deleted = 0
+		LEFT JOIN order_record o ON o.setmeal_id = ws.id
+		WHERE ws.week_number BETWEEN ? AND ?
+		GROUP BY ws.id, ws.week_number, ws.meal_type, ws.remark, s.description
+		ORDER BY ws.week_number, ws.meal_type, ws.remark, ws.id
+	`, model.OrderStatusBooked, model.OrderStatusCollected, model.OrderStatusTemp, model.OrderStatusExpired, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []model.SetmealOrderCount{}
+	for rows.Next() {
+		var c model.SetmealOrderCount
+		if err := rows.Scan(&c.WeeklySetmealId, &c.Date, &c.MealType, &c.Window, &c.Description,
+			&c.Booked, &c.Collected, &c.Temp, &c.Expired); err != nil {
+			return nil, err
+		}
+		// 周套餐备注为“套餐”+窗口
+		c.Window = strings.TrimPrefix(c.Window, "套餐")
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+// queryItems 执行返回 id、名称、分类ID、次数 四列的统计查询
+func (r *analyticsRepository) queryItems(query string, args ...interface{}) ([]model.StatItem, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []model.StatItem{}
+	for rows.Next() {
+		var item model.StatItem
+		if err := rows.Scan(&item.Id, &item.Name, &item.CategoryId, &item.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
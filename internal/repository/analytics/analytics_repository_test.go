@@ -0,0 +1,194 @@
+package analytics
+
+import (
+	"canteen/internal/model"
+	"canteen/internal/testutil"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+)
+
+const fixtureDate = "20990601"
+
+// analyticsFixture 两个部门的用户在同一天分别点了 A、C 窗口套餐，其中一单已取消
+type analyticsFixture struct {
+	deptId                int
+	braised, greens, cold int
+}
+
+func seedAnalyticsFixture(t *testing.T, db *sql.DB) analyticsFixture {
+	t.Helper()
+	var ids struct{ users, dishes, setmeals, weekly, orders []int64 }
+	t.Cleanup(func() {
+		for _, id := range ids.orders {
+			db.Exec("DELETE FROM order_record WHERE id = ?", id)
+		}
+		for _, id := range ids.weekly {
+			db.Exec("DELETE FROM weekly_setmeal WHERE id = ?", id)
+		}
+		for _, id := range ids.setmeals {
+			db.Exec("DELETE FROM setmeal_dish WHERE setmeal_id = ?", id)
+			db.Exec("DELETE FROM setmeal WHERE id = ?", id)
+		}
+		for _, id := range ids.dishes {
+			db.Exec("DELETE FROM dish WHERE id = ?", id)
+		}
+		for _, id := range ids.users {
+			db.Exec("DELETE FROM sys_user WHERE user_id = ?", id)
+		}
+	})
+	insert := func(ids *[]int64, query string, args ...interface{}) int64 {
+		result, err := db.Exec(query, args...)
+		if err != nil {
+			t.Fatalf("插入测试数据失败: %v", err)
+		}
+		id, _ := result.LastInsertId()
+		*ids = append(*ids, id)
+		return id
+	}
+
+	deptId := int(time.Now().UnixNano()%100000) + 900000
+	tag := fmt.Sprint(time.Now().UnixNano())
+	userA := insert(&ids.users, "INSERT INTO sys_user (dept_id, nick_name) VALUES (?, '统计测试甲')", deptId)
+	userB := insert(&ids.users, "INSERT INTO sys_user (dept_id, nick_name) VALUES (?, '统计测试乙')", deptId+1)
+
+	braised := insert(&ids.dishes, "INSERT INTO dish (name, category_id) VALUES (?, 1)", "红烧肉"+tag)
+	greens := insert(&ids.dishes, "INSERT INTO dish (name, category_id) VALUES (?, 2)", "清炒时蔬"+tag)
+	cold := insert(&ids.dishes, "INSERT INTO dish (name, category_id) VALUES (?, 3)", "凉拌木耳"+tag)
+
+	setmealA := insert(&ids.setmeals, "INSERT INTO setmeal (name, code, description) VALUES ('套餐A', ?, ?)", "M"+fixtureDate+"-午餐-A", "测试套餐A"+tag)
+	setmealC := insert(&ids.setmeals, "INSERT INTO setmeal (name, code, description) VALUES ('套餐C', ?, ?)", "M"+fixtureDate+"-午餐-C", "测试套餐C"+tag)
+	for _, sd := range [][2]int64{{setmealA, braised}, {setmealA, greens}, {setmealC, braised}, {setmealC, cold}} {
+		if _, err := db.Exec("INSERT INTO setmeal_dish (setmeal_id, dish_id) VALUES (?, ?)", sd[0], sd[1]); err != nil {
+			t.Fatalf("插入套餐菜品失败: %v", err)
+		}
+	}
+
+	weeklyA := insert(&ids.weekly, "INSERT INTO weekly_setmeal (week_number, weekday, meal_type, setmeal_id, remark) VALUES (?, '周一', '午餐', ?, '套餐A')", fixtureDate, setmealA)
+	weeklyC := insert(&ids.weekly, "INSERT INTO weekly_setmeal (week_number, weekday, meal_type, setmeal_id, remark) VALUES (?, '周一', '午餐', ?, '套餐C')", fixtureDate, setmealC)
+
+	for _, o := range []struct {
+		userId, weeklyId int64
+		status           string
+	}{
+		{userA, weeklyA, model.OrderStatusCollected},
+		{userB, weeklyA, model.OrderStatusBooked},
+		{userA, weeklyC, model.OrderStatusCollected},
+		{userB, weeklyC, model.OrderStatusCancelled},
+	} {
+		insert(&ids.orders, `
+			INSERT INTO order_record (user_id, status, meal_type, week_number, order_date, weekday, setmeal_id)
+			VALUES (?, ?, '午餐', ?, '2099-06-01', '周一', ?)
+		`, o.userId, o.status, fixtureDate, o.weeklyId)
+	}
+
+	return analyticsFixture{deptId: deptId, braised: int(braised), greens: int(greens), cold: int(cold)}
+}
+
+// countsById 仅保留测试数据中的统计项，避免受库中其他数据影响
+func countsById(items []model.StatItem, ids ...int) map[int]int {
+	counts := map[int]int{}
+	for _, item := range items {
+		for _, id := range ids {
+			if item.Id == id {
+				counts[id] = item.Count
+			}
+		}
+	}
+	return counts
+}
+
+func TestCountSetmealOrders(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	f := seedAnalyticsFixture(t, db)
+	repo := NewAnalyticsRepository(db)
+
+	tests := []struct {
+		name   string
+		filter model.StatsFilter
+		want   []int
+	}{
+		{"默认排除已取消", model.StatsFilter{}, []int{2, 1}},
+		{"按窗口", model.StatsFilter{Window: "C"}, []int{1}},
+		{"按部门", model.StatsFilter{DeptId: f.deptId}, []int{1, 1}},
+		{"按状态", model.StatsFilter{Status: model.OrderStatusCancelled}, []int{1}},
+		{"按餐别", model.StatsFilter{MealType: "晚餐"}, nil},
+	}
+	for _, tt := range tests {
+		tt.filter.StartDate, tt.filter.EndDate = fixtureDate, fixtureDate
+		items, err := repo.CountSetmealOrders(tt.filter)
+		if err != nil {
+			t.Fatalf("%s CountSetmealOrders() err = %v", tt.name, err)
+		}
+		if len(items) != len(tt.want) {
+			t.Errorf("%s CountSetmealOrders() = %+v", tt.name, items)
+			continue
+		}
+		for i, count := range tt.want {
+			if items[i].Count != count {
+				t.Errorf("%s 第%d项 = %+v, 期望 %d", tt.name, i+1, items[i], count)
+			}
+		}
+	}
+}
+
+func TestCountDishOrdersAndAppearances(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	f := seedAnalyticsFixture(t, db)
+	repo := NewAnalyticsRepository(db)
+	filter := model.StatsFilter{StartDate: fixtureDate, EndDate: fixtureDate}
+
+	orders, err := repo.CountDishOrders(filter)
+	if err != nil {
+		t.Fatalf("CountDishOrders() err = %v", err)
+	}
+	got := countsById(orders, f.braised, f.greens, f.cold)
+	if got[f.braised] != 3 || got[f.greens] != 2 || got[f.cold] != 1 {
+		t.Errorf("菜品点餐次数 = %v", got)
+	}
+	if len(orders) > 0 && orders[0].Id != f.braised {
+		t.Errorf("应按次数降序，首项为 %+v", orders[0])
+	}
+
+	appearances, err := repo.CountDishAppearances(filter)
+	if err != nil {
+		t.Fatalf("CountDishAppearances() err = %v", err)
+	}
+	got = countsById(appearances, f.braised, f.greens, f.cold)
+	if got[f.braised] != 2 || got[f.greens] != 1 || got[f.cold] != 1 {
+		t.Errorf("菜品出现次数 = %v", got)
+	}
+
+	// 出现次数不受部门、状态筛选影响
+	filter.Window, filter.DeptId = "A", f.deptId
+	appearances, err = repo.CountDishAppearances(filter)
+	if err != nil {
+		t.Fatalf("CountDishAppearances(A) err = %v", err)
+	}
+	got = countsById(appearances, f.braised, f.greens, f.cold)
+	if got[f.braised] != 1 || got[f.greens] != 1 || got[f.cold] != 0 {
+		t.Errorf("A 窗口菜品出现次数 = %v", got)
+	}
+}
+
+func TestFindSetmealOrderCounts(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	seedAnalyticsFixture(t, db)
+
+	counts, err := NewAnalyticsRepository(db).FindSetmealOrderCounts(fixtureDate, fixtureDate)
+	if err != nil {
+		t.Fatalf("FindSetmealOrderCounts() err = %v", err)
+	}
+	got := map[string]model.SetmealOrderCount{}
+	for _, c := range counts {
+		got[c.Window] = c
+	}
+	if a := got["A"]; a.Booked != 1 || a.Collected != 1 || a.Expired != 0 || a.MealType != "午餐" {
+		t.Errorf("A 窗口 = %+v", a)
+	}
+	// 已取消的订单不计入任何状态
+	if c := got["C"]; c.Booked != 0 || c.Collected != 1 || c.Temp != 0 {
+		t.Errorf("C 窗口 = %+v", c)
+	}
+}
@@ -0,0 +1,55 @@
+package billing
+
+import (
+	"canteen/internal/model"
+	"canteen/internal/repository/canteen_config"
+	"database/sql"
+)
+
+type BillingRepository interface {
+	// FindBillableCounts 按用户、餐别、状态统计 [startDate, endDate]（yyyyMMdd）内已领取、临时用餐及已过期的订单数，
+	// 部门取用户当前所在部门
+	FindBillableCounts(startDate, endDate string) ([]model.BillingCount, error)
+	// FindPriceConfigs 读取 canteen_config 中 meal_price 开头的单价配置
+	FindPriceConfigs() (map[string]string, error)
+}
+
+type billingRepository struct {
+	db         *sql.DB
+	configRepo canteen_config.ConfigRepository
+}
+
+func NewBillingRepository(db *sql.DB) BillingRepository {
+	return &billingRepository{db: db, configRepo: canteen_config.NewConfigRepository(db)}
+}
+
+func (r *billingRepository) FindBillableCounts(startDate, endDate string) ([]model.BillingCount, error) {
+	rows, err := r.db.Query(`
+		SELECT ord.user_id, IFNULL(s.user_name, ''), IFNULL(s.nick_name, ''), IFNULL(s.dept_id, 0), IFNULL(sd.dept_name, ''),
+			IFNULL(ord.meal_type, ''), ord.status, COUNT(*)
+		FROM order_record ord
+		LEFT JOIN sys_user s ON ord.user_id = s.user_id
+		LEFT JOIN sys_dept sd ON s.dept_id = sd.dept_id
+		WHERE ord.week_number BETWEEN ? AND ? AND ord.status IN (?, ?, ?)
+		GROUP BY ord.user_id, s.user_name, s.nick_name, s.dept_id, sd.dept_name, ord.meal_type, ord.status
+		ORDER BY sd.dept_name, s.user_name, ord.user_id
+	`, startDate, endDate, model.OrderStatusCollected, model.OrderStatusTemp, model.OrderStatusExpired)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []model.BillingCount{}
+	for rows.Next() {
+		var c model.BillingCount
+		if err := rows.Scan(&c.UserId, &c.WorkNo, &c.Name, &c.DeptId, &c.DeptName, &c.MealType, &c.Status, &c.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+func (r *billingRepository) FindPriceConfigs() (map[string]string, error) {
+	return r.configRepo.FindByPrefix("meal_price")
+}
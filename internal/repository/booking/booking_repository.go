@@ -0,0 +1,279 @@
+package booking
+
+import (
+	"canteen/internal/model"
+	"canteen/internal/repository/canteen_config"
+	"database/sql"
+	"strings"
+)
+
+type BookingRepository interface {
+	// FindOptions 查询 [startDate, endDate] 的周套餐及菜品，日期为 yyyyMMdd
+	FindOptions(startDate, endDate string) ([]model.SetmealOption, error)
+	FindOption(optionId int) (*model.SetmealOption, error)
+	// FindOrders 分页查询用户报餐订单，日期为 yyyyMMdd，为空表示不限
+	FindOrders(userId int, startDate, endDate string, offset, limit int) ([]model.BookingOrder, int, error)
+	FindOrderById(orderId int) (*model.BookingOrder, error)
+	// FindCutoffConfigs 读取 canteen_config 中 booking_cutoff、cancel_cutoff 开头的截止规则配置
+	FindCutoffConfigs() (map[string]string, error)
+	// FindMealPeriodCodes 查询餐别名称到餐次编码的映射
+	FindMealPeriodCodes() (map[string]string, error)
+	// FindUserDept 查询用户部门，未设置部门时返回 0
+	FindUserDept(userId int) (int, error)
+	// FindBlockedUntil 查询用户在 date（yyyy-MM-dd）因爽约暂停报餐的截止日期，未暂停时返回空字符串
+	FindBlockedUntil(userId int, date string) (string, error)
+	// WithTx 在同一数据库事务中执行 fn，fn 返回错误时整体回滚
+	WithTx(fn func(tx BookingTx) error) error
+}
+
+// BookingTx 报餐事务内可执行的操作
+type BookingTx interface {
+	// LockUser 加行锁，同一用户的并发报餐将串行执行
+	LockUser(userId int) error
+	// FindActiveOrder 加锁查询用户当日指定餐别未取消、未作废的订单，不存在时返回 sql.ErrNoRows
+	FindActiveOrder(userId int, mealType string, date string) (*model.BookingOrder, error)
+	LockOrder(orderId int) (*model.BookingOrder, error)
+	CreateOrder(userId int, option *model.SetmealOption) (int, error)
+	// UpdateOrderSetmeal 更换已报餐订单的套餐，订单已不处于已报餐状态时返回 false
+	UpdateOrderSetmeal(orderId int, optionId int) (bool, error)
+	// CancelOrder 取消已报餐订单，订单已不处于已报餐状态时返回 false
+	CancelOrder(orderId int) (bool, error)
+}
+
+type bookingRepository struct {
+	db         *sql.DB
+	configRepo canteen_config.ConfigRepository
+}
+
+func NewBookingRepository(db *sql.DB) BookingRepository {
+	return &bookingRepository{db: db, configRepo: canteen_config.NewConfigRepository(db)}
+}
+
+const optionQuery = `
+	SELECT ws.id, ws.week_number, ws.weekday, ws.meal_type, IFNULL(ws.remark, ''), IFNULL(ws.setmeal_id, 0), IFNULL(s.name, ''),
+		IFNULL((
+			SELECT GROUP_CONCAT(d.name ORDER BY sd.sort, sd.id SEPARATOR ',')
+			FROM setmeal_dish sd JOIN dish d ON d.id = sd.dish_id AND d.is_deleted = 0
+			WHERE sd.setmeal_id = ws.setmeal_id
+		), '')
+	FROM weekly_setmeal ws
+	LEFT JOIN setmeal s ON s.id = ws.setmeal_id
+`
+
+func scanOption(row interface{ Scan(...interface{}) error }) (*model.SetmealOption, error) {
+	var option model.SetmealOption
+	var dishes string
+	err := row.Scan(&option.Id, &option.Date, &option.Weekday, &option.MealType, &option.Remark, &option.SetmealId,
+		&option.SetmealName, &dishes)
+	if err != nil {
+		return nil, err
+	}
+	option.Dishes = []string{}
+	if dishes != "" {
+		option.Dishes = strings.Split(dishes, ",")
+	}
+	return &option, nil
+}
+
+func (r *bookingRepository) FindOptions(startDate, endDate string) ([]model.SetmealOption, error) {
+	rows, err := r.db.Query(optionQuery+`
+		WHERE ws.week_number BETWEEN ? AND ?
+		ORDER BY ws.week_number, (SELECT MIN(mp.sort) FROM meal_period mp WHERE mp.name = ws.meal_type), ws.meal_type, ws.remark, ws.id
+	`, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	options := []model.SetmealOption{}
+	for rows.Next() {
+		option, err := scanOption(rows)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, *option)
+	}
+
+	return options, rows.Err()
+}
+
+func (r *bookingRepository) FindOption(optionId int) (*model.SetmealOption, error) {
+	return scanOption(r.db.QueryRow(optionQuery+" WHERE ws.id = ?", optionId))
+}
+
+const bookingOrderQuery = `
+	SELECT o.id, o.user_id, o.week_number, o.weekday, o.meal_type, o.status, IFNULL(o.setmeal_id, 0),
+		IFNULL(ws.remark, ''), IFNULL(s.name, ''), o.create_time, o.update_time
+	FROM order_record o
+	LEFT JOIN weekly_setmeal ws ON ws.id = o.setmeal_id
+	LEFT JOIN setmeal s ON s.id = ws.setmeal_id
+`
+
+func scanBookingOrder(row interface{ Scan(...interface{}) error }) (*model.BookingOrder, error) {
+	var order model.BookingOrder
+	var createTime, updateTime sql.NullTime
+	err := row.Scan(&order.Id, &order.UserId, &order.Date, &order.Weekday, &order.MealType, &order.Status, &order.OptionId,
+		&order.Remark, &order.SetmealName, &createTime, &updateTime)
+	if err != nil {
+		return nil, err
+	}
+	if createTime.Valid {
+		order.CreateTime = createTime.Time.Format("2006-01-02 15:04:05")
+	}
+	if updateTime.Valid {
+		order.UpdateTime = updateTime.Time.Format("2006-01-02 15:04:05")
+	}
+	return &order, nil
+}
+
+func (r *bookingRepository) FindOrders(userId int, startDate, endDate string, offset, limit int) ([]model.BookingOrder, int, error) {
+	where := " WHERE o.user_id = ?"
+	args := []interface{}{userId}
+	if startDate != "" {
+		where += " AND o.week_number >= ?"
+		args = append(args, startDate)
+	}
+	if endDate != "" {
+		where += " AND o.week_number <= ?"
+		args = append(args, endDate)
+	}
+
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM order_record o"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.db.Query(bookingOrderQuery+where+" ORDER BY o.week_number DESC, o.id DESC LIMIT ? OFFSET ?",
+		append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	orders := []model.BookingOrder{}
+	for rows.Next() {
+		order, err := scanBookingOrder(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		orders = append(orders, *order)
+	}
+
+	return orders, total, rows.Err()
+}
+
+func (r *bookingRepository) FindOrderById(orderId int) (*model.BookingOrder, error) {
+	return scanBookingOrder(r.db.QueryRow(bookingOrderQuery+" WHERE o.id = ?", orderId))
+}
+
+func (r *bookingRepository) FindCutoffConfigs() (map[string]string, error) {
+	return r.configRepo.FindByPrefix("booking_cutoff", "cancel_cutoff")
+}
+
+func (r *bookingRepository) FindMealPeriodCodes() (map[string]string, error) {
+	rows, err := r.db.Query("SELECT name, code FROM meal_period ORDER BY canteen = 'main' DESC, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	codes := map[string]string{}
+	for rows.Next() {
+		var name, code string
+		if err := rows.Scan(&name, &code); err != nil {
+			return nil, err
+		}
+		if _, ok := codes[name]; !ok {
+			codes[name] = code
+		}
+	}
+
+	return codes, rows.Err()
+}
+
+func (r *bookingRepository) FindUserDept(userId int) (int, error) {
+	var deptId int
+	err := r.db.QueryRow("SELECT IFNULL(dept_id, 0) FROM sys_user WHERE user_id = ?", userId).Scan(&deptId)
+	return deptId, err
+}
+
+func (r *bookingRepository) FindBlockedUntil(userId int, date string) (string, error) {
+	var until sql.NullString
+	err := r.db.QueryRow(`
+		SELECT DATE_FORMAT(MAX(end_date), '%Y-%m-%d') FROM booking_block
+		WHERE user_id = ? AND revoked = 0 AND start_date <= ? AND end_date >= ?
+	`, userId, date, date).Scan(&until)
+	return until.String, err
+}
+
+func (r *bookingRepository) WithTx(fn func(tx BookingTx) error) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(&bookingTx{tx: tx}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+type bookingTx struct {
+	tx *sql.Tx
+}
+
+func (t *bookingTx) LockUser(userId int) error {
+	var id int
+	return t.tx.QueryRow("SELECT user_id FROM sys_user WHERE user_id = ? FOR UPDATE", userId).Scan(&id)
+}
+
+func (t *bookingTx) FindActiveOrder(userId int, mealType string, date string) (*model.BookingOrder, error) {
+	return scanBookingOrder(t.tx.QueryRow(bookingOrderQuery+`
+		WHERE o.user_id = ? AND o.meal_type = ? AND o.week_number = ? AND o.status NOT IN (?, ?)
+		LIMIT 1
+		FOR UPDATE
+	`, userId, mealType, date, model.OrderStatusVoided, model.OrderStatusCancelled))
+}
+
+func (t *bookingTx) LockOrder(orderId int) (*model.BookingOrder, error) {
+	return scanBookingOrder(t.tx.QueryRow(bookingOrderQuery+" WHERE o.id = ? FOR UPDATE", orderId))
+}
+
+func (t *bookingTx) CreateOrder(userId int, option *model.SetmealOption) (int, error) {
+	result, err := t.tx.Exec(`
+		INSERT INTO order_record
+		(user_id, week_number, order_date, weekday, meal_type, setmeal_id, status, create_time, update_time)
+		VALUES (?, ?, STR_TO_DATE(?, '%Y%m%d'), ?, ?, ?, ?, NOW(), NOW())
+	`, userId, option.Date, option.Date, option.Weekday, option.MealType, option.Id, model.OrderStatusBooked)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+func (t *bookingTx) UpdateOrderSetmeal(orderId int, optionId int) (bool, error) {
+	result, err := t.tx.Exec(
+		"UPDATE order_record SET setmeal_id = ?, update_time = NOW() WHERE id = ? AND status = ?",
+		optionId, orderId, model.OrderStatusBooked,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+func (t *bookingTx) CancelOrder(orderId int) (bool, error) {
+	result, err := t.tx.Exec(
+		"UPDATE order_record SET status = ?, update_time = NOW() WHERE id = ? AND status = ?",
+		model.OrderStatusCancelled, orderId, model.OrderStatusBooked,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
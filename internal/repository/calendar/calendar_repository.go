@@ -0,0 +1,120 @@
+package calendar
+
+import (
+	"canteen/internal/model"
+	"database/sql"
+	"time"
+)
+
+type CalendarRepository interface {
+	// FindRange 查询 [start, end] 内登记的日期，日期为 yyyy-MM-dd
+	FindRange(start, end string) ([]model.CalendarDay, error)
+	// FindByDate 查询指定日期的登记，未登记时返回 nil
+	FindByDate(day time.Time) (*model.CalendarDay, error)
+	// SaveAll 在同一事务中按日期新增或覆盖登记
+	SaveAll(days []model.CalendarDay) error
+	// Delete 删除指定日期（yyyy-MM-dd）的登记，不存在时返回 sql.ErrNoRows
+	Delete(date string) error
+}
+
+type calendarRepository struct {
+	db *sql.DB
+}
+
+func NewCalendarRepository(db *sql.DB) CalendarRepository {
+	return &calendarRepository{db: db}
+}
+
+const calendarColumns = `id, calendar_date, day_type, name, weekday, remark, create_time, update_time`
+
+func scanCalendarDay(row interface{ Scan(...interface{}) error }) (*model.CalendarDay, error) {
+	var day model.CalendarDay
+	var date time.Time
+	var name, remark sql.NullString
+	var weekday sql.NullInt64
+	var createTime, updateTime sql.NullTime
+	if err := row.Scan(&day.Id, &date, &day.DayType, &name, &weekday, &remark, &createTime, &updateTime); err != nil {
+		return nil, err
+	}
+	day.Date = date.Format("2006-01-02")
+	day.Name = name.String
+	day.Weekday = int(weekday.Int64)
+	day.Remark = remark.String
+	if createTime.Valid {
+		day.CreateTime = createTime.Time.Format("2006-01-02 15:04:05")
+	}
+	if updateTime.Valid {
+		day.UpdateTime = updateTime.Time.Format("2006-01-02 15:04:05")
+	}
+	return &day, nil
+}
+
+func (r *calendarRepository) FindRange(start, end string) ([]model.CalendarDay, error) {
+	rows, err := r.db.Query(
+		"SELECT "+calendarColumns+" FROM canteen_calendar WHERE calendar_date BETWEEN ? AND ? ORDER BY calendar_date",
+		start, end,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	days := []model.CalendarDay{}
+	for rows.Next() {
+		day, err := scanCalendarDay(rows)
+		if err != nil {
+			return nil, err
+		}
+		days = append(days, *day)
+	}
+
+	return days, rows.Err()
+}
+
+func (r *calendarRepository) FindByDate(day time.Time) (*model.CalendarDay, error) {
+	d, err := scanCalendarDay(r.db.QueryRow(
+		"SELECT "+calendarColumns+" FROM canteen_calendar WHERE calendar_date = ?",
+		day.Format("2006-01-02"),
+	))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return d, err
+}
+
+func (r *calendarRepository) SaveAll(days []model.CalendarDay) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, day := range days {
+		_, err := tx.Exec(`
+			INSERT INTO canteen_calendar (calendar_date, day_type, name, weekday, remark, create_time, update_time)
+			VALUES (?, ?, ?, NULLIF(?, 0), ?, NOW(), NOW())
+			ON DUPLICATE KEY UPDATE day_type = VALUES(day_type), name = VALUES(name), weekday = VALUES(weekday),
+				remark = VALUES(remark), update_time = NOW()
+		`, day.Date, day.DayType, day.Name, day.Weekday, day.Remark)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *calendarRepository) Delete(date string) error {
+	result, err := r.db.Exec("DELETE FROM canteen_calendar WHERE calendar_date = ?", date)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package canteen_config
+
+import (
+	"database/sql"
+	"strings"
+)
+
+type ConfigRepository interface {
+	// FindByPrefix 读取 canteen_config 中以任一 prefix 开头的配置，值为 NULL 时返回空字符串
+	FindByPrefix(prefixes ...string) (map[string]string, error)
+}
+
+type configRepository struct {
+	db *sql.DB
+}
+
+func NewConfigRepository(db *sql.DB) ConfigRepository {
+	return &configRepository{db: db}
+}
+
+// likeEscaper 转义 LIKE 通配符，前缀中的 _ 按字面匹配
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `_`, `\_`, `%`, `\%`)
+
+func (r *configRepository) FindByPrefix(prefixes ...string) (map[string]string, error) {
+	configs := map[string]string{}
+	if len(prefixes) == 0 {
+		return configs, nil
+	}
+
+	conditions := make([]string, len(prefixes))
+	args := make([]interface{}, len(prefixes))
+	for i, prefix := range prefixes {
+		conditions[i] = "config_key LIKE ?"
+		args[i] = likeEscaper.Replace(prefix) + "%"
+	}
+	rows, err := r.db.Query("SELECT config_key, IFNULL(config_value, '') FROM canteen_config WHERE "+
+		strings.Join(conditions, " OR "), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+		configs[key] = value
+	}
+	return configs, rows.Err()
+}
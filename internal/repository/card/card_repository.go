@@ -2,16 +2,55 @@ package card
 
 import (
 	"canteen/internal/model"
+	"canteen/internal/repository/ledger"
 	"database/sql"
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
 )
 
+// ErrDuplicateTransaction 交易流水已存在（终端重复提交同一交易号）
+var ErrDuplicateTransaction = errors.New("交易流水已存在")
+
+// CardBlockedError 卡片处于挂失、冻结或注销状态，不能刷卡
+type CardBlockedError struct {
+	Status string
+}
+
+func (e *CardBlockedError) Error() string {
+	return "卡片已" + e.Status
+}
+
 type CardRepository interface {
+	// FindUserByCardNo 查询持卡用户，卡片不可用时同时返回用户与 *CardBlockedError
 	FindUserByCardNo(cardNo string) (*model.UserVo, error)
 	FindOrderRecord(userId int, mealType string, weekNumber string, weekday string) (*model.OrderRecord, error)
-	CreateOrderRecord(order *model.OrderRecord) error
-	UpdateOrderStatus(orderId int, status string) error
-	UpdateUserCount(userId int, count int) error
+	FindOrderRecordById(orderId int) (*model.OrderRecord, error)
+	// FindWindowSetmealId 查询指定日期、餐别、窗口对应的周套餐ID
+	FindWindowSetmealId(weekNumber string, mealType string, window string) (int, error)
 	GetCanteenConfigs() (flexibleDeptId, fixedDeptId, flexibleDinnerStart, fixedDinnerStart string, err error)
+	// WithTx 在同一数据库事务中执行 fn，fn 返回错误时整体回滚
+	WithTx(fn func(tx CardTx) error) error
+}
+
+// CardTx 核销事务内可执行的操作
+type CardTx interface {
+	// LockUser 加行锁读取用户信息，同一用户的并发核销将串行执行
+	LockUser(userId int) (*model.UserVo, error)
+	// FindOrderRecord 加锁查询用户当日指定餐别的订单
+	FindOrderRecord(userId int, mealType string, weekNumber string) (*model.OrderRecord, error)
+	// ClaimOrder 将订单由已报餐置为已领取，订单不处于已报餐状态时返回 false
+	ClaimOrder(orderId int) (bool, error)
+	CreateOrderRecord(order *model.OrderRecord) (int, error)
+	// AdjustUserCount 基于当前值调整用餐次数并写入次数流水
+	AdjustUserCount(entry *model.CountLedger) error
+	// CreateTransactionLog 记录核销交易流水，交易号重复时返回 ErrDuplicateTransaction
+	CreateTransactionLog(txLog *model.TransactionLog) error
+	// LockOrder 加行锁读取订单
+	LockOrder(orderId int) (*model.OrderRecord, error)
+	// RevertOrder 将订单由 fromStatus 改为 toStatus，订单已不处于 fromStatus 时返回 false
+	RevertOrder(orderId int, fromStatus string, toStatus string) (bool, error)
+	CreateRefundLog(refundLog *model.RefundLog) (int, error)
 }
 
 type cardRepository struct {
@@ -24,26 +63,134 @@ func NewCardRepository(db *sql.DB) CardRepository {
 
 func (r *cardRepository) FindUserByCardNo(cardNo string) (*model.UserVo, error) {
 	var user model.UserVo
-	err := r.db.QueryRow(
-		"SELECT user_id, dept_id, nick_name, count, card_no FROM sys_user WHERE card_no = ?",
-		cardNo,
-	).Scan(&user.UserId, &user.DeptId, &user.NickName, &user.Count, &user.CardNo)
-	return &user, err
+	var status string
+	// 用户当前卡号已被外部改为其他卡时，原卡视为注销
+	err := r.db.QueryRow(`
+		SELECT u.user_id, u.dept_id, u.nick_name, u.count, c.card_no,
+			CASE WHEN c.status = ? AND NOT (u.card_no <=> c.card_no) THEN ? ELSE c.status END
+		FROM sys_card c
+		JOIN sys_user u ON u.user_id = c.user_id
+		WHERE c.card_no = ?
+	`, model.CardStatusActive, model.CardStatusRetired, cardNo).Scan(&user.UserId, &user.DeptId, &user.NickName, &user.Count, &user.CardNo, &status)
+	if errors.Is(err, sql.ErrNoRows) {
+		// 尚未登记到卡片表的卡号按用户表中的当前卡号处理
+		err = r.db.QueryRow(
+			"SELECT user_id, dept_id, nick_name, count, card_no FROM sys_user WHERE card_no = ?",
+			cardNo,
+		).Scan(&user.UserId, &user.DeptId, &user.NickName, &user.Count, &user.CardNo)
+		return &user, err
+	}
+	if err != nil {
+		return &user, err
+	}
+	if status != model.CardStatusActive {
+		return &user, &CardBlockedError{Status: status}
+	}
+	return &user, nil
 }
 
 func (r *cardRepository) FindOrderRecord(userId int, mealType string, weekNumber string, weekday string) (*model.OrderRecord, error) {
 	var order model.OrderRecord
 	err := r.db.QueryRow(`
-		SELECT o.id, o.status, o.setmeal_id, o.user_id 
-		FROM order_record o 
-		WHERE o.user_id = ? AND o.meal_type = ? AND o.week_number = ? AND o.weekday = ?
-	`, userId, mealType, weekNumber, weekday).Scan(&order.Id, &order.Status, &order.MealId, &order.UserId)
+		SELECT o.id, o.status, o.setmeal_id, o.user_id
+		FROM order_record o
+		WHERE o.user_id = ? AND o.meal_type = ? AND o.week_number = ? AND o.weekday = ? AND o.status NOT IN (?, ?)
+	`, userId, mealType, weekNumber, weekday, model.OrderStatusVoided, model.OrderStatusCancelled).Scan(&order.Id, &order.Status, &order.MealId, &order.UserId)
 	return &order, err
 }
 
-func (r *cardRepository) CreateOrderRecord(order *model.OrderRecord) error {
-	_, err := r.db.Exec(`
-		INSERT INTO order_record 
+func (r *cardRepository) FindOrderRecordById(orderId int) (*model.OrderRecord, error) {
+	return scanOrderRecord(r.db.QueryRow(`
+		SELECT `+orderRecordColumns+`
+		FROM order_record
+		WHERE id = ?
+	`, orderId))
+}
+
+func (r *cardRepository) FindWindowSetmealId(weekNumber string, mealType string, window string) (int, error) {
+	var id int
+	err := r.db.QueryRow(`
+		SELECT id FROM weekly_setmeal
+		WHERE week_number = ? AND meal_type = ? AND remark = ?
+		ORDER BY id DESC LIMIT 1
+	`, weekNumber, mealType, "套餐"+window).Scan(&id)
+	return id, err
+}
+
+func (r *cardRepository) GetCanteenConfigs() (flexibleDeptId, fixedDeptId, flexibleDinnerStart, fixedDinnerStart string, err error) {
+	err = r.db.QueryRow(`
+		SELECT
+			(SELECT config_value FROM canteen_config WHERE config_key='flexible_dept_id'),
+			(SELECT config_value FROM canteen_config WHERE config_key='fixed_dept_id'),
+			(SELECT config_value FROM canteen_config WHERE config_key='flexible_dinner_start_time'),
+			(SELECT config_value FROM canteen_config WHERE config_key='fixed_dinner_start_time')
+	`).Scan(&flexibleDeptId, &fixedDeptId, &flexibleDinnerStart, &fixedDinnerStart)
+	return flexibleDeptId, fixedDeptId, flexibleDinnerStart, fixedDinnerStart, err
+}
+
+func (r *cardRepository) WithTx(fn func(tx CardTx) error) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(&cardTx{tx: tx}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+type cardTx struct {
+	tx *sql.Tx
+}
+
+func (t *cardTx) LockUser(userId int) (*model.UserVo, error) {
+	var user model.UserVo
+	err := t.tx.QueryRow(
+		"SELECT user_id, dept_id, nick_name, count, card_no FROM sys_user WHERE user_id = ? FOR UPDATE",
+		userId,
+	).Scan(&user.UserId, &user.DeptId, &user.NickName, &user.Count, &user.CardNo)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (t *cardTx) FindOrderRecord(userId int, mealType string, weekNumber string) (*model.OrderRecord, error) {
+	var order model.OrderRecord
+	err := t.tx.QueryRow(`
+		SELECT o.id, o.status, o.setmeal_id, o.user_id
+		FROM order_record o
+		WHERE o.user_id = ? AND o.meal_type = ? AND o.week_number = ? AND o.status NOT IN (?, ?)
+		LIMIT 1
+		FOR UPDATE
+	`, userId, mealType, weekNumber, model.OrderStatusVoided, model.OrderStatusCancelled).Scan(&order.Id, &order.Status, &order.MealId, &order.UserId)
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (t *cardTx) ClaimOrder(orderId int) (bool, error) {
+	result, err := t.tx.Exec(
+		"UPDATE order_record SET status = ?, update_time = NOW() WHERE id = ? AND status = ?",
+		model.OrderStatusCollected, orderId, model.OrderStatusBooked,
+	)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func (t *cardTx) CreateOrderRecord(order *model.OrderRecord) (int, error) {
+	result, err := t.tx.Exec(`
+		INSERT INTO order_record
 		(user_id, week_number, order_date, weekday, meal_type, setmeal_id, quantity, status, create_time, update_time)
 		VALUES (?, ?, ?, ?, ?, ?, 1, ?, NOW(), NOW())
 	`,
@@ -55,26 +202,108 @@ func (r *cardRepository) CreateOrderRecord(order *model.OrderRecord) error {
 		order.MealId,
 		order.Status,
 	)
-	return err
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	return int(id), err
 }
 
-func (r *cardRepository) UpdateOrderStatus(orderId int, status string) error {
-	_, err := r.db.Exec("UPDATE order_record SET status = ? WHERE id = ?", status, orderId)
-	return err
+func (t *cardTx) AdjustUserCount(entry *model.CountLedger) error {
+	return ledger.AdjustCount(t.tx, entry)
 }
 
-func (r *cardRepository) UpdateUserCount(userId int, count int) error {
-	_, err := r.db.Exec("UPDATE sys_user SET count = ? WHERE user_id = ?", count, userId)
+func (t *cardTx) CreateTransactionLog(txLog *model.TransactionLog) error {
+	_, err := t.tx.Exec(`
+		INSERT INTO consum_transaction_log
+		(device_no, order_no, card_no, user_id, order_record_id, meal_type, response, create_time)
+		VALUES (?, ?, ?, ?, ?, ?, ?, NOW())
+	`,
+		txLog.DeviceNo,
+		txLog.OrderNo,
+		txLog.CardNo,
+		txLog.UserId,
+		txLog.OrderRecordId,
+		txLog.MealType,
+		txLog.Response,
+	)
+	if isDuplicateKey(err) {
+		return ErrDuplicateTransaction
+	}
 	return err
 }
 
-func (r *cardRepository) GetCanteenConfigs() (flexibleDeptId, fixedDeptId, flexibleDinnerStart, fixedDinnerStart string, err error) {
-	err = r.db.QueryRow(`
-		SELECT 
-			(SELECT config_value FROM canteen_config WHERE config_key='flexible_dept_id'),
-			(SELECT config_value FROM canteen_config WHERE config_key='fixed_dept_id'),
-			(SELECT config_value FROM canteen_config WHERE config_key='flexible_dinner_start_time'),
-			(SELECT config_value FROM canteen_config WHERE config_key='fixed_dinner_start_time')
-	`).Scan(&flexibleDeptId, &fixedDeptId, &flexibleDinnerStart, &fixedDinnerStart)
-	return flexibleDeptId, fixedDeptId, flexibleDinnerStart, fixedDinnerStart, err
+func (t *cardTx) LockOrder(orderId int) (*model.OrderRecord, error) {
+	return scanOrderRecord(t.tx.QueryRow(`
+		SELECT `+orderRecordColumns+`
+		FROM order_record
+		WHERE id = ?
+		FOR UPDATE
+	`, orderId))
+}
+
+func (t *cardTx) RevertOrder(orderId int, fromStatus string, toStatus string) (bool, error) {
+	result, err := t.tx.Exec(
+		"UPDATE order_record SET status = ?, update_time = NOW() WHERE id = ? AND status = ?",
+		toStatus, orderId, fromStatus,
+	)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func (t *cardTx) CreateRefundLog(refundLog *model.RefundLog) (int, error) {
+	result, err := t.tx.Exec(`
+		INSERT INTO consum_refund
+		(order_record_id, user_id, device_no, order_no, meal_type, from_status, to_status, operator, reason, create_time)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, NOW())
+	`,
+		refundLog.OrderRecordId,
+		refundLog.UserId,
+		refundLog.DeviceNo,
+		refundLog.OrderNo,
+		refundLog.MealType,
+		refundLog.FromStatus,
+		refundLog.ToStatus,
+		refundLog.Operator,
+		refundLog.Reason,
+	)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+const orderRecordColumns = `id, user_id, status, setmeal_id, meal_type, week_number, order_date, weekday`
+
+func scanOrderRecord(row *sql.Row) (*model.OrderRecord, error) {
+	var order model.OrderRecord
+	var status, mealType, weekNumber, weekday sql.NullString
+	var mealId sql.NullInt64
+	var orderDate sql.NullTime
+	err := row.Scan(&order.Id, &order.UserId, &status, &mealId, &mealType, &weekNumber, &orderDate, &weekday)
+	if err != nil {
+		return nil, err
+	}
+	order.Status = status.String
+	order.MealId = int(mealId.Int64)
+	order.MealType = mealType.String
+	order.WeekNumber = weekNumber.String
+	order.Weekday = weekday.String
+	if orderDate.Valid {
+		order.OrderDate = orderDate.Time.Format("2006-01-02")
+	}
+	return &order, nil
+}
+
+// isDuplicateKey 判断是否为唯一键冲突
+func isDuplicateKey(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
 }
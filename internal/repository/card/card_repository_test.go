@@ -0,0 +1,152 @@
+package card
+
+import (
+	"canteen/internal/model"
+	"canteen/internal/testutil"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errNotClaimed = errors.New("订单已被领取")
+
+// TestConcurrentSwipesClaimOrderOnce 两次同时刷卡只能有一次领取成功，且只扣减一次次数
+func TestConcurrentSwipesClaimOrderOnce(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+
+	cardNo := fmt.Sprintf("T%d", time.Now().UnixNano())
+	result, err := db.Exec("INSERT INTO sys_user (dept_id, nick_name, count, card_no) VALUES (1, '并发测试', 10, ?)", cardNo)
+	if err != nil {
+		t.Fatalf("插入用户失败: %v", err)
+	}
+	userId, _ := result.LastInsertId()
+
+	result, err = db.Exec(`
+		INSERT INTO order_record (user_id, status, meal_type, week_number, order_date, weekday, setmeal_id)
+		VALUES (?, ?, '午餐', '20250102', '2025-01-02', '周四', 1)
+	`, userId, model.OrderStatusBooked)
+	if err != nil {
+		t.Fatalf("插入订单失败: %v", err)
+	}
+	orderId, _ := result.LastInsertId()
+
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM count_ledger WHERE user_id = ?", userId)
+		db.Exec("DELETE FROM order_record WHERE id = ?", orderId)
+		db.Exec("DELETE FROM sys_user WHERE user_id = ?", userId)
+	})
+
+	repo := NewCardRepository(db)
+
+	const swipes = 2
+	errs := make([]error, swipes)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < swipes; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			errs[i] = repo.WithTx(func(tx CardTx) error {
+				if _, err := tx.LockUser(int(userId)); err != nil {
+					return err
+				}
+				claimed, err := tx.ClaimOrder(int(orderId))
+				if err != nil {
+					return err
+				}
+				if !claimed {
+					return errNotClaimed
+				}
+				return tx.AdjustUserCount(&model.CountLedger{
+					UserId:     int(userId),
+					Change:     -1,
+					ChangeType: model.LedgerTypeSwipe,
+					RefType:    model.LedgerRefOrder,
+					RefId:      fmt.Sprint(orderId),
+				})
+			})
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case errors.Is(err, errNotClaimed):
+		default:
+			t.Fatalf("核销事务异常: %v", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Fatalf("期望仅一次领取成功，实际 %d 次", succeeded)
+	}
+
+	var status string
+	if err := db.QueryRow("SELECT status FROM order_record WHERE id = ?", orderId).Scan(&status); err != nil {
+		t.Fatalf("查询订单失败: %v", err)
+	}
+	if status != model.OrderStatusCollected {
+		t.Fatalf("订单状态应为%s，实际为%s", model.OrderStatusCollected, status)
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT count FROM sys_user WHERE user_id = ?", userId).Scan(&count); err != nil {
+		t.Fatalf("查询用户失败: %v", err)
+	}
+	if count != 9 {
+		t.Fatalf("用户次数应为 9，实际为 %d", count)
+	}
+
+	var entries, balance int
+	if err := db.QueryRow("SELECT COUNT(*), MIN(balance) FROM count_ledger WHERE user_id = ?", userId).Scan(&entries, &balance); err != nil {
+		t.Fatalf("查询次数流水失败: %v", err)
+	}
+	if entries != 1 || balance != 9 {
+		t.Fatalf("应记录一条余额为 9 的流水，实际 %d 条，余额 %d", entries, balance)
+	}
+}
+
+// TestFindUserByCardNoChecksCardStatus 卡片表中非正常状态的卡及已被替换的旧卡均不能刷卡
+func TestFindUserByCardNoChecksCardStatus(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+
+	cardNo := fmt.Sprintf("T%d", time.Now().UnixNano())
+	result, err := db.Exec("INSERT INTO sys_user (dept_id, nick_name, count, card_no) VALUES (1, '卡片测试', 10, ?)", cardNo)
+	if err != nil {
+		t.Fatalf("插入用户失败: %v", err)
+	}
+	userId, _ := result.LastInsertId()
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM sys_card WHERE user_id = ?", userId)
+		db.Exec("DELETE FROM sys_user WHERE user_id = ?", userId)
+	})
+
+	repo := NewCardRepository(db)
+	var blocked *CardBlockedError
+
+	// 未登记到卡片表时按用户表卡号查询
+	if user, err := repo.FindUserByCardNo(cardNo); err != nil || user.UserId != int(userId) {
+		t.Fatalf("FindUserByCardNo() = %+v, %v", user, err)
+	}
+
+	if _, err := db.Exec("INSERT INTO sys_card (card_no, user_id, status) VALUES (?, ?, ?)", cardNo, userId, model.CardStatusLost); err != nil {
+		t.Fatalf("插入卡片失败: %v", err)
+	}
+	user, err := repo.FindUserByCardNo(cardNo)
+	if !errors.As(err, &blocked) || blocked.Status != model.CardStatusLost || user.UserId != int(userId) {
+		t.Fatalf("挂失卡 FindUserByCardNo() = %+v, %v", user, err)
+	}
+
+	// 正常卡但用户当前卡号已变更
+	db.Exec("UPDATE sys_card SET status = ? WHERE card_no = ?", model.CardStatusActive, cardNo)
+	db.Exec("UPDATE sys_user SET card_no = ? WHERE user_id = ?", cardNo+"N", userId)
+	if _, err := repo.FindUserByCardNo(cardNo); !errors.As(err, &blocked) || blocked.Status != model.CardStatusRetired {
+		t.Fatalf("旧卡 FindUserByCardNo() err = %v, 期望已注销", err)
+	}
+}
@@ -0,0 +1,209 @@
+package card_manage
+
+import (
+	"canteen/internal/model"
+	"database/sql"
+)
+
+type CardManageRepository interface {
+	// FindCards 查询卡片，userId 为 0、status 为空时不限
+	FindCards(userId int, status string) ([]model.Card, error)
+	FindByCardNo(cardNo string) (*model.Card, error)
+	FindLogs(cardNo string) ([]model.CardLog, error)
+	// WithTx 在同一数据库事务中执行 fn，fn 返回错误时整体回滚
+	WithTx(fn func(tx CardManageTx) error) error
+}
+
+// CardManageTx 卡片状态变更事务内可执行的操作
+type CardManageTx interface {
+	// EnsureCard 将仅登记在 sys_user.card_no 中的卡号补录为正常卡
+	EnsureCard(cardNo string) error
+	// LockCard 加行锁读取卡片
+	LockCard(cardNo string) (*model.Card, error)
+	UpdateStatus(cardId int, status string, replacedBy string, remark string) error
+	CreateCard(card *model.Card) (int, error)
+	// CardNoInUse 卡号是否已登记在卡片表或用户表中
+	CardNoInUse(cardNo string) (bool, error)
+	// SetUserCardNo 更新用户当前卡号
+	SetUserCardNo(userId int, cardNo string) error
+	CreateLog(cardLog *model.CardLog) error
+}
+
+type cardManageRepository struct {
+	db *sql.DB
+}
+
+func NewCardManageRepository(db *sql.DB) CardManageRepository {
+	return &cardManageRepository{db: db}
+}
+
+const cardColumns = `c.id, c.card_no, c.user_id, u.nick_name, c.status, c.replaced_by, c.remark, c.status_time, c.create_time`
+
+// rowScanner 兼容 *sql.Row 与 *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanCard(row rowScanner) (*model.Card, error) {
+	var card model.Card
+	var nickName, replacedBy, remark sql.NullString
+	var statusTime, createTime sql.NullTime
+	err := row.Scan(&card.Id, &card.CardNo, &card.UserId, &nickName, &card.Status, &replacedBy, &remark, &statusTime, &createTime)
+	if err != nil {
+		return nil, err
+	}
+	card.NickName = nickName.String
+	card.ReplacedBy = replacedBy.String
+	card.Remark = remark.String
+	if statusTime.Valid {
+		card.StatusTime = statusTime.Time.Format("2006-01-02 15:04:05")
+	}
+	if createTime.Valid {
+		card.CreateTime = createTime.Time.Format("2006-01-02 15:04:05")
+	}
+	return &card, nil
+}
+
+func (r *cardManageRepository) FindCards(userId int, status string) ([]model.Card, error) {
+	query := "SELECT " + cardColumns + " FROM sys_card c LEFT JOIN sys_user u ON u.user_id = c.user_id WHERE 1 = 1"
+	args := []interface{}{}
+	if userId != 0 {
+		query += " AND c.user_id = ?"
+		args = append(args, userId)
+	}
+	if status != "" {
+		query += " AND c.status = ?"
+		args = append(args, status)
+	}
+	rows, err := r.db.Query(query+" ORDER BY c.user_id, c.id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cards := []model.Card{}
+	for rows.Next() {
+		card, err := scanCard(rows)
+		if err != nil {
+			return nil, err
+		}
+		cards = append(cards, *card)
+	}
+
+	return cards, rows.Err()
+}
+
+func (r *cardManageRepository) FindByCardNo(cardNo string) (*model.Card, error) {
+	return scanCard(r.db.QueryRow(
+		"SELECT "+cardColumns+" FROM sys_card c LEFT JOIN sys_user u ON u.user_id = c.user_id WHERE c.card_no = ?",
+		cardNo,
+	))
+}
+
+func (r *cardManageRepository) FindLogs(cardNo string) ([]model.CardLog, error) {
+	rows, err := r.db.Query(`
+		SELECT id, card_no, user_id, action, from_status, to_status, operator, remark, create_time
+		FROM sys_card_log
+		WHERE card_no = ?
+		ORDER BY id DESC
+	`, cardNo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	logs := []model.CardLog{}
+	for rows.Next() {
+		var cardLog model.CardLog
+		var fromStatus, remark sql.NullString
+		var createTime sql.NullTime
+		if err := rows.Scan(&cardLog.Id, &cardLog.CardNo, &cardLog.UserId, &cardLog.Action, &fromStatus, &cardLog.ToStatus,
+			&cardLog.Operator, &remark, &createTime); err != nil {
+			return nil, err
+		}
+		cardLog.FromStatus = fromStatus.String
+		cardLog.Remark = remark.String
+		if createTime.Valid {
+			cardLog.CreateTime = createTime.Time.Format("2006-01-02 15:04:05")
+		}
+		logs = append(logs, cardLog)
+	}
+
+	return logs, rows.Err()
+}
+
+func (r *cardManageRepository) WithTx(fn func(tx CardManageTx) error) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(&cardManageTx{tx: tx}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+type cardManageTx struct {
+	tx *sql.Tx
+}
+
+func (t *cardManageTx) EnsureCard(cardNo string) error {
+	_, err := t.tx.Exec(`
+		INSERT IGNORE INTO sys_card (card_no, user_id, status, status_time, create_time, update_time)
+		SELECT card_no, user_id, ?, NOW(), NOW(), NOW()
+		FROM sys_user WHERE card_no = ?
+	`, model.CardStatusActive, cardNo)
+	return err
+}
+
+func (t *cardManageTx) LockCard(cardNo string) (*model.Card, error) {
+	return scanCard(t.tx.QueryRow(
+		"SELECT "+cardColumns+" FROM sys_card c LEFT JOIN sys_user u ON u.user_id = c.user_id WHERE c.card_no = ? FOR UPDATE",
+		cardNo,
+	))
+}
+
+func (t *cardManageTx) UpdateStatus(cardId int, status string, replacedBy string, remark string) error {
+	_, err := t.tx.Exec(`
+		UPDATE sys_card
+		SET status = ?, replaced_by = NULLIF(?, ''), remark = ?, status_time = NOW(), update_time = NOW()
+		WHERE id = ?
+	`, status, replacedBy, remark, cardId)
+	return err
+}
+
+func (t *cardManageTx) CreateCard(card *model.Card) (int, error) {
+	result, err := t.tx.Exec(`
+		INSERT INTO sys_card (card_no, user_id, status, remark, status_time, create_time, update_time)
+		VALUES (?, ?, ?, ?, NOW(), NOW(), NOW())
+	`, card.CardNo, card.UserId, card.Status, card.Remark)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+func (t *cardManageTx) CardNoInUse(cardNo string) (bool, error) {
+	var exists int
+	err := t.tx.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM sys_card WHERE card_no = ?) OR EXISTS(SELECT 1 FROM sys_user WHERE card_no = ?)
+	`, cardNo, cardNo).Scan(&exists)
+	return exists == 1, err
+}
+
+func (t *cardManageTx) SetUserCardNo(userId int, cardNo string) error {
+	_, err := t.tx.Exec("UPDATE sys_user SET card_no = ?, update_time = NOW() WHERE user_id = ?", cardNo, userId)
+	return err
+}
+
+func (t *cardManageTx) CreateLog(cardLog *model.CardLog) error {
+	_, err := t.tx.Exec(`
+		INSERT INTO sys_card_log (card_no, user_id, action, from_status, to_status, operator, remark, create_time)
+		VALUES (?, ?, ?, NULLIF(?, ''), ?, ?, ?, NOW())
+	`, cardLog.CardNo, cardLog.UserId, cardLog.Action, cardLog.FromStatus, cardLog.ToStatus, cardLog.Operator, cardLog.Remark)
+	return err
+}
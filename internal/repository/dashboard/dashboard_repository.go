@@ -0,0 +1,49 @@
+package dashboard
+
+import (
+	"canteen/internal/model"
+	"database/sql"
+	"strings"
+)
+
+type DashboardRepository interface {
+	// CountOrdersByWindow 按订单所属周套餐的窗口统计某日（yyyyMMdd）某餐别的已报餐、已领取、临时用餐订单数
+	CountOrdersByWindow(date, mealType string) ([]model.WindowCounter, error)
+}
+
+type dashboardRepository struct {
+	db *sql.DB
+}
+
+func NewDashboardRepository(db *sql.DB) DashboardRepository {
+	return &dashboardRepository{db: db}
+}
+
+func (r *dashboardRepository) CountOrdersByWindow(date, mealType string) ([]model.WindowCounter, error) {
+	rows, err := r.db.Query(`
+		SELECT IFNULL(ws.remark, ''),
+			SUM(CASE WHEN o.status = ? THEN 1 ELSE 0 END),
+			SUM(CASE WHEN o.status = ? THEN 1 ELSE 0 END),
+			SUM(CASE WHEN o.status = ? THEN 1 ELSE 0 END)
+		FROM order_record o
+		JOIN weekly_setmeal ws ON ws.id = o.setmeal_id
+		WHERE o.week_number = ? AND o.meal_type = ?
+		GROUP BY ws.remark
+	`, model.OrderStatusBooked, model.OrderStatusCollected, model.OrderStatusTemp, date, mealType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counters := []model.WindowCounter{}
+	for rows.Next() {
+		var c model.WindowCounter
+		if err := rows.Scan(&c.Window, &c.Remaining, &c.Collected, &c.Temp); err != nil {
+			return nil, err
+		}
+		// 周套餐备注为“套餐”+窗口
+		c.Window = strings.TrimPrefix(c.Window, "套餐")
+		counters = append(counters, c)
+	}
+	return counters, rows.Err()
+}
@@ -0,0 +1,133 @@
+package device
+
+import (
+	"canteen/internal/model"
+	"database/sql"
+	"strings"
+)
+
+type DeviceRepository interface {
+	FindAll() ([]model.Device, error)
+	FindById(id int) (*model.Device, error)
+	FindBySerialNo(serialNo string) (*model.Device, error)
+	Create(device *model.Device) (int64, error)
+	Update(device *model.Device) error
+	Delete(id int) error
+	UpdateLastSeen(id int) error
+}
+
+type deviceRepository struct {
+	db *sql.DB
+}
+
+func NewDeviceRepository(db *sql.DB) DeviceRepository {
+	return &deviceRepository{db: db}
+}
+
+const deviceColumns = `id, serial_no, window_code, canteen, meal_types, enabled, last_seen_time, remark`
+
+// rowScanner 兼容 *sql.Row 与 *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanDevice(row rowScanner) (*model.Device, error) {
+	var device model.Device
+	var canteen, mealTypes, remark sql.NullString
+	var lastSeen sql.NullTime
+	err := row.Scan(&device.Id, &device.SerialNo, &device.Window, &canteen, &mealTypes, &device.Enabled, &lastSeen, &remark)
+	if err != nil {
+		return nil, err
+	}
+	device.Canteen = canteen.String
+	device.MealTypes = splitMealTypes(mealTypes.String)
+	device.Remark = remark.String
+	if lastSeen.Valid {
+		device.LastSeenTime = lastSeen.Time.Format("2006-01-02 15:04:05")
+	}
+	return &device, nil
+}
+
+func (r *deviceRepository) FindAll() ([]model.Device, error) {
+	rows, err := r.db.Query("SELECT " + deviceColumns + " FROM device ORDER BY canteen, window_code, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	devices := []model.Device{}
+	for rows.Next() {
+		device, err := scanDevice(rows)
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, *device)
+	}
+
+	return devices, rows.Err()
+}
+
+func (r *deviceRepository) FindById(id int) (*model.Device, error) {
+	return scanDevice(r.db.QueryRow("SELECT "+deviceColumns+" FROM device WHERE id = ?", id))
+}
+
+func (r *deviceRepository) FindBySerialNo(serialNo string) (*model.Device, error) {
+	return scanDevice(r.db.QueryRow("SELECT "+deviceColumns+" FROM device WHERE serial_no = ?", serialNo))
+}
+
+func (r *deviceRepository) Create(device *model.Device) (int64, error) {
+	result, err := r.db.Exec(`
+		INSERT INTO device (serial_no, window_code, canteen, meal_types, enabled, remark, create_time, update_time)
+		VALUES (?, ?, ?, ?, ?, ?, NOW(), NOW())
+	`, device.SerialNo, device.Window, device.Canteen, strings.Join(device.MealTypes, ","), device.Enabled, device.Remark)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func (r *deviceRepository) Update(device *model.Device) error {
+	_, err := r.db.Exec(`
+		UPDATE device
+		SET serial_no = ?, window_code = ?, canteen = ?, meal_types = ?, enabled = ?, remark = ?, update_time = NOW()
+		WHERE id = ?
+	`, device.SerialNo, device.Window, device.Canteen, strings.Join(device.MealTypes, ","), device.Enabled, device.Remark, device.Id)
+	return err
+}
+
+func (r *deviceRepository) Delete(id int) error {
+	result, err := r.db.Exec("DELETE FROM device WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+func (r *deviceRepository) UpdateLastSeen(id int) error {
+	_, err := r.db.Exec("UPDATE device SET last_seen_time = NOW() WHERE id = ?", id)
+	return err
+}
+
+// requireAffected 未命中任何记录时返回 sql.ErrNoRows
+func requireAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// splitMealTypes 将逗号分隔的餐别字符串拆分为列表
+func splitMealTypes(value string) []string {
+	mealTypes := []string{}
+	for _, t := range strings.Split(value, ",") {
+		t = strings.TrimSpace(t)
+		if t != "" {
+			mealTypes = append(mealTypes, t)
+		}
+	}
+	return mealTypes
+}
@@ -0,0 +1,418 @@
+package dish
+
+import (
+	"canteen/internal/model"
+	"database/sql"
+	"strings"
+)
+
+// dish_tag.tag_type 取值
+const (
+	tagTypeAllergen = "allergen"
+	tagTypeDiet     = "diet"
+)
+
+type DishRepository interface {
+	// FindDishes 按条件分页查询未删除的菜品，返回当页菜品及总数
+	FindDishes(query model.DishQuery, offset, limit int) ([]model.Dish, int, error)
+	// FindDishById 查询未删除的菜品，不存在时返回 sql.ErrNoRows
+	FindDishById(id int) (*model.Dish, error)
+	// DishCodeInUse 编码是否已被 excludeId 以外的未删除菜品使用
+	DishCodeInUse(code string, excludeId int) (bool, error)
+	// CreateDish 在同一事务中新建菜品及其标签、营养成分
+	CreateDish(dish *model.Dish) (int64, error)
+	// UpdateDish 在同一事务中修改菜品并重写标签、营养成分
+	UpdateDish(dish *model.Dish) error
+	// DeleteDish 软删除菜品，套餐中的关联保留
+	DeleteDish(id int) error
+	// FindTags 按菜品ID查询过敏原、饮食标签及营养成分，未标注的菜品返回空标签
+	FindTags(dishIds []int) (map[int]model.DishTags, error)
+	// FindSetmealTags 按套餐ID查询其未删除菜品的标签
+	FindSetmealTags(setmealIds []int) (map[int][]model.DishTags, error)
+
+	FindCategories() ([]model.DishCategory, error)
+	FindCategoryById(id int) (*model.DishCategory, error)
+	// CountDishes 统计分类下未删除的菜品数
+	CountDishes(categoryId int) (int, error)
+	CreateCategory(category *model.DishCategory) (int64, error)
+	UpdateCategory(category *model.DishCategory) error
+	DeleteCategory(id int) error
+}
+
+type dishRepository struct {
+	db *sql.DB
+}
+
+func NewDishRepository(db *sql.DB) DishRepository {
+	return &dishRepository{db: db}
+}
+
+const dishColumns = `
+	d.id, d.name, IFNULL(d.category_id, 0), IFNULL(c.name, ''), IFNULL(d.code, ''), IFNULL(d.description, ''),
+	IFNULL(d.image, ''), IFNULL(d.status, ''), IFNULL(d.sort, 0), d.create_time, d.update_time
+`
+
+func scanDish(row interface{ Scan(...interface{}) error }) (*model.Dish, error) {
+	var d model.Dish
+	var createTime, updateTime sql.NullTime
+	if err := row.Scan(&d.Id, &d.Name, &d.CategoryId, &d.CategoryName, &d.Code, &d.Description,
+		&d.Image, &d.Status, &d.Sort, &createTime, &updateTime); err != nil {
+		return nil, err
+	}
+	if createTime.Valid {
+		d.CreateTime = createTime.Time.Format("2006-01-02 15:04:05")
+	}
+	if updateTime.Valid {
+		d.UpdateTime = updateTime.Time.Format("2006-01-02 15:04:05")
+	}
+	return &d, nil
+}
+
+func (r *dishRepository) FindDishes(query model.DishQuery, offset, limit int) ([]model.Dish, int, error) {
+	where := " WHERE d.is_deleted = 0"
+	args := []interface{}{}
+	if query.Keyword != "" {
+		where += " AND (d.name LIKE ? OR d.code LIKE ?)"
+		keyword := "%" + query.Keyword + "%"
+		args = append(args, keyword, keyword)
+	}
+	if query.CategoryId != 0 {
+		where += " AND d.category_id = ?"
+		args = append(args, query.CategoryId)
+	}
+	if query.Status != "" {
+		where += " AND d.status = ?"
+		args = append(args, query.Status)
+	}
+
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM dish d"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.db.Query(
+		"SELECT "+dishColumns+" FROM dish d LEFT JOIN dish_category c ON c.id = d.category_id"+where+
+			" ORDER BY c.sort, d.category_id, d.sort, d.id LIMIT ? OFFSET ?",
+		append(args, limit, offset)...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	dishes := []model.Dish{}
+	for rows.Next() {
+		d, err := scanDish(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		dishes = append(dishes, *d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	ids := make([]int, len(dishes))
+	for i, d := range dishes {
+		ids[i] = d.Id
+	}
+	tags, err := r.FindTags(ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range dishes {
+		dishes[i].DishTags = tags[dishes[i].Id]
+	}
+	return dishes, total, nil
+}
+
+func (r *dishRepository) FindDishById(id int) (*model.Dish, error) {
+	d, err := scanDish(r.db.QueryRow(
+		"SELECT "+dishColumns+" FROM dish d LEFT JOIN dish_category c ON c.id = d.category_id WHERE d.id = ? AND d.is_deleted = 0",
+		id,
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := r.FindTags([]int{id})
+	if err != nil {
+		return nil, err
+	}
+	d.DishTags = tags[id]
+	return d, nil
+}
+
+func (r *dishRepository) DishCodeInUse(code string, excludeId int) (bool, error) {
+	var exists int
+	err := r.db.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM dish WHERE code = ? AND id <> ? AND is_deleted = 0)",
+		code, excludeId,
+	).Scan(&exists)
+	return exists == 1, err
+}
+
+func (r *dishRepository) CreateDish(d *model.Dish) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO dish (name, category_id, code, description, image, status, sort, create_time, update_time, is_deleted)
+		VALUES (?, NULLIF(?, 0), NULLIF(?, ''), ?, NULLIF(?, ''), ?, ?, NOW(), NOW(), 0)
+	`, d.Name, d.CategoryId, d.Code, d.Description, d.Image, d.Status, d.Sort)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if err := saveDishTags(tx, int(id), d.DishTags); err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+func (r *dishRepository) UpdateDish(d *model.Dish) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		UPDATE dish
+		SET name = ?, category_id = NULLIF(?, 0), code = NULLIF(?, ''), description = ?, image = NULLIF(?, ''), status = ?, sort = ?, update_time = NOW()
+		WHERE id = ? AND is_deleted = 0
+	`, d.Name, d.CategoryId, d.Code, d.Description, d.Image, d.Status, d.Sort, d.Id); err != nil {
+		return err
+	}
+	if err := saveDishTags(tx, d.Id, d.DishTags); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// saveDishTags 重写菜品的标签，营养成分为空时删除记录
+func saveDishTags(tx *sql.Tx, dishId int, tags model.DishTags) error {
+	if _, err := tx.Exec("DELETE FROM dish_tag WHERE dish_id = ?", dishId); err != nil {
+		return err
+	}
+	for tagType, values := range map[string][]string{tagTypeAllergen: tags.Allergens, tagTypeDiet: tags.Diets} {
+		for _, tag := range values {
+			if _, err := tx.Exec("INSERT INTO dish_tag (dish_id, tag_type, tag) VALUES (?, ?, ?)", dishId, tagType, tag); err != nil {
+				return err
+			}
+		}
+	}
+
+	if tags.Nutrition == nil {
+		_, err := tx.Exec("DELETE FROM dish_nutrition WHERE dish_id = ?", dishId)
+		return err
+	}
+	n := tags.Nutrition
+	_, err := tx.Exec(`
+		INSERT INTO dish_nutrition (dish_id, energy_kcal, protein_g, fat_g, carbohydrate_g, sodium_mg, update_time)
+		VALUES (?, ?, ?, ?, ?, ?, NOW())
+		ON DUPLICATE KEY UPDATE energy_kcal = VALUES(energy_kcal), protein_g = VALUES(protein_g), fat_g = VALUES(fat_g),
+			carbohydrate_g = VALUES(carbohydrate_g), sodium_mg = VALUES(sodium_mg), update_time = NOW()
+	`, dishId, n.EnergyKcal, n.ProteinG, n.FatG, n.CarbohydrateG, n.SodiumMg)
+	return err
+}
+
+func (r *dishRepository) DeleteDish(id int) error {
+	result, err := r.db.Exec("UPDATE dish SET is_deleted = 1, update_time = NOW() WHERE id = ? AND is_deleted = 0", id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *dishRepository) FindTags(dishIds []int) (map[int]model.DishTags, error) {
+	tags := make(map[int]model.DishTags, len(dishIds))
+	if len(dishIds) == 0 {
+		return tags, nil
+	}
+	for _, id := range dishIds {
+		tags[id] = model.DishTags{Allergens: []string{}, Diets: []string{}}
+	}
+	in, args := inClause(dishIds)
+
+	rows, err := r.db.Query("SELECT dish_id, tag_type, tag FROM dish_tag WHERE dish_id IN ("+in+") ORDER BY dish_id, id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var dishId int
+		var tagType, tag string
+		if err := rows.Scan(&dishId, &tagType, &tag); err != nil {
+			return nil, err
+		}
+		t := tags[dishId]
+		switch tagType {
+		case tagTypeAllergen:
+			t.Allergens = append(t.Allergens, tag)
+		case tagTypeDiet:
+			t.Diets = append(t.Diets, tag)
+		}
+		tags[dishId] = t
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	nutritionRows, err := r.db.Query(`
+		SELECT dish_id, energy_kcal, protein_g, fat_g, carbohydrate_g, sodium_mg
+		FROM dish_nutrition
+		WHERE dish_id IN (`+in+`)
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer nutritionRows.Close()
+	for nutritionRows.Next() {
+		var dishId int
+		var n model.Nutrition
+		if err := nutritionRows.Scan(&dishId, &n.EnergyKcal, &n.ProteinG, &n.FatG, &n.CarbohydrateG, &n.SodiumMg); err != nil {
+			return nil, err
+		}
+		t := tags[dishId]
+		t.Nutrition = &n
+		tags[dishId] = t
+	}
+	return tags, nutritionRows.Err()
+}
+
+func (r *dishRepository) FindSetmealTags(setmealIds []int) (map[int][]model.DishTags, error) {
+	result := map[int][]model.DishTags{}
+	if len(setmealIds) == 0 {
+		return result, nil
+	}
+	in, args := inClause(setmealIds)
+	rows, err := r.db.Query(`
+		SELECT sd.setmeal_id, sd.dish_id
+		FROM setmeal_dish sd
+		JOIN dish d ON d.id = sd.dish_id AND d.is_deleted = 0
+		WHERE sd.setmeal_id IN (`+in+`)
+		ORDER BY sd.setmeal_id, sd.sort, sd.id
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dishIds := map[int][]int{}
+	all := []int{}
+	for rows.Next() {
+		var setmealId, dishId int
+		if err := rows.Scan(&setmealId, &dishId); err != nil {
+			return nil, err
+		}
+		dishIds[setmealId] = append(dishIds[setmealId], dishId)
+		all = append(all, dishId)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tags, err := r.FindTags(all)
+	if err != nil {
+		return nil, err
+	}
+	for setmealId, ids := range dishIds {
+		for _, id := range ids {
+			result[setmealId] = append(result[setmealId], tags[id])
+		}
+	}
+	return result, nil
+}
+
+// inClause 生成 IN 子句的占位符及参数
+func inClause(ids []int) (string, []interface{}) {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return "?" + strings.Repeat(",?", len(ids)-1), args
+}
+
+func (r *dishRepository) FindCategories() ([]model.DishCategory, error) {
+	rows, err := r.db.Query("SELECT id, name, IFNULL(code, ''), IFNULL(sort, 0) FROM dish_category ORDER BY sort, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []model.DishCategory{}
+	for rows.Next() {
+		var c model.DishCategory
+		if err := rows.Scan(&c.Id, &c.Name, &c.Code, &c.Sort); err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
+
+func (r *dishRepository) FindCategoryById(id int) (*model.DishCategory, error) {
+	var c model.DishCategory
+	err := r.db.QueryRow("SELECT id, name, IFNULL(code, ''), IFNULL(sort, 0) FROM dish_category WHERE id = ?", id).
+		Scan(&c.Id, &c.Name, &c.Code, &c.Sort)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *dishRepository) CountDishes(categoryId int) (int, error) {
+	var count int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM dish WHERE category_id = ? AND is_deleted = 0", categoryId).Scan(&count)
+	return count, err
+}
+
+func (r *dishRepository) CreateCategory(c *model.DishCategory) (int64, error) {
+	result, err := r.db.Exec(`
+		INSERT INTO dish_category (name, code, sort, create_time, update_time)
+		VALUES (?, NULLIF(?, ''), ?, NOW(), NOW())
+	`, c.Name, c.Code, c.Sort)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func (r *dishRepository) UpdateCategory(c *model.DishCategory) error {
+	_, err := r.db.Exec(
+		"UPDATE dish_category SET name = ?, code = NULLIF(?, ''), sort = ?, update_time = NOW() WHERE id = ?",
+		c.Name, c.Code, c.Sort, c.Id,
+	)
+	return err
+}
+
+func (r *dishRepository) DeleteCategory(id int) error {
+	result, err := r.db.Exec("DELETE FROM dish_category WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
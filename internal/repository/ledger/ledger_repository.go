@@ -0,0 +1,237 @@
+package ledger
+
+import (
+	"canteen/internal/model"
+	"database/sql"
+	"errors"
+)
+
+// ErrInsufficientCount 调整后次数将小于 0
+var ErrInsufficientCount = errors.New("用餐次数不足")
+
+// Execer 兼容 *sql.DB 与 *sql.Tx，供其他仓储在各自事务中记账
+type Execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// AdjustCount 调整用户次数并写入流水，流水余额取调整后的 sys_user.count。
+// 所有对 sys_user.count 的修改都应通过该函数，且须在持有用户行锁的事务中调用
+func AdjustCount(exec Execer, entry *model.CountLedger) error {
+	if _, err := exec.Exec("UPDATE sys_user SET count = count + ? WHERE user_id = ?", entry.Change, entry.UserId); err != nil {
+		return err
+	}
+	return insertLedger(exec, entry)
+}
+
+// insertLedger 写入流水，余额取当前 sys_user.count
+func insertLedger(exec Execer, entry *model.CountLedger) error {
+	result, err := exec.Exec(`
+		INSERT INTO count_ledger
+		(user_id, change_amount, balance, change_type, ref_type, ref_id, operator, remark, create_time)
+		SELECT user_id, ?, count, ?, ?, ?, ?, ?, NOW()
+		FROM sys_user WHERE user_id = ?
+	`, entry.Change, entry.ChangeType, entry.RefType, entry.RefId, entry.Operator, entry.Remark, entry.UserId)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+type LedgerRepository interface {
+	// FindByUser 分页查询用户流水，日期为 yyyy-MM-dd，为空表示不限
+	FindByUser(userId int, startDate, endDate string, offset, limit int) ([]model.CountLedger, int, error)
+	SumByUser(userId int) (int, error)
+	// Adjust 在事务中锁定用户并调整次数，调整后小于 0 时返回 ErrInsufficientCount
+	Adjust(entry *model.CountLedger) error
+	// Correct 写入对账调整流水，使流水合计与当前 sys_user.count 一致，不修改次数
+	Correct(userId int, operator string, remark string) (*model.CountLedger, error)
+	// SeedOpeningBalances 为尚无期初余额流水的用户补写期初余额：已有流水时取首条流水变动前的余额，否则取当前次数
+	SeedOpeningBalances() (int64, error)
+	FindDrifts() ([]model.CountDrift, error)
+	SaveDrifts(drifts []model.CountDrift) error
+}
+
+type ledgerRepository struct {
+	db *sql.DB
+}
+
+func NewLedgerRepository(db *sql.DB) LedgerRepository {
+	return &ledgerRepository{db: db}
+}
+
+func (r *ledgerRepository) FindByUser(userId int, startDate, endDate string, offset, limit int) ([]model.CountLedger, int, error) {
+	where := "WHERE user_id = ?"
+	args := []interface{}{userId}
+	if startDate != "" {
+		where += " AND create_time >= ?"
+		args = append(args, startDate+" 00:00:00")
+	}
+	if endDate != "" {
+		where += " AND create_time <= ?"
+		args = append(args, endDate+" 23:59:59")
+	}
+
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM count_ledger "+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.db.Query(`
+		SELECT id, user_id, change_amount, balance, change_type, ref_type, ref_id, operator, remark, create_time
+		FROM count_ledger `+where+`
+		ORDER BY id DESC
+		LIMIT ? OFFSET ?
+	`, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := []model.CountLedger{}
+	for rows.Next() {
+		var entry model.CountLedger
+		var refType, refId, operator, remark sql.NullString
+		var createTime sql.NullTime
+		if err := rows.Scan(&entry.Id, &entry.UserId, &entry.Change, &entry.Balance, &entry.ChangeType,
+			&refType, &refId, &operator, &remark, &createTime); err != nil {
+			return nil, 0, err
+		}
+		entry.RefType = refType.String
+		entry.RefId = refId.String
+		entry.Operator = operator.String
+		entry.Remark = remark.String
+		if createTime.Valid {
+			entry.CreateTime = createTime.Time.Format("2006-01-02 15:04:05")
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, total, rows.Err()
+}
+
+func (r *ledgerRepository) SumByUser(userId int) (int, error) {
+	var sum int
+	err := r.db.QueryRow("SELECT IFNULL(SUM(change_amount), 0) FROM count_ledger WHERE user_id = ?", userId).Scan(&sum)
+	return sum, err
+}
+
+func (r *ledgerRepository) Adjust(entry *model.CountLedger) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var count int
+	if err := tx.QueryRow("SELECT count FROM sys_user WHERE user_id = ? FOR UPDATE", entry.UserId).Scan(&count); err != nil {
+		return err
+	}
+	if count+entry.Change < 0 {
+		return ErrInsufficientCount
+	}
+
+	if err := AdjustCount(tx, entry); err != nil {
+		return err
+	}
+	entry.Balance = count + entry.Change
+
+	return tx.Commit()
+}
+
+func (r *ledgerRepository) Correct(userId int, operator string, remark string) (*model.CountLedger, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var count, sum int
+	if err := tx.QueryRow("SELECT count FROM sys_user WHERE user_id = ? FOR UPDATE", userId).Scan(&count); err != nil {
+		return nil, err
+	}
+	if err := tx.QueryRow("SELECT IFNULL(SUM(change_amount), 0) FROM count_ledger WHERE user_id = ?", userId).Scan(&sum); err != nil {
+		return nil, err
+	}
+
+	entry := &model.CountLedger{
+		UserId:     userId,
+		Change:     count - sum,
+		Balance:    count,
+		ChangeType: model.LedgerTypeCorrect,
+		Operator:   operator,
+		Remark:     remark,
+	}
+	if entry.Change == 0 {
+		return entry, nil
+	}
+	if err := insertLedger(tx, entry); err != nil {
+		return nil, err
+	}
+
+	return entry, tx.Commit()
+}
+
+func (r *ledgerRepository) SeedOpeningBalances() (int64, error) {
+	result, err := r.db.Exec(`
+		INSERT INTO count_ledger (user_id, change_amount, balance, change_type, create_time)
+		SELECT u.user_id, IFNULL(f.balance - f.change_amount, u.count), IFNULL(f.balance - f.change_amount, u.count), ?,
+			IFNULL(f.create_time, NOW())
+		FROM sys_user u
+		LEFT JOIN count_ledger f ON f.id = (SELECT MIN(id) FROM count_ledger WHERE user_id = u.user_id)
+		WHERE NOT EXISTS (SELECT 1 FROM count_ledger l WHERE l.user_id = u.user_id AND l.change_type = ?)
+	`, model.LedgerTypeOpening, model.LedgerTypeOpening)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (r *ledgerRepository) FindDrifts() ([]model.CountDrift, error) {
+	rows, err := r.db.Query(`
+		SELECT u.user_id, u.nick_name, IFNULL(l.total, 0), u.count
+		FROM sys_user u
+		LEFT JOIN (
+			SELECT user_id, SUM(change_amount) AS total FROM count_ledger GROUP BY user_id
+		) l ON l.user_id = u.user_id
+		WHERE IFNULL(l.total, 0) <> u.count
+		ORDER BY u.user_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	drifts := []model.CountDrift{}
+	for rows.Next() {
+		var drift model.CountDrift
+		var nickName sql.NullString
+		if err := rows.Scan(&drift.UserId, &nickName, &drift.LedgerSum, &drift.Count); err != nil {
+			return nil, err
+		}
+		drift.NickName = nickName.String
+		drift.Drift = drift.Count - drift.LedgerSum
+		drifts = append(drifts, drift)
+	}
+
+	return drifts, rows.Err()
+}
+
+func (r *ledgerRepository) SaveDrifts(drifts []model.CountDrift) error {
+	for _, drift := range drifts {
+		_, err := r.db.Exec(`
+			INSERT INTO count_drift (user_id, ledger_sum, count, drift, check_time)
+			VALUES (?, ?, ?, ?, NOW())
+		`, drift.UserId, drift.LedgerSum, drift.Count, drift.Drift)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
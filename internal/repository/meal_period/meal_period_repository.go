@@ -0,0 +1,138 @@
+package meal_period
+
+import (
+	"canteen/internal/model"
+	"database/sql"
+	"strconv"
+	"strings"
+)
+
+type MealPeriodRepository interface {
+	FindAll() ([]model.MealPeriod, error)
+	FindByCanteen(canteen string) ([]model.MealPeriod, error)
+	FindById(id int) (*model.MealPeriod, error)
+	Create(period *model.MealPeriod) (int64, error)
+	Update(period *model.MealPeriod) error
+	Delete(id int) error
+}
+
+type mealPeriodRepository struct {
+	db *sql.DB
+}
+
+func NewMealPeriodRepository(db *sql.DB) MealPeriodRepository {
+	return &mealPeriodRepository{db: db}
+}
+
+const mealPeriodColumns = `id, canteen, name, code, start_time, end_time, weekdays, sort, enabled`
+
+// rowScanner 兼容 *sql.Row 与 *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanMealPeriod(row rowScanner) (*model.MealPeriod, error) {
+	var period model.MealPeriod
+	var weekdays string
+	err := row.Scan(&period.Id, &period.Canteen, &period.Name, &period.Code, &period.StartTime, &period.EndTime, &weekdays, &period.Sort, &period.Enabled)
+	if err != nil {
+		return nil, err
+	}
+	period.Weekdays = splitWeekdays(weekdays)
+	return &period, nil
+}
+
+func (r *mealPeriodRepository) query(query string, args ...interface{}) ([]model.MealPeriod, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	periods := []model.MealPeriod{}
+	for rows.Next() {
+		period, err := scanMealPeriod(rows)
+		if err != nil {
+			return nil, err
+		}
+		periods = append(periods, *period)
+	}
+
+	return periods, rows.Err()
+}
+
+func (r *mealPeriodRepository) FindAll() ([]model.MealPeriod, error) {
+	return r.query("SELECT " + mealPeriodColumns + " FROM meal_period ORDER BY canteen, sort, start_time")
+}
+
+func (r *mealPeriodRepository) FindByCanteen(canteen string) ([]model.MealPeriod, error) {
+	return r.query("SELECT "+mealPeriodColumns+" FROM meal_period WHERE canteen = ? ORDER BY sort, start_time", canteen)
+}
+
+func (r *mealPeriodRepository) FindById(id int) (*model.MealPeriod, error) {
+	return scanMealPeriod(r.db.QueryRow("SELECT "+mealPeriodColumns+" FROM meal_period WHERE id = ?", id))
+}
+
+func (r *mealPeriodRepository) Create(period *model.MealPeriod) (int64, error) {
+	result, err := r.db.Exec(`
+		INSERT INTO meal_period (canteen, name, code, start_time, end_time, weekdays, sort, enabled, create_time, update_time)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, NOW(), NOW())
+	`, period.Canteen, period.Name, period.Code, period.StartTime, period.EndTime, joinWeekdays(period.Weekdays), period.Sort, period.Enabled)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func (r *mealPeriodRepository) Update(period *model.MealPeriod) error {
+	_, err := r.db.Exec(`
+		UPDATE meal_period
+		SET canteen = ?, name = ?, code = ?, start_time = ?, end_time = ?, weekdays = ?, sort = ?, enabled = ?, update_time = NOW()
+		WHERE id = ?
+	`, period.Canteen, period.Name, period.Code, period.StartTime, period.EndTime, joinWeekdays(period.Weekdays), period.Sort, period.Enabled, period.Id)
+	return err
+}
+
+// Delete 删除餐次及其套餐模板
+func (r *mealPeriodRepository) Delete(id int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("DELETE FROM meal_period WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	if _, err := tx.Exec("DELETE FROM setmeal_template WHERE meal_period_id = ?", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// splitWeekdays 解析逗号分隔的星期列表
+func splitWeekdays(value string) []int {
+	weekdays := []int{}
+	for _, s := range strings.Split(value, ",") {
+		if d, err := strconv.Atoi(strings.TrimSpace(s)); err == nil {
+			weekdays = append(weekdays, d)
+		}
+	}
+	return weekdays
+}
+
+func joinWeekdays(weekdays []int) string {
+	parts := make([]string, 0, len(weekdays))
+	for _, d := range weekdays {
+		parts = append(parts, strconv.Itoa(d))
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,270 @@
+package menu
+
+import (
+	"canteen/internal/model"
+	"database/sql"
+	"strings"
+)
+
+type MenuRepository interface {
+	// FindCategories 按排序查询菜品分类
+	FindCategories() ([]model.DishCategory, error)
+	// FindDishes 查询未删除的菜品，按分类、排序返回
+	FindDishes() ([]model.MenuDish, error)
+	// FindDishIds 按名称查询未删除的菜品，返回名称到ID的映射
+	FindDishIds(names []string) (map[string]int, error)
+	// FindWeeklySlots 查询 [startDate, endDate]（yyyyMMdd）内的周套餐
+	FindWeeklySlots(startDate, endDate string) ([]model.WeeklySlot, error)
+	// FindSetmealIdsByCodes 按编码查询未删除的套餐，返回编码到ID的映射
+	FindSetmealIdsByCodes(codes []string) (map[string]int, error)
+	// FindSetmealDishes 查询套餐的菜品，按排序返回
+	FindSetmealDishes(setmealIds []int) (map[int][]model.MenuDish, error)
+	// SaveMenus 在同一事务中新建菜品、新建或更新套餐菜品，并将套餐关联到周套餐；未变化的菜单跳过
+	SaveMenus(menus []model.WeekMenu, newDishes []model.MenuDish) error
+}
+
+type menuRepository struct {
+	db *sql.DB
+}
+
+func NewMenuRepository(db *sql.DB) MenuRepository {
+	return &menuRepository{db: db}
+}
+
+func (r *menuRepository) FindCategories() ([]model.DishCategory, error) {
+	rows, err := r.db.Query("SELECT id, name, IFNULL(code, ''), IFNULL(sort, 0) FROM dish_category ORDER BY sort, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []model.DishCategory{}
+	for rows.Next() {
+		var c model.DishCategory
+		if err := rows.Scan(&c.Id, &c.Name, &c.Code, &c.Sort); err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
+
+func (r *menuRepository) FindDishes() ([]model.MenuDish, error) {
+	rows, err := r.db.Query(`
+		SELECT d.id, d.name, IFNULL(d.category_id, 0), IFNULL(c.name, '')
+		FROM dish d
+		LEFT JOIN dish_category c ON c.id = d.category_id
+		WHERE d.is_deleted = 0
+		ORDER BY c.sort, d.category_id, d.sort, d.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dishes := []model.MenuDish{}
+	for rows.Next() {
+		var d model.MenuDish
+		if err := rows.Scan(&d.DishId, &d.Name, &d.CategoryId, &d.Category); err != nil {
+			return nil, err
+		}
+		dishes = append(dishes, d)
+	}
+	return dishes, rows.Err()
+}
+
+func (r *menuRepository) FindDishIds(names []string) (map[string]int, error) {
+	ids := map[string]int{}
+	if len(names) == 0 {
+		return ids, nil
+	}
+
+	placeholders, args := buildInClause(names)
+	rows, err := r.db.Query("SELECT id, name FROM dish WHERE is_deleted = 0 AND name IN ("+placeholders+") ORDER BY id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		// 同名菜品取最早创建的
+		if _, ok := ids[name]; !ok {
+			ids[name] = id
+		}
+	}
+	return ids, rows.Err()
+}
+
+func (r *menuRepository) FindWeeklySlots(startDate, endDate string) ([]model.WeeklySlot, error) {
+	rows, err := r.db.Query(`
+		SELECT id, week_number, meal_type, IFNULL(remark, ''), IFNULL(setmeal_id, 0)
+		FROM weekly_setmeal
+		WHERE week_number BETWEEN ? AND ?
+		ORDER BY week_number, id
+	`, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	slots := []model.WeeklySlot{}
+	for rows.Next() {
+		var slot model.WeeklySlot
+		if err := rows.Scan(&slot.Id, &slot.WeekNumber, &slot.MealType, &slot.Remark, &slot.SetmealId); err != nil {
+			return nil, err
+		}
+		slots = append(slots, slot)
+	}
+	return slots, rows.Err()
+}
+
+func (r *menuRepository) FindSetmealIdsByCodes(codes []string) (map[string]int, error) {
+	ids := map[string]int{}
+	if len(codes) == 0 {
+		return ids, nil
+	}
+
+	placeholders, args := buildInClause(codes)
+	rows, err := r.db.Query("SELECT id, code FROM setmeal WHERE is_deleted = 0 AND code IN ("+placeholders+") ORDER BY id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var code string
+		if err := rows.Scan(&id, &code); err != nil {
+			return nil, err
+		}
+		ids[code] = id
+	}
+	return ids, rows.Err()
+}
+
+func (r *menuRepository) FindSetmealDishes(setmealIds []int) (map[int][]model.MenuDish, error) {
+	dishes := map[int][]model.MenuDish{}
+	if len(setmealIds) == 0 {
+		return dishes, nil
+	}
+
+	args := make([]interface{}, len(setmealIds))
+	for i, id := range setmealIds {
+		args[i] = id
+	}
+	rows, err := r.db.Query(`
+		SELECT sd.setmeal_id, d.id, d.name, IFNULL(d.category_id, 0), IFNULL(c.name, '')
+		FROM setmeal_dish sd
+		JOIN dish d ON d.id = sd.dish_id
+		LEFT JOIN dish_category c ON c.id = d.category_id
+		WHERE sd.setmeal_id IN (?`+strings.Repeat(",?", len(args)-1)+`)
+		ORDER BY sd.setmeal_id, sd.sort, sd.id
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var setmealId int
+		var d model.MenuDish
+		if err := rows.Scan(&setmealId, &d.DishId, &d.Name, &d.CategoryId, &d.Category); err != nil {
+			return nil, err
+		}
+		dishes[setmealId] = append(dishes[setmealId], d)
+	}
+	return dishes, rows.Err()
+}
+
+func (r *menuRepository) SaveMenus(menus []model.WeekMenu, newDishes []model.MenuDish) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	created := map[string]int{}
+	for _, dish := range newDishes {
+		result, err := tx.Exec(`
+			INSERT INTO dish (name, category_id, status, create_time, update_time, is_deleted)
+			VALUES (?, NULLIF(?, 0), '启用', NOW(), NOW(), 0)
+		`, dish.Name, dish.CategoryId)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		created[dish.Name] = int(id)
+	}
+
+	for _, menu := range menus {
+		if menu.Action == model.MenuActionUnchanged {
+			continue
+		}
+
+		names := make([]string, len(menu.Dishes))
+		for i, dish := range menu.Dishes {
+			names[i] = dish.Name
+		}
+		name := menu.Day + " " + menu.MealType + " 套餐" + menu.Window
+		description := strings.Join(names, "+")
+
+		setmealId := menu.SetmealId
+		if setmealId == 0 {
+			result, err := tx.Exec(`
+				INSERT INTO setmeal (name, code, description, status, create_time, update_time, is_deleted)
+				VALUES (?, ?, ?, '启用', NOW(), NOW(), 0)
+			`, name, menu.SetmealCode, description)
+			if err != nil {
+				return err
+			}
+			id, err := result.LastInsertId()
+			if err != nil {
+				return err
+			}
+			setmealId = int(id)
+		} else {
+			if _, err := tx.Exec("UPDATE setmeal SET name = ?, description = ?, update_time = NOW() WHERE id = ?", name, description, setmealId); err != nil {
+				return err
+			}
+			if _, err := tx.Exec("DELETE FROM setmeal_dish WHERE setmeal_id = ?", setmealId); err != nil {
+				return err
+			}
+		}
+
+		for i, dish := range menu.Dishes {
+			dishId := dish.DishId
+			if dishId == 0 {
+				dishId = created[dish.Name]
+			}
+			if _, err := tx.Exec(
+				"INSERT INTO setmeal_dish (setmeal_id, dish_id, sort, create_time) VALUES (?, ?, ?, NOW())",
+				setmealId, dishId, i+1,
+			); err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.Exec("UPDATE weekly_setmeal SET setmeal_id = ? WHERE id = ?", setmealId, menu.WeeklySetmealId); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// buildInClause 构造 SQL 中 IN 子句的 (?, ?, ...) 和参数列表
+func buildInClause(values []string) (string, []interface{}) {
+	args := make([]interface{}, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+	return "?" + strings.Repeat(",?", len(values)-1), args
+}
@@ -0,0 +1,122 @@
+package noshow
+
+import (
+	"canteen/internal/model"
+	"canteen/internal/repository/canteen_config"
+	"database/sql"
+)
+
+type NoShowRepository interface {
+	// FindBookingCounts 按用户统计 [startDate, endDate]（yyyyMMdd）内已领取及已过期的订单数，部门取用户当前所在部门；
+	// sinceLastBlock 为 true 时不计用户最近一次暂停报餐开始前的订单，已处罚过的爽约不再重复计入
+	FindBookingCounts(startDate, endDate string, sinceLastBlock bool) ([]model.NoShowUser, error)
+	// FindNoShowConfigs 读取 canteen_config 中 no_show 开头的配置
+	FindNoShowConfigs() (map[string]string, error)
+	// FindActiveBlocks 查询 date（yyyy-MM-dd）生效中的暂停报餐记录
+	FindActiveBlocks(date string) ([]model.BookingBlock, error)
+	CreateBlock(block model.BookingBlock) (int, error)
+	// RevokeBlock 解除暂停报餐，记录不存在或已解除时返回 false
+	RevokeBlock(id int) (bool, error)
+}
+
+type noShowRepository struct {
+	db         *sql.DB
+	configRepo canteen_config.ConfigRepository
+}
+
+func NewNoShowRepository(db *sql.DB) NoShowRepository {
+	return &noShowRepository{db: db, configRepo: canteen_config.NewConfigRepository(db)}
+}
+
+func (r *noShowRepository) FindBookingCounts(startDate, endDate string, sinceLastBlock bool) ([]model.NoShowUser, error) {
+	query := `
+		SELECT ord.user_id, IFNULL(s.user_name, ''), IFNULL(s.nick_name, ''), IFNULL(s.dept_id, 0), IFNULL(sd.dept_name, ''),
+			SUM(CASE WHEN ord.status = ? THEN 1 ELSE 0 END),
+			SUM(CASE WHEN ord.status = ? THEN 1 ELSE 0 END)
+		FROM order_record ord
+		LEFT JOIN sys_user s ON ord.user_id = s.user_id
+		LEFT JOIN sys_dept sd ON s.dept_id = sd.dept_id
+		WHERE ord.week_number BETWEEN ? AND ? AND ord.status IN (?, ?)
+	`
+	if sinceLastBlock {
+		query += `
+		AND ord.week_number >= IFNULL((
+			SELECT DATE_FORMAT(MAX(b.start_date), '%Y%m%d') FROM booking_block b WHERE b.user_id = ord.user_id
+		), '')
+	`
+	}
+	query += `
+		GROUP BY ord.user_id, s.user_name, s.nick_name, s.dept_id, sd.dept_name
+	`
+	rows, err := r.db.Query(query, model.OrderStatusCollected, model.OrderStatusExpired,
+		startDate, endDate, model.OrderStatusCollected, model.OrderStatusExpired)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []model.NoShowUser{}
+	for rows.Next() {
+		var u model.NoShowUser
+		var collected int
+		if err := rows.Scan(&u.UserId, &u.WorkNo, &u.Name, &u.DeptId, &u.DeptName, &collected, &u.NoShow); err != nil {
+			return nil, err
+		}
+		u.Booked = collected + u.NoShow
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func (r *noShowRepository) FindNoShowConfigs() (map[string]string, error) {
+	return r.configRepo.FindByPrefix("no_show")
+}
+
+func (r *noShowRepository) FindActiveBlocks(date string) ([]model.BookingBlock, error) {
+	rows, err := r.db.Query(`
+		SELECT b.id, b.user_id, IFNULL(s.user_name, ''), IFNULL(s.nick_name, ''), IFNULL(sd.dept_name, ''),
+			DATE_FORMAT(b.start_date, '%Y-%m-%d'), DATE_FORMAT(b.end_date, '%Y-%m-%d'), b.no_show_count, IFNULL(b.reason, ''),
+			IFNULL(DATE_FORMAT(b.create_time, '%Y-%m-%d %H:%i:%s'), '')
+		FROM booking_block b
+		LEFT JOIN sys_user s ON b.user_id = s.user_id
+		LEFT JOIN sys_dept sd ON s.dept_id = sd.dept_id
+		WHERE b.revoked = 0 AND b.start_date <= ? AND b.end_date >= ?
+		ORDER BY b.end_date, b.id
+	`, date, date)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	blocks := []model.BookingBlock{}
+	for rows.Next() {
+		var b model.BookingBlock
+		if err := rows.Scan(&b.Id, &b.UserId, &b.WorkNo, &b.Name, &b.DeptName,
+			&b.StartDate, &b.EndDate, &b.NoShow, &b.Reason, &b.CreateTime); err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, b)
+	}
+	return blocks, rows.Err()
+}
+
+func (r *noShowRepository) CreateBlock(block model.BookingBlock) (int, error) {
+	result, err := r.db.Exec(`
+		INSERT INTO booking_block (user_id, start_date, end_date, no_show_count, reason)
+		VALUES (?, ?, ?, ?, ?)
+	`, block.UserId, block.StartDate, block.EndDate, block.NoShow, block.Reason)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+func (r *noShowRepository) RevokeBlock(id int) (bool, error) {
+	result, err := r.db.Exec("UPDATE booking_block SET revoked = 1 WHERE id = ? AND revoked = 0", id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
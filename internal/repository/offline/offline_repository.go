@@ -0,0 +1,168 @@
+package offline
+
+import (
+	"canteen/internal/model"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// ErrDuplicateOffline 同一终端交易号的脱机记录已存在
+var ErrDuplicateOffline = errors.New("脱机记录已存在")
+
+type OfflineRepository interface {
+	Create(record *model.OfflineTransaction, transTime time.Time) (int, error)
+	FindById(id int) (*model.OfflineTransaction, error)
+	FindByDeviceOrder(deviceNo string, orderNo string) (*model.OfflineTransaction, error)
+	FindByStatus(status string) ([]model.OfflineTransaction, error)
+	UpdateStatus(id int, status string, reason string) error
+	Resolve(id int, operator string, remark string) error
+}
+
+type offlineRepository struct {
+	db *sql.DB
+}
+
+func NewOfflineRepository(db *sql.DB) OfflineRepository {
+	return &offlineRepository{db: db}
+}
+
+const offlineColumns = `
+	o.id, o.device_no, o.order_no, o.card_no, u.nick_name, o.trans_time, o.money, o.pay_type, o.card_mode,
+	o.status, o.reason, o.operator, o.handle_remark, o.handle_time, o.create_time
+`
+
+// rowScanner 兼容 *sql.Row 与 *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanOffline(row rowScanner) (*model.OfflineTransaction, error) {
+	var record model.OfflineTransaction
+	var cardNo, nickName, money, reason, operator, handleRemark sql.NullString
+	var payType, cardMode sql.NullInt64
+	var transTime, handleTime, createTime sql.NullTime
+	err := row.Scan(&record.Id, &record.DeviceNo, &record.OrderNo, &cardNo, &nickName, &transTime, &money, &payType, &cardMode,
+		&record.Status, &reason, &operator, &handleRemark, &handleTime, &createTime)
+	if err != nil {
+		return nil, err
+	}
+
+	record.CardNo = cardNo.String
+	record.NickName = nickName.String
+	record.Money = money.String
+	record.PayType = int(payType.Int64)
+	record.CardMode = int(cardMode.Int64)
+	record.Reason = reason.String
+	record.Operator = operator.String
+	record.HandleRemark = handleRemark.String
+	record.TransTime = formatNullTime(transTime)
+	record.HandleTime = formatNullTime(handleTime)
+	record.CreateTime = formatNullTime(createTime)
+	return &record, nil
+}
+
+func (r *offlineRepository) Create(record *model.OfflineTransaction, transTime time.Time) (int, error) {
+	result, err := r.db.Exec(`
+		INSERT INTO offline_transaction
+		(device_no, order_no, card_no, trans_time, money, pay_type, card_mode, status, create_time, update_time)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, NOW(), NOW())
+	`,
+		record.DeviceNo,
+		record.OrderNo,
+		record.CardNo,
+		transTime,
+		record.Money,
+		record.PayType,
+		record.CardMode,
+		record.Status,
+	)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+			return 0, ErrDuplicateOffline
+		}
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+func (r *offlineRepository) FindById(id int) (*model.OfflineTransaction, error) {
+	return scanOffline(r.db.QueryRow(`
+		SELECT `+offlineColumns+`
+		FROM offline_transaction o
+		LEFT JOIN sys_user u ON o.card_no = u.card_no
+		WHERE o.id = ?
+	`, id))
+}
+
+func (r *offlineRepository) FindByDeviceOrder(deviceNo string, orderNo string) (*model.OfflineTransaction, error) {
+	return scanOffline(r.db.QueryRow(`
+		SELECT `+offlineColumns+`
+		FROM offline_transaction o
+		LEFT JOIN sys_user u ON o.card_no = u.card_no
+		WHERE o.device_no = ? AND o.order_no = ?
+	`, deviceNo, orderNo))
+}
+
+func (r *offlineRepository) FindByStatus(status string) ([]model.OfflineTransaction, error) {
+	rows, err := r.db.Query(`
+		SELECT `+offlineColumns+`
+		FROM offline_transaction o
+		LEFT JOIN sys_user u ON o.card_no = u.card_no
+		WHERE o.status = ?
+		ORDER BY o.trans_time
+	`, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []model.OfflineTransaction{}
+	for rows.Next() {
+		record, err := scanOffline(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, *record)
+	}
+
+	return records, rows.Err()
+}
+
+func (r *offlineRepository) UpdateStatus(id int, status string, reason string) error {
+	_, err := r.db.Exec(
+		"UPDATE offline_transaction SET status = ?, reason = ?, update_time = NOW() WHERE id = ?",
+		status, reason, id,
+	)
+	return err
+}
+
+func (r *offlineRepository) Resolve(id int, operator string, remark string) error {
+	result, err := r.db.Exec(`
+		UPDATE offline_transaction
+		SET status = ?, operator = ?, handle_remark = ?, handle_time = NOW(), update_time = NOW()
+		WHERE id = ? AND status = ?
+	`, model.OfflineStatusResolved, operator, remark, id, model.OfflineStatusReconcile)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func formatNullTime(t sql.NullTime) string {
+	if !t.Valid {
+		return ""
+	}
+	return t.Time.Format("2006-01-02 15:04:05")
+}
@@ -2,21 +2,26 @@ package order
 
 import (
 	"canteen/internal/model"
+	"canteen/internal/repository/ledger"
 	"database/sql"
 	"fmt"
 	"log"
 	"strconv"
-	"time"
 
 	"github.com/xuri/excelize/v2"
 )
 
 type OrderRepository interface {
 	FindByWeekNumber(weekNumber string) ([]model.OrderRecord, error)
-	UpdateStatusToExpired(weekNumber string) error
-	FindExpiredOrdersByWeekNumber(weekNumber string) ([]int, error)
-	CreateOrder(order *model.OrderRecord) error
+	// FindUsersWithBookedOrders 查询当日仍有已报餐（未领取）订单的用户
+	FindUsersWithBookedOrders(weekNumber string) ([]int, error)
+	// ExpireUserOrders 将用户当日已报餐订单置为已过期，有订单过期且次数大于 0 时扣减一次并记账
+	ExpireUserOrders(userId int, weekNumber string) (expired int64, charged bool, err error)
+	// CancelBookedOrders 将当日已报餐订单置为已取消，不扣减次数，用于停餐日
+	CancelBookedOrders(weekNumber string) (int64, error)
 	FindOrdersForExport(weekNumber string) ([]ExportOrderRecord, error)
+	// EachOrderForMonth 按日期、部门、工号顺序逐行读取 yyyyMM 月的订单并回调，不整体加载到内存
+	EachOrderForMonth(month string, fn func(ExportOrderRecord) error) error
 	ExportToExcel(date string) (*excelize.File, error)
 }
 
@@ -25,6 +30,7 @@ type orderRepository struct {
 }
 
 type ExportOrderRecord struct {
+	UserId    int
 	WorkNo    string
 	Name      string
 	Dept      string
@@ -59,15 +65,7 @@ func (r *orderRepository) FindByWeekNumber(weekNumber string) ([]model.OrderReco
 	return orders, rows.Err()
 }
 
-func (r *orderRepository) UpdateStatusToExpired(weekNumber string) error {
-	_, err := r.db.Exec(
-		"UPDATE order_record SET status = '已过期', update_time = NOW() WHERE week_number = ? AND status = '已报餐'",
-		weekNumber,
-	)
-	return err
-}
-
-func (r *orderRepository) FindExpiredOrdersByWeekNumber(weekNumber string) ([]int, error) {
+func (r *orderRepository) FindUsersWithBookedOrders(weekNumber string) ([]int, error) {
 	rows, err := r.db.Query(
 		"SELECT DISTINCT user_id FROM order_record WHERE week_number = ? AND status = '已报餐'",
 		weekNumber,
@@ -89,12 +87,60 @@ func (r *orderRepository) FindExpiredOrdersByWeekNumber(weekNumber string) ([]in
 	return userIds, rows.Err()
 }
 
-func (r *orderRepository) CreateOrder(order *model.OrderRecord) error {
-	query := `INSERT INTO order_record (user_id, meal_id, status, create_time, meal_type, week_number, order_date, weekday) 
-	          VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
-	_, err := r.db.Exec(query, order.UserId, order.MealId, order.Status, time.Now(), 
-		order.MealType, order.WeekNumber, order.OrderDate, order.Weekday)
-	return err
+func (r *orderRepository) ExpireUserOrders(userId int, weekNumber string) (int64, bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+
+	// 与核销相同的加锁顺序：先用户后订单
+	var count int
+	err = tx.QueryRow("SELECT count FROM sys_user WHERE user_id = ? FOR UPDATE", userId).Scan(&count)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, false, err
+	}
+	userExists := err == nil
+
+	result, err := tx.Exec(
+		"UPDATE order_record SET status = '已过期', update_time = NOW() WHERE user_id = ? AND week_number = ? AND status = '已报餐'",
+		userId, weekNumber,
+	)
+	if err != nil {
+		return 0, false, err
+	}
+	expired, err := result.RowsAffected()
+	if err != nil {
+		return 0, false, err
+	}
+
+	charged := userExists && expired > 0 && count > 0
+	if charged {
+		err = ledger.AdjustCount(tx, &model.CountLedger{
+			UserId:     userId,
+			Change:     -1,
+			ChangeType: model.LedgerTypeExpiry,
+			RefType:    model.LedgerRefDate,
+			RefId:      weekNumber,
+			Remark:     fmt.Sprintf("过期订单%d个", expired),
+		})
+		if err != nil {
+			return 0, false, err
+		}
+	}
+
+	return expired, charged, tx.Commit()
+}
+
+func (r *orderRepository) CancelBookedOrders(weekNumber string) (int64, error) {
+	result, err := r.db.Exec(
+		"UPDATE order_record SET status = ?, update_time = NOW() WHERE week_number = ? AND status = ?",
+		model.OrderStatusCancelled, weekNumber, model.OrderStatusBooked,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 func (r *orderRepository) FindOrdersForExport(weekNumber string) ([]ExportOrderRecord, error) {
@@ -133,6 +179,40 @@ func (r *orderRepository) FindOrdersForExport(weekNumber string) ([]ExportOrderR
 	return orders, rows.Err()
 }
 
+func (r *orderRepository) EachOrderForMonth(month string, fn func(ExportOrderRecord) error) error {
+	rows, err := r.db.Query(`
+		SELECT
+			ord.user_id,
+			IFNULL(s.user_name, ''),
+			IFNULL(s.nick_name, ''),
+			IFNULL(sd.dept_name, ''),
+			IFNULL(ord.meal_type, ''),
+			DATE_FORMAT(STR_TO_DATE(CAST(ord.week_number AS CHAR), '%Y%m%d'), '%Y/%c/%e'),
+			IFNULL(ord.weekday, ''),
+			IFNULL(ord.status, '')
+		FROM order_record ord
+		LEFT JOIN sys_user s ON ord.user_id = s.user_id
+		LEFT JOIN sys_dept sd ON s.dept_id = sd.dept_id
+		WHERE ord.week_number LIKE ?
+		ORDER BY ord.week_number, sd.dept_name, s.user_name, ord.id
+	`, month+"%")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var o ExportOrderRecord
+		if err := rows.Scan(&o.UserId, &o.WorkNo, &o.Name, &o.Dept, &o.MealType, &o.Date, &o.Weekday, &o.Status); err != nil {
+			return err
+		}
+		if err := fn(o); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
 // ExportToExcel 将订单数据导出到Excel
 func (r *orderRepository) ExportToExcel(date string) (*excelize.File, error) {
 	// 验证日期格式
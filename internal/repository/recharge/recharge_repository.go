@@ -0,0 +1,221 @@
+package recharge
+
+import (
+	"canteen/internal/model"
+	"canteen/internal/repository/ledger"
+	"database/sql"
+	"errors"
+)
+
+// ErrAlreadyApplied 同一关联单据已为该用户记账（月度配额重复执行）
+var ErrAlreadyApplied = errors.New("本月配额已发放")
+
+type RechargeRepository interface {
+	// FindUsersByDept 查询部门下的用户，deptId 为 0 时查询全部用户
+	FindUsersByDept(deptId int) ([]model.UserVo, error)
+	// Recharge 在事务中锁定用户，按 mode 计算变动并记账，返回调整前次数。
+	// 变动为 0 时不写流水；entry 带关联单据且已记账时返回 ErrAlreadyApplied
+	Recharge(entry *model.CountLedger, mode string, amount int) (int, error)
+	// FindAppliedUsers 查询已按指定关联单据记账的用户
+	FindAppliedUsers(refType string, refId string) (map[int]bool, error)
+
+	FindRules() ([]model.AllotmentRule, error)
+	FindRuleById(id int) (*model.AllotmentRule, error)
+	CreateRule(rule *model.AllotmentRule) (int64, error)
+	UpdateRule(rule *model.AllotmentRule) error
+	DeleteRule(id int) error
+	// MarkRuleRun 记录规则最近执行月份
+	MarkRuleRun(id int, month string) error
+}
+
+type rechargeRepository struct {
+	db *sql.DB
+}
+
+func NewRechargeRepository(db *sql.DB) RechargeRepository {
+	return &rechargeRepository{db: db}
+}
+
+func (r *rechargeRepository) FindUsersByDept(deptId int) ([]model.UserVo, error) {
+	query := "SELECT user_id, dept_id, nick_name, count, card_no FROM sys_user"
+	args := []interface{}{}
+	if deptId != 0 {
+		query += " WHERE dept_id = ?"
+		args = append(args, deptId)
+	}
+	rows, err := r.db.Query(query+" ORDER BY user_id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []model.UserVo{}
+	for rows.Next() {
+		var user model.UserVo
+		var deptId sql.NullInt64
+		var nickName, cardNo sql.NullString
+		if err := rows.Scan(&user.UserId, &deptId, &nickName, &user.Count, &cardNo); err != nil {
+			return nil, err
+		}
+		user.DeptId = int(deptId.Int64)
+		user.NickName = nickName.String
+		user.CardNo = cardNo.String
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+func (r *rechargeRepository) Recharge(entry *model.CountLedger, mode string, amount int) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var before int
+	if err := tx.QueryRow("SELECT count FROM sys_user WHERE user_id = ? FOR UPDATE", entry.UserId).Scan(&before); err != nil {
+		return 0, err
+	}
+
+	if entry.RefType != "" && entry.RefId != "" {
+		var exists int
+		err := tx.QueryRow(
+			"SELECT 1 FROM count_ledger WHERE ref_type = ? AND ref_id = ? AND user_id = ? LIMIT 1",
+			entry.RefType, entry.RefId, entry.UserId,
+		).Scan(&exists)
+		if err == nil {
+			return before, ErrAlreadyApplied
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return before, err
+		}
+	}
+
+	entry.Change = amount
+	if mode == model.RechargeModeSet {
+		entry.Change = amount - before
+	}
+	entry.Balance = before + entry.Change
+	if entry.Change == 0 {
+		return before, nil
+	}
+	if entry.Balance < 0 {
+		return before, ledger.ErrInsufficientCount
+	}
+
+	if err := ledger.AdjustCount(tx, entry); err != nil {
+		return before, err
+	}
+
+	return before, tx.Commit()
+}
+
+func (r *rechargeRepository) FindAppliedUsers(refType string, refId string) (map[int]bool, error) {
+	rows, err := r.db.Query("SELECT DISTINCT user_id FROM count_ledger WHERE ref_type = ? AND ref_id = ?", refType, refId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]bool{}
+	for rows.Next() {
+		var userId int
+		if err := rows.Scan(&userId); err != nil {
+			return nil, err
+		}
+		applied[userId] = true
+	}
+
+	return applied, rows.Err()
+}
+
+const allotmentRuleColumns = `id, name, dept_id, mode, amount, day_of_month, enabled, last_run_month, remark, create_time, update_time`
+
+// rowScanner 兼容 *sql.Row 与 *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRule(row rowScanner) (*model.AllotmentRule, error) {
+	var rule model.AllotmentRule
+	var lastRunMonth, remark sql.NullString
+	var createTime, updateTime sql.NullTime
+	err := row.Scan(&rule.Id, &rule.Name, &rule.DeptId, &rule.Mode, &rule.Amount, &rule.DayOfMonth, &rule.Enabled,
+		&lastRunMonth, &remark, &createTime, &updateTime)
+	if err != nil {
+		return nil, err
+	}
+	rule.LastRunMonth = lastRunMonth.String
+	rule.Remark = remark.String
+	if createTime.Valid {
+		rule.CreateTime = createTime.Time.Format("2006-01-02 15:04:05")
+	}
+	if updateTime.Valid {
+		rule.UpdateTime = updateTime.Time.Format("2006-01-02 15:04:05")
+	}
+	return &rule, nil
+}
+
+func (r *rechargeRepository) FindRules() ([]model.AllotmentRule, error) {
+	rows, err := r.db.Query("SELECT " + allotmentRuleColumns + " FROM count_allotment_rule ORDER BY day_of_month, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []model.AllotmentRule{}
+	for rows.Next() {
+		rule, err := scanRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, *rule)
+	}
+
+	return rules, rows.Err()
+}
+
+func (r *rechargeRepository) FindRuleById(id int) (*model.AllotmentRule, error) {
+	return scanRule(r.db.QueryRow("SELECT "+allotmentRuleColumns+" FROM count_allotment_rule WHERE id = ?", id))
+}
+
+func (r *rechargeRepository) CreateRule(rule *model.AllotmentRule) (int64, error) {
+	result, err := r.db.Exec(`
+		INSERT INTO count_allotment_rule (name, dept_id, mode, amount, day_of_month, enabled, last_run_month, remark, create_time, update_time)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, NOW(), NOW())
+	`, rule.Name, rule.DeptId, rule.Mode, rule.Amount, rule.DayOfMonth, rule.Enabled, rule.LastRunMonth, rule.Remark)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func (r *rechargeRepository) UpdateRule(rule *model.AllotmentRule) error {
+	_, err := r.db.Exec(`
+		UPDATE count_allotment_rule
+		SET name = ?, dept_id = ?, mode = ?, amount = ?, day_of_month = ?, enabled = ?, remark = ?, update_time = NOW()
+		WHERE id = ?
+	`, rule.Name, rule.DeptId, rule.Mode, rule.Amount, rule.DayOfMonth, rule.Enabled, rule.Remark, rule.Id)
+	return err
+}
+
+func (r *rechargeRepository) DeleteRule(id int) error {
+	result, err := r.db.Exec("DELETE FROM count_allotment_rule WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *rechargeRepository) MarkRuleRun(id int, month string) error {
+	_, err := r.db.Exec("UPDATE count_allotment_rule SET last_run_month = ?, update_time = NOW() WHERE id = ?", month, id)
+	return err
+}
@@ -0,0 +1,190 @@
+package setmeal
+
+import (
+	"canteen/internal/model"
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+type SetmealRepository interface {
+	// FindById 查询未删除的套餐及其菜品，不存在时返回 sql.ErrNoRows
+	FindById(id int) (*model.Setmeal, error)
+	// FindIdByCode 查询编码对应的未删除套餐，不存在时返回 0
+	FindIdByCode(code string) (int, error)
+	// FindSlot 查询周套餐，不存在时返回 sql.ErrNoRows
+	FindSlot(weeklySetmealId int) (*model.WeeklySlot, error)
+	// FindDishes 按ID查询未删除的菜品
+	FindDishes(ids []int) (map[int]model.Dish, error)
+	// Create 在同一事务中新建套餐及菜品，并关联到周套餐
+	Create(setmeal *model.Setmeal, weeklySetmealId int) (int64, error)
+	// Update 在同一事务中修改套餐并按顺序重写菜品
+	Update(setmeal *model.Setmeal) error
+}
+
+type setmealRepository struct {
+	db *sql.DB
+}
+
+func NewSetmealRepository(db *sql.DB) SetmealRepository {
+	return &setmealRepository{db: db}
+}
+
+func (r *setmealRepository) FindById(id int) (*model.Setmeal, error) {
+	var s model.Setmeal
+	var createTime, updateTime sql.NullTime
+	err := r.db.QueryRow(`
+		SELECT id, IFNULL(name, ''), IFNULL(code, ''), IFNULL(description, ''), IFNULL(status, ''), create_time, update_time
+		FROM setmeal
+		WHERE id = ? AND is_deleted = 0
+	`, id).Scan(&s.Id, &s.Name, &s.Code, &s.Description, &s.Status, &createTime, &updateTime)
+	if err != nil {
+		return nil, err
+	}
+	if createTime.Valid {
+		s.CreateTime = createTime.Time.Format("2006-01-02 15:04:05")
+	}
+	if updateTime.Valid {
+		s.UpdateTime = updateTime.Time.Format("2006-01-02 15:04:05")
+	}
+
+	rows, err := r.db.Query(`
+		SELECT d.id, d.name, IFNULL(d.category_id, 0), IFNULL(c.name, ''), IFNULL(sd.sort, 0)
+		FROM setmeal_dish sd
+		JOIN dish d ON d.id = sd.dish_id
+		LEFT JOIN dish_category c ON c.id = d.category_id
+		WHERE sd.setmeal_id = ?
+		ORDER BY sd.sort, sd.id
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	s.Dishes = []model.SetmealDish{}
+	for rows.Next() {
+		var d model.SetmealDish
+		if err := rows.Scan(&d.DishId, &d.Name, &d.CategoryId, &d.CategoryName, &d.Sort); err != nil {
+			return nil, err
+		}
+		s.Dishes = append(s.Dishes, d)
+	}
+	return &s, rows.Err()
+}
+
+func (r *setmealRepository) FindIdByCode(code string) (int, error) {
+	var id int
+	err := r.db.QueryRow("SELECT id FROM setmeal WHERE code = ? AND is_deleted = 0 ORDER BY id LIMIT 1", code).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return id, err
+}
+
+func (r *setmealRepository) FindSlot(weeklySetmealId int) (*model.WeeklySlot, error) {
+	var slot model.WeeklySlot
+	err := r.db.QueryRow(`
+		SELECT id, week_number, meal_type, IFNULL(remark, ''), IFNULL(setmeal_id, 0)
+		FROM weekly_setmeal
+		WHERE id = ?
+	`, weeklySetmealId).Scan(&slot.Id, &slot.WeekNumber, &slot.MealType, &slot.Remark, &slot.SetmealId)
+	if err != nil {
+		return nil, err
+	}
+	return &slot, nil
+}
+
+func (r *setmealRepository) FindDishes(ids []int) (map[int]model.Dish, error) {
+	dishes := map[int]model.Dish{}
+	if len(ids) == 0 {
+		return dishes, nil
+	}
+
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	rows, err := r.db.Query(`
+		SELECT d.id, d.name, IFNULL(d.category_id, 0), IFNULL(c.name, ''), IFNULL(d.status, '')
+		FROM dish d
+		LEFT JOIN dish_category c ON c.id = d.category_id
+		WHERE d.is_deleted = 0 AND d.id IN (?`+strings.Repeat(",?", len(ids)-1)+`)
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var d model.Dish
+		if err := rows.Scan(&d.Id, &d.Name, &d.CategoryId, &d.CategoryName, &d.Status); err != nil {
+			return nil, err
+		}
+		dishes[d.Id] = d
+	}
+	return dishes, rows.Err()
+}
+
+func (r *setmealRepository) Create(s *model.Setmeal, weeklySetmealId int) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO setmeal (name, code, description, status, create_time, update_time, is_deleted)
+		VALUES (?, ?, ?, '启用', NOW(), NOW(), 0)
+	`, s.Name, s.Code, s.Description)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if err := insertSetmealDishes(tx, int(id), s.Dishes); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec("UPDATE weekly_setmeal SET setmeal_id = ? WHERE id = ?", id, weeklySetmealId); err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+func (r *setmealRepository) Update(s *model.Setmeal) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		"UPDATE setmeal SET name = ?, description = ?, update_time = NOW() WHERE id = ? AND is_deleted = 0",
+		s.Name, s.Description, s.Id,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM setmeal_dish WHERE setmeal_id = ?", s.Id); err != nil {
+		return err
+	}
+	if err := insertSetmealDishes(tx, s.Id, s.Dishes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// insertSetmealDishes 按顺序写入套餐菜品，sort 从 1 开始
+func insertSetmealDishes(tx *sql.Tx, setmealId int, dishes []model.SetmealDish) error {
+	for i, d := range dishes {
+		if _, err := tx.Exec(
+			"INSERT INTO setmeal_dish (setmeal_id, dish_id, sort, create_time) VALUES (?, ?, ?, NOW())",
+			setmealId, d.DishId, i+1,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package setmeal_template
+
+import (
+	"canteen/internal/model"
+	"database/sql"
+)
+
+type SetmealTemplateRepository interface {
+	// FindAll 查询套餐模板，mealPeriodId 为 0 时返回全部
+	FindAll(mealPeriodId int) ([]model.SetmealTemplate, error)
+	FindById(id int) (*model.SetmealTemplate, error)
+	Create(template *model.SetmealTemplate) (int64, error)
+	Update(template *model.SetmealTemplate) error
+	Delete(id int) error
+}
+
+type setmealTemplateRepository struct {
+	db *sql.DB
+}
+
+func NewSetmealTemplateRepository(db *sql.DB) SetmealTemplateRepository {
+	return &setmealTemplateRepository{db: db}
+}
+
+const setmealTemplateQuery = `
+	SELECT t.id, t.meal_period_id, IFNULL(p.canteen, ''), IFNULL(p.name, ''), t.weekday, t.window_code, t.sort, t.enabled, IFNULL(t.remark, '')
+	FROM setmeal_template t
+	LEFT JOIN meal_period p ON p.id = t.meal_period_id
+`
+
+func scanSetmealTemplate(row interface{ Scan(...interface{}) error }) (*model.SetmealTemplate, error) {
+	var t model.SetmealTemplate
+	if err := row.Scan(&t.Id, &t.MealPeriodId, &t.Canteen, &t.MealType, &t.Weekday, &t.Window, &t.Sort, &t.Enabled, &t.Remark); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *setmealTemplateRepository) FindAll(mealPeriodId int) ([]model.SetmealTemplate, error) {
+	query := setmealTemplateQuery
+	args := []interface{}{}
+	if mealPeriodId > 0 {
+		query += " WHERE t.meal_period_id = ?"
+		args = append(args, mealPeriodId)
+	}
+	query += " ORDER BY t.meal_period_id, t.weekday, t.sort, t.window_code"
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []model.SetmealTemplate{}
+	for rows.Next() {
+		t, err := scanSetmealTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, *t)
+	}
+
+	return templates, rows.Err()
+}
+
+func (r *setmealTemplateRepository) FindById(id int) (*model.SetmealTemplate, error) {
+	return scanSetmealTemplate(r.db.QueryRow(setmealTemplateQuery+" WHERE t.id = ?", id))
+}
+
+func (r *setmealTemplateRepository) Create(t *model.SetmealTemplate) (int64, error) {
+	result, err := r.db.Exec(`
+		INSERT INTO setmeal_template (meal_period_id, weekday, window_code, sort, enabled, remark, create_time, update_time)
+		VALUES (?, ?, ?, ?, ?, ?, NOW(), NOW())
+	`, t.MealPeriodId, t.Weekday, t.Window, t.Sort, t.Enabled, t.Remark)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func (r *setmealTemplateRepository) Update(t *model.SetmealTemplate) error {
+	_, err := r.db.Exec(`
+		UPDATE setmeal_template
+		SET meal_period_id = ?, weekday = ?, window_code = ?, sort = ?, enabled = ?, remark = ?, update_time = NOW()
+		WHERE id = ?
+	`, t.MealPeriodId, t.Weekday, t.Window, t.Sort, t.Enabled, t.Remark, t.Id)
+	return err
+}
+
+func (r *setmealTemplateRepository) Delete(id int) error {
+	result, err := r.db.Exec("DELETE FROM setmeal_template WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
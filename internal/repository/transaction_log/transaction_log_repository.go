@@ -0,0 +1,91 @@
+package transaction_log
+
+import (
+	"canteen/internal/model"
+	"database/sql"
+)
+
+type TransactionLogRepository interface {
+	FindByDeviceOrder(deviceNo string, orderNo string) (*model.TransactionLog, error)
+	// FindByOrderNo 按终端交易号查询各设备上的交易流水
+	FindByOrderNo(orderNo string) ([]model.TransactionLog, error)
+	// FindLatestByOrderRecordId 查询订单最近一次核销的交易流水
+	FindLatestByOrderRecordId(orderRecordId int) (*model.TransactionLog, error)
+}
+
+type transactionLogRepository struct {
+	db *sql.DB
+}
+
+func NewTransactionLogRepository(db *sql.DB) TransactionLogRepository {
+	return &transactionLogRepository{db: db}
+}
+
+const transactionLogColumns = `id, device_no, order_no, card_no, user_id, order_record_id, meal_type, response, create_time`
+
+// rowScanner 兼容 *sql.Row 与 *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTransactionLog(row rowScanner) (*model.TransactionLog, error) {
+	var txLog model.TransactionLog
+	var cardNo, mealType, response sql.NullString
+	var userId, orderRecordId sql.NullInt64
+	var createTime sql.NullTime
+	err := row.Scan(&txLog.Id, &txLog.DeviceNo, &txLog.OrderNo, &cardNo, &userId, &orderRecordId, &mealType, &response, &createTime)
+	if err != nil {
+		return nil, err
+	}
+
+	txLog.CardNo = cardNo.String
+	txLog.UserId = int(userId.Int64)
+	txLog.OrderRecordId = int(orderRecordId.Int64)
+	txLog.MealType = mealType.String
+	txLog.Response = response.String
+	if createTime.Valid {
+		txLog.CreateTime = createTime.Time.Format("2006-01-02 15:04:05")
+	}
+	return &txLog, nil
+}
+
+func (r *transactionLogRepository) FindByDeviceOrder(deviceNo string, orderNo string) (*model.TransactionLog, error) {
+	return scanTransactionLog(r.db.QueryRow(`
+		SELECT `+transactionLogColumns+`
+		FROM consum_transaction_log
+		WHERE device_no = ? AND order_no = ?
+	`, deviceNo, orderNo))
+}
+
+func (r *transactionLogRepository) FindByOrderNo(orderNo string) ([]model.TransactionLog, error) {
+	rows, err := r.db.Query(`
+		SELECT `+transactionLogColumns+`
+		FROM consum_transaction_log
+		WHERE order_no = ?
+		ORDER BY id
+	`, orderNo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	txLogs := []model.TransactionLog{}
+	for rows.Next() {
+		txLog, err := scanTransactionLog(rows)
+		if err != nil {
+			return nil, err
+		}
+		txLogs = append(txLogs, *txLog)
+	}
+	return txLogs, rows.Err()
+}
+
+func (r *transactionLogRepository) FindLatestByOrderRecordId(orderRecordId int) (*model.TransactionLog, error) {
+	return scanTransactionLog(r.db.QueryRow(`
+		SELECT `+transactionLogColumns+`
+		FROM consum_transaction_log
+		WHERE order_record_id = ?
+		ORDER BY id DESC
+		LIMIT 1
+	`, orderRecordId))
+}
@@ -9,7 +9,8 @@ type UserRepository interface {
 	FindByCardNo(cardNo string) (*model.UserVo, error)
 	FindById(userId int) (*model.UserVo, error)
 	FindByNickName(nickName string) (*model.UserVo, error)
-	DecreaseCountByUserId(userId int) error
+	// FindCredential 按登录账号查询用户号及密码哈希
+	FindCredential(userName string) (int, string, error)
 }
 
 type userRepository struct {
@@ -50,7 +51,9 @@ func (r *userRepository) FindByNickName(nickName string) (*model.UserVo, error)
 	return &user, nil
 }
 
-func (r *userRepository) DecreaseCountByUserId(userId int) error {
-	_, err := r.db.Exec("UPDATE sys_user SET count = GREATEST(count - 1, 0) WHERE user_id = ?", userId)
-	return err
-}
\ No newline at end of file
+func (r *userRepository) FindCredential(userName string) (int, string, error) {
+	var userId int
+	var password sql.NullString
+	err := r.db.QueryRow("SELECT user_id, password FROM sys_user WHERE user_name = ?", userName).Scan(&userId, &password)
+	return userId, password.String, err
+}
@@ -0,0 +1,58 @@
+package router
+
+import (
+	"canteen/internal/infrastructure/auth"
+	"canteen/internal/infrastructure/config"
+	"canteen/internal/infrastructure/logging"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminTokenHeader 管理接口令牌请求头
+const AdminTokenHeader = "X-Admin-Token"
+
+// RequireAdmin 管理员鉴权中间件，校验请求头中的令牌与配置 admin.token 一致
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		expected := config.GetString("admin.token")
+		token := c.GetHeader(AdminTokenHeader)
+
+		if expected == "" || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+			logging.GetIllegalLogger().Printf("[未授权管理请求] IP: %s  Method: %s  Path: %s",
+				c.ClientIP(), c.Request.Method, c.Request.URL.Path)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"status":  401,
+				"message": "无管理权限",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireEmployee 员工鉴权中间件，校验 Authorization: Bearer <令牌> 并写入当前用户号
+func RequireEmployee() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := strings.TrimSpace(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
+		userId, err := auth.ParseToken(token, time.Now())
+		if token == "" || err != nil {
+			message := "请先登录"
+			if token != "" {
+				message = err.Error()
+			}
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"status":  401,
+				"message": message,
+			})
+			return
+		}
+
+		c.Set(auth.ContextUserId, userId)
+		c.Next()
+	}
+}
@@ -1,13 +1,29 @@
 package router
 
 import (
+	"canteen/internal/controller/analytics"
+	"canteen/internal/controller/billing"
+	"canteen/internal/controller/booking"
+	"canteen/internal/controller/calendar"
 	"canteen/internal/controller/card"
+	"canteen/internal/controller/card_manage"
+	"canteen/internal/controller/dashboard"
+	"canteen/internal/controller/debug"
+	"canteen/internal/controller/device"
+	"canteen/internal/controller/dish"
 	"canteen/internal/controller/health"
-	"canteen/internal/controller/order_record_detail"
+	"canteen/internal/controller/meal_period"
+	"canteen/internal/controller/noshow"
+	"canteen/internal/controller/offline"
+	"canteen/internal/controller/recharge"
+	"canteen/internal/controller/setmeal"
+	"canteen/internal/controller/setmeal_template"
 	"canteen/internal/controller/tempDirect"
 	"canteen/internal/controller/uploadFile"
 	"canteen/internal/controller/user"
+	"canteen/internal/infrastructure/config"
 	"canteen/internal/infrastructure/logging"
+	"log"
 	"net/http"
 	"strings"
 	"time"
@@ -24,6 +40,16 @@ func BlockInvalidRequests() gin.HandlerFunc {
 		"/temp/v1/",
 		"/user/v1/",
 		"/order/v1/",
+		"/device/v1/",
+		"/offline/v1/",
+		"/meal/v1/",
+		"/debug/v1/",
+		"/recharge/v1/",
+		"/card/v1/",
+		"/calendar/v1/",
+		"/billing/v1/",
+		"/dashboard/v1/",
+		"/noshow/v1/",
 	}
 	blockedPaths := map[string]bool{
 		"/hxz/v1/test": true,
@@ -82,7 +108,9 @@ func RegisterRoutes(router *gin.Engine) {
 		commonGroup.GET("/health", health.HealthCheckHandler)
 		commonGroup.GET("/exportDayRcord", tempDirect.ExportOrdersByDate)
 		commonGroup.GET("/exportMonthRecord", tempDirect.ExportOrdersByMonth)
-		commonGroup.POST("/uploadWeekMenu", tempDirect.UploadWeekMenuHandler)
+		commonGroup.POST("/uploadWeekMenu", RequireAdmin(), tempDirect.UploadWeekMenuHandler)
+		commonGroup.GET("/downloadWeekMenuTemplate", RequireAdmin(), tempDirect.DownloadWeekMenuTemplateHandler)
+		commonGroup.GET("/exportWeekMenu", RequireAdmin(), tempDirect.ExportWeekMenuHandler)
 		commonGroup.POST("/dateImport", tempDirect.DateImport)
 		commonGroup.GET("/DishDetail/:id", tempDirect.DishDetail)
 	}
@@ -90,19 +118,147 @@ func RegisterRoutes(router *gin.Engine) {
 	userApi := router.Group("/user")
 	userGroup := userApi.Group("/v1")
 	{
+		userGroup.POST("/login", user.LoginHandler)
+		userGroup.GET("/getProfile", RequireEmployee(), user.GetProfileHandler)
 		userGroup.GET("/getUser/:user_id", user.GetUserHandler)
 		userGroup.GET("/getUserByNickName", user.GetUserByNickNameHandler)
+		userGroup.GET("/getCountLedger/:user_id", RequireAdmin(), user.GetCountLedgerHandler)
+		userGroup.POST("/adjustCount", RequireAdmin(), user.AdjustCountHandler)
+		userGroup.GET("/getCountDrifts", RequireAdmin(), user.GetCountDriftsHandler)
+		userGroup.POST("/reconcileCount/:user_id", RequireAdmin(), user.ReconcileCountHandler)
+	}
+
+	// 充值及月度配额接口仅限管理员
+	rechargeApi := router.Group("/recharge")
+	rechargeGroup := rechargeApi.Group("/v1", RequireAdmin())
+	{
+		rechargeGroup.POST("/recharge", recharge.RechargeHandler)
+		rechargeGroup.POST("/rechargeSheet", recharge.RechargeSheetHandler)
+		rechargeGroup.GET("/getAllotmentRules", recharge.GetAllotmentRulesHandler)
+		rechargeGroup.GET("/getAllotmentRule/:id", recharge.GetAllotmentRuleHandler)
+		rechargeGroup.POST("/createAllotmentRule", recharge.CreateAllotmentRuleHandler)
+		rechargeGroup.PUT("/updateAllotmentRule/:id", recharge.UpdateAllotmentRuleHandler)
+		rechargeGroup.DELETE("/deleteAllotmentRule/:id", recharge.DeleteAllotmentRuleHandler)
+		rechargeGroup.POST("/runAllotmentRule/:id", recharge.RunAllotmentRuleHandler)
+	}
+
+	// 卡片挂失、补卡等管理接口仅限管理员
+	cardManageApi := router.Group("/card")
+	cardManageGroup := cardManageApi.Group("/v1", RequireAdmin())
+	{
+		cardManageGroup.GET("/getCards", card_manage.GetCardsHandler)
+		cardManageGroup.GET("/getCard/:card_no", card_manage.GetCardHandler)
+		cardManageGroup.POST("/reportLoss", card_manage.ReportLossHandler)
+		cardManageGroup.POST("/freezeCard", card_manage.FreezeCardHandler)
+		cardManageGroup.POST("/restoreCard", card_manage.RestoreCardHandler)
+		cardManageGroup.POST("/retireCard", card_manage.RetireCardHandler)
+		cardManageGroup.POST("/replaceCard", card_manage.ReplaceCardHandler)
 	}
 
 	orderApi := router.Group("/order")
 	orderGroup := orderApi.Group("/v1")
 	{
-		orderGroup.GET("/getCMealSelectionStats", order_record_detail.GetCMealSelectionStatsHandler)
-		orderGroup.GET("/getAllMealSelectionStats", order_record_detail.GetAllMealSelectionStatsHandler)
-		orderGroup.GET("/getBasicDishStats", order_record_detail.GetBasicDishStatsHandler)
-		orderGroup.GET("/getDishAppearanceStats", order_record_detail.GetDishAppearanceStatsHandler)
-		orderGroup.GET("/getUserDishOrderStats", order_record_detail.GetUserDishOrderStatsHandler)
-		orderGroup.GET("/getDishStatsComparison", order_record_detail.GetDishStatsComparisonHandler)
+		orderGroup.GET("/getCMealSelectionStats", analytics.GetCMealSelectionStatsHandler)
+		orderGroup.GET("/getAllMealSelectionStats", analytics.GetAllMealSelectionStatsHandler)
+		orderGroup.GET("/getBasicDishStats", analytics.GetBasicDishStatsHandler)
+		orderGroup.GET("/getDishAppearanceStats", analytics.GetDishAppearanceStatsHandler)
+		orderGroup.GET("/getUserDishOrderStats", analytics.GetUserDishOrderStatsHandler)
+		orderGroup.GET("/getDishStatsComparison", analytics.GetDishStatsComparisonHandler)
+		orderGroup.GET("/getDemandForecast", analytics.GetDemandForecastHandler)
+		orderGroup.GET("/getForecastBacktest", analytics.GetForecastBacktestHandler)
+
+		// 员工报餐接口需登录
+		orderGroup.GET("/getWeekMenu", RequireEmployee(), booking.GetWeekMenuHandler)
+		orderGroup.POST("/book", RequireEmployee(), booking.BookHandler)
+		orderGroup.PUT("/changeBooking/:id", RequireEmployee(), booking.ChangeBookingHandler)
+		orderGroup.POST("/cancelBooking/:id", RequireEmployee(), booking.CancelBookingHandler)
+		orderGroup.GET("/getMyOrders", RequireEmployee(), booking.GetMyOrdersHandler)
+		orderGroup.POST("/adminBook", RequireAdmin(), booking.AdminBookHandler)
+		orderGroup.PUT("/adminChangeBooking/:id", RequireAdmin(), booking.AdminChangeBookingHandler)
+		orderGroup.POST("/adminCancelBooking/:id", RequireAdmin(), booking.AdminCancelBookingHandler)
+	}
+
+	deviceApi := router.Group("/device")
+	deviceGroup := deviceApi.Group("/v1", RequireAdmin())
+	{
+		deviceGroup.GET("/getDevices", device.GetDevicesHandler)
+		deviceGroup.GET("/getDevice/:id", device.GetDeviceHandler)
+		deviceGroup.POST("/createDevice", device.CreateDeviceHandler)
+		deviceGroup.PUT("/updateDevice/:id", device.UpdateDeviceHandler)
+		deviceGroup.DELETE("/deleteDevice/:id", device.DeleteDeviceHandler)
+	}
+
+	offlineApi := router.Group("/offline")
+	offlineGroup := offlineApi.Group("/v1", RequireAdmin())
+	{
+		offlineGroup.GET("/getReconciliations", offline.GetReconciliationsHandler)
+		offlineGroup.POST("/resolveReconciliation/:id", offline.ResolveReconciliationHandler)
+	}
+
+	mealApi := router.Group("/meal")
+	mealGroup := mealApi.Group("/v1")
+	{
+		mealGroup.GET("/getMealPeriods", meal_period.GetMealPeriodsHandler)
+		mealGroup.GET("/getMealPeriod/:id", meal_period.GetMealPeriodHandler)
+		mealGroup.POST("/createMealPeriod", RequireAdmin(), meal_period.CreateMealPeriodHandler)
+		mealGroup.PUT("/updateMealPeriod/:id", RequireAdmin(), meal_period.UpdateMealPeriodHandler)
+		mealGroup.DELETE("/deleteMealPeriod/:id", RequireAdmin(), meal_period.DeleteMealPeriodHandler)
+		mealGroup.GET("/getSetmealTemplates", setmeal_template.GetSetmealTemplatesHandler)
+		mealGroup.GET("/getSetmealTemplate/:id", setmeal_template.GetSetmealTemplateHandler)
+		mealGroup.POST("/createSetmealTemplate", RequireAdmin(), setmeal_template.CreateSetmealTemplateHandler)
+		mealGroup.PUT("/updateSetmealTemplate/:id", RequireAdmin(), setmeal_template.UpdateSetmealTemplateHandler)
+		mealGroup.DELETE("/deleteSetmealTemplate/:id", RequireAdmin(), setmeal_template.DeleteSetmealTemplateHandler)
+		mealGroup.GET("/getSetmeal/:id", setmeal.GetSetmealHandler)
+		mealGroup.POST("/createSetmeal", RequireAdmin(), setmeal.CreateSetmealHandler)
+		mealGroup.PUT("/updateSetmeal/:id", RequireAdmin(), setmeal.UpdateSetmealHandler)
+		mealGroup.POST("/cloneSetmeal/:id", RequireAdmin(), setmeal.CloneSetmealHandler)
+		mealGroup.GET("/getDishes", dish.GetDishesHandler)
+		mealGroup.GET("/getDish/:id", dish.GetDishHandler)
+		mealGroup.POST("/createDish", RequireAdmin(), dish.CreateDishHandler)
+		mealGroup.PUT("/updateDish/:id", RequireAdmin(), dish.UpdateDishHandler)
+		mealGroup.DELETE("/deleteDish/:id", RequireAdmin(), dish.DeleteDishHandler)
+		mealGroup.GET("/getDishCategories", dish.GetDishCategoriesHandler)
+		mealGroup.GET("/getDishCategory/:id", dish.GetDishCategoryHandler)
+		mealGroup.POST("/createDishCategory", RequireAdmin(), dish.CreateDishCategoryHandler)
+		mealGroup.PUT("/updateDishCategory/:id", RequireAdmin(), dish.UpdateDishCategoryHandler)
+		mealGroup.DELETE("/deleteDishCategory/:id", RequireAdmin(), dish.DeleteDishCategoryHandler)
+		mealGroup.POST("/generateWeekSetmeals", RequireAdmin(), setmeal_template.GenerateWeekSetmealsHandler)
+	}
+
+	// 节假日及调休日历，查询公开，维护仅限管理员
+	calendarApi := router.Group("/calendar")
+	calendarGroup := calendarApi.Group("/v1")
+	{
+		calendarGroup.GET("/getCalendarDays", calendar.GetCalendarDaysHandler)
+		calendarGroup.POST("/saveCalendarDay", RequireAdmin(), calendar.SaveCalendarDayHandler)
+		calendarGroup.DELETE("/deleteCalendarDay/:date", RequireAdmin(), calendar.DeleteCalendarDayHandler)
+		calendarGroup.POST("/importCalendar", RequireAdmin(), calendar.ImportCalendarHandler)
+	}
+
+	// 部门结算，仅限管理员
+	billingApi := router.Group("/billing")
+	billingGroup := billingApi.Group("/v1", RequireAdmin())
+	{
+		billingGroup.GET("/getReport", billing.GetBillingReportHandler)
+		billingGroup.GET("/exportReport", billing.ExportBillingReportHandler)
+	}
+
+	// 实时看板，仅限管理员
+	dashboardApi := router.Group("/dashboard")
+	dashboardGroup := dashboardApi.Group("/v1", RequireAdmin())
+	{
+		dashboardGroup.GET("/getSnapshot", dashboard.GetSnapshotHandler)
+		dashboardGroup.GET("/stream", dashboard.StreamHandler)
+	}
+
+	// 爽约统计及暂停报餐，仅限管理员
+	noShowApi := router.Group("/noshow")
+	noShowGroup := noShowApi.Group("/v1", RequireAdmin())
+	{
+		noShowGroup.GET("/getReport", noshow.GetNoShowReportHandler)
+		noShowGroup.GET("/exportReport", noshow.ExportNoShowReportHandler)
+		noShowGroup.GET("/getBlocks", noshow.GetBlocksHandler)
+		noShowGroup.POST("/liftBlock/:id", noshow.LiftBlockHandler)
 	}
 
 	cardApi := router.Group("/hxz")
@@ -111,12 +267,24 @@ func RegisterRoutes(router *gin.Engine) {
 		cardGroup.POST("/ConsumTransactions", card.ConsumTransactionHandler)
 		cardGroup.POST("/ServerTime", card.ServerTimeHandler)
 		cardGroup.POST("/OffLines", card.OffLineHandler)
+		cardGroup.POST("/Refund", RequireAdmin(), card.RefundHandler)
+	}
+
+	// 调试接口仅在配置 debug.enabled 开启时注册，生产环境必须关闭
+	if config.GetBool("debug.enabled") {
+		log.Println("WARNING: debug endpoints are enabled")
+		debugApi := router.Group("/debug")
+		debugGroup := debugApi.Group("/v1", RequireAdmin())
+		{
+			debugGroup.GET("/getTime", debug.GetTimeHandler)
+			debugGroup.POST("/setTime", debug.SetTimeHandler)
+			debugGroup.POST("/resetTime", debug.ResetTimeHandler)
+		}
 	}
 
 	tempApi := router.Group("/temp")
 	tempGroup := tempApi.Group("/v1")
 	{
-		tempGroup.POST("/InsertMealSQL", tempDirect.InsertMealSQL)
 		tempGroup.POST("/upload", uploadFile.UploadFileHandler)
 	}
 }
@@ -133,12 +301,12 @@ func Config() *gin.Engine {
 
 	// CORS 配置
 	router.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"*"},                                       // 允许所有域名跨域访问，如果需要可以改为特定域名
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}, // 允许的请求方法
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"}, // 允许的请求头
-		ExposeHeaders:    []string{"Content-Length"},                          // 允许的响应头
-		AllowCredentials: true,                                                // 是否允许携带凭证（如 Cookie）
-		MaxAge:           12 * time.Hour,                                      // 设置缓存时间
+		AllowOrigins:     []string{"*"},                                                         // 允许所有域名跨域访问，如果需要可以改为特定域名
+		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},                   // 允许的请求方法
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", AdminTokenHeader}, // 允许的请求头
+		ExposeHeaders:    []string{"Content-Length"},                                            // 允许的响应头
+		AllowCredentials: true,                                                                  // 是否允许携带凭证（如 Cookie）
+		MaxAge:           12 * time.Hour,                                                        // 设置缓存时间
 	}))
 
 	// 拦截非法请求
@@ -0,0 +1,213 @@
+package analytics
+
+import (
+	"canteen/internal/infrastructure/clock"
+	"canteen/internal/model"
+	"canteen/internal/repository/analytics"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// knownStatuses 可筛选的订单状态
+var knownStatuses = []string{
+	model.OrderStatusBooked,
+	model.OrderStatusCollected,
+	model.OrderStatusTemp,
+	model.OrderStatusExpired,
+	model.OrderStatusCancelled,
+	model.OrderStatusVoided,
+}
+
+// InvalidFilterError 统计参数校验未通过，控制器据此返回 400，其余错误视为查询失败
+type InvalidFilterError struct {
+	Message string
+}
+
+func (e *InvalidFilterError) Error() string {
+	return e.Message
+}
+
+// invalidf 构造参数校验错误
+func invalidf(format string, args ...interface{}) error {
+	return &InvalidFilterError{Message: fmt.Sprintf(format, args...)}
+}
+
+type AnalyticsService interface {
+	// SetmealStats 按套餐统计订单数
+	SetmealStats(filter model.StatsFilter) (*model.Stats, error)
+	// DishOrderStats 按菜品统计点餐次数
+	DishOrderStats(filter model.StatsFilter) (*model.Stats, error)
+	// BasicDishStats 按套餐描述中的菜名统计点餐次数，订单所选套餐的每个菜名各计一次；
+	// 菜名以“+”分隔，兼容此前导入或组合的套餐以“、”分隔的描述
+	BasicDishStats(filter model.StatsFilter) (*model.Stats, error)
+	// DishAppearanceStats 按菜品统计在周套餐中出现的次数
+	DishAppearanceStats(filter model.StatsFilter) (*model.Stats, error)
+	// DishComparison 对比菜品出现次数与点餐次数，按点餐次数/出现次数降序
+	DishComparison(filter model.StatsFilter) (*model.DishComparison, error)
+	// ForecastWeek 预测 date（yyyyMMdd，为空表示下周）所在周每个周套餐的备餐份数
+	ForecastWeek(date string) (*model.WeekForecast, error)
+	// BacktestForecast 对本周之前 weeks 个完整周（为 0 时为 4 周）回测预测误差
+	BacktestForecast(weeks int) (*model.ForecastBacktest, error)
+}
+
+type analyticsService struct {
+	analyticsRepo analytics.AnalyticsRepository
+	clock         clock.Clock
+}
+
+func NewAnalyticsService(analyticsRepo analytics.AnalyticsRepository, clk clock.Clock) AnalyticsService {
+	return &analyticsService{analyticsRepo: analyticsRepo, clock: clk}
+}
+
+func (s *analyticsService) SetmealStats(filter model.StatsFilter) (*model.Stats, error) {
+	return s.stats(filter, s.analyticsRepo.CountSetmealOrders)
+}
+
+func (s *analyticsService) DishOrderStats(filter model.StatsFilter) (*model.Stats, error) {
+	return s.stats(filter, s.analyticsRepo.CountDishOrders)
+}
+
+func (s *analyticsService) BasicDishStats(filter model.StatsFilter) (*model.Stats, error) {
+	return s.stats(filter, func(f model.StatsFilter) ([]model.StatItem, error) {
+		setmeals, err := s.analyticsRepo.CountSetmealOrders(f)
+		if err != nil {
+			return nil, err
+		}
+		counts := map[string]int{}
+		for _, setmeal := range setmeals {
+			for _, name := range strings.FieldsFunc(setmeal.Name, isDishSeparator) {
+				if name = strings.TrimSpace(name); name != "" {
+					counts[name] += setmeal.Count
+				}
+			}
+		}
+		items := make([]model.StatItem, 0, len(counts))
+		for name, count := range counts {
+			items = append(items, model.StatItem{Name: name, Count: count})
+		}
+		sort.Slice(items, func(i, j int) bool {
+			if items[i].Count != items[j].Count {
+				return items[i].Count > items[j].Count
+			}
+			return items[i].Name < items[j].Name
+		})
+		return items, nil
+	})
+}
+
+func (s *analyticsService) DishAppearanceStats(filter model.StatsFilter) (*model.Stats, error) {
+	return s.stats(filter, s.analyticsRepo.CountDishAppearances)
+}
+
+func (s *analyticsService) DishComparison(filter model.StatsFilter) (*model.DishComparison, error) {
+	if err := normalizeFilter(&filter); err != nil {
+		return nil, err
+	}
+	appearances, err := s.analyticsRepo.CountDishAppearances(filter)
+	if err != nil {
+		return nil, err
+	}
+	orders, err := s.analyticsRepo.CountDishOrders(filter)
+	if err != nil {
+		return nil, err
+	}
+	orderCounts := make(map[int]int, len(orders))
+	for _, o := range orders {
+		orderCounts[o.Id] = o.Count
+	}
+
+	// 以出现过的菜品为准，未被点过的菜品点餐次数为 0
+	items := make([]model.DishComparisonItem, 0, len(appearances))
+	for _, a := range appearances {
+		item := model.DishComparisonItem{
+			DishId:          a.Id,
+			DishName:        a.Name,
+			CategoryId:      a.CategoryId,
+			AppearanceCount: a.Count,
+			OrderCount:      orderCounts[a.Id],
+		}
+		if a.Count > 0 {
+			item.Ratio = float64(item.OrderCount) / float64(a.Count)
+		}
+		items = append(items, item)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].Ratio != items[j].Ratio {
+			return items[i].Ratio > items[j].Ratio
+		}
+		return items[i].OrderCount > items[j].OrderCount
+	})
+
+	return &model.DishComparison{StartDate: displayDate(filter.StartDate), EndDate: displayDate(filter.EndDate), Items: items}, nil
+}
+
+// stats 校验筛选条件后执行单项统计
+func (s *analyticsService) stats(filter model.StatsFilter, count func(model.StatsFilter) ([]model.StatItem, error)) (*model.Stats, error) {
+	if err := normalizeFilter(&filter); err != nil {
+		return nil, err
+	}
+	items, err := count(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &model.Stats{StartDate: displayDate(filter.StartDate), EndDate: displayDate(filter.EndDate), Items: items}
+	for _, item := range items {
+		stats.Total += item.Count
+	}
+	return stats, nil
+}
+
+// normalizeFilter 将 yyyy-MM-dd 或 yyyyMMdd 日期统一为 yyyyMMdd，并校验筛选条件
+func normalizeFilter(f *model.StatsFilter) error {
+	if f.StartDate == "" || f.EndDate == "" {
+		return invalidf("开始日期(start_date)和结束日期(end_date)不能为空")
+	}
+	start, err := parseDate(f.StartDate)
+	if err != nil {
+		return invalidf("开始日期格式错误: %s，请使用 yyyy-MM-dd 格式", f.StartDate)
+	}
+	end, err := parseDate(f.EndDate)
+	if err != nil {
+		return invalidf("结束日期格式错误: %s，请使用 yyyy-MM-dd 格式", f.EndDate)
+	}
+	if end.Before(start) {
+		return invalidf("结束日期不能早于开始日期")
+	}
+	f.StartDate, f.EndDate = start.Format("20060102"), end.Format("20060102")
+
+	if f.DeptId < 0 {
+		return invalidf("无效的部门ID: %d", f.DeptId)
+	}
+	f.MealType = strings.TrimSpace(f.MealType)
+	f.Window = strings.ToUpper(strings.TrimPrefix(strings.TrimSpace(f.Window), "套餐"))
+	if f.Status = strings.TrimSpace(f.Status); f.Status != "" {
+		known := false
+		for _, status := range knownStatuses {
+			known = known || status == f.Status
+		}
+		if !known {
+			return invalidf("无效的订单状态: %s，可选 %s", f.Status, strings.Join(knownStatuses, "、"))
+		}
+	}
+	return nil
+}
+
+// isDishSeparator 套餐描述中菜名之间的分隔符
+func isDishSeparator(r rune) bool {
+	return r == '+' || r == '、'
+}
+
+func parseDate(value string) (time.Time, error) {
+	if strings.Contains(value, "-") {
+		return time.Parse("2006-01-02", value)
+	}
+	return time.Parse("20060102", value)
+}
+
+// displayDate yyyyMMdd 转换为 yyyy-MM-dd
+func displayDate(date string) string {
+	return date[:4] + "-" + date[4:6] + "-" + date[6:]
+}
@@ -0,0 +1,137 @@
+package analytics
+
+import (
+	"canteen/internal/infrastructure/clock"
+	"canteen/internal/model"
+	"canteen/internal/repository/analytics"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// fakeAnalyticsRepo 返回固定的统计结果，并记录收到的筛选条件
+type fakeAnalyticsRepo struct {
+	analytics.AnalyticsRepository
+	orders, appearances []model.StatItem
+	filter              model.StatsFilter
+	counts              []model.SetmealOrderCount
+}
+
+func (r *fakeAnalyticsRepo) CountSetmealOrders(filter model.StatsFilter) ([]model.StatItem, error) {
+	r.filter = filter
+	return r.orders, nil
+}
+
+func (r *fakeAnalyticsRepo) CountDishOrders(filter model.StatsFilter) ([]model.StatItem, error) {
+	r.filter = filter
+	return r.orders, nil
+}
+
+func (r *fakeAnalyticsRepo) CountDishAppearances(filter model.StatsFilter) ([]model.StatItem, error) {
+	r.filter = filter
+	return r.appearances, nil
+}
+
+func (r *fakeAnalyticsRepo) FindSetmealOrderCounts(startDate, endDate string) ([]model.SetmealOrderCount, error) {
+	counts := []model.SetmealOrderCount{}
+	for _, c := range r.counts {
+		if c.Date >= startDate && c.Date <= endDate {
+			counts = append(counts, c)
+		}
+	}
+	return counts, nil
+}
+
+func TestStatsNormalizesFilter(t *testing.T) {
+	repo := &fakeAnalyticsRepo{orders: []model.StatItem{{Name: "红烧肉套餐", Count: 5}, {Name: "素食套餐", Count: 3}}}
+	s := NewAnalyticsService(repo, clock.System())
+
+	stats, err := s.SetmealStats(model.StatsFilter{StartDate: "2025-06-01", EndDate: "20250607", Window: " 套餐c ", Status: model.OrderStatusCollected})
+	if err != nil {
+		t.Fatalf("SetmealStats() err = %v", err)
+	}
+	if stats.StartDate != "2025-06-01" || stats.EndDate != "2025-06-07" || stats.Total != 8 || len(stats.Items) != 2 {
+		t.Errorf("SetmealStats() = %+v", stats)
+	}
+	if repo.filter.StartDate != "20250601" || repo.filter.EndDate != "20250607" || repo.filter.Window != "C" {
+		t.Errorf("传给仓储的筛选条件 = %+v", repo.filter)
+	}
+
+	tests := []struct {
+		filter model.StatsFilter
+		want   string
+	}{
+		{model.StatsFilter{EndDate: "2025-06-07"}, "不能为空"},
+		{model.StatsFilter{StartDate: "2025/06/01", EndDate: "2025-06-07"}, "开始日期格式错误"},
+		{model.StatsFilter{StartDate: "2025-06-07", EndDate: "2025-06-01"}, "不能早于开始日期"},
+		{model.StatsFilter{StartDate: "2025-06-01", EndDate: "2025-06-07", DeptId: -1}, "无效的部门ID"},
+		{model.StatsFilter{StartDate: "2025-06-01", EndDate: "2025-06-07", Status: "已完成"}, "无效的订单状态"},
+	}
+	for _, tt := range tests {
+		_, err := s.DishOrderStats(tt.filter)
+		var invalid *InvalidFilterError
+		if !errors.As(err, &invalid) || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("DishOrderStats(%+v) err = %v, 期望参数错误且包含 %s", tt.filter, err, tt.want)
+		}
+	}
+}
+
+func TestBasicDishStatsSplitsSetmealDescription(t *testing.T) {
+	repo := &fakeAnalyticsRepo{orders: []model.StatItem{
+		{Name: "红烧肉+清炒时蔬+米饭", Count: 5},
+		{Name: "番茄炒蛋+ 清炒时蔬 +米饭", Count: 3},
+		{Name: "米饭", Count: 1},
+		// 组合或导入生成的套餐，旧数据以“、”分隔
+		{Name: "红烧肉、米饭", Count: 2},
+	}}
+	s := NewAnalyticsService(repo, clock.System())
+
+	stats, err := s.BasicDishStats(model.StatsFilter{StartDate: "2025-06-01", EndDate: "2025-06-07"})
+	if err != nil {
+		t.Fatalf("BasicDishStats() err = %v", err)
+	}
+	var got []string
+	for _, item := range stats.Items {
+		got = append(got, fmt.Sprintf("%s:%d", item.Name, item.Count))
+	}
+	if strings.Join(got, ",") != "米饭:11,清炒时蔬:8,红烧肉:7,番茄炒蛋:3" || stats.Total != 29 {
+		t.Errorf("BasicDishStats() = %v, total=%d", got, stats.Total)
+	}
+}
+
+func TestDishComparisonSortsByRatio(t *testing.T) {
+	repo := &fakeAnalyticsRepo{
+		appearances: []model.StatItem{
+			{Id: 1, Name: "红烧肉", Count: 4},
+			{Id: 2, Name: "清炒时蔬", Count: 2},
+			{Id: 3, Name: "凉拌木耳", Count: 2},
+			{Id: 4, Name: "番茄炒蛋", Count: 1},
+		},
+		orders: []model.StatItem{
+			{Id: 1, Name: "红烧肉", Count: 20},
+			{Id: 2, Name: "清炒时蔬", Count: 10},
+			{Id: 4, Name: "番茄炒蛋", Count: 8},
+		},
+	}
+	s := NewAnalyticsService(repo, clock.System())
+
+	comparison, err := s.DishComparison(model.StatsFilter{StartDate: "2025-06-01", EndDate: "2025-06-30"})
+	if err != nil {
+		t.Fatalf("DishComparison() err = %v", err)
+	}
+	// 比值相同时按点餐次数降序，未被点过的菜品比值为 0
+	want := []string{"番茄炒蛋:8/1", "红烧肉:20/4", "清炒时蔬:10/2", "凉拌木耳:0/2"}
+	if len(comparison.Items) != len(want) {
+		t.Fatalf("DishComparison() = %+v", comparison.Items)
+	}
+	for i, item := range comparison.Items {
+		got := fmt.Sprintf("%s:%d/%d", item.DishName, item.OrderCount, item.AppearanceCount)
+		if got != want[i] {
+			t.Errorf("第%d项 = %s, 期望 %s", i+1, got, want[i])
+		}
+	}
+	if comparison.Items[1].Ratio != 5 {
+		t.Errorf("红烧肉比值 = %v", comparison.Items[1].Ratio)
+	}
+}
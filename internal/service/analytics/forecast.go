@@ -0,0 +1,259 @@
+package analytics
+
+import (
+	"canteen/internal/model"
+	"math"
+	"time"
+)
+
+const (
+	// forecastHistoryWeeks 预测时参考的历史周数
+	forecastHistoryWeeks = 8
+	// minNoShowBookings 历史报餐数少于此值时爽约率改用上一级汇总
+	minNoShowBookings    = 20
+	defaultBacktestWeeks = 4
+	maxBacktestWeeks     = 26
+)
+
+var weekdayNames = [...]string{"周日", "周一", "周二", "周三", "周四", "周五", "周六"}
+
+func (s *analyticsService) ForecastWeek(date string) (*model.WeekForecast, error) {
+	today := s.today()
+	monday, err := weekOf(date, today)
+	if err != nil {
+		return nil, err
+	}
+	start, end := monday.Format("20060102"), monday.AddDate(0, 0, 6).Format("20060102")
+
+	// 历史截至该周前一天，且不含今天及以后尚未用餐的订单
+	historyEnd := monday.AddDate(0, 0, -1)
+	if yesterday := today.AddDate(0, 0, -1); yesterday.Before(historyEnd) {
+		historyEnd = yesterday
+	}
+	history, err := s.analyticsRepo.FindSetmealOrderCounts(monday.AddDate(0, 0, -7*forecastHistoryWeeks).Format("20060102"), historyEnd.Format("20060102"))
+	if err != nil {
+		return nil, err
+	}
+	slots, err := s.analyticsRepo.FindSetmealOrderCounts(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	f := newForecaster(history)
+	result := &model.WeekForecast{
+		WeekStart:    displayDate(start),
+		WeekEnd:      displayDate(end),
+		HistoryWeeks: forecastHistoryWeeks,
+		Items:        make([]model.SetmealForecast, 0, len(slots)),
+	}
+	for _, slot := range slots {
+		item := f.predict(slot)
+		result.Total += item.Forecast
+		result.Items = append(result.Items, item)
+	}
+	return result, nil
+}
+
+func (s *analyticsService) BacktestForecast(weeks int) (*model.ForecastBacktest, error) {
+	if weeks == 0 {
+		weeks = defaultBacktestWeeks
+	}
+	if weeks < 0 || weeks > maxBacktestWeeks {
+		return nil, invalidf("回测周数应为 1-%d", maxBacktestWeeks)
+	}
+
+	// 回测本周之前的完整周，每周只使用该周之前的历史数据
+	thisMonday := mondayOf(s.today())
+	first := thisMonday.AddDate(0, 0, -7*weeks)
+	rows, err := s.analyticsRepo.FindSetmealOrderCounts(first.AddDate(0, 0, -7*forecastHistoryWeeks).Format("20060102"), thisMonday.AddDate(0, 0, -1).Format("20060102"))
+	if err != nil {
+		return nil, err
+	}
+
+	report := &model.ForecastBacktest{Weeks: make([]model.BacktestWeek, 0, weeks)}
+	all := []model.SetmealForecast{}
+	for monday := first; monday.Before(thisMonday); monday = monday.AddDate(0, 0, 7) {
+		historyStart := monday.AddDate(0, 0, -7*forecastHistoryWeeks).Format("20060102")
+		start, end := monday.Format("20060102"), monday.AddDate(0, 0, 6).Format("20060102")
+
+		var history, slots []model.SetmealOrderCount
+		for _, row := range rows {
+			switch {
+			case row.Date >= historyStart && row.Date < start:
+				history = append(history, row)
+			case row.Date >= start && row.Date <= end:
+				slots = append(slots, row)
+			}
+		}
+
+		f := newForecaster(history)
+		week := model.BacktestWeek{WeekStart: displayDate(start), Items: make([]model.SetmealForecast, 0, len(slots))}
+		for _, slot := range slots {
+			item := f.predict(slot)
+			actual := slot.Collected + slot.Temp
+			item.Actual = &actual
+			week.Items = append(week.Items, item)
+		}
+		week.ForecastAccuracy = accuracyOf(week.Items)
+		report.Weeks = append(report.Weeks, week)
+		all = append(all, week.Items...)
+	}
+	report.ForecastAccuracy = accuracyOf(all)
+	return report, nil
+}
+
+// history 一组历史周套餐的订单数累计
+type history struct {
+	occurrences int // 周套餐数
+	bookings    int // 报餐数：已报餐+已领取+已过期
+	expired     int
+	temp        int
+	served      int // 实际用餐：已领取+临时用餐
+}
+
+func (h *history) add(c model.SetmealOrderCount) {
+	h.occurrences++
+	h.bookings += bookingsOf(c)
+	h.expired += c.Expired
+	h.temp += c.Temp
+	h.served += c.Collected + c.Temp
+}
+
+// forecaster 按 星期+餐别+窗口、餐别+窗口、餐别、全部 四级汇总历史数据，样本不足时逐级放宽
+type forecaster struct {
+	levels [4]map[string]*history
+}
+
+func newForecaster(rows []model.SetmealOrderCount) *forecaster {
+	f := &forecaster{}
+	for i := range f.levels {
+		f.levels[i] = map[string]*history{}
+	}
+	for _, row := range rows {
+		for i, key := range forecastKeys(row) {
+			h := f.levels[i][key]
+			if h == nil {
+				h = &history{}
+				f.levels[i][key] = h
+			}
+			h.add(row)
+		}
+	}
+	return f
+}
+
+func forecastKeys(c model.SetmealOrderCount) [4]string {
+	return [4]string{weekdayOf(c.Date) + "|" + c.MealType + "|" + c.Window, c.MealType + "|" + c.Window, c.MealType, ""}
+}
+
+// predict 有报餐时按 报餐数×(1-爽约率)+平均临时用餐数 预测，尚无报餐时取历史平均用餐数，结果向上取整
+func (f *forecaster) predict(slot model.SetmealOrderCount) model.SetmealForecast {
+	item := model.SetmealForecast{
+		WeeklySetmealId: slot.WeeklySetmealId,
+		Date:            displayDate(slot.Date),
+		Weekday:         weekdayOf(slot.Date),
+		MealType:        slot.MealType,
+		Window:          slot.Window,
+		Description:     slot.Description,
+		Booked:          bookingsOf(slot),
+	}
+
+	var levels []*history
+	for i, key := range forecastKeys(slot) {
+		if h := f.levels[i][key]; h != nil {
+			levels = append(levels, h)
+		}
+	}
+	if len(levels) > 0 {
+		// 平均值取最细的一级，爽约率取报餐数足够的最细一级，均不足时取全部
+		h := levels[0]
+		item.Samples = h.occurrences
+		item.WalkIn = float64(h.temp) / float64(h.occurrences)
+		item.HistoryAverage = float64(h.served) / float64(h.occurrences)
+		rate := levels[len(levels)-1]
+		for _, h := range levels {
+			if h.bookings >= minNoShowBookings {
+				rate = h
+				break
+			}
+		}
+		if rate.bookings > 0 {
+			item.NoShowRate = float64(rate.expired) / float64(rate.bookings)
+		}
+	}
+
+	expected := item.HistoryAverage
+	item.Basis = model.ForecastBasisHistory
+	if item.Booked > 0 {
+		expected = float64(item.Booked)*(1-item.NoShowRate) + item.WalkIn
+		item.Basis = model.ForecastBasisBooking
+	}
+	item.Forecast = int(math.Ceil(expected - 1e-9))
+	item.NoShowRate = roundTo(item.NoShowRate, 4)
+	item.WalkIn = roundTo(item.WalkIn, 2)
+	item.HistoryAverage = roundTo(item.HistoryAverage, 2)
+	return item
+}
+
+// accuracyOf 汇总带实际用餐数的预测项的误差
+func accuracyOf(items []model.SetmealForecast) model.ForecastAccuracy {
+	a := model.ForecastAccuracy{Slots: len(items)}
+	absError := 0
+	for _, item := range items {
+		a.Forecast += item.Forecast
+		a.Actual += *item.Actual
+		diff := item.Forecast - *item.Actual
+		if diff < 0 {
+			diff = -diff
+		}
+		absError += diff
+	}
+	a.Bias = a.Forecast - a.Actual
+	if a.Slots > 0 {
+		a.MAE = roundTo(float64(absError)/float64(a.Slots), 2)
+	}
+	if a.Actual > 0 {
+		a.WAPE = roundTo(float64(absError)/float64(a.Actual), 4)
+	}
+	return a
+}
+
+func bookingsOf(c model.SetmealOrderCount) int {
+	return c.Booked + c.Collected + c.Expired
+}
+
+func roundTo(x float64, places int) float64 {
+	p := math.Pow10(places)
+	return math.Round(x*p) / p
+}
+
+// weekdayOf 按日期（yyyyMMdd）计算星期，调休上班日按实际星期统计
+func weekdayOf(date string) string {
+	day, err := time.ParseInLocation("20060102", date, time.Local)
+	if err != nil {
+		return ""
+	}
+	return weekdayNames[day.Weekday()]
+}
+
+func (s *analyticsService) today() time.Time {
+	now := s.clock.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// weekOf 返回 date（yyyyMMdd）所在周的周一，date 为空时为下周一
+func weekOf(date string, today time.Time) (time.Time, error) {
+	if date == "" {
+		return mondayOf(today).AddDate(0, 0, 7), nil
+	}
+	day, err := time.ParseInLocation("20060102", date, time.Local)
+	if err != nil {
+		return time.Time{}, invalidf("日期格式错误: %s，请使用 yyyyMMdd 格式", date)
+	}
+	return mondayOf(day), nil
+}
+
+// mondayOf 返回 day 所在周的周一
+func mondayOf(day time.Time) time.Time {
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
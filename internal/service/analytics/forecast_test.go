@@ -0,0 +1,83 @@
+package analytics
+
+import (
+	"canteen/internal/infrastructure/clock"
+	"canteen/internal/model"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// forecastHistory 2025-04-21 起每周一午餐 A 窗口报餐 20 份，18 份领取、2 份过期、1 份临时用餐；6 月 2 日过期 6 份
+func forecastHistory() []model.SetmealOrderCount {
+	counts := []model.SetmealOrderCount{}
+	for day := time.Date(2025, 4, 21, 0, 0, 0, 0, time.Local); day.Before(time.Date(2025, 6, 10, 0, 0, 0, 0, time.Local)); day = day.AddDate(0, 0, 7) {
+		c := model.SetmealOrderCount{Date: day.Format("20060102"), MealType: "午餐", Window: "A", Collected: 18, Expired: 2, Temp: 1}
+		if c.Date == "20250602" {
+			c.Collected, c.Expired = 14, 6
+		}
+		counts = append(counts, c)
+	}
+	return counts
+}
+
+func TestForecastWeek(t *testing.T) {
+	repo := &fakeAnalyticsRepo{counts: append(forecastHistory(),
+		// 本周尚未用餐的报餐不计入历史
+		model.SetmealOrderCount{WeeklySetmealId: 1, Date: "20250611", MealType: "午餐", Window: "A", Booked: 20},
+		model.SetmealOrderCount{WeeklySetmealId: 2, Date: "20250616", MealType: "午餐", Window: "A", Booked: 30},
+		model.SetmealOrderCount{WeeklySetmealId: 3, Date: "20250616", MealType: "午餐", Window: "B"},
+		model.SetmealOrderCount{WeeklySetmealId: 4, Date: "20250617", MealType: "午餐", Window: "A", Booked: 10},
+	)}
+	s := NewAnalyticsService(repo, clock.Fixed(time.Date(2025, 6, 11, 10, 0, 0, 0, time.Local)))
+
+	forecast, err := s.ForecastWeek("")
+	if err != nil {
+		t.Fatalf("ForecastWeek() err = %v", err)
+	}
+	if forecast.WeekStart != "2025-06-16" || forecast.WeekEnd != "2025-06-22" || len(forecast.Items) != 3 {
+		t.Fatalf("ForecastWeek() = %+v", forecast)
+	}
+	// 爽约率 20/160；30×0.875+1 向上取整为 28，B 窗口无报餐按午餐历史平均 18.5，周二按午餐 A 窗口汇总 10×0.875+1
+	want := []string{"2:报餐:28", "3:历史:19", "4:报餐:10"}
+	for i, item := range forecast.Items {
+		if got := fmt.Sprintf("%d:%s:%d", item.WeeklySetmealId, item.Basis, item.Forecast); got != want[i] {
+			t.Errorf("第%d项 = %s, 期望 %s (%+v)", i+1, got, want[i], item)
+		}
+	}
+	if first := forecast.Items[0]; first.NoShowRate != 0.125 || first.WalkIn != 1 || first.Samples != 8 || first.Weekday != "周一" {
+		t.Errorf("周一午餐 A = %+v", first)
+	}
+	if forecast.Total != 57 {
+		t.Errorf("合计 = %d, 期望 57", forecast.Total)
+	}
+
+	if _, err := s.ForecastWeek("2025-06-16"); err == nil {
+		t.Errorf("非 yyyyMMdd 格式应报错")
+	}
+}
+
+func TestBacktestForecast(t *testing.T) {
+	s := NewAnalyticsService(&fakeAnalyticsRepo{counts: forecastHistory()}, clock.Fixed(time.Date(2025, 6, 11, 10, 0, 0, 0, time.Local)))
+
+	report, err := s.BacktestForecast(2)
+	if err != nil {
+		t.Fatalf("BacktestForecast() err = %v", err)
+	}
+	if len(report.Weeks) != 2 || report.Weeks[0].WeekStart != "2025-05-26" || report.Weeks[1].WeekStart != "2025-06-02" {
+		t.Fatalf("回测周 = %+v", report.Weeks)
+	}
+	// 两周均预测 20×0.9+1=19 份，实际分别为 19、15 份
+	if w := report.Weeks[1]; w.Forecast != 19 || w.Actual != 15 || w.MAE != 4 || w.WAPE != 0.2667 || *w.Items[0].Actual != 15 {
+		t.Errorf("6 月 2 日所在周 = %+v", w)
+	}
+	if report.Slots != 2 || report.MAE != 2 || report.WAPE != 0.1176 || report.Bias != 4 {
+		t.Errorf("合计误差 = %+v", report.ForecastAccuracy)
+	}
+
+	for _, weeks := range []int{-1, maxBacktestWeeks + 1} {
+		if _, err := s.BacktestForecast(weeks); err == nil {
+			t.Errorf("BacktestForecast(%d) 应报错", weeks)
+		}
+	}
+}
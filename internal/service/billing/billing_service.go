@@ -0,0 +1,182 @@
+package billing
+
+import (
+	"canteen/internal/infrastructure/daterange"
+	"canteen/internal/model"
+	"canteen/internal/repository/billing"
+	"canteen/internal/service/meal_period"
+	"sort"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// maxBillingDays 单次结算的最大天数
+const maxBillingDays = 366
+
+type BillingService interface {
+	// GetReport 统计 [startDate, endDate]（yyyyMMdd）内各部门、人员已领取、临时用餐及已过期的份数和金额
+	GetReport(startDate, endDate string) (*model.BillingReport, error)
+	// ExportReport 导出结算单，包含部门账单及人员明细两个工作表
+	ExportReport(startDate, endDate string) (*excelize.File, error)
+}
+
+type billingService struct {
+	billingRepo   billing.BillingRepository
+	periodService meal_period.MealPeriodService
+}
+
+func NewBillingService(billingRepo billing.BillingRepository, periodService meal_period.MealPeriodService) BillingService {
+	return &billingService{billingRepo: billingRepo, periodService: periodService}
+}
+
+func (s *billingService) GetReport(startDate, endDate string) (*model.BillingReport, error) {
+	if err := daterange.Validate(startDate, endDate, maxBillingDays); err != nil {
+		return nil, err
+	}
+
+	counts, err := s.billingRepo.FindBillableCounts(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	configs, err := s.billingRepo.FindPriceConfigs()
+	if err != nil {
+		return nil, err
+	}
+	codes, err := s.periodService.MealCodes()
+	if err != nil {
+		return nil, err
+	}
+	policy := pricePolicy{configs: configs, codes: codes}
+
+	report := &model.BillingReport{StartDate: startDate, EndDate: endDate, Depts: []model.DeptBill{}, Users: []model.UserBill{}, Unpriced: []string{}}
+	depts := map[int]*deptTotal{}
+	users := map[int]*userTotal{}
+	unpriced := map[string]bool{}
+	var totalCents int64
+
+	for _, c := range counts {
+		userType := model.BillingUserStaff
+		if c.DeptId == model.GuestDeptId {
+			userType = model.BillingUserGuest
+		}
+		cents, ok, err := policy.price(c.MealType, userType)
+		if err != nil {
+			return nil, err
+		}
+		if label := c.MealType + "/" + userType; !ok && !unpriced[label] {
+			unpriced[label] = true
+			report.Unpriced = append(report.Unpriced, label)
+		}
+		amount := cents * int64(c.Count)
+
+		dept := depts[c.DeptId]
+		if dept == nil {
+			name := c.DeptName
+			if name == "" {
+				name = "未分配部门"
+			}
+			dept = &deptTotal{bill: model.DeptBill{DeptId: c.DeptId, DeptName: name}, items: map[string]*itemTotal{}}
+			depts[c.DeptId] = dept
+		}
+		dept.bill.Count += c.Count
+		dept.cents += amount
+
+		item := dept.items[c.MealType+"/"+userType]
+		if item == nil {
+			item = &itemTotal{item: model.BillingItem{MealType: c.MealType, UserType: userType, UnitPrice: yuan(cents)}}
+			dept.items[c.MealType+"/"+userType] = item
+		}
+		addStatus(&item.item.Collected, &item.item.Temp, &item.item.Expired, c)
+		item.item.Count += c.Count
+		item.cents += amount
+
+		user := users[c.UserId]
+		if user == nil {
+			user = &userTotal{bill: model.UserBill{
+				UserId: c.UserId, WorkNo: c.WorkNo, Name: c.Name, DeptId: c.DeptId, DeptName: dept.bill.DeptName, UserType: userType,
+			}}
+			users[c.UserId] = user
+		}
+		addStatus(&user.bill.Collected, &user.bill.Temp, &user.bill.Expired, c)
+		user.bill.Count += c.Count
+		user.cents += amount
+
+		report.Count += c.Count
+		totalCents += amount
+	}
+	report.Amount = yuan(totalCents)
+
+	for _, dept := range depts {
+		dept.bill.Amount = yuan(dept.cents)
+		dept.bill.Items = []model.BillingItem{}
+		for _, item := range dept.items {
+			item.item.Amount = yuan(item.cents)
+			dept.bill.Items = append(dept.bill.Items, item.item)
+		}
+		sort.Slice(dept.bill.Items, func(i, j int) bool {
+			a, b := dept.bill.Items[i], dept.bill.Items[j]
+			if a.MealType != b.MealType {
+				return a.MealType < b.MealType
+			}
+			return a.UserType < b.UserType
+		})
+		report.Depts = append(report.Depts, dept.bill)
+	}
+	sort.Slice(report.Depts, func(i, j int) bool {
+		a, b := report.Depts[i], report.Depts[j]
+		if a.DeptName != b.DeptName {
+			return a.DeptName < b.DeptName
+		}
+		return a.DeptId < b.DeptId
+	})
+
+	for _, user := range users {
+		user.bill.Amount = yuan(user.cents)
+		report.Users = append(report.Users, user.bill)
+	}
+	sort.Slice(report.Users, func(i, j int) bool {
+		a, b := report.Users[i], report.Users[j]
+		if a.DeptName != b.DeptName {
+			return a.DeptName < b.DeptName
+		}
+		if a.WorkNo != b.WorkNo {
+			return a.WorkNo < b.WorkNo
+		}
+		return a.UserId < b.UserId
+	})
+	return report, nil
+}
+
+// 统计过程中按分累计金额，最后换算为元，避免浮点误差
+type (
+	deptTotal struct {
+		bill  model.DeptBill
+		cents int64
+		items map[string]*itemTotal // 餐别/用户类型
+	}
+	itemTotal struct {
+		item  model.BillingItem
+		cents int64
+	}
+	userTotal struct {
+		bill  model.UserBill
+		cents int64
+	}
+)
+
+// addStatus 按订单状态累加份数
+func addStatus(collected, temp, expired *int, c model.BillingCount) {
+	switch c.Status {
+	case model.OrderStatusCollected:
+		*collected += c.Count
+	case model.OrderStatusTemp:
+		*temp += c.Count
+	case model.OrderStatusExpired:
+		*expired += c.Count
+	}
+}
+
+// yuan 分转换为元
+func yuan(cents int64) float64 {
+	return float64(cents) / 100
+}
@@ -0,0 +1,143 @@
+package billing
+
+import (
+	"canteen/internal/model"
+	"canteen/internal/repository/billing"
+	"canteen/internal/service/meal_period"
+	"strings"
+	"testing"
+)
+
+// fakeBillingRepo 固定的计费订单数及单价配置
+type fakeBillingRepo struct {
+	billing.BillingRepository
+	counts  []model.BillingCount
+	configs map[string]string
+}
+
+func (r *fakeBillingRepo) FindBillableCounts(startDate, endDate string) ([]model.BillingCount, error) {
+	return r.counts, nil
+}
+
+func (r *fakeBillingRepo) FindPriceConfigs() (map[string]string, error) {
+	return r.configs, nil
+}
+
+// fakePeriodService 仅实现 MealCodes
+type fakePeriodService struct {
+	meal_period.MealPeriodService
+}
+
+func (s fakePeriodService) MealCodes() (map[string]string, error) {
+	return map[string]string{"午餐": "lunch", "晚餐": "dinner"}, nil
+}
+
+func newFakeBillingRepo() *fakeBillingRepo {
+	return &fakeBillingRepo{
+		counts: []model.BillingCount{
+			{UserId: 1, WorkNo: "E001", Name: "张三", DeptId: 10, DeptName: "财务部", MealType: "午餐", Status: model.OrderStatusCollected, Count: 20},
+			{UserId: 1, WorkNo: "E001", Name: "张三", DeptId: 10, DeptName: "财务部", MealType: "午餐", Status: model.OrderStatusExpired, Count: 2},
+			{UserId: 1, WorkNo: "E001", Name: "张三", DeptId: 10, DeptName: "财务部", MealType: "晚餐", Status: model.OrderStatusCollected, Count: 5},
+			{UserId: 2, WorkNo: "E002", Name: "李四", DeptId: 10, DeptName: "财务部", MealType: "午餐", Status: model.OrderStatusCollected, Count: 18},
+			{UserId: 3, WorkNo: "G001", Name: "访客", DeptId: model.GuestDeptId, DeptName: "客户", MealType: "午餐", Status: model.OrderStatusTemp, Count: 3},
+			{UserId: 3, WorkNo: "G001", Name: "访客", DeptId: model.GuestDeptId, DeptName: "客户", MealType: "夜宵", Status: model.OrderStatusTemp, Count: 1},
+		},
+		configs: map[string]string{
+			"meal_price.lunch":       "12.5",
+			"meal_price.lunch.guest": "25",
+			"meal_price.staff":       "10",
+		},
+	}
+}
+
+func TestGetReportAppliesPrices(t *testing.T) {
+	s := NewBillingService(newFakeBillingRepo(), fakePeriodService{})
+
+	report, err := s.GetReport("20250601", "20250630")
+	if err != nil {
+		t.Fatalf("GetReport() err = %v", err)
+	}
+	// 员工午餐 12.5 × 40，晚餐按 staff 兜底 10 × 5；客户午餐 25 × 3，夜宵未配置单价
+	if report.Count != 49 || report.Amount != 625 {
+		t.Errorf("合计 = %d 份 %.2f 元, 期望 49 份 625.00 元", report.Count, report.Amount)
+	}
+	if strings.Join(report.Unpriced, ",") != "夜宵/客户" {
+		t.Errorf("未配置单价 = %v", report.Unpriced)
+	}
+
+	if len(report.Depts) != 2 || report.Depts[0].DeptName != "客户" || report.Depts[1].DeptName != "财务部" {
+		t.Fatalf("部门账单 = %+v", report.Depts)
+	}
+	finance := report.Depts[1]
+	if finance.Count != 45 || finance.Amount != 550 || len(finance.Items) != 2 {
+		t.Errorf("财务部账单 = %+v", finance)
+	}
+	lunch := finance.Items[0]
+	if lunch.MealType != "午餐" || lunch.UnitPrice != 12.5 || lunch.Collected != 38 || lunch.Expired != 2 || lunch.Amount != 500 {
+		t.Errorf("财务部午餐 = %+v", lunch)
+	}
+	guest := report.Depts[0]
+	if guest.Items[0].UserType != model.BillingUserGuest || guest.Amount != 75 {
+		t.Errorf("客户账单 = %+v", guest)
+	}
+
+	if len(report.Users) != 3 || report.Users[1].WorkNo != "E001" || report.Users[1].Count != 27 || report.Users[1].Amount != 325 {
+		t.Errorf("人员明细 = %+v", report.Users)
+	}
+}
+
+func TestGetReportValidation(t *testing.T) {
+	repo := newFakeBillingRepo()
+	s := NewBillingService(repo, fakePeriodService{})
+
+	tests := []struct {
+		start, end string
+		want       string
+	}{
+		{"", "20250630", "不能为空"},
+		{"2025-06-01", "20250630", "开始日期格式错误"},
+		{"20250630", "20250601", "不能早于开始日期"},
+		{"20250101", "20260102", "不能超过 366 天"},
+	}
+	for _, tt := range tests {
+		if _, err := s.GetReport(tt.start, tt.end); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("GetReport(%s, %s) err = %v, 期望包含 %s", tt.start, tt.end, err, tt.want)
+		}
+	}
+
+	repo.configs = map[string]string{"meal_price": "十元"}
+	if _, err := s.GetReport("20250601", "20250630"); err == nil || !strings.Contains(err.Error(), "配置 meal_price 无效") {
+		t.Errorf("无效单价应报错，实际 %v", err)
+	}
+}
+
+func TestExportReport(t *testing.T) {
+	s := NewBillingService(newFakeBillingRepo(), fakePeriodService{})
+
+	f, err := s.ExportReport("20250601", "20250630")
+	if err != nil {
+		t.Fatalf("ExportReport() err = %v", err)
+	}
+	rows, err := f.GetRows(deptBillSheet)
+	if err != nil {
+		t.Fatalf("GetRows(%s) err = %v", deptBillSheet, err)
+	}
+	// 标题、期间、表头，客户 2 行 + 小计，财务部 2 行 + 小计，总计，未配置单价说明
+	if len(rows) != 11 || rows[1][0] != "结算期间：2025-06-01 至 2025-06-30" {
+		t.Fatalf("部门账单 = %v", rows)
+	}
+	if rows[9][0] != "总计" || rows[9][7] != "49" || rows[9][8] != "625.00" {
+		t.Errorf("总计行 = %v", rows[9])
+	}
+	if !strings.Contains(rows[10][0], "夜宵/客户") {
+		t.Errorf("缺少未配置单价说明: %v", rows[10])
+	}
+
+	users, err := f.GetRows(userDetailSheet)
+	if err != nil {
+		t.Fatalf("GetRows(%s) err = %v", userDetailSheet, err)
+	}
+	if len(users) != 4 || users[0][0] != "工号" {
+		t.Errorf("人员明细 = %v", users)
+	}
+}
@@ -0,0 +1,127 @@
+package billing
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	deptBillSheet   = "部门账单"
+	userDetailSheet = "人员明细"
+)
+
+var (
+	deptBillHeaders   = []interface{}{"部门", "餐别", "用户类型", "单价(元)", "已领取", "临时用餐", "已过期", "份数", "金额(元)"}
+	userDetailHeaders = []interface{}{"工号", "姓名", "部门", "用户类型", "已领取", "临时用餐", "已过期", "份数", "金额(元)"}
+)
+
+func (s *billingService) ExportReport(startDate, endDate string) (*excelize.File, error) {
+	report, err := s.GetReport(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	f := excelize.NewFile()
+	f.SetSheetName("Sheet1", deptBillSheet)
+	if _, err := f.NewSheet(userDetailSheet); err != nil {
+		return nil, err
+	}
+	styles, err := newBillStyles(f)
+	if err != nil {
+		return nil, err
+	}
+
+	// 部门账单：标题、期间、表头，每个部门的结算行后跟小计，最后为总计
+	period := fmt.Sprintf("结算期间：%s 至 %s", displayDate(report.StartDate), displayDate(report.EndDate))
+	rows := [][]interface{}{{"食堂用餐结算单"}, {period}, deptBillHeaders}
+	subtotals := []int{}
+	for _, dept := range report.Depts {
+		for _, item := range dept.Items {
+			rows = append(rows, []interface{}{dept.DeptName, item.MealType, item.UserType, item.UnitPrice,
+				item.Collected, item.Temp, item.Expired, item.Count, item.Amount})
+		}
+		rows = append(rows, []interface{}{dept.DeptName, "小计", "", "", "", "", "", dept.Count, dept.Amount})
+		subtotals = append(subtotals, len(rows))
+	}
+	rows = append(rows, []interface{}{"总计", "", "", "", "", "", "", report.Count, report.Amount})
+	subtotals = append(subtotals, len(rows))
+	if len(report.Unpriced) > 0 {
+		rows = append(rows, []interface{}{"未配置单价（按 0 元计）：" + strings.Join(report.Unpriced, "、")})
+	}
+	if err := writeRows(f, deptBillSheet, rows); err != nil {
+		return nil, err
+	}
+	if err := f.MergeCell(deptBillSheet, "A1", "I1"); err != nil {
+		return nil, err
+	}
+	f.SetCellStyle(deptBillSheet, "A1", "A1", styles.title)
+	f.SetCellStyle(deptBillSheet, "A3", "I3", styles.header)
+	f.SetCellStyle(deptBillSheet, "D4", fmt.Sprintf("D%d", len(rows)), styles.money)
+	f.SetCellStyle(deptBillSheet, "I4", fmt.Sprintf("I%d", len(rows)), styles.money)
+	for _, row := range subtotals {
+		f.SetCellStyle(deptBillSheet, fmt.Sprintf("A%d", row), fmt.Sprintf("H%d", row), styles.header)
+		f.SetCellStyle(deptBillSheet, fmt.Sprintf("I%d", row), fmt.Sprintf("I%d", row), styles.moneyBold)
+	}
+	f.SetColWidth(deptBillSheet, "A", "A", 20)
+
+	// 人员明细
+	rows = [][]interface{}{userDetailHeaders}
+	for _, u := range report.Users {
+		rows = append(rows, []interface{}{u.WorkNo, u.Name, u.DeptName, u.UserType,
+			u.Collected, u.Temp, u.Expired, u.Count, u.Amount})
+	}
+	if err := writeRows(f, userDetailSheet, rows); err != nil {
+		return nil, err
+	}
+	f.SetCellStyle(userDetailSheet, "A1", "I1", styles.header)
+	f.SetCellStyle(userDetailSheet, "I2", fmt.Sprintf("I%d", len(rows)), styles.money)
+	f.SetColWidth(userDetailSheet, "C", "C", 20)
+	f.SetPanes(userDetailSheet, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"})
+
+	return f, nil
+}
+
+// billStyles 结算单使用的单元格样式
+type billStyles struct {
+	title, header, money, moneyBold int
+}
+
+func newBillStyles(f *excelize.File) (*billStyles, error) {
+	var s billStyles
+	var err error
+	if s.title, err = f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true, Size: 14}, Alignment: &excelize.Alignment{Horizontal: "center"}}); err != nil {
+		return nil, err
+	}
+	if s.header, err = f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}}); err != nil {
+		return nil, err
+	}
+	// 内置格式 2 为 0.00
+	if s.money, err = f.NewStyle(&excelize.Style{NumFmt: 2}); err != nil {
+		return nil, err
+	}
+	if s.moneyBold, err = f.NewStyle(&excelize.Style{NumFmt: 2, Font: &excelize.Font{Bold: true}}); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// writeRows 从第一行起逐行写入
+func writeRows(f *excelize.File, sheet string, rows [][]interface{}) error {
+	for i, row := range rows {
+		cell, err := excelize.CoordinatesToCellName(1, i+1)
+		if err != nil {
+			return err
+		}
+		if err := f.SetSheetRow(sheet, cell, &row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// displayDate yyyyMMdd 转换为 yyyy-MM-dd
+func displayDate(date string) string {
+	return date[:4] + "-" + date[4:6] + "-" + date[6:]
+}
@@ -0,0 +1,51 @@
+package billing
+
+import (
+	"canteen/internal/model"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// 单价配置键（canteen_config），取值为元，按以下顺序取第一个已配置的值：
+//
+//	meal_price.<餐次编码>.<staff|guest>
+//	meal_price.<餐次编码>
+//	meal_price.<staff|guest>
+//	meal_price
+//
+// staff 为员工，guest 为客户部门（model.GuestDeptId）。均未配置时按 0 元计，并在报表的 unpriced 中列出。
+const priceKey = "meal_price"
+
+// pricePolicy 一次统计内使用的单价配置
+type pricePolicy struct {
+	configs map[string]string // canteen_config 中的单价配置
+	codes   map[string]string // 餐别名称 -> 餐次编码
+}
+
+// price 返回餐别、用户类型的单价（分），未配置时 ok 为 false
+func (p pricePolicy) price(mealType, userType string) (cents int64, ok bool, err error) {
+	suffix := "staff"
+	if userType == model.BillingUserGuest {
+		suffix = "guest"
+	}
+	var keys []string
+	if code := p.codes[mealType]; code != "" {
+		keys = append(keys, priceKey+"."+code+"."+suffix, priceKey+"."+code)
+	}
+	keys = append(keys, priceKey+"."+suffix, priceKey)
+
+	for _, key := range keys {
+		value := strings.TrimSpace(p.configs[key])
+		if value == "" {
+			continue
+		}
+		yuan, err := strconv.ParseFloat(value, 64)
+		if err != nil || yuan < 0 {
+			return 0, false, fmt.Errorf("配置 %s 无效: %s", key, value)
+		}
+		return int64(math.Round(yuan * 100)), true, nil
+	}
+	return 0, false, nil
+}
@@ -0,0 +1,490 @@
+package booking
+
+import (
+	"canteen/internal/infrastructure/clock"
+	"canteen/internal/model"
+	"canteen/internal/repository/booking"
+	"canteen/internal/repository/calendar"
+	"canteen/internal/repository/dish"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// OrderHistory 个人报餐记录
+type OrderHistory struct {
+	Total  int                  `json:"total"`
+	Orders []model.BookingOrder `json:"orders"`
+}
+
+type BookingService interface {
+	// GetWeekMenu 获取 date 所在周（yyyyMMdd，为空表示下周）的套餐及本人报餐情况，
+	// 不满足 filter 的套餐不展示，时段本身及本人订单保留
+	GetWeekMenu(userId int, date string, filter model.MenuFilter) ([]model.BookingSlot, error)
+	// Book 报餐截止前报餐，同一用户同一天同一餐别仅允许一个订单，因爽约暂停报餐期间不可报餐
+	Book(userId int, optionId int) (*model.BookingOrder, error)
+	// ChangeBooking 报餐截止前更换同一天同一餐别的套餐
+	ChangeBooking(userId int, orderId int, optionId int) (*model.BookingOrder, error)
+	// CancelBooking 取消截止前取消报餐
+	CancelBooking(userId int, orderId int) (*model.BookingOrder, error)
+	// GetOrderHistory 分页查询个人报餐记录，日期为 yyyyMMdd，为空表示不限
+	GetOrderHistory(userId int, startDate, endDate string, page, pageSize int) (*OrderHistory, error)
+	// AdminBook 管理员代员工报餐，Override 为 true 时忽略截止时间
+	AdminBook(req model.AdminBookingRequest) (*model.BookingOrder, error)
+	AdminChangeBooking(orderId int, req model.AdminBookingRequest) (*model.BookingOrder, error)
+	AdminCancelBooking(orderId int, req model.AdminBookingRequest) (*model.BookingOrder, error)
+}
+
+type bookingService struct {
+	bookingRepo  booking.BookingRepository
+	calendarRepo calendar.CalendarRepository
+	dishRepo     dish.DishRepository
+	clock        clock.Clock
+}
+
+func NewBookingService(bookingRepo booking.BookingRepository, calendarRepo calendar.CalendarRepository, dishRepo dish.DishRepository, clk clock.Clock) BookingService {
+	return &bookingService{bookingRepo: bookingRepo, calendarRepo: calendarRepo, dishRepo: dishRepo, clock: clk}
+}
+
+func (s *bookingService) GetWeekMenu(userId int, date string, filter model.MenuFilter) ([]model.BookingSlot, error) {
+	if err := validateFilter(filter); err != nil {
+		return nil, err
+	}
+	now := s.clock.Now()
+	day := now.AddDate(0, 0, 7)
+	if date != "" {
+		var err error
+		if day, err = time.ParseInLocation("20060102", date, time.Local); err != nil {
+			return nil, fmt.Errorf("日期格式错误: %s，请使用 yyyyMMdd 格式", date)
+		}
+	}
+	monday := day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	start, end := monday.Format("20060102"), monday.AddDate(0, 0, 6).Format("20060102")
+
+	options, err := s.bookingRepo.FindOptions(start, end)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.attachTags(options); err != nil {
+		return nil, err
+	}
+	orders, _, err := s.bookingRepo.FindOrders(userId, start, end, 0, 100)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := s.loadPolicy()
+	if err != nil {
+		return nil, err
+	}
+	deptId, err := s.bookingRepo.FindUserDept(userId)
+	if err != nil {
+		return nil, err
+	}
+	blockedUntil, err := s.bookingRepo.FindBlockedUntil(userId, now.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	days, err := s.calendarRepo.FindRange(monday.Format("2006-01-02"), monday.AddDate(0, 0, 6).Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	closed := map[string]bool{}
+	for _, d := range days {
+		if d.DayType == model.CalendarHoliday {
+			closed[strings.ReplaceAll(d.Date, "-", "")] = true
+		}
+	}
+
+	mine := map[string]*model.BookingOrder{}
+	for i := range orders {
+		o := &orders[i]
+		if o.Status == model.OrderStatusCancelled || o.Status == model.OrderStatusVoided {
+			continue
+		}
+		mine[o.Date+o.MealType] = o
+	}
+
+	slots := []model.BookingSlot{}
+	index := map[string]int{}
+	for _, option := range options {
+		if closed[option.Date] {
+			continue
+		}
+		key := option.Date + option.MealType
+		i, ok := index[key]
+		if !ok {
+			bookBy, err := policy.deadline(cutoffKindBooking, option.MealType, deptId, option.Date)
+			if err != nil {
+				return nil, err
+			}
+			cancelBy, err := policy.deadline(cutoffKindCancel, option.MealType, deptId, option.Date)
+			if err != nil {
+				return nil, err
+			}
+			order := mine[key]
+			pending := order != nil && order.Status == model.OrderStatusBooked
+			slots = append(slots, model.BookingSlot{
+				Date:         option.Date,
+				Weekday:      option.Weekday,
+				MealType:     option.MealType,
+				Cutoff:       bookBy.Format("2006-01-02 15:04"),
+				CancelCutoff: cancelBy.Format("2006-01-02 15:04"),
+				Bookable:     now.Before(bookBy) && ((order == nil && blockedUntil == "") || pending),
+				Cancellable:  now.Before(cancelBy) && pending,
+				Order:        order,
+				Options:      []model.SetmealOption{},
+			})
+			i = len(slots) - 1
+			index[key] = i
+		}
+		if filter.Matches(option.Tags) {
+			slots[i].Options = append(slots[i].Options, option)
+		}
+	}
+
+	return slots, nil
+}
+
+// attachTags 为已排菜的套餐汇总菜品标签
+func (s *bookingService) attachTags(options []model.SetmealOption) error {
+	setmealIds := []int{}
+	for _, option := range options {
+		if option.SetmealId != 0 {
+			setmealIds = append(setmealIds, option.SetmealId)
+		}
+	}
+	if len(setmealIds) == 0 {
+		return nil
+	}
+
+	dishTags, err := s.dishRepo.FindSetmealTags(setmealIds)
+	if err != nil {
+		return err
+	}
+	for i := range options {
+		if options[i].SetmealId == 0 {
+			continue
+		}
+		tags := model.AggregateTags(dishTags[options[i].SetmealId])
+		options[i].Tags = &tags
+	}
+	return nil
+}
+
+// validateFilter 校验筛选的过敏原及饮食标签在可选范围内
+func validateFilter(filter model.MenuFilter) error {
+	for _, check := range []struct {
+		tags       []string
+		vocabulary []string
+		kind       string
+	}{
+		{filter.Exclude, model.Allergens, "过敏原"},
+		{filter.Diets, model.DietTags, "饮食标签"},
+	} {
+		for _, tag := range check.tags {
+			known := false
+			for _, v := range check.vocabulary {
+				if v == tag {
+					known = true
+					break
+				}
+			}
+			if !known {
+				return fmt.Errorf("无效的%s: %s，可选 %s", check.kind, tag, strings.Join(check.vocabulary, "、"))
+			}
+		}
+	}
+	return nil
+}
+
+func (s *bookingService) Book(userId int, optionId int) (*model.BookingOrder, error) {
+	until, err := s.bookingRepo.FindBlockedUntil(userId, s.clock.Now().Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	if until != "" {
+		return nil, fmt.Errorf("因多次报餐未就餐，已暂停报餐至 %s，如有疑问请联系管理员", until)
+	}
+	order, err := s.book(userId, optionId, false)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("员工报餐: user_id=%d, 日期=%s, 餐别=%s, 套餐=%s, 订单ID=%d", userId, order.Date, order.MealType, order.Remark, order.Id)
+	return order, nil
+}
+
+func (s *bookingService) ChangeBooking(userId int, orderId int, optionId int) (*model.BookingOrder, error) {
+	order, err := s.change(userId, orderId, optionId, false)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("员工改餐: user_id=%d, 订单ID=%d, 日期=%s, 餐别=%s, 新套餐=%s", userId, orderId, order.Date, order.MealType, order.Remark)
+	return order, nil
+}
+
+func (s *bookingService) CancelBooking(userId int, orderId int) (*model.BookingOrder, error) {
+	order, err := s.cancel(userId, orderId, false)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("员工取消报餐: user_id=%d, 订单ID=%d, 日期=%s, 餐别=%s", userId, orderId, order.Date, order.MealType)
+	return order, nil
+}
+
+func (s *bookingService) AdminBook(req model.AdminBookingRequest) (*model.BookingOrder, error) {
+	if err := normalizeAdminRequest(&req); err != nil {
+		return nil, err
+	}
+	if req.UserId <= 0 {
+		return nil, errors.New("无效的用户ID")
+	}
+	order, err := s.book(req.UserId, req.OptionId, req.Override)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("管理员代报餐: user_id=%d, 订单ID=%d, 日期=%s, 餐别=%s, 套餐=%s, 忽略截止=%v, 操作人=%s, 说明=%s",
+		req.UserId, order.Id, order.Date, order.MealType, order.Remark, req.Override, req.Operator, req.Remark)
+	return order, nil
+}
+
+func (s *bookingService) AdminChangeBooking(orderId int, req model.AdminBookingRequest) (*model.BookingOrder, error) {
+	if err := normalizeAdminRequest(&req); err != nil {
+		return nil, err
+	}
+	order, err := s.change(0, orderId, req.OptionId, req.Override)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("管理员代改餐: user_id=%d, 订单ID=%d, 日期=%s, 餐别=%s, 新套餐=%s, 忽略截止=%v, 操作人=%s, 说明=%s",
+		order.UserId, orderId, order.Date, order.MealType, order.Remark, req.Override, req.Operator, req.Remark)
+	return order, nil
+}
+
+func (s *bookingService) AdminCancelBooking(orderId int, req model.AdminBookingRequest) (*model.BookingOrder, error) {
+	if err := normalizeAdminRequest(&req); err != nil {
+		return nil, err
+	}
+	order, err := s.cancel(0, orderId, req.Override)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("管理员代取消报餐: user_id=%d, 订单ID=%d, 日期=%s, 餐别=%s, 忽略截止=%v, 操作人=%s, 说明=%s",
+		order.UserId, orderId, order.Date, order.MealType, req.Override, req.Operator, req.Remark)
+	return order, nil
+}
+
+func (s *bookingService) GetOrderHistory(userId int, startDate, endDate string, page, pageSize int) (*OrderHistory, error) {
+	for _, d := range []string{startDate, endDate} {
+		if d == "" {
+			continue
+		}
+		if _, err := time.Parse("20060102", d); err != nil {
+			return nil, fmt.Errorf("日期格式错误: %s，请使用 yyyyMMdd 格式", d)
+		}
+	}
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 200 {
+		pageSize = 50
+	}
+
+	orders, total, err := s.bookingRepo.FindOrders(userId, startDate, endDate, (page-1)*pageSize, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	return &OrderHistory{Total: total, Orders: orders}, nil
+}
+
+func (s *bookingService) book(userId int, optionId int, override bool) (*model.BookingOrder, error) {
+	option, err := s.findOption(optionId)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkOpen(option.Date); err != nil {
+		return nil, err
+	}
+	if !override {
+		if err := s.checkCutoff(cutoffKindBooking, userId, option.MealType, option.Date); err != nil {
+			return nil, err
+		}
+	}
+
+	var orderId int
+	err = s.bookingRepo.WithTx(func(tx booking.BookingTx) error {
+		if err := tx.LockUser(userId); err != nil {
+			return err
+		}
+		existing, err := tx.FindActiveOrder(userId, option.MealType, option.Date)
+		if err == nil {
+			return fmt.Errorf("%s%s已有订单（%s），请勿重复报餐", option.Weekday, option.MealType, existing.Status)
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		orderId, err = tx.CreateOrder(userId, option)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.bookingRepo.FindOrderById(orderId)
+}
+
+// change 更换订单套餐，userId 为 0 时不校验订单归属
+func (s *bookingService) change(userId int, orderId int, optionId int, override bool) (*model.BookingOrder, error) {
+	option, err := s.findOption(optionId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.bookingRepo.WithTx(func(tx booking.BookingTx) error {
+		order, err := lockOrder(tx, userId, orderId)
+		if err != nil {
+			return err
+		}
+		if order.Date != option.Date || order.MealType != option.MealType {
+			return errors.New("只能更换为同一天同一餐别的套餐")
+		}
+		if order.OptionId == option.Id {
+			return errors.New("已选择该套餐")
+		}
+		if !override {
+			if err := s.checkCutoff(cutoffKindBooking, order.UserId, order.MealType, order.Date); err != nil {
+				return err
+			}
+		}
+		ok, err := tx.UpdateOrderSetmeal(orderId, option.Id)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("订单当前状态为%s，无法改餐", order.Status)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.bookingRepo.FindOrderById(orderId)
+}
+
+// cancel 取消订单，userId 为 0 时不校验订单归属
+func (s *bookingService) cancel(userId int, orderId int, override bool) (*model.BookingOrder, error) {
+	err := s.bookingRepo.WithTx(func(tx booking.BookingTx) error {
+		order, err := lockOrder(tx, userId, orderId)
+		if err != nil {
+			return err
+		}
+		if order.Status != model.OrderStatusBooked {
+			return fmt.Errorf("订单当前状态为%s，无法取消", order.Status)
+		}
+		if !override {
+			if err := s.checkCutoff(cutoffKindCancel, order.UserId, order.MealType, order.Date); err != nil {
+				return err
+			}
+		}
+		ok, err := tx.CancelOrder(orderId)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("订单当前状态为%s，无法取消", order.Status)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.bookingRepo.FindOrderById(orderId)
+}
+
+func (s *bookingService) findOption(optionId int) (*model.SetmealOption, error) {
+	if optionId <= 0 {
+		return nil, errors.New("请选择套餐")
+	}
+	option, err := s.bookingRepo.FindOption(optionId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.New("套餐不存在")
+	}
+	return option, err
+}
+
+// checkOpen 校验 yyyyMMdd 用餐日不是节假日
+func (s *bookingService) checkOpen(date string) error {
+	t, err := time.ParseInLocation("20060102", date, time.Local)
+	if err != nil {
+		return fmt.Errorf("日期格式错误: %s", date)
+	}
+	day, err := s.calendarRepo.FindByDate(t)
+	if err != nil {
+		return err
+	}
+	if _, open := day.ScheduleOn(t); !open {
+		return fmt.Errorf("%s为%s（%s），不供餐", t.Format("2006-01-02"), day.DayType, day.Name)
+	}
+	return nil
+}
+
+// checkCutoff 校验当前时间早于用户所在部门该餐别的报餐或取消截止时间
+func (s *bookingService) checkCutoff(kind string, userId int, mealType string, date string) error {
+	policy, err := s.loadPolicy()
+	if err != nil {
+		return err
+	}
+	deptId, err := s.bookingRepo.FindUserDept(userId)
+	if err != nil {
+		return err
+	}
+	deadline, err := policy.deadline(kind, mealType, deptId, date)
+	if err != nil {
+		return err
+	}
+	if s.clock.Now().Before(deadline) {
+		return nil
+	}
+
+	if kind == cutoffKindCancel {
+		return fmt.Errorf("%s取消已于 %s 截止，如需取消请联系管理员", mealType, deadline.Format("2006-01-02 15:04"))
+	}
+	return fmt.Errorf("%s报餐已于 %s 截止", mealType, deadline.Format("2006-01-02 15:04"))
+}
+
+func (s *bookingService) loadPolicy() (cutoffPolicy, error) {
+	configs, err := s.bookingRepo.FindCutoffConfigs()
+	if err != nil {
+		return cutoffPolicy{}, err
+	}
+	codes, err := s.bookingRepo.FindMealPeriodCodes()
+	if err != nil {
+		return cutoffPolicy{}, err
+	}
+	return cutoffPolicy{configs: configs, codes: codes}, nil
+}
+
+// lockOrder 锁定订单，userId 不为 0 时他人订单视为不存在
+func lockOrder(tx booking.BookingTx, userId int, orderId int) (*model.BookingOrder, error) {
+	order, err := tx.LockOrder(orderId)
+	if err != nil {
+		return nil, err
+	}
+	if userId != 0 && order.UserId != userId {
+		return nil, sql.ErrNoRows
+	}
+	return order, nil
+}
+
+func normalizeAdminRequest(req *model.AdminBookingRequest) error {
+	req.Operator = strings.TrimSpace(req.Operator)
+	req.Remark = strings.TrimSpace(req.Remark)
+	if req.Operator == "" {
+		return errors.New("操作人不能为空")
+	}
+	return nil
+}
@@ -0,0 +1,417 @@
+package booking
+
+import (
+	"canteen/internal/infrastructure/clock"
+	"canteen/internal/model"
+	"canteen/internal/repository/booking"
+	"canteen/internal/repository/calendar"
+	"canteen/internal/repository/dish"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeBookingRepo 内存周套餐与订单表，事务直接作用于内存数据
+type fakeBookingRepo struct {
+	booking.BookingRepository
+	options map[int]*model.SetmealOption
+	orders  []*model.BookingOrder
+	configs map[string]string
+	depts   map[int]int
+	blocked map[int]string // 用户 -> 暂停报餐截止日期
+}
+
+func (r *fakeBookingRepo) FindOption(optionId int) (*model.SetmealOption, error) {
+	if o, ok := r.options[optionId]; ok {
+		copied := *o
+		return &copied, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *fakeBookingRepo) FindOptions(startDate, endDate string) ([]model.SetmealOption, error) {
+	options := []model.SetmealOption{}
+	for id := 1; id <= len(r.options); id++ {
+		if o, ok := r.options[id]; ok && o.Date >= startDate && o.Date <= endDate {
+			options = append(options, *o)
+		}
+	}
+	return options, nil
+}
+
+func (r *fakeBookingRepo) FindOrders(userId int, startDate, endDate string, offset, limit int) ([]model.BookingOrder, int, error) {
+	orders := []model.BookingOrder{}
+	for _, o := range r.orders {
+		if o.UserId == userId && (startDate == "" || o.Date >= startDate) && (endDate == "" || o.Date <= endDate) {
+			orders = append(orders, *o)
+		}
+	}
+	return orders, len(orders), nil
+}
+
+func (r *fakeBookingRepo) FindOrderById(orderId int) (*model.BookingOrder, error) {
+	for _, o := range r.orders {
+		if o.Id == orderId {
+			copied := *o
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *fakeBookingRepo) FindCutoffConfigs() (map[string]string, error) {
+	return r.configs, nil
+}
+
+func (r *fakeBookingRepo) FindMealPeriodCodes() (map[string]string, error) {
+	return map[string]string{"午餐": "lunch", "晚餐": "dinner"}, nil
+}
+
+func (r *fakeBookingRepo) FindUserDept(userId int) (int, error) {
+	return r.depts[userId], nil
+}
+
+func (r *fakeBookingRepo) FindBlockedUntil(userId int, date string) (string, error) {
+	if until := r.blocked[userId]; until >= date {
+		return until, nil
+	}
+	return "", nil
+}
+
+func (r *fakeBookingRepo) WithTx(fn func(tx booking.BookingTx) error) error {
+	return fn(r)
+}
+
+func (r *fakeBookingRepo) LockUser(userId int) error {
+	return nil
+}
+
+func (r *fakeBookingRepo) FindActiveOrder(userId int, mealType string, date string) (*model.BookingOrder, error) {
+	for _, o := range r.orders {
+		if o.UserId == userId && o.MealType == mealType && o.Date == date &&
+			o.Status != model.OrderStatusCancelled && o.Status != model.OrderStatusVoided {
+			return o, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *fakeBookingRepo) LockOrder(orderId int) (*model.BookingOrder, error) {
+	return r.FindOrderById(orderId)
+}
+
+func (r *fakeBookingRepo) CreateOrder(userId int, option *model.SetmealOption) (int, error) {
+	id := len(r.orders) + 1
+	r.orders = append(r.orders, &model.BookingOrder{
+		Id: id, UserId: userId, Date: option.Date, Weekday: option.Weekday, MealType: option.MealType,
+		Status: model.OrderStatusBooked, OptionId: option.Id, Remark: option.Remark,
+	})
+	return id, nil
+}
+
+func (r *fakeBookingRepo) UpdateOrderSetmeal(orderId int, optionId int) (bool, error) {
+	o := r.orders[orderId-1]
+	if o.Status != model.OrderStatusBooked {
+		return false, nil
+	}
+	o.OptionId = optionId
+	o.Remark = r.options[optionId].Remark
+	return true, nil
+}
+
+func (r *fakeBookingRepo) CancelOrder(orderId int) (bool, error) {
+	o := r.orders[orderId-1]
+	if o.Status != model.OrderStatusBooked {
+		return false, nil
+	}
+	o.Status = model.OrderStatusCancelled
+	return true, nil
+}
+
+// fakeCalendarRepo 内存日历，按 yyyy-MM-dd 登记
+type fakeCalendarRepo struct {
+	calendar.CalendarRepository
+	days map[string]model.CalendarDay
+}
+
+func (r *fakeCalendarRepo) FindRange(start, end string) ([]model.CalendarDay, error) {
+	days := []model.CalendarDay{}
+	for date, d := range r.days {
+		if date >= start && date <= end {
+			days = append(days, d)
+		}
+	}
+	return days, nil
+}
+
+func (r *fakeCalendarRepo) FindByDate(day time.Time) (*model.CalendarDay, error) {
+	if d, ok := r.days[day.Format("2006-01-02")]; ok {
+		return &d, nil
+	}
+	return nil, nil
+}
+
+// fakeDishRepo 按套餐ID返回菜品标签
+type fakeDishRepo struct {
+	dish.DishRepository
+	setmealTags map[int][]model.DishTags
+}
+
+func (r *fakeDishRepo) FindSetmealTags(setmealIds []int) (map[int][]model.DishTags, error) {
+	result := map[int][]model.DishTags{}
+	for _, id := range setmealIds {
+		result[id] = r.setmealTags[id]
+	}
+	return result, nil
+}
+
+// 2025-06-09 为周一
+func newTestService(now string) (*bookingService, *fakeBookingRepo) {
+	repo := &fakeBookingRepo{
+		options: map[int]*model.SetmealOption{
+			1: {Id: 1, Date: "20250609", Weekday: "周一", MealType: "午餐", Remark: "套餐A"},
+			2: {Id: 2, Date: "20250609", Weekday: "周一", MealType: "午餐", Remark: "套餐B"},
+			3: {Id: 3, Date: "20250609", Weekday: "周一", MealType: "晚餐", Remark: "套餐A"},
+			4: {Id: 4, Date: "20250610", Weekday: "周二", MealType: "午餐", Remark: "套餐A"},
+		},
+		configs: map[string]string{},
+		depts:   map[int]int{1: 10, 2: 20},
+	}
+	t, _ := time.ParseInLocation("2006-01-02 15:04", now, time.Local)
+	calendarRepo := &fakeCalendarRepo{days: map[string]model.CalendarDay{}}
+	dishRepo := &fakeDishRepo{setmealTags: map[int][]model.DishTags{}}
+	return &bookingService{bookingRepo: repo, calendarRepo: calendarRepo, dishRepo: dishRepo, clock: clock.Fixed(t)}, repo
+}
+
+func TestBookRejectsSecondOrderForSameMeal(t *testing.T) {
+	s, _ := newTestService("2025-06-05 10:00")
+
+	if _, err := s.Book(1, 1); err != nil {
+		t.Fatalf("首次报餐失败: %v", err)
+	}
+	if _, err := s.Book(1, 2); err == nil {
+		t.Errorf("同一天同一餐别重复报餐应被拒绝")
+	}
+	if _, err := s.Book(1, 3); err != nil {
+		t.Errorf("同一天其他餐别应可报餐: %v", err)
+	}
+	if _, err := s.Book(2, 2); err != nil {
+		t.Errorf("其他用户应可报餐: %v", err)
+	}
+}
+
+func TestBookRejectsBlockedUser(t *testing.T) {
+	s, repo := newTestService("2025-06-05 10:00")
+	repo.blocked = map[int]string{1: "2025-06-05", 2: "2025-06-04"}
+
+	if _, err := s.Book(1, 1); err == nil || !strings.Contains(err.Error(), "2025-06-05") {
+		t.Errorf("暂停报餐期间应拒绝报餐, err=%v", err)
+	}
+	if _, err := s.Book(2, 1); err != nil {
+		t.Errorf("暂停已结束应可报餐: %v", err)
+	}
+	if _, err := s.AdminBook(model.AdminBookingRequest{UserId: 1, OptionId: 1, Operator: "admin"}); err != nil {
+		t.Errorf("管理员代报餐不受暂停限制: %v", err)
+	}
+
+	slots, err := s.GetWeekMenu(1, "20250609", model.MenuFilter{})
+	if err != nil {
+		t.Fatalf("GetWeekMenu 失败: %v", err)
+	}
+	for _, slot := range slots {
+		if slot.Bookable != (slot.Order != nil) {
+			t.Errorf("%s%s 暂停期间仅已报餐时段可改餐, bookable=%v", slot.Date, slot.MealType, slot.Bookable)
+		}
+	}
+}
+
+func TestBookingCutoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		now     string
+		configs map[string]string
+		wantErr bool
+	}{
+		{"默认截止前", "2025-06-08 16:59", nil, false},
+		{"默认截止时刻", "2025-06-08 17:00", nil, true},
+		{"配置无效", "2025-06-05 10:00", map[string]string{"booking_cutoff": "17点"}, true},
+		{"全局规则", "2025-06-07 12:00", map[string]string{"booking_cutoff": "2 10:00"}, true},
+		{"当天截止", "2025-06-09 08:00", map[string]string{"booking_cutoff": "0 09:30"}, false},
+		{"餐次规则优先", "2025-06-08 16:30", map[string]string{"booking_cutoff": "0 09:30", "booking_cutoff.lunch": "1 16:00"}, true},
+		{"部门规则优先", "2025-06-09 10:00", map[string]string{"booking_cutoff.lunch": "1 16:00", "booking_cutoff.lunch.dept_10": "0 10:30"}, false},
+		{"其他部门规则不生效", "2025-06-09 10:00", map[string]string{"booking_cutoff.lunch.dept_20": "0 10:30"}, true},
+		{"部门通用规则", "2025-06-08 18:00", map[string]string{"booking_cutoff.dept_10": "0 08:00"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, repo := newTestService(tt.now)
+			if tt.configs != nil {
+				repo.configs = tt.configs
+			}
+			_, err := s.Book(1, 1)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Book() err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestChangeAndCancelBooking(t *testing.T) {
+	s, repo := newTestService("2025-06-05 10:00")
+	order, err := s.Book(1, 1)
+	if err != nil {
+		t.Fatalf("报餐失败: %v", err)
+	}
+
+	if _, err := s.ChangeBooking(1, order.Id, 4); err == nil {
+		t.Errorf("更换为其他日期的套餐应被拒绝")
+	}
+	if _, err := s.ChangeBooking(2, order.Id, 2); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("修改他人订单应返回不存在，实际 %v", err)
+	}
+	changed, err := s.ChangeBooking(1, order.Id, 2)
+	if err != nil || changed.OptionId != 2 {
+		t.Fatalf("改餐失败: %+v, %v", changed, err)
+	}
+
+	cancelled, err := s.CancelBooking(1, order.Id)
+	if err != nil || cancelled.Status != model.OrderStatusCancelled {
+		t.Fatalf("取消失败: %+v, %v", cancelled, err)
+	}
+	if _, err := s.CancelBooking(1, order.Id); err == nil {
+		t.Errorf("重复取消应被拒绝")
+	}
+	if _, err := s.Book(1, 1); err != nil {
+		t.Errorf("取消后应可重新报餐: %v", err)
+	}
+	if len(repo.orders) != 2 {
+		t.Errorf("订单数 = %d, 期望 2", len(repo.orders))
+	}
+}
+
+func TestGetWeekMenuGroupsOptionsAndMarksOwnOrder(t *testing.T) {
+	s, _ := newTestService("2025-06-05 10:00")
+	if _, err := s.Book(1, 2); err != nil {
+		t.Fatalf("报餐失败: %v", err)
+	}
+
+	slots, err := s.GetWeekMenu(1, "", model.MenuFilter{})
+	if err != nil {
+		t.Fatalf("GetWeekMenu() err = %v", err)
+	}
+	if len(slots) != 3 {
+		t.Fatalf("时段数 = %d, 期望 3", len(slots))
+	}
+	lunch := slots[0]
+	if len(lunch.Options) != 2 || lunch.Order == nil || lunch.Order.OptionId != 2 || !lunch.Bookable {
+		t.Errorf("周一午餐不符: %+v", lunch)
+	}
+	if lunch.Cutoff != "2025-06-08 17:00" {
+		t.Errorf("截止时间 = %s, 期望 2025-06-08 17:00", lunch.Cutoff)
+	}
+	if slots[1].Order != nil {
+		t.Errorf("周一晚餐不应有订单")
+	}
+}
+
+func TestGetWeekMenuFiltersByTags(t *testing.T) {
+	s, repo := newTestService("2025-06-05 10:00")
+	repo.options[1].SetmealId = 11
+	repo.options[2].SetmealId = 12
+	s.dishRepo.(*fakeDishRepo).setmealTags = map[int][]model.DishTags{
+		11: {{Allergens: []string{"猪肉"}}, {Diets: []string{"素食", "清真"}}},
+		12: {{Diets: []string{"清真", "辣"}}, {Allergens: []string{"花生"}, Diets: []string{"清真"}}},
+	}
+	if _, err := s.Book(1, 1); err != nil {
+		t.Fatalf("报餐失败: %v", err)
+	}
+
+	slots, err := s.GetWeekMenu(1, "", model.MenuFilter{})
+	if err != nil {
+		t.Fatalf("GetWeekMenu() err = %v", err)
+	}
+	tags := slots[0].Options[1].Tags
+	if tags == nil || len(tags.Allergens) != 1 || tags.Allergens[0] != "花生" || len(tags.Diets) != 2 || tags.Diets[0] != "清真" {
+		t.Errorf("套餐B标签 = %+v", tags)
+	}
+
+	// 套餐A 含猪肉被过滤，已报的订单仍保留；未排菜的周一晚餐筛选时不展示套餐
+	slots, err = s.GetWeekMenu(1, "", model.MenuFilter{Exclude: []string{"猪肉"}, Diets: []string{"清真"}})
+	if err != nil {
+		t.Fatalf("GetWeekMenu() err = %v", err)
+	}
+	if len(slots) != 3 || len(slots[0].Options) != 1 || slots[0].Options[0].Id != 2 || slots[0].Order == nil {
+		t.Errorf("周一午餐筛选结果不符: %+v", slots[0])
+	}
+	if len(slots[1].Options) != 0 {
+		t.Errorf("未排菜的套餐不应通过筛选: %+v", slots[1].Options)
+	}
+
+	if _, err := s.GetWeekMenu(1, "", model.MenuFilter{Exclude: []string{"香菜"}}); err == nil {
+		t.Errorf("未知过敏原应报错")
+	}
+}
+
+func TestCancelCutoffAndAdminOverride(t *testing.T) {
+	s, repo := newTestService("2025-06-05 10:00")
+	repo.configs = map[string]string{"booking_cutoff.lunch": "1 16:00", "cancel_cutoff.lunch": "0 09:00"}
+	order, err := s.Book(1, 1)
+	if err != nil {
+		t.Fatalf("报餐失败: %v", err)
+	}
+
+	// 报餐截止后、取消截止前：不能改餐，可以取消
+	s.clock = clock.Fixed(time.Date(2025, 6, 9, 8, 30, 0, 0, time.Local))
+	if _, err := s.ChangeBooking(1, order.Id, 2); err == nil {
+		t.Errorf("报餐截止后改餐应被拒绝")
+	}
+	slots, err := s.GetWeekMenu(1, "20250609", model.MenuFilter{})
+	if err != nil {
+		t.Fatalf("GetWeekMenu() err = %v", err)
+	}
+	if slots[0].Bookable || !slots[0].Cancellable || slots[0].CancelCutoff != "2025-06-09 09:00" {
+		t.Errorf("周一午餐不符: %+v", slots[0])
+	}
+
+	// 取消截止后仅管理员可忽略截止时间取消
+	s.clock = clock.Fixed(time.Date(2025, 6, 9, 9, 0, 0, 0, time.Local))
+	if _, err := s.CancelBooking(1, order.Id); err == nil {
+		t.Errorf("取消截止后取消应被拒绝")
+	}
+	if _, err := s.AdminCancelBooking(order.Id, model.AdminBookingRequest{Operator: "管理员"}); err == nil {
+		t.Errorf("未设置 override 时管理员同样受截止时间限制")
+	}
+	if _, err := s.AdminCancelBooking(order.Id, model.AdminBookingRequest{Override: true}); err == nil {
+		t.Errorf("缺少操作人应被拒绝")
+	}
+	cancelled, err := s.AdminCancelBooking(order.Id, model.AdminBookingRequest{Override: true, Operator: "管理员"})
+	if err != nil || cancelled.Status != model.OrderStatusCancelled {
+		t.Fatalf("管理员取消失败: %+v, %v", cancelled, err)
+	}
+
+	booked, err := s.AdminBook(model.AdminBookingRequest{UserId: 1, OptionId: 2, Override: true, Operator: "管理员"})
+	if err != nil || booked.OptionId != 2 {
+		t.Fatalf("管理员代报餐失败: %+v, %v", booked, err)
+	}
+}
+
+func TestHolidayClosesBooking(t *testing.T) {
+	s, _ := newTestService("2025-06-05 10:00")
+	s.calendarRepo.(*fakeCalendarRepo).days["2025-06-09"] = model.CalendarDay{Date: "2025-06-09", DayType: model.CalendarHoliday, Name: "端午节"}
+
+	if _, err := s.Book(1, 1); err == nil {
+		t.Errorf("节假日报餐应被拒绝")
+	}
+	if _, err := s.Book(1, 4); err != nil {
+		t.Errorf("非节假日应可报餐: %v", err)
+	}
+	slots, err := s.GetWeekMenu(1, "", model.MenuFilter{})
+	if err != nil {
+		t.Fatalf("GetWeekMenu() err = %v", err)
+	}
+	if len(slots) != 1 || slots[0].Date != "20250610" {
+		t.Errorf("节假日不应展示套餐: %+v", slots)
+	}
+}
@@ -0,0 +1,115 @@
+package booking
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 截止规则配置键（canteen_config），按以下顺序取第一个已配置的值：
+//
+//	booking_cutoff.<餐次编码>.dept_<部门ID>
+//	booking_cutoff.<餐次编码>
+//	booking_cutoff.dept_<部门ID>
+//	booking_cutoff
+//
+// 取值格式为“提前天数 HH:mm”，如 "1 16:00" 表示用餐前一天 16:00 截止，"0 09:00" 表示当天 9:00 截止。
+// 取消截止 cancel_cutoff 按同样顺序查找，均未配置时与报餐截止相同。
+const (
+	cutoffKindBooking = "booking_cutoff"
+	cutoffKindCancel  = "cancel_cutoff"
+
+	defaultCutoff = "1 17:00"
+)
+
+// cutoffRule 截止规则：用餐日前 days 天的 hour:minute
+type cutoffRule struct {
+	days   int
+	hour   int
+	minute int
+}
+
+func parseCutoffRule(value string) (cutoffRule, error) {
+	fields := strings.Fields(value)
+	var rule cutoffRule
+	var at string
+	switch len(fields) {
+	case 1:
+		at = fields[0]
+	case 2:
+		days, err := strconv.Atoi(fields[0])
+		if err != nil || days < 0 {
+			return cutoffRule{}, fmt.Errorf("提前天数无效: %s", fields[0])
+		}
+		rule.days, at = days, fields[1]
+	default:
+		return cutoffRule{}, fmt.Errorf("格式应为“提前天数 HH:mm”: %s", value)
+	}
+	t, err := time.Parse("15:04", at)
+	if err != nil {
+		return cutoffRule{}, fmt.Errorf("时间格式应为 HH:mm: %s", at)
+	}
+	rule.hour, rule.minute = t.Hour(), t.Minute()
+	return rule, nil
+}
+
+// deadline 计算 yyyyMMdd 用餐日的截止时间
+func (r cutoffRule) deadline(date string) (time.Time, error) {
+	day, err := time.ParseInLocation("20060102", date, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("日期格式错误: %s", date)
+	}
+	return time.Date(day.Year(), day.Month(), day.Day()-r.days, r.hour, r.minute, 0, 0, time.Local), nil
+}
+
+// cutoffPolicy 一次请求内使用的截止规则配置
+type cutoffPolicy struct {
+	configs map[string]string // canteen_config 中的截止规则
+	codes   map[string]string // 餐别名称 -> 餐次编码
+}
+
+// deadline 返回指定餐别、部门在 date 的报餐或取消截止时间
+func (p cutoffPolicy) deadline(kind string, mealType string, deptId int, date string) (time.Time, error) {
+	kinds := []string{kind}
+	if kind == cutoffKindCancel {
+		kinds = append(kinds, cutoffKindBooking)
+	}
+
+	key, value := "", defaultCutoff
+	for _, k := range kinds {
+		if key, value = p.lookup(k, mealType, deptId); key != "" {
+			break
+		}
+	}
+	if key == "" {
+		value = defaultCutoff
+	}
+
+	rule, err := parseCutoffRule(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("配置 %s 无效: %v", key, err)
+	}
+	return rule.deadline(date)
+}
+
+func (p cutoffPolicy) lookup(kind string, mealType string, deptId int) (string, string) {
+	var keys []string
+	if code := p.codes[mealType]; code != "" {
+		if deptId > 0 {
+			keys = append(keys, fmt.Sprintf("%s.%s.dept_%d", kind, code, deptId))
+		}
+		keys = append(keys, kind+"."+code)
+	}
+	if deptId > 0 {
+		keys = append(keys, fmt.Sprintf("%s.dept_%d", kind, deptId))
+	}
+	keys = append(keys, kind)
+
+	for _, key := range keys {
+		if value := strings.TrimSpace(p.configs[key]); value != "" {
+			return key, value
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,144 @@
+package calendar
+
+import (
+	"canteen/internal/infrastructure/clock"
+	"canteen/internal/model"
+	"canteen/internal/repository/calendar"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type CalendarService interface {
+	// ListDays 查询 [start, end] 内登记的节假日及调休上班日，日期为 yyyy-MM-dd
+	ListDays(start, end string) ([]model.CalendarDay, error)
+	// SaveDay 新增或覆盖指定日期的登记
+	SaveDay(day model.CalendarDay) (*model.CalendarDay, error)
+	DeleteDay(date string) error
+	// Import 按文件扩展名解析 Excel（.xlsx）或 ICS（.ics）日历并覆盖导入，任一日期有误时整体不导入
+	Import(r io.Reader, filename string) (*model.CalendarImportResult, error)
+}
+
+type calendarService struct {
+	calendarRepo calendar.CalendarRepository
+	clock        clock.Clock
+}
+
+func NewCalendarService(calendarRepo calendar.CalendarRepository, clk clock.Clock) CalendarService {
+	return &calendarService{calendarRepo: calendarRepo, clock: clk}
+}
+
+func (s *calendarService) ListDays(start, end string) ([]model.CalendarDay, error) {
+	if start == "" || end == "" {
+		// 默认查询今年
+		year := s.clock.Now().Year()
+		start, end = fmt.Sprintf("%d-01-01", year), fmt.Sprintf("%d-12-31", year)
+	}
+	for _, d := range []string{start, end} {
+		if _, err := time.Parse("2006-01-02", d); err != nil {
+			return nil, fmt.Errorf("日期格式错误: %s，请使用 yyyy-MM-dd 格式", d)
+		}
+	}
+	return s.calendarRepo.FindRange(start, end)
+}
+
+func (s *calendarService) SaveDay(day model.CalendarDay) (*model.CalendarDay, error) {
+	if err := normalizeDay(&day); err != nil {
+		return nil, err
+	}
+	if err := s.calendarRepo.SaveAll([]model.CalendarDay{day}); err != nil {
+		return nil, err
+	}
+
+	log.Printf("登记日历: 日期=%s, 类型=%s, 名称=%s, 按星期=%d", day.Date, day.DayType, day.Name, day.Weekday)
+	t, _ := time.ParseInLocation("2006-01-02", day.Date, time.Local)
+	return s.calendarRepo.FindByDate(t)
+}
+
+func (s *calendarService) DeleteDay(date string) error {
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return fmt.Errorf("日期格式错误: %s，请使用 yyyy-MM-dd 格式", date)
+	}
+	if err := s.calendarRepo.Delete(date); err != nil {
+		return err
+	}
+	log.Printf("删除日历登记: 日期=%s", date)
+	return nil
+}
+
+func (s *calendarService) Import(r io.Reader, filename string) (*model.CalendarImportResult, error) {
+	var days []model.CalendarDay
+	var err error
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".xlsx":
+		days, err = parseCalendarSheet(r)
+	case ".ics":
+		days, err = parseICS(r)
+	default:
+		return nil, errors.New("仅支持 .xlsx 或 .ics 文件")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(days) == 0 {
+		return nil, errors.New("文件中没有日期")
+	}
+
+	// 同一日期以最后一条为准
+	result := &model.CalendarImportResult{Days: []model.CalendarDay{}}
+	index := map[string]int{}
+	for _, d := range days {
+		if i, ok := index[d.Date]; ok {
+			result.Days[i] = d
+			continue
+		}
+		index[d.Date] = len(result.Days)
+		result.Days = append(result.Days, d)
+	}
+	for _, d := range result.Days {
+		if d.DayType == model.CalendarHoliday {
+			result.Holidays++
+		} else {
+			result.Workdays++
+		}
+	}
+	result.Total = len(result.Days)
+
+	if err := s.calendarRepo.SaveAll(result.Days); err != nil {
+		return nil, err
+	}
+	log.Printf("导入日历: 文件=%s, 节假日=%d, 调休上班=%d", filename, result.Holidays, result.Workdays)
+	return result, nil
+}
+
+// normalizeDay 校验并规范化日期登记，调休上班未指定星期时按周一供餐
+func normalizeDay(day *model.CalendarDay) error {
+	day.Date = strings.TrimSpace(day.Date)
+	day.Name = strings.TrimSpace(day.Name)
+	day.Remark = strings.TrimSpace(day.Remark)
+
+	t, err := time.Parse("2006-01-02", day.Date)
+	if err != nil {
+		return fmt.Errorf("日期格式错误: %s，请使用 yyyy-MM-dd 格式", day.Date)
+	}
+	day.Date = t.Format("2006-01-02")
+
+	switch day.DayType {
+	case model.CalendarHoliday:
+		day.Weekday = 0
+	case model.CalendarWorkday:
+		if day.Weekday == 0 {
+			day.Weekday = 1
+		}
+		if day.Weekday < 1 || day.Weekday > 7 {
+			return fmt.Errorf("无效的星期: %d", day.Weekday)
+		}
+	default:
+		return fmt.Errorf("日期类型须为%s或%s: %s", model.CalendarHoliday, model.CalendarWorkday, day.DayType)
+	}
+	return nil
+}
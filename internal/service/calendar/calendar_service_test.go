@@ -0,0 +1,32 @@
+package calendar
+
+import (
+	"canteen/internal/infrastructure/clock"
+	"canteen/internal/model"
+	"canteen/internal/repository/calendar"
+	"testing"
+	"time"
+)
+
+// fakeCalendarRepo 记录查询范围
+type fakeCalendarRepo struct {
+	calendar.CalendarRepository
+	start, end string
+}
+
+func (r *fakeCalendarRepo) FindRange(start, end string) ([]model.CalendarDay, error) {
+	r.start, r.end = start, end
+	return []model.CalendarDay{}, nil
+}
+
+func TestListDaysDefaultsToCurrentYear(t *testing.T) {
+	repo := &fakeCalendarRepo{}
+	s := NewCalendarService(repo, clock.Fixed(time.Date(2030, 3, 1, 9, 0, 0, 0, time.Local)))
+
+	if _, err := s.ListDays("", ""); err != nil {
+		t.Fatalf("ListDays() err = %v", err)
+	}
+	if repo.start != "2030-01-01" || repo.end != "2030-12-31" {
+		t.Errorf("查询范围 = %s ~ %s, 期望按注入时钟取 2030 年", repo.start, repo.end)
+	}
+}
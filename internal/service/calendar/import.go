@@ -0,0 +1,247 @@
+package calendar
+
+import (
+	"bufio"
+	"canteen/internal/model"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// calendarHeaders Excel 首行表头与字段的对应关系
+var calendarHeaders = map[string]string{
+	"日期":  "date",
+	"类型":  "type",
+	"名称":  "name",
+	"按星期": "weekday",
+	"备注":  "remark",
+}
+
+// dayTypes 文件中的类型写法，“班”类为调休上班，“休”类为节假日
+var dayTypes = map[string]string{
+	"节假日": model.CalendarHoliday,
+	"假日":  model.CalendarHoliday,
+	"放假":  model.CalendarHoliday,
+	"休息":  model.CalendarHoliday,
+	"休":   model.CalendarHoliday,
+	"调休":  model.CalendarWorkday,
+	"补班":  model.CalendarWorkday,
+	"上班":  model.CalendarWorkday,
+	"班":   model.CalendarWorkday,
+}
+
+var weekdayNames = map[string]int{
+	"周一": 1, "周二": 2, "周三": 3, "周四": 4, "周五": 5, "周六": 6, "周日": 7,
+	"星期一": 1, "星期二": 2, "星期三": 3, "星期四": 4, "星期五": 5, "星期六": 6, "星期日": 7, "星期天": 7,
+}
+
+// maxEventDays 单个 ICS 事件最多展开的天数
+const maxEventDays = 31
+
+// parseCalendarSheet 解析 Excel 日历，首行为表头：日期 / 类型 / 名称 / 按星期 / 备注
+func parseCalendarSheet(r io.Reader) ([]model.CalendarDay, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("解析Excel文件失败: %w", err)
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, errors.New("Excel文件中没有工作表")
+	}
+	// 读取原始值，日期单元格为序列号
+	rows, err := f.GetRows(sheets[0], excelize.Options{RawCellValue: true})
+	if err != nil {
+		return nil, fmt.Errorf("读取工作表数据失败: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("日历为空")
+	}
+
+	columns := map[string]int{}
+	for i, cell := range rows[0] {
+		if key, ok := calendarHeaders[strings.TrimSpace(cell)]; ok {
+			if _, exists := columns[key]; !exists {
+				columns[key] = i
+			}
+		}
+	}
+	_, hasDate := columns["date"]
+	_, hasType := columns["type"]
+	if !hasDate || !hasType {
+		return nil, errors.New("日历首行须包含 日期 和 类型 列")
+	}
+
+	cell := func(row []string, key string) string {
+		i, ok := columns[key]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	days := []model.CalendarDay{}
+	for i, row := range rows[1:] {
+		date, dayType := cell(row, "date"), cell(row, "type")
+		if date == "" && dayType == "" {
+			continue
+		}
+
+		day := model.CalendarDay{
+			Name:    cell(row, "name"),
+			DayType: dayTypes[dayType],
+			Remark:  cell(row, "remark"),
+		}
+		t, err := parseSheetDate(date)
+		if err == nil {
+			day.Date = t.Format("2006-01-02")
+			day.Weekday, err = parseWeekday(cell(row, "weekday"))
+		}
+		if err == nil && day.DayType == "" {
+			err = fmt.Errorf("无法识别的类型: %s", dayType)
+		}
+		if err == nil {
+			err = normalizeDay(&day)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("第%d行: %v", i+2, err)
+		}
+		days = append(days, day)
+	}
+
+	return days, nil
+}
+
+// parseSheetDate 解析 Excel 日期序列号或常见日期文本
+func parseSheetDate(value string) (time.Time, error) {
+	if serial, err := strconv.ParseFloat(value, 64); err == nil && len(value) != 8 {
+		return excelize.ExcelDateToTime(serial, false)
+	}
+	for _, layout := range []string{"2006-01-02", "2006-1-2", "2006/1/2", "2006.1.2", "20060102", "2006年1月2日"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("日期格式错误: %s", value)
+}
+
+// parseWeekday 解析 1-7 或 周一、星期一 等写法，为空时返回 0
+func parseWeekday(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if d, ok := weekdayNames[value]; ok {
+		return d, nil
+	}
+	d, err := strconv.Atoi(value)
+	if err != nil || d < 1 || d > 7 {
+		return 0, fmt.Errorf("无效的星期: %s", value)
+	}
+	return d, nil
+}
+
+// parseICS 解析 ICS 日历，全天事件的 SUMMARY 含“班”为调休上班，否则为节假日；DTEND 不含当天
+func parseICS(r io.Reader) ([]model.CalendarDay, error) {
+	lines, err := unfoldICS(r)
+	if err != nil {
+		return nil, fmt.Errorf("读取ICS文件失败: %w", err)
+	}
+
+	days := []model.CalendarDay{}
+	var event map[string]string
+	for _, line := range lines {
+		switch {
+		case line == "BEGIN:VEVENT":
+			event = map[string]string{}
+		case line == "END:VEVENT":
+			if event == nil {
+				continue
+			}
+			expanded, err := expandEvent(event)
+			if err != nil {
+				return nil, fmt.Errorf("事件 %s: %v", event["SUMMARY"], err)
+			}
+			days = append(days, expanded...)
+			event = nil
+		case event != nil:
+			name, value, ok := strings.Cut(line, ":")
+			if !ok {
+				continue
+			}
+			// 去掉 DTSTART;VALUE=DATE 等属性参数
+			name, _, _ = strings.Cut(name, ";")
+			event[strings.ToUpper(name)] = value
+		}
+	}
+	if event != nil {
+		return nil, errors.New("ICS文件不完整，缺少 END:VEVENT")
+	}
+
+	return days, nil
+}
+
+// unfoldICS 读取 ICS 内容行，合并以空格或制表符开头的折行
+func unfoldICS(r io.Reader) ([]string, error) {
+	lines := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+func expandEvent(event map[string]string) ([]model.CalendarDay, error) {
+	start, err := parseICSDate(event["DTSTART"])
+	if err != nil {
+		return nil, err
+	}
+	end := start.AddDate(0, 0, 1)
+	if event["DTEND"] != "" {
+		if end, err = parseICSDate(event["DTEND"]); err != nil {
+			return nil, err
+		}
+	}
+	if !end.After(start) || end.Sub(start) > maxEventDays*24*time.Hour {
+		return nil, fmt.Errorf("事件日期范围无效: %s - %s", event["DTSTART"], event["DTEND"])
+	}
+
+	summary := strings.NewReplacer(`\,`, ",", `\;`, ";", `\n`, " ").Replace(strings.TrimSpace(event["SUMMARY"]))
+	day := model.CalendarDay{DayType: model.CalendarHoliday, Name: summary}
+	if strings.Contains(summary, "班") {
+		day.DayType = model.CalendarWorkday
+	}
+
+	days := []model.CalendarDay{}
+	for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+		day.Date = d.Format("2006-01-02")
+		if err := normalizeDay(&day); err != nil {
+			return nil, err
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+// parseICSDate 解析 20251001 或 20251001T000000Z 形式的日期，仅取日期部分
+func parseICSDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if len(value) < 8 {
+		return time.Time{}, fmt.Errorf("日期格式错误: %s", value)
+	}
+	t, err := time.ParseInLocation("20060102", value[:8], time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("日期格式错误: %s", value)
+	}
+	return t, nil
+}
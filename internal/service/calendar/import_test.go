@@ -0,0 +1,119 @@
+package calendar
+
+import (
+	"bytes"
+	"canteen/internal/model"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestParseICS(t *testing.T) {
+	ics := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT",
+		"DTSTART;VALUE=DATE:20251001",
+		"DTEND;VALUE=DATE:20251004",
+		"SUMMARY:国庆",
+		" 节",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART;VALUE=DATE:20250928",
+		"SUMMARY:国庆节 补班",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	days, err := parseICS(strings.NewReader(ics))
+	if err != nil {
+		t.Fatalf("parseICS() err = %v", err)
+	}
+	want := []model.CalendarDay{
+		{Date: "2025-10-01", DayType: model.CalendarHoliday, Name: "国庆节"},
+		{Date: "2025-10-02", DayType: model.CalendarHoliday, Name: "国庆节"},
+		{Date: "2025-10-03", DayType: model.CalendarHoliday, Name: "国庆节"},
+		{Date: "2025-09-28", DayType: model.CalendarWorkday, Name: "国庆节 补班", Weekday: 1},
+	}
+	if len(days) != len(want) {
+		t.Fatalf("日期数 = %d, 期望 %d: %+v", len(days), len(want), days)
+	}
+	for i := range want {
+		if days[i] != want[i] {
+			t.Errorf("第%d天 = %+v, 期望 %+v", i+1, days[i], want[i])
+		}
+	}
+}
+
+func TestParseICSRejectsInvalidEvent(t *testing.T) {
+	tests := map[string]string{
+		"结束早于开始": "BEGIN:VEVENT\nDTSTART:20251005\nDTEND:20251001\nSUMMARY:假期\nEND:VEVENT",
+		"缺少开始日期": "BEGIN:VEVENT\nSUMMARY:假期\nEND:VEVENT",
+		"事件未结束":  "BEGIN:VEVENT\nDTSTART:20251001\nSUMMARY:假期",
+	}
+	for name, ics := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseICS(strings.NewReader(ics)); err == nil {
+				t.Errorf("parseICS() 应返回错误")
+			}
+		})
+	}
+}
+
+func sheetReader(t *testing.T, rows [][]interface{}) *bytes.Reader {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := f.SetSheetRow("Sheet1", cell, &row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestParseCalendarSheet(t *testing.T) {
+	r := sheetReader(t, [][]interface{}{
+		{"日期", "类型", "名称", "按星期"},
+		{"2026-01-01", "休", "元旦"},
+		{"2026/2/14", "班", "春节调休", "周五"},
+		{},
+		{"20260215", "补班", "", 3},
+	})
+
+	days, err := parseCalendarSheet(r)
+	if err != nil {
+		t.Fatalf("parseCalendarSheet() err = %v", err)
+	}
+	want := []model.CalendarDay{
+		{Date: "2026-01-01", DayType: model.CalendarHoliday, Name: "元旦"},
+		{Date: "2026-02-14", DayType: model.CalendarWorkday, Name: "春节调休", Weekday: 5},
+		{Date: "2026-02-15", DayType: model.CalendarWorkday, Weekday: 3},
+	}
+	if len(days) != len(want) {
+		t.Fatalf("日期数 = %d, 期望 %d: %+v", len(days), len(want), days)
+	}
+	for i := range want {
+		if days[i] != want[i] {
+			t.Errorf("第%d天 = %+v, 期望 %+v", i+1, days[i], want[i])
+		}
+	}
+}
+
+func TestParseCalendarSheetReportsRow(t *testing.T) {
+	r := sheetReader(t, [][]interface{}{
+		{"日期", "类型"},
+		{"2026-01-01", "休"},
+		{"2026-01-02", "加班"},
+	})
+
+	_, err := parseCalendarSheet(r)
+	if err == nil || !strings.HasPrefix(err.Error(), "第3行") {
+		t.Errorf("parseCalendarSheet() err = %v, 期望指出第3行", err)
+	}
+}
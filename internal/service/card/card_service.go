@@ -3,6 +3,7 @@ package card
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
@@ -10,10 +11,15 @@ import (
 	"strings"
 	"time"
 
+	"canteen/internal/infrastructure/clock"
 	"canteen/internal/model"
 	"canteen/internal/repository/card"
+	"canteen/internal/repository/offline"
 	"canteen/internal/repository/order"
+	"canteen/internal/repository/transaction_log"
 	"canteen/internal/repository/user"
+	"canteen/internal/service/device"
+	"canteen/internal/service/meal_period"
 
 	"github.com/go-redis/redis/v8"
 )
@@ -21,7 +27,22 @@ import (
 type CardService interface {
 	ProcessConsumTransaction(req model.ConsumTransaction, deviceID string) (*model.ConsumResponse, error)
 	GetServerTime() time.Time
-	ProcessOffLineRequest(req model.OffLineRequest) error
+	ProcessOffLineRequest(req model.OffLineRequest, deviceID string) error
+	// RefundTransaction 撤销一次核销：已领取订单恢复为已报餐，临时订单作废，并返还用餐次数
+	RefundTransaction(req model.RefundRequest) (*model.RefundLog, error)
+}
+
+// Cache 核销所需的缓存操作，*redis.Client 即满足该接口
+type Cache interface {
+	Get(ctx context.Context, key string) *redis.StringCmd
+	HGetAll(ctx context.Context, key string) *redis.StringStringMapCmd
+	HSet(ctx context.Context, key string, values ...interface{}) *redis.IntCmd
+	Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd
+}
+
+// EventPublisher 在线刷卡结果的发布者，*events.Bus 即满足该接口
+type EventPublisher interface {
+	Publish(event model.SwipeEvent)
 }
 
 type ConsumResponse struct {
@@ -42,59 +63,223 @@ type ConsumResponse struct {
 }
 
 type cardService struct {
-	userRepo  user.UserRepository
-	orderRepo order.OrderRepository
-	cardRepo  card.CardRepository
-	redis     *redis.Client
+	userRepo      user.UserRepository
+	orderRepo     order.OrderRepository
+	cardRepo      card.CardRepository
+	txLogRepo     transaction_log.TransactionLogRepository
+	offlineRepo   offline.OfflineRepository
+	deviceService device.DeviceService
+	periodService meal_period.MealPeriodService
+	redis         Cache
+	clock         clock.Clock
+	events        EventPublisher
 }
 
-func NewCardService(userRepo user.UserRepository, orderRepo order.OrderRepository, cardRepo card.CardRepository, redisClient *redis.Client) CardService {
+func NewCardService(userRepo user.UserRepository, orderRepo order.OrderRepository, cardRepo card.CardRepository, txLogRepo transaction_log.TransactionLogRepository, offlineRepo offline.OfflineRepository, deviceService device.DeviceService, periodService meal_period.MealPeriodService, redisClient Cache, clk clock.Clock, publisher EventPublisher) CardService {
 	return &cardService{
-		userRepo:  userRepo,
-		orderRepo: orderRepo,
-		cardRepo:  cardRepo,
-		redis:     redisClient,
+		userRepo:      userRepo,
+		orderRepo:     orderRepo,
+		cardRepo:      cardRepo,
+		txLogRepo:     txLogRepo,
+		offlineRepo:   offlineRepo,
+		deviceService: deviceService,
+		periodService: periodService,
+		redis:         redisClient,
+		clock:         clk,
+		events:        publisher,
 	}
 }
 
 func (s *cardService) ProcessConsumTransaction(req model.ConsumTransaction, deviceID string) (*model.ConsumResponse, error) {
-	ctx := context.Background()
-	deviceToRemark := map[string]string{
-		"0180800116": "A",
-		"0127448632": "B",
-		"0158577664": "C",
+	// 终端重试时根据交易流水号返回原始结果，避免重复核销
+	if req.Order != "" {
+		response, found, err := s.replayTransaction(deviceID, req.Order)
+		if err != nil || found {
+			return response, err
+		}
+	}
+
+	now := s.clock.Now()
+	event := &model.SwipeEvent{Time: now, DeviceId: deviceID}
+	response, err := s.consume(req, deviceID, now, event)
+	if errors.Is(err, card.ErrDuplicateTransaction) {
+		// 并发重试：另一请求已先行提交，本次事务已回滚，结果已由该请求发布
+		response, _, err = s.replayTransaction(deviceID, req.Order)
+	} else {
+		s.publish(event, response, err)
+	}
+	var re *ruleError
+	if errors.As(err, &re) && re.response != nil {
+		return re.response, nil
+	}
+	return response, err
+}
+
+// replayTransaction 查询已记录的交易流水并返回原始核销结果
+func (s *cardService) replayTransaction(deviceID string, orderNo string) (*model.ConsumResponse, bool, error) {
+	txLog, err := s.txLogRepo.FindByDeviceOrder(deviceID, orderNo)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, false, nil
+		}
+		log.Printf("TAG: 查询交易流水失败: %v", err)
+		return nil, false, fmt.Errorf("查询交易流水失败: %v", err)
+	}
+
+	log.Printf("TAG: 重复交易请求, deviceID=%s, Order=%s, 返回原核销结果", deviceID, orderNo)
+	var response model.ConsumResponse
+	if err := json.Unmarshal([]byte(txLog.Response), &response); err != nil {
+		log.Printf("TAG: 解析原核销结果失败: %v", err)
+		return nil, true, fmt.Errorf("交易记录异常")
+	}
+	return &response, true, nil
+}
+
+// recordTransaction 在核销事务内记录交易流水
+func (s *cardService) recordTransaction(tx card.CardTx, req model.ConsumTransaction, deviceID string, order *model.OrderRecord, response *model.ConsumResponse) error {
+	if req.Order == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		log.Printf("TAG: 序列化核销结果失败: %v", err)
+		return fmt.Errorf("记录交易流水失败: %v", err)
+	}
+
+	txLog := &model.TransactionLog{
+		DeviceNo:      deviceID,
+		OrderNo:       req.Order,
+		CardNo:        req.CardNo,
+		UserId:        order.UserId,
+		OrderRecordId: order.Id,
+		MealType:      order.MealType,
+		Response:      string(data),
 	}
+	if err := tx.CreateTransactionLog(txLog); err != nil {
+		if errors.Is(err, card.ErrDuplicateTransaction) {
+			log.Printf("TAG: 交易流水重复, deviceID=%s, Order=%s", deviceID, req.Order)
+			return err
+		}
+		log.Printf("TAG: 记录交易流水失败, deviceID=%s, Order=%s, err=%v", deviceID, req.Order, err)
+		return fmt.Errorf("记录交易流水失败: %v", err)
+	}
+	return nil
+}
+
+// publish 发布在线刷卡结果，供实时看板统计
+func (s *cardService) publish(event *model.SwipeEvent, response *model.ConsumResponse, err error) {
+	if s.events == nil {
+		return
+	}
+	switch {
+	case err == nil:
+		event.Message = response.Message
+	case errors.Is(err, errWrongWindow):
+		event.Result, event.Message = model.SwipeResultWrongWindow, err.Error()
+	case isRuleViolation(err):
+		event.Result, event.Message = model.SwipeResultRejected, err.Error()
+	default:
+		event.Result, event.Message = model.SwipeResultError, err.Error()
+	}
+	s.events.Publish(*event)
+}
+
+// ruleError 核销规则校验未通过（区别于数据库等系统异常）
+type ruleError struct {
+	msg string
+	// response 非空时在线核销直接返回该响应，供终端播报
+	response *model.ConsumResponse
+}
+
+func (e *ruleError) Error() string {
+	return e.msg
+}
+
+func ruleErrorf(format string, args ...interface{}) error {
+	return &ruleError{msg: fmt.Sprintf(format, args...)}
+}
+
+// errWrongWindow 报餐套餐不在当前窗口
+var errWrongWindow = &ruleError{msg: "请前往正确的窗口刷卡取餐"}
+
+// isRuleViolation 判断错误是否为核销规则校验未通过
+func isRuleViolation(err error) bool {
+	var re *ruleError
+	return errors.As(err, &re)
+}
+
+// consume 按 now 时刻执行核销，并在 event 中记录窗口、餐别及成功时的核销结果
+func (s *cardService) consume(req model.ConsumTransaction, deviceID string, now time.Time, event *model.SwipeEvent) (*model.ConsumResponse, error) {
+	ctx := context.Background()
 
 	log.Printf("TAG: 核销开始")
 	log.Printf("传入卡号=%s", req.CardNo)
 
+	// 解析刷卡设备
+	terminal, err := s.deviceService.ResolveDevice(deviceID)
+	if err != nil {
+		if errors.Is(err, device.ErrUnknownDevice) || errors.Is(err, device.ErrDeviceDisabled) {
+			return nil, ruleErrorf("%v，无法取餐", err)
+		}
+		return nil, fmt.Errorf("%v，无法取餐", err)
+	}
+	event.Canteen, event.Window = terminal.Canteen, terminal.Window
+
 	// 查询用户信息
 	user, err := s.cardRepo.FindUserByCardNo(req.CardNo)
+	var blocked *card.CardBlockedError
+	if errors.As(err, &blocked) {
+		log.Printf("TAG: 卡片不可用 user_id=%d,卡号=%s,状态=%s", user.UserId, req.CardNo, blocked.Status)
+		event.Name = user.NickName
+		return nil, &ruleError{msg: blocked.Error(), response: blockedResponse(req, user.NickName, blocked.Status)}
+	}
 	if err != nil {
 		log.Printf("TAG: 查询用户信息失败: %v", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ruleErrorf("查询用户信息失败: %v", err)
+		}
 		return nil, fmt.Errorf("查询用户信息失败: %v", err)
 	}
 
 	log.Printf("TAG: 获取到用户信息 user_id=%d,名称=%s,卡号=%s", user.UserId, user.NickName, user.CardNo)
+	event.Name = user.NickName
 
-	now := time.Now()
 	dateStr := now.Format("20060102")
 	weekdays := [...]string{"周日", "周一", "周二", "周三", "周四", "周五", "周六"}
 	weekday := weekdays[now.Weekday()]
 
-	// 判断餐类型
-	var mealType string
-	if now.Hour() >= 11 && now.Hour() < 14 {
-		mealType = "午餐"
-	} else {
-		mealType = "晚餐"
+	// 按设备所属食堂的餐次时段判断餐类型
+	period, err := s.periodService.Resolve(terminal.Canteen, now)
+	if err != nil {
+		if errors.Is(err, meal_period.ErrNoMealPeriod) {
+			log.Printf("TAG: 当前时间 %v 不在食堂 %s 的任何餐次时段内", now, terminal.Canteen)
+			return nil, ruleErrorf("%v", err)
+		}
+		return nil, err
+	}
+	mealType := period.Name
+	event.MealType = mealType
+
+	// 窗口规则按日历对应的星期执行，与周套餐生成一致（调休上班日按补班的星期）；订单仍按当天的星期登记
+	serveDay, open, err := s.periodService.ScheduleOn(now)
+	if err != nil {
+		log.Printf("TAG: 查询日历失败, date=%s, err=%v", now.Format("2006-01-02"), err)
+	}
+	if !open {
+		serveDay = now.Weekday()
+	}
+
+	if !terminal.ServesMealType(mealType) {
+		log.Printf("TAG: 设备 %s 不供应%s", terminal.SerialNo, mealType)
+		return nil, ruleErrorf("本窗口不供应%s", mealType)
 	}
 
 	// 客户处理逻辑
-	if user.DeptId == 219 {
+	if user.DeptId == model.GuestDeptId {
 		log.Printf("TAG: 客户刷卡 dept_id=219")
 
-		mealID, err := s.getMealIDFromRedis(ctx, deviceID, deviceToRemark, dateStr, now)
+		mealID, err := s.getMealIDFromRedis(ctx, period, terminal.Window, dateStr)
 		if err != nil {
 			return nil, fmt.Errorf("获取套餐ID失败: %v", err)
 		}
@@ -103,7 +288,7 @@ func (s *cardService) ProcessConsumTransaction(req model.ConsumTransaction, devi
 		order := &model.OrderRecord{
 			UserId:     user.UserId,
 			MealId:     mealID,
-			Status:     "临时用餐",
+			Status:     model.OrderStatusTemp,
 			MealType:   mealType,
 			WeekNumber: dateStr,
 			OrderDate:  now.Format("2006-01-02"),
@@ -111,33 +296,14 @@ func (s *cardService) ProcessConsumTransaction(req model.ConsumTransaction, devi
 		}
 
 		// 开始事务
-		if err := s.createTempOrderAndDecreaseCount(order, user.UserId, user.Count); err != nil {
-			return nil, err
-		}
-
-		return &model.ConsumResponse{
-			Status:     1,
-			Message:    "核销成功:" + mealType,
-			Name:       user.NickName,
-			CardNo:     req.CardNo,
-			Money:      0,
-			Subsidy:    0.00,
-			Times:      user.Count - 1,
-			Integral:   0.00,
-			InTime:     "",
-			OutTime:    "",
-			Cumulative: "",
-			Amount:     req.Amount,
-			VoiceID:    "核销成功",
-			Text:       user.NickName + ":" + mealType + "核销成功",
-		}, nil
+		event.Result = model.SwipeResultTemp
+		return s.createTempOrderAndDecreaseCount(req, deviceID, order, false)
 	}
 
 	// 员工处理逻辑
-	if mealType == "晚餐" {
-		ok, msg := s.checkDinnerTime(ctx, user.DeptId, now)
-		if !ok {
-			return nil, errors.New(msg)
+	if period.Code == "dinner" {
+		if err := s.checkDinnerTime(ctx, user.DeptId, now, period); err != nil {
+			return nil, err
 		}
 	}
 
@@ -165,7 +331,7 @@ func (s *cardService) ProcessConsumTransaction(req model.ConsumTransaction, devi
 	if isUnordered {
 		log.Printf("TAG: 未报餐，创建临时订单")
 
-		mealID, err := s.getMealIDFromRedis(ctx, deviceID, deviceToRemark, dateStr, now)
+		mealID, err := s.getMealIDFromRedis(ctx, period, terminal.Window, dateStr)
 		if err != nil {
 			return nil, fmt.Errorf("获取套餐ID失败: %v", err)
 		}
@@ -173,116 +339,124 @@ func (s *cardService) ProcessConsumTransaction(req model.ConsumTransaction, devi
 		tempOrder := &model.OrderRecord{
 			UserId:     user.UserId,
 			MealId:     mealID,
-			Status:     "临时用餐",
+			Status:     model.OrderStatusTemp,
 			MealType:   mealType,
 			WeekNumber: dateStr,
 			OrderDate:  now.Format("2006-01-02"),
 			Weekday:    weekday,
 		}
 
-		if err := s.createTempOrderAndDecreaseCount(tempOrder, user.UserId, user.Count); err != nil {
-			return nil, err
-		}
-
-		return &model.ConsumResponse{
-			Status:     1,
-			Message:    "核销成功:" + mealType,
-			Name:       user.NickName,
-			CardNo:     req.CardNo,
-			Money:      0,
-			Subsidy:    0.00,
-			Times:      user.Count - 1,
-			Integral:   0.00,
-			InTime:     "",
-			OutTime:    "",
-			Cumulative: "",
-			Amount:     req.Amount,
-			VoiceID:    "核销成功",
-			Text:       user.NickName + ":" + mealType + "核销成功",
-		}, nil
+		event.Result = model.SwipeResultTemp
+		return s.createTempOrderAndDecreaseCount(req, deviceID, tempOrder, true)
 	}
 
 	// 检查是否重复刷卡
-	if order.Status == "已领取" || order.Status == "临时用餐" {
+	if order.Status == model.OrderStatusCollected || order.Status == model.OrderStatusTemp {
 		log.Printf("TAG: 重复刷卡，订单已领取")
-		return nil, fmt.Errorf("该卡今天%s重复刷卡取餐！", mealType)
+		return nil, ruleErrorf("该卡今天%s重复刷卡取餐！", mealType)
 	}
 
 	// 检查窗口是否正确
-	window, ok := deviceToRemark[deviceID]
-	if !ok {
-		log.Printf("TAG: 未知设备ID: %s", deviceID)
-		return nil, fmt.Errorf("未知设备，无法取餐")
-	}
+	window := terminal.Window
 
 	// 除周六外，其他日期不可刷其他套餐
-	if weekday != "周六" {
-		mealTypeMap := map[string]string{"午餐": "lunch", "晚餐": "dinner"}
-		redisKey := fmt.Sprintf("%s-%s-%s", dateStr, mealTypeMap[mealType], window)
+	if serveDay != time.Saturday {
+		redisKey := fmt.Sprintf("%s-%s-%s", dateStr, period.Code, window)
 
 		cachedMealIDStr, err := s.redis.Get(ctx, redisKey).Result()
-		if err != nil {
-			log.Printf("TAG: Redis 获取失败, key=%s, err=%v", redisKey, err)
-			return nil, fmt.Errorf("窗口配置读取失败")
-		}
-
-		cachedMealID, err := strconv.Atoi(cachedMealIDStr)
-		if err != nil {
-			log.Printf("TAG: Redis 缓存的 MealID 解析失败: %v", err)
-			return nil, fmt.Errorf("系统配置异常")
+		var cachedMealID int
+		if err == nil {
+			cachedMealID, err = strconv.Atoi(cachedMealIDStr)
+			if err != nil {
+				log.Printf("TAG: Redis 缓存的 MealID 解析失败: %v", err)
+				return nil, fmt.Errorf("系统配置异常")
+			}
+		} else {
+			// 缓存缺失（如脱机补录历史日期）时回源数据库
+			log.Printf("TAG: Redis 获取失败, key=%s, err=%v, 回源数据库", redisKey, err)
+			cachedMealID, err = s.cardRepo.FindWindowSetmealId(dateStr, mealType, window)
+			if err != nil {
+				log.Printf("TAG: 查询窗口套餐失败: %v", err)
+				return nil, fmt.Errorf("窗口配置读取失败")
+			}
 		}
 
 		if cachedMealID != order.MealId {
 			log.Printf("TAG: 用户刷错窗口, 正确套餐ID=%d, 当前窗口套餐ID=%d", order.MealId, cachedMealID)
-			return nil, fmt.Errorf("请前往正确的窗口刷卡取餐")
+			return nil, errWrongWindow
 		}
 	}
 
 	// 更新订单状态并减少用户次数
-	if err := s.updateOrderStatusAndDecreaseCount(order.Id, "已领取", user.UserId, user.Count); err != nil {
+	order.MealType = mealType
+	event.Result = model.SwipeResultCollected
+	response, err := s.updateOrderStatusAndDecreaseCount(req, deviceID, order)
+	if err != nil {
 		return nil, err
 	}
 
 	log.Printf("TAG: 核销成功: %s，用户: %s", mealType, user.NickName)
+	return response, nil
+}
+
+// swipeLedger 构造刷卡核销扣减一次的次数流水
+func swipeLedger(order *model.OrderRecord) *model.CountLedger {
+	return &model.CountLedger{
+		UserId:     order.UserId,
+		Change:     -1,
+		ChangeType: model.LedgerTypeSwipe,
+		RefType:    model.LedgerRefOrder,
+		RefId:      strconv.Itoa(order.Id),
+		Remark:     order.MealType,
+	}
+}
 
+// successResponse 构造核销成功响应
+func successResponse(req model.ConsumTransaction, name string, mealType string, times int) *model.ConsumResponse {
 	return &model.ConsumResponse{
 		Status:     1,
 		Message:    "核销成功:" + mealType,
-		Name:       user.NickName,
+		Name:       name,
 		CardNo:     req.CardNo,
 		Money:      0,
 		Subsidy:    0.00,
-		Times:      user.Count - 1,
+		Times:      times,
 		Integral:   0.00,
 		InTime:     "",
 		OutTime:    "",
 		Cumulative: "",
 		Amount:     req.Amount,
 		VoiceID:    "核销成功",
-		Text:       user.NickName + ":" + mealType + "核销成功",
-	}, nil
+		Text:       name + ":" + mealType + "核销成功",
+	}
 }
 
-// 从Redis获取套餐ID
-func (s *cardService) getMealIDFromRedis(ctx context.Context, deviceID string, deviceToRemark map[string]string, dateStr string, now time.Time) (int, error) {
-	var mealTypeEn string
-	if now.Hour() >= 11 && now.Hour() < 14 {
-		mealTypeEn = "lunch"
-	} else {
-		mealTypeEn = "dinner"
+// blockedResponse 构造卡片挂失、冻结或注销时的拒绝响应
+func blockedResponse(req model.ConsumTransaction, name string, status string) *model.ConsumResponse {
+	return &model.ConsumResponse{
+		Status:  0,
+		Message: "卡片已" + status,
+		Name:    name,
+		CardNo:  req.CardNo,
+		Amount:  req.Amount,
+		VoiceID: "卡片已" + status,
+		Text:    name + ":此卡已" + status + "，请联系管理员",
 	}
+}
 
-	remarkEn, ok := deviceToRemark[deviceID]
-	if !ok {
-		// 设备ID未映射到套餐类型，默认A
-		remarkEn = "A"
-	}
+// 从Redis获取套餐ID
+func (s *cardService) getMealIDFromRedis(ctx context.Context, period *model.MealPeriod, window string, dateStr string) (int, error) {
+	mealType := period.Name
 
-	key := fmt.Sprintf("%s-%s-%s", dateStr, mealTypeEn, remarkEn)
+	key := fmt.Sprintf("%s-%s-%s", dateStr, period.Code, window)
 	setmealIDStr, err := s.redis.Get(ctx, key).Result()
 	if err != nil {
 		if err == redis.Nil {
-			// 缓存不存在，使用默认值
+			// 缓存不存在，回源数据库，仍未找到时使用默认值
+			if mealId, err := s.cardRepo.FindWindowSetmealId(dateStr, mealType, window); err == nil {
+				log.Printf("Redis key not found: %s, using weekly_setmeal ID %d", key, mealId)
+				return mealId, nil
+			}
 			log.Printf("Redis key not found: %s, using default ID 1", key)
 			return 1, nil
 		}
@@ -299,8 +473,8 @@ func (s *cardService) getMealIDFromRedis(ctx context.Context, deviceID string, d
 	return mealId, nil
 }
 
-// 检查晚餐时间
-func (s *cardService) checkDinnerTime(ctx context.Context, userDeptId int, now time.Time) (bool, string) {
+// 检查晚餐时间，结束时间取晚餐时段配置
+func (s *cardService) checkDinnerTime(ctx context.Context, userDeptId int, now time.Time, period *model.MealPeriod) error {
 	const redisKey = "canteen:dinner_config"
 	var (
 		flexibleDeptIdsStr, fixedDeptIdsStr, flexibleDinnerStart, fixedDinnerStart string
@@ -312,7 +486,7 @@ func (s *cardService) checkDinnerTime(ctx context.Context, userDeptId int, now t
 		flexibleDeptIdsStr, fixedDeptIdsStr, flexibleDinnerStart, fixedDinnerStart, err = s.cardRepo.GetCanteenConfigs()
 		if err != nil {
 			log.Printf("TAG: 查询晚餐时间配置失败: %v", err)
-			return false, "系统配置错误"
+			return errors.New("系统配置错误")
 		}
 
 		s.redis.HSet(ctx, redisKey, "flexible_dept_id", flexibleDeptIdsStr)
@@ -350,7 +524,7 @@ func (s *cardService) checkDinnerTime(ctx context.Context, userDeptId int, now t
 
 	if !isFlexible && !isFixed {
 		log.Printf("TAG: 部门未配置用餐规则 dept_id=%d", userDeptId)
-		return false, "部门未配置用餐规则"
+		return ruleErrorf("部门未配置用餐规则")
 	}
 
 	var dinnerStart string
@@ -367,54 +541,350 @@ func (s *cardService) checkDinnerTime(ctx context.Context, userDeptId int, now t
 	startMin, _ := strconv.Atoi(startParts[1])
 
 	dinnerStartTime := time.Date(now.Year(), now.Month(), now.Day(), startHour, startMin, 0, 0, now.Location())
-	dinnerEndTime := time.Date(now.Year(), now.Month(), now.Day(), 21, 0, 0, 0, now.Location())
+	dinnerEndTime := period.EndOn(now)
 
 	if now.Before(dinnerStartTime) || now.After(dinnerEndTime) {
 		log.Printf("TAG: 当前时间 %v 不在晚餐时间段 %v ~ %v", now, dinnerStartTime, dinnerEndTime)
-		return false, "不在就餐时间范围内"
+		return ruleErrorf("不在就餐时间范围内")
 	}
-	return true, ""
+	return nil
 }
 
 // 创建临时订单并减少用户次数
-func (s *cardService) createTempOrderAndDecreaseCount(order *model.OrderRecord, userId int, count int) error {
+func (s *cardService) createTempOrderAndDecreaseCount(req model.ConsumTransaction, deviceID string, order *model.OrderRecord, checkDuplicate bool) (*model.ConsumResponse, error) {
+	var response *model.ConsumResponse
+
 	// 使用数据库事务
-	if err := s.cardRepo.CreateOrderRecord(order); err != nil {
-		log.Printf("TAG: 创建临时订单失败: %v", err)
-		return fmt.Errorf("创建订单失败: %v", err)
-	}
+	err := s.cardRepo.WithTx(func(tx card.CardTx) error {
+		user, err := tx.LockUser(order.UserId)
+		if err != nil {
+			log.Printf("TAG: 锁定用户失败: %v", err)
+			return fmt.Errorf("查询用户信息失败: %v", err)
+		}
+
+		// 加锁后复查，防止并发刷卡重复创建临时订单
+		if checkDuplicate {
+			existing, err := tx.FindOrderRecord(order.UserId, order.MealType, order.WeekNumber)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				log.Printf("TAG: 查询订单失败: %v", err)
+				return fmt.Errorf("查询订单失败: %v", err)
+			}
+			if err == nil && (existing.Status == model.OrderStatusCollected || existing.Status == model.OrderStatusTemp) {
+				log.Printf("TAG: 重复刷卡，订单已领取")
+				return ruleErrorf("该卡今天%s重复刷卡取餐！", order.MealType)
+			}
+		}
+
+		orderId, err := tx.CreateOrderRecord(order)
+		if err != nil {
+			log.Printf("TAG: 创建临时订单失败: %v", err)
+			return fmt.Errorf("创建订单失败: %v", err)
+		}
+		order.Id = orderId
 
-	if err := s.cardRepo.UpdateUserCount(userId, count-1); err != nil {
-		log.Printf("TAG: 扣除次数失败: %v", err)
-		return fmt.Errorf("扣次数失败: %v", err)
+		if err := tx.AdjustUserCount(swipeLedger(order)); err != nil {
+			log.Printf("TAG: 扣除次数失败: %v", err)
+			return fmt.Errorf("扣次数失败: %v", err)
+		}
+
+		response = successResponse(req, user.NickName, order.MealType, user.Count-1)
+		return s.recordTransaction(tx, req, deviceID, order, response)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return response, nil
 }
 
 // 更新订单状态并减少用户次数
-func (s *cardService) updateOrderStatusAndDecreaseCount(orderId int, status string, userId int, count int) error {
-	// 更新订单状态
-	if err := s.cardRepo.UpdateOrderStatus(orderId, status); err != nil {
-		log.Printf("TAG: 更新订单失败: %v", err)
-		return fmt.Errorf("更新失败: %v", err)
-	}
+func (s *cardService) updateOrderStatusAndDecreaseCount(req model.ConsumTransaction, deviceID string, order *model.OrderRecord) (*model.ConsumResponse, error) {
+	var response *model.ConsumResponse
+
+	err := s.cardRepo.WithTx(func(tx card.CardTx) error {
+		user, err := tx.LockUser(order.UserId)
+		if err != nil {
+			log.Printf("TAG: 锁定用户失败: %v", err)
+			return fmt.Errorf("查询用户信息失败: %v", err)
+		}
+
+		// 仅当订单仍为已报餐时才可领取
+		claimed, err := tx.ClaimOrder(order.Id)
+		if err != nil {
+			log.Printf("TAG: 更新订单失败: %v", err)
+			return fmt.Errorf("更新失败: %v", err)
+		}
+		if !claimed {
+			log.Printf("TAG: 订单 %d 已不处于已报餐状态，拒绝重复领取", order.Id)
+			return ruleErrorf("该卡今天%s重复刷卡取餐！", order.MealType)
+		}
+		order.Status = model.OrderStatusCollected
 
-	// 减少用户次数
-	if err := s.cardRepo.UpdateUserCount(userId, count-1); err != nil {
-		log.Printf("TAG: 扣除次数失败: %v", err)
-		return fmt.Errorf("扣次数失败: %v", err)
+		// 减少用户次数
+		if err := tx.AdjustUserCount(swipeLedger(order)); err != nil {
+			log.Printf("TAG: 扣除次数失败: %v", err)
+			return fmt.Errorf("扣次数失败: %v", err)
+		}
+
+		response = successResponse(req, user.NickName, order.MealType, user.Count-1)
+		return s.recordTransaction(tx, req, deviceID, order, response)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return response, nil
 }
 
 func (s *cardService) GetServerTime() time.Time {
-	return time.Now()
+	return s.clock.Now()
 }
 
-func (s *cardService) ProcessOffLineRequest(req model.OffLineRequest) error {
+// ProcessOffLineRequest 处理终端脱机期间的消费记录：持久化后按终端记录的消费时间重放核销规则，
+// 未通过规则校验的记录进入待对账列表，由管理员处理
+func (s *cardService) ProcessOffLineRequest(req model.OffLineRequest, deviceID string) error {
 	// 处理离线请求
 	log.Printf("Processing offline request: %+v", req)
+
+	if deviceID == "" {
+		deviceID = strconv.Itoa(req.DeviceNumber)
+	}
+	if req.Order == "" {
+		return errors.New("缺少终端交易号")
+	}
+	transTime, err := parseOfflineTime(req.Time)
+	if err != nil {
+		return fmt.Errorf("消费时间格式错误: %s", req.Time)
+	}
+
+	record, err := s.offlineRepo.FindByDeviceOrder(deviceID, req.Order)
+	switch {
+	case err == nil:
+		if record.Status != model.OfflineStatusPending && record.Status != model.OfflineStatusFailed {
+			log.Printf("TAG: 脱机记录重复上传, deviceID=%s, Order=%s, 状态=%s", deviceID, req.Order, record.Status)
+			return nil
+		}
+	case errors.Is(err, sql.ErrNoRows):
+		record = &model.OfflineTransaction{
+			DeviceNo: deviceID,
+			OrderNo:  req.Order,
+			CardNo:   req.CardNo,
+			Money:    req.Money,
+			PayType:  req.PayType,
+			CardMode: req.CardMode,
+			Status:   model.OfflineStatusPending,
+		}
+		id, err := s.offlineRepo.Create(record, transTime)
+		if errors.Is(err, offline.ErrDuplicateOffline) {
+			log.Printf("TAG: 脱机记录并发重复上传, deviceID=%s, Order=%s", deviceID, req.Order)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("保存脱机记录失败: %v", err)
+		}
+		record.Id = id
+	default:
+		return fmt.Errorf("查询脱机记录失败: %v", err)
+	}
+
+	// 交易号已在线核销过（终端未收到响应后转为脱机记录）时不再重复扣次
+	_, found, err := s.replayTransaction(deviceID, req.Order)
+	if err != nil {
+		s.markOffline(record.Id, model.OfflineStatusFailed, err.Error())
+		return err
+	}
+	if found {
+		s.markOffline(record.Id, model.OfflineStatusSettled, "交易已在线核销")
+		return nil
+	}
+
+	// 按终端记录的消费时间重放核销规则
+	consumReq := model.ConsumTransaction{
+		Order:    req.Order,
+		CardNo:   req.CardNo,
+		CardMode: req.CardMode,
+		PayType:  req.PayType,
+		Amount:   req.Money,
+	}
+	// 脱机补录不属于实时核销，不发布事件
+	_, err = s.consume(consumReq, deviceID, transTime, &model.SwipeEvent{})
+	switch {
+	case err == nil, errors.Is(err, card.ErrDuplicateTransaction):
+		s.markOffline(record.Id, model.OfflineStatusSettled, "")
+	case isRuleViolation(err):
+		log.Printf("TAG: 脱机记录未通过核销规则, 进入待对账, deviceID=%s, Order=%s, 原因=%v", deviceID, req.Order, err)
+		s.markOffline(record.Id, model.OfflineStatusReconcile, err.Error())
+	default:
+		s.markOffline(record.Id, model.OfflineStatusFailed, err.Error())
+		return fmt.Errorf("处理脱机记录失败: %v", err)
+	}
+
 	return nil
 }
+
+func (s *cardService) RefundTransaction(req model.RefundRequest) (*model.RefundLog, error) {
+	req.Operator = strings.TrimSpace(req.Operator)
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Operator == "" {
+		return nil, errors.New("操作人不能为空")
+	}
+	if req.Reason == "" {
+		return nil, errors.New("撤销原因不能为空")
+	}
+
+	orderId, txLog, err := s.locateRefund(req)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := s.cardRepo.FindOrderRecordById(orderId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("订单 %d 不存在", orderId)
+		}
+		log.Printf("TAG: 查询订单失败: %v", err)
+		return nil, fmt.Errorf("查询订单失败: %v", err)
+	}
+
+	refundLog := &model.RefundLog{
+		OrderRecordId: order.Id,
+		UserId:        order.UserId,
+		MealType:      order.MealType,
+		Operator:      req.Operator,
+		Reason:        req.Reason,
+	}
+	if txLog != nil {
+		refundLog.DeviceNo = txLog.DeviceNo
+		refundLog.OrderNo = txLog.OrderNo
+	}
+
+	err = s.cardRepo.WithTx(func(tx card.CardTx) error {
+		// 与核销相同的加锁顺序：先用户后订单
+		user, err := tx.LockUser(order.UserId)
+		if err != nil {
+			log.Printf("TAG: 锁定用户失败: %v", err)
+			return fmt.Errorf("查询用户信息失败: %v", err)
+		}
+		locked, err := tx.LockOrder(order.Id)
+		if err != nil {
+			log.Printf("TAG: 锁定订单失败: %v", err)
+			return fmt.Errorf("查询订单失败: %v", err)
+		}
+
+		var toStatus string
+		switch locked.Status {
+		case model.OrderStatusCollected:
+			toStatus = model.OrderStatusBooked
+		case model.OrderStatusTemp:
+			toStatus = model.OrderStatusVoided
+		default:
+			return fmt.Errorf("订单当前状态为%s，无法撤销", locked.Status)
+		}
+
+		reverted, err := tx.RevertOrder(locked.Id, locked.Status, toStatus)
+		if err != nil {
+			log.Printf("TAG: 撤销订单失败: %v", err)
+			return fmt.Errorf("撤销订单失败: %v", err)
+		}
+		if !reverted {
+			return errors.New("订单状态已变更，请刷新后重试")
+		}
+
+		refundLog.FromStatus = locked.Status
+		refundLog.ToStatus = toStatus
+		refundLog.Count = user.Count + 1
+		refundLog.Id, err = tx.CreateRefundLog(refundLog)
+		if err != nil {
+			log.Printf("TAG: 记录撤销审计失败: %v", err)
+			return fmt.Errorf("记录撤销审计失败: %v", err)
+		}
+
+		err = tx.AdjustUserCount(&model.CountLedger{
+			UserId:     user.UserId,
+			Change:     1,
+			ChangeType: model.LedgerTypeRefund,
+			RefType:    model.LedgerRefRefund,
+			RefId:      strconv.Itoa(refundLog.Id),
+			Operator:   refundLog.Operator,
+			Remark:     refundLog.Reason,
+		})
+		if err != nil {
+			log.Printf("TAG: 返还次数失败: %v", err)
+			return fmt.Errorf("返还次数失败: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	refundLog.CreateTime = s.clock.Now().Format("2006-01-02 15:04:05")
+	log.Printf("TAG: 撤销核销成功, 订单=%d, %s -> %s, 操作人=%s, 原因=%s",
+		refundLog.OrderRecordId, refundLog.FromStatus, refundLog.ToStatus, refundLog.Operator, refundLog.Reason)
+	return refundLog, nil
+}
+
+// locateRefund 根据订单号或终端交易号定位待撤销的订单及其交易流水
+func (s *cardService) locateRefund(req model.RefundRequest) (int, *model.TransactionLog, error) {
+	if req.OrderId > 0 {
+		txLog, err := s.txLogRepo.FindLatestByOrderRecordId(req.OrderId)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return 0, nil, fmt.Errorf("查询交易流水失败: %v", err)
+		}
+		return req.OrderId, txLog, nil
+	}
+
+	if req.Order == "" {
+		return 0, nil, errors.New("请提供终端交易号或订单号")
+	}
+
+	var txLog *model.TransactionLog
+	if req.DeviceNo != "" {
+		found, err := s.txLogRepo.FindByDeviceOrder(req.DeviceNo, req.Order)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return 0, nil, fmt.Errorf("未找到交易 %s 的核销记录", req.Order)
+			}
+			return 0, nil, fmt.Errorf("查询交易流水失败: %v", err)
+		}
+		txLog = found
+	} else {
+		txLogs, err := s.txLogRepo.FindByOrderNo(req.Order)
+		if err != nil {
+			return 0, nil, fmt.Errorf("查询交易流水失败: %v", err)
+		}
+		switch len(txLogs) {
+		case 0:
+			return 0, nil, fmt.Errorf("未找到交易 %s 的核销记录", req.Order)
+		case 1:
+			txLog = &txLogs[0]
+		default:
+			return 0, nil, fmt.Errorf("交易号 %s 存在于多台设备，请指定设备号", req.Order)
+		}
+	}
+
+	if txLog.OrderRecordId == 0 {
+		return 0, nil, fmt.Errorf("交易 %s 未关联订单", req.Order)
+	}
+	return txLog.OrderRecordId, txLog, nil
+}
+
+// markOffline 更新脱机记录处理状态
+func (s *cardService) markOffline(id int, status string, reason string) {
+	if err := s.offlineRepo.UpdateStatus(id, status, reason); err != nil {
+		log.Printf("TAG: 更新脱机记录状态失败, id=%d, err=%v", id, err)
+	}
+}
+
+// parseOfflineTime 解析终端上传的消费时间
+func parseOfflineTime(value string) (time.Time, error) {
+	layouts := []string{"20060102150405", "2006-01-02 15:04:05", "2006/01/02 15:04:05"}
+	var err error
+	for _, layout := range layouts {
+		var t time.Time
+		if t, err = time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
@@ -0,0 +1,559 @@
+package card
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"canteen/internal/infrastructure/clock"
+	"canteen/internal/model"
+	"canteen/internal/service/meal_period"
+)
+
+const (
+	monday   = "20250602"
+	saturday = "20250607"
+)
+
+func at(date string, clockTime string) time.Time {
+	t, err := time.ParseInLocation("20060102 15:04", date+" "+clockTime, time.Local)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// fixture 核销测试数据：
+// 用户 1 弹性部门(100，17:00 开餐)，用户 2 客户(219)，用户 3 固定部门(200，18:00 开餐)，用户 4 未配置部门；
+// A 窗口供应午餐、晚餐，B 窗口仅供应午餐；周一午餐 A/B 窗口套餐为 11/12，晚餐 A 窗口为 21
+type fixture struct {
+	repo     *fakeCardRepo
+	cache    *fakeCache
+	devices  map[string]*model.Device
+	events   *fakePublisher
+	calendar *fakeCalendarRepo
+}
+
+func newFixture() *fixture {
+	repo := newFakeCardRepo()
+	repo.addUser(model.UserVo{UserId: 1, NickName: "张三", Count: 10, DeptId: 100, CardNo: "E001"})
+	repo.addUser(model.UserVo{UserId: 2, NickName: "访客", Count: 5, DeptId: 219, CardNo: "G001"})
+	repo.addUser(model.UserVo{UserId: 3, NickName: "李四", Count: 10, DeptId: 200, CardNo: "F001"})
+	repo.addUser(model.UserVo{UserId: 4, NickName: "王五", Count: 10, DeptId: 300, CardNo: "U001"})
+	repo.configs = [4]string{"100", "200", "17:00", "18:00"}
+	repo.windowSetmeals[monday+"-午餐-A"] = 11
+	repo.windowSetmeals[monday+"-午餐-B"] = 12
+	repo.windowSetmeals[monday+"-晚餐-A"] = 21
+
+	cache := newFakeCache()
+	for _, date := range []string{monday, saturday} {
+		cache.values[date+"-lunch-A"] = "11"
+		cache.values[date+"-lunch-B"] = "12"
+		cache.values[date+"-dinner-A"] = "21"
+	}
+
+	return &fixture{
+		repo:     repo,
+		cache:    cache,
+		events:   &fakePublisher{},
+		calendar: &fakeCalendarRepo{days: map[string]*model.CalendarDay{}},
+		devices: map[string]*model.Device{
+			"DEV-A":   {SerialNo: "DEV-A", Window: "A", Canteen: "main", MealTypes: []string{"午餐", "晚餐"}, Enabled: true},
+			"DEV-B":   {SerialNo: "DEV-B", Window: "B", Canteen: "main", MealTypes: []string{"午餐"}, Enabled: true},
+			"DEV-OFF": {SerialNo: "DEV-OFF", Window: "C", Canteen: "main", MealTypes: []string{"午餐", "晚餐"}, Enabled: false},
+		},
+	}
+}
+
+func (f *fixture) service(now time.Time) CardService {
+	periods := &fakePeriodRepo{periods: []model.MealPeriod{
+		{Canteen: "main", Name: "午餐", Code: "lunch", StartTime: "11:00", EndTime: "14:00", Weekdays: []int{1, 2, 3, 4, 5, 6}, Enabled: true},
+		{Canteen: "main", Name: "晚餐", Code: "dinner", StartTime: "14:00", EndTime: "21:00", Weekdays: []int{1, 2, 3, 4, 5, 6}, Enabled: true},
+	}}
+	return NewCardService(nil, nil, f.repo, f.repo, nil,
+		&fakeDeviceService{devices: f.devices},
+		meal_period.NewMealPeriodService(periods, f.calendar),
+		f.cache, clock.Fixed(now), f.events)
+}
+
+// book 为用户预订指定日期、餐别的套餐
+func (f *fixture) book(userId int, date string, mealType string, mealId int, status string) *model.OrderRecord {
+	d, _ := time.Parse("20060102", date)
+	return f.repo.addOrder(model.OrderRecord{
+		Id:         len(f.repo.orders) + 1,
+		UserId:     userId,
+		Status:     status,
+		MealId:     mealId,
+		MealType:   mealType,
+		WeekNumber: date,
+		OrderDate:  d.Format("2006-01-02"),
+		Weekday:    [...]string{"周日", "周一", "周二", "周三", "周四", "周五", "周六"}[d.Weekday()],
+	})
+}
+
+func TestProcessConsumTransaction(t *testing.T) {
+	tests := []struct {
+		name   string
+		now    time.Time
+		device string
+		cardNo string
+		setup  func(f *fixture)
+		// wantErr 非空时期望核销失败且错误信息包含该内容
+		wantErr string
+		// wantMeal 核销成功时的餐别
+		wantMeal string
+		// wantCount 核销后用户剩余次数
+		wantCount int
+		// wantOrder 核销后用户当日该餐别订单的状态及套餐，为空表示不应存在订单
+		wantStatus string
+		wantMealId int
+	}{
+		{
+			name: "预订午餐在正确窗口核销", now: at(monday, "12:00"), device: "DEV-A", cardNo: "E001",
+			setup:    func(f *fixture) { f.book(1, monday, "午餐", 11, model.OrderStatusBooked) },
+			wantMeal: "午餐", wantCount: 9, wantStatus: model.OrderStatusCollected, wantMealId: 11,
+		},
+		{
+			name: "午餐结束前一分钟仍为午餐", now: at(monday, "13:59"), device: "DEV-A", cardNo: "E001",
+			setup:    func(f *fixture) { f.book(1, monday, "午餐", 11, model.OrderStatusBooked) },
+			wantMeal: "午餐", wantCount: 9, wantStatus: model.OrderStatusCollected, wantMealId: 11,
+		},
+		{
+			name: "午餐开始前不在任何餐次", now: at(monday, "10:59"), device: "DEV-A", cardNo: "E001",
+			setup:   func(f *fixture) { f.book(1, monday, "午餐", 11, model.OrderStatusBooked) },
+			wantErr: "不在就餐时间范围内", wantCount: 10, wantStatus: model.OrderStatusBooked, wantMealId: 11,
+		},
+		{
+			name: "14:00 起为晚餐时段且未到部门开餐时间", now: at(monday, "14:00"), device: "DEV-A", cardNo: "E001",
+			setup:   func(f *fixture) { f.book(1, monday, "午餐", 11, model.OrderStatusBooked) },
+			wantErr: "不在就餐时间范围内", wantCount: 10,
+		},
+		{
+			name: "刷错窗口", now: at(monday, "12:00"), device: "DEV-A", cardNo: "E001",
+			setup:   func(f *fixture) { f.book(1, monday, "午餐", 12, model.OrderStatusBooked) },
+			wantErr: "请前往正确的窗口", wantCount: 10, wantStatus: model.OrderStatusBooked, wantMealId: 12,
+		},
+		{
+			name: "周六不校验窗口", now: at(saturday, "12:00"), device: "DEV-A", cardNo: "E001",
+			setup:    func(f *fixture) { f.book(1, saturday, "午餐", 12, model.OrderStatusBooked) },
+			wantMeal: "午餐", wantCount: 9, wantStatus: model.OrderStatusCollected, wantMealId: 12,
+		},
+		{
+			name: "调休周六按补班的星期校验窗口", now: at(saturday, "12:00"), device: "DEV-A", cardNo: "E001",
+			setup: func(f *fixture) {
+				f.calendar.days[saturday] = &model.CalendarDay{DayType: model.CalendarWorkday, Weekday: 1}
+				f.book(1, saturday, "午餐", 12, model.OrderStatusBooked)
+			},
+			wantErr: "请前往正确的窗口", wantCount: 10, wantStatus: model.OrderStatusBooked, wantMealId: 12,
+		},
+		{
+			name: "窗口缓存缺失时回源数据库", now: at(monday, "12:00"), device: "DEV-A", cardNo: "E001",
+			setup: func(f *fixture) {
+				delete(f.cache.values, monday+"-lunch-A")
+				f.book(1, monday, "午餐", 11, model.OrderStatusBooked)
+			},
+			wantMeal: "午餐", wantCount: 9, wantStatus: model.OrderStatusCollected, wantMealId: 11,
+		},
+		{
+			name: "窗口缓存与数据库均缺失", now: at(monday, "12:00"), device: "DEV-A", cardNo: "E001",
+			setup: func(f *fixture) {
+				delete(f.cache.values, monday+"-lunch-A")
+				delete(f.repo.windowSetmeals, monday+"-午餐-A")
+				f.book(1, monday, "午餐", 11, model.OrderStatusBooked)
+			},
+			wantErr: "窗口配置读取失败", wantCount: 10, wantStatus: model.OrderStatusBooked, wantMealId: 11,
+		},
+		{
+			name: "已领取后重复刷卡", now: at(monday, "12:30"), device: "DEV-A", cardNo: "E001",
+			setup:   func(f *fixture) { f.book(1, monday, "午餐", 11, model.OrderStatusCollected) },
+			wantErr: "重复刷卡", wantCount: 10, wantStatus: model.OrderStatusCollected, wantMealId: 11,
+		},
+		{
+			name: "临时用餐后重复刷卡", now: at(monday, "12:30"), device: "DEV-A", cardNo: "E001",
+			setup:   func(f *fixture) { f.book(1, monday, "午餐", 11, model.OrderStatusTemp) },
+			wantErr: "重复刷卡", wantCount: 10, wantStatus: model.OrderStatusTemp, wantMealId: 11,
+		},
+		{
+			name: "订单被并发请求抢先领取", now: at(monday, "12:00"), device: "DEV-A", cardNo: "E001",
+			setup: func(f *fixture) {
+				f.book(1, monday, "午餐", 11, model.OrderStatusBooked)
+				f.repo.claimLost = true
+			},
+			wantErr: "重复刷卡", wantCount: 10, wantStatus: model.OrderStatusBooked, wantMealId: 11,
+		},
+		{
+			name: "未报餐创建临时订单", now: at(monday, "12:00"), device: "DEV-B", cardNo: "E001",
+			wantMeal: "午餐", wantCount: 9, wantStatus: model.OrderStatusTemp, wantMealId: 12,
+		},
+		{
+			name: "未报餐且无窗口套餐时使用默认套餐", now: at(monday, "12:00"), device: "DEV-A", cardNo: "E001",
+			setup: func(f *fixture) {
+				delete(f.cache.values, monday+"-lunch-A")
+				delete(f.repo.windowSetmeals, monday+"-午餐-A")
+			},
+			wantMeal: "午餐", wantCount: 9, wantStatus: model.OrderStatusTemp, wantMealId: 1,
+		},
+		{
+			name: "客户刷卡创建临时订单", now: at(monday, "12:00"), device: "DEV-A", cardNo: "G001",
+			wantMeal: "午餐", wantCount: 4, wantStatus: model.OrderStatusTemp, wantMealId: 11,
+		},
+		{
+			name: "客户晚餐不校验部门开餐时间", now: at(monday, "15:00"), device: "DEV-A", cardNo: "G001",
+			wantMeal: "晚餐", wantCount: 4, wantStatus: model.OrderStatusTemp, wantMealId: 21,
+		},
+		{
+			name: "未登记设备", now: at(monday, "12:00"), device: "DEV-X", cardNo: "E001",
+			wantErr: "未登记的设备", wantCount: 10,
+		},
+		{
+			name: "已停用设备", now: at(monday, "12:00"), device: "DEV-OFF", cardNo: "E001",
+			wantErr: "设备已停用", wantCount: 10,
+		},
+		{
+			name: "卡号不存在", now: at(monday, "12:00"), device: "DEV-A", cardNo: "X999",
+			wantErr: "查询用户信息失败",
+		},
+		{
+			name: "周日没有餐次", now: at("20250608", "12:00"), device: "DEV-A", cardNo: "E001",
+			wantErr: "不在就餐时间范围内", wantCount: 10,
+		},
+		{
+			name: "窗口不供应晚餐", now: at(monday, "18:00"), device: "DEV-B", cardNo: "E001",
+			wantErr: "本窗口不供应晚餐", wantCount: 10,
+		},
+		{
+			name: "弹性部门晚餐开餐即可核销", now: at(monday, "17:00"), device: "DEV-A", cardNo: "E001",
+			setup:    func(f *fixture) { f.book(1, monday, "晚餐", 21, model.OrderStatusBooked) },
+			wantMeal: "晚餐", wantCount: 9, wantStatus: model.OrderStatusCollected, wantMealId: 21,
+		},
+		{
+			name: "固定部门未到开餐时间", now: at(monday, "17:30"), device: "DEV-A", cardNo: "F001",
+			setup:   func(f *fixture) { f.book(3, monday, "晚餐", 21, model.OrderStatusBooked) },
+			wantErr: "不在就餐时间范围内", wantCount: 10, wantStatus: model.OrderStatusBooked, wantMealId: 21,
+		},
+		{
+			name: "固定部门晚餐截止前核销", now: at(monday, "20:59"), device: "DEV-A", cardNo: "F001",
+			setup:    func(f *fixture) { f.book(3, monday, "晚餐", 21, model.OrderStatusBooked) },
+			wantMeal: "晚餐", wantCount: 9, wantStatus: model.OrderStatusCollected, wantMealId: 21,
+		},
+		{
+			name: "21:00 晚餐截止", now: at(monday, "21:00"), device: "DEV-A", cardNo: "F001",
+			setup:   func(f *fixture) { f.book(3, monday, "晚餐", 21, model.OrderStatusBooked) },
+			wantErr: "不在就餐时间范围内", wantCount: 10, wantStatus: model.OrderStatusBooked, wantMealId: 21,
+		},
+		{
+			name: "晚餐开餐时间优先使用缓存配置", now: at(monday, "17:30"), device: "DEV-A", cardNo: "F001",
+			setup: func(f *fixture) {
+				f.cache.hashes["canteen:dinner_config"] = map[string]string{
+					"flexible_dept_id":           "100",
+					"fixed_dept_id":              "200",
+					"flexible_dinner_start_time": "17:00",
+					"fixed_dinner_start_time":    "17:00",
+				}
+				f.book(3, monday, "晚餐", 21, model.OrderStatusBooked)
+			},
+			wantMeal: "晚餐", wantCount: 9, wantStatus: model.OrderStatusCollected, wantMealId: 21,
+		},
+		{
+			name: "部门未配置用餐规则", now: at(monday, "18:00"), device: "DEV-A", cardNo: "U001",
+			wantErr: "部门未配置用餐规则", wantCount: 10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture()
+			if tt.setup != nil {
+				tt.setup(f)
+			}
+			user, _ := f.repo.FindUserByCardNo(tt.cardNo)
+
+			response, err := f.service(tt.now).ProcessConsumTransaction(model.ConsumTransaction{
+				Order:  "T0001",
+				CardNo: tt.cardNo,
+				Amount: "0",
+			}, tt.device)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("期望错误包含 %q，实际 err=%v, response=%+v", tt.wantErr, err, response)
+				}
+				if len(f.repo.txLogs) != 0 {
+					t.Errorf("核销失败不应记录交易流水")
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("期望核销成功，实际错误: %v", err)
+				}
+				if response.Status != 1 || response.Message != "核销成功:"+tt.wantMeal {
+					t.Errorf("响应不符: %+v", response)
+				}
+				if response.Times != tt.wantCount {
+					t.Errorf("响应剩余次数 = %d, 期望 %d", response.Times, tt.wantCount)
+				}
+				if _, err := f.repo.FindByDeviceOrder(tt.device, "T0001"); err != nil {
+					t.Errorf("核销成功应记录交易流水: %v", err)
+				}
+			}
+
+			if user.UserId == 0 {
+				return
+			}
+			if got := f.repo.users[user.UserId].Count; got != tt.wantCount {
+				t.Errorf("剩余次数 = %d, 期望 %d", got, tt.wantCount)
+			}
+
+			mealType := tt.wantMeal
+			if mealType == "" {
+				mealType = "午餐"
+				if tt.now.Hour() >= 14 {
+					mealType = "晚餐"
+				}
+			}
+			order := f.repo.findOrder(user.UserId, mealType, tt.now.Format("20060102"))
+			switch {
+			case tt.wantStatus == "" && order != nil:
+				t.Errorf("不应存在订单，实际 %+v", order)
+			case tt.wantStatus != "" && order == nil:
+				t.Errorf("期望订单状态 %s，实际无订单", tt.wantStatus)
+			case order != nil && (order.Status != tt.wantStatus || order.MealId != tt.wantMealId):
+				t.Errorf("订单 = %s/%d, 期望 %s/%d", order.Status, order.MealId, tt.wantStatus, tt.wantMealId)
+			}
+		})
+	}
+}
+
+func TestProcessConsumTransactionReplaysRetriedOrder(t *testing.T) {
+	f := newFixture()
+	f.book(1, monday, "午餐", 11, model.OrderStatusBooked)
+	svc := f.service(at(monday, "12:00"))
+	req := model.ConsumTransaction{Order: "T0001", CardNo: "E001", Amount: "0"}
+
+	first, err := svc.ProcessConsumTransaction(req, "DEV-A")
+	if err != nil {
+		t.Fatalf("首次核销失败: %v", err)
+	}
+
+	// 终端未收到响应后以同一交易号重试
+	retried, err := svc.ProcessConsumTransaction(req, "DEV-A")
+	if err != nil {
+		t.Fatalf("重试核销失败: %v", err)
+	}
+	if *retried != *first {
+		t.Errorf("重试响应 = %+v, 期望与首次一致 %+v", retried, first)
+	}
+	if got := f.repo.users[1].Count; got != 9 {
+		t.Errorf("剩余次数 = %d, 期望只扣减一次", got)
+	}
+	if len(f.repo.ledger) != 1 || f.repo.ledger[0].ChangeType != model.LedgerTypeSwipe || f.repo.ledger[0].Balance != 9 {
+		t.Errorf("次数流水不符: %+v", f.repo.ledger)
+	}
+
+	// 新交易号再次刷卡视为重复取餐
+	req.Order = "T0002"
+	if _, err := svc.ProcessConsumTransaction(req, "DEV-A"); err == nil || !strings.Contains(err.Error(), "重复刷卡") {
+		t.Errorf("期望重复刷卡错误，实际 %v", err)
+	}
+}
+
+func TestProcessConsumTransactionPublishesEvents(t *testing.T) {
+	f := newFixture()
+	f.book(1, monday, "午餐", 11, model.OrderStatusBooked)
+	f.book(3, monday, "午餐", 12, model.OrderStatusBooked)
+	svc := f.service(at(monday, "12:00"))
+
+	swipes := []struct{ cardNo, device, order string }{
+		{"E001", "DEV-A", "T0001"},
+		{"F001", "DEV-A", "T0002"}, // 报餐套餐在 B 窗口
+		{"U001", "DEV-B", "T0003"}, // 未报餐
+		{"E001", "DEV-A", "T0004"}, // 重复刷卡
+		{"E001", "DEV-A", "T0001"}, // 终端重试不重复发布
+		{"E001", "DEV-X", "T0005"},
+	}
+	for _, s := range swipes {
+		svc.ProcessConsumTransaction(model.ConsumTransaction{Order: s.order, CardNo: s.cardNo, Amount: "0"}, s.device)
+	}
+
+	want := []string{
+		"A/午餐/张三/" + model.SwipeResultCollected,
+		"A/午餐/李四/" + model.SwipeResultWrongWindow,
+		"B/午餐/王五/" + model.SwipeResultTemp,
+		"A/午餐/张三/" + model.SwipeResultRejected,
+		"///" + model.SwipeResultRejected,
+	}
+	if len(f.events.events) != len(want) {
+		t.Fatalf("发布事件 = %+v", f.events.events)
+	}
+	for i, e := range f.events.events {
+		if got := e.Window + "/" + e.MealType + "/" + e.Name + "/" + e.Result; got != want[i] {
+			t.Errorf("第%d个事件 = %s, 期望 %s", i+1, got, want[i])
+		}
+	}
+	if e := f.events.events[0]; e.Canteen != "main" || e.DeviceId != "DEV-A" || !e.Time.Equal(at(monday, "12:00")) || !strings.Contains(e.Message, "核销成功") {
+		t.Errorf("核销成功事件 = %+v", e)
+	}
+}
+
+func TestProcessConsumTransactionRejectsBlockedCard(t *testing.T) {
+	for _, status := range []string{model.CardStatusLost, model.CardStatusFrozen, model.CardStatusRetired} {
+		t.Run(status, func(t *testing.T) {
+			f := newFixture()
+			f.book(1, monday, "午餐", 11, model.OrderStatusBooked)
+			f.repo.cardStatus["E001"] = status
+
+			response, err := f.service(at(monday, "12:00")).ProcessConsumTransaction(model.ConsumTransaction{
+				Order:  "T0001",
+				CardNo: "E001",
+				Amount: "0",
+			}, "DEV-A")
+			if err != nil {
+				t.Fatalf("卡片停用应返回播报响应，实际错误: %v", err)
+			}
+			if response.Status != 0 || response.VoiceID != "卡片已"+status || !strings.Contains(response.Text, status) {
+				t.Errorf("响应不符: %+v", response)
+			}
+			if got := f.repo.users[1].Count; got != 10 {
+				t.Errorf("剩余次数 = %d, 不应扣减", got)
+			}
+			if order := f.repo.findOrder(1, "午餐", monday); order.Status != model.OrderStatusBooked {
+				t.Errorf("订单状态 = %s, 不应被领取", order.Status)
+			}
+			if len(f.repo.txLogs) != 0 {
+				t.Errorf("拒绝刷卡不应记录交易流水")
+			}
+		})
+	}
+}
+
+func TestGetServerTimeUsesClock(t *testing.T) {
+	now := at(monday, "12:00")
+	if got := newFixture().service(now).GetServerTime(); !got.Equal(now) {
+		t.Errorf("GetServerTime() = %v, 期望 %v", got, now)
+	}
+}
+
+func TestRefundTransaction(t *testing.T) {
+	tests := []struct {
+		name string
+		// booked 为 true 时用户预订午餐，否则刷卡生成临时订单
+		booked  bool
+		refunds []model.RefundRequest
+		wantErr string
+		// wantStatus 撤销后订单状态
+		wantStatus string
+		wantCount  int
+	}{
+		{
+			name: "按交易号撤销已领取订单", booked: true,
+			refunds:    []model.RefundRequest{{Order: "T0001", Operator: "管理员", Reason: "刷错窗口"}},
+			wantStatus: model.OrderStatusBooked, wantCount: 10,
+		},
+		{
+			name:       "按订单号撤销临时订单",
+			refunds:    []model.RefundRequest{{OrderId: 1001, Operator: "管理员", Reason: "误刷"}},
+			wantStatus: model.OrderStatusVoided, wantCount: 10,
+		},
+		{
+			name: "按设备号和交易号撤销", booked: true,
+			refunds:    []model.RefundRequest{{Order: "T0001", DeviceNo: "DEV-A", Operator: "管理员", Reason: "误刷"}},
+			wantStatus: model.OrderStatusBooked, wantCount: 10,
+		},
+		{
+			name: "重复撤销", booked: true,
+			refunds: []model.RefundRequest{
+				{Order: "T0001", Operator: "管理员", Reason: "误刷"},
+				{Order: "T0001", Operator: "管理员", Reason: "误刷"},
+			},
+			wantErr: "无法撤销", wantStatus: model.OrderStatusBooked, wantCount: 10,
+		},
+		{
+			name: "交易号不存在", booked: true,
+			refunds: []model.RefundRequest{{Order: "T9999", Operator: "管理员", Reason: "误刷"}},
+			wantErr: "未找到交易", wantStatus: model.OrderStatusCollected, wantCount: 9,
+		},
+		{
+			name: "缺少撤销原因", booked: true,
+			refunds: []model.RefundRequest{{Order: "T0001", Operator: "管理员"}},
+			wantErr: "撤销原因不能为空", wantStatus: model.OrderStatusCollected, wantCount: 9,
+		},
+		{
+			name: "未指定交易号和订单号", booked: true,
+			refunds: []model.RefundRequest{{Operator: "管理员", Reason: "误刷"}},
+			wantErr: "请提供终端交易号或订单号", wantStatus: model.OrderStatusCollected, wantCount: 9,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture()
+			if tt.booked {
+				f.book(1, monday, "午餐", 11, model.OrderStatusBooked)
+			}
+			svc := f.service(at(monday, "12:00"))
+			if _, err := svc.ProcessConsumTransaction(model.ConsumTransaction{Order: "T0001", CardNo: "E001"}, "DEV-A"); err != nil {
+				t.Fatalf("核销失败: %v", err)
+			}
+
+			var err error
+			for _, req := range tt.refunds {
+				if _, err = svc.RefundTransaction(req); err != nil {
+					break
+				}
+			}
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("期望错误包含 %q，实际 %v", tt.wantErr, err)
+				}
+			} else if err != nil {
+				t.Fatalf("撤销失败: %v", err)
+			}
+
+			txLog, _ := f.repo.FindByDeviceOrder("DEV-A", "T0001")
+			order := f.repo.orderById(txLog.OrderRecordId)
+			if order.Status != tt.wantStatus {
+				t.Errorf("订单状态 = %s, 期望 %s", order.Status, tt.wantStatus)
+			}
+			if got := f.repo.users[1].Count; got != tt.wantCount {
+				t.Errorf("剩余次数 = %d, 期望 %d", got, tt.wantCount)
+			}
+			if tt.wantErr == "" {
+				if len(f.repo.refunds) != 1 {
+					t.Fatalf("审计记录数 = %d, 期望 1", len(f.repo.refunds))
+				}
+				refund := f.repo.refunds[0]
+				if refund.DeviceNo != "DEV-A" || refund.OrderNo != "T0001" || refund.ToStatus != tt.wantStatus || refund.Operator != "管理员" {
+					t.Errorf("审计记录不符: %+v", refund)
+				}
+				last := f.repo.ledger[len(f.repo.ledger)-1]
+				if last.ChangeType != model.LedgerTypeRefund || last.Change != 1 || last.RefId != "1" || last.Balance != tt.wantCount {
+					t.Errorf("返还流水不符: %+v", last)
+				}
+			}
+		})
+	}
+}
+
+func TestRefundTransactionAllowsSwipeAgain(t *testing.T) {
+	f := newFixture()
+	svc := f.service(at(monday, "12:00"))
+	if _, err := svc.ProcessConsumTransaction(model.ConsumTransaction{Order: "T0001", CardNo: "E001"}, "DEV-B"); err != nil {
+		t.Fatalf("核销失败: %v", err)
+	}
+	if _, err := svc.RefundTransaction(model.RefundRequest{Order: "T0001", Operator: "管理员", Reason: "刷错窗口"}); err != nil {
+		t.Fatalf("撤销失败: %v", err)
+	}
+
+	// 作废的临时订单不影响在正确窗口重新取餐
+	response, err := svc.ProcessConsumTransaction(model.ConsumTransaction{Order: "T0002", CardNo: "E001"}, "DEV-A")
+	if err != nil {
+		t.Fatalf("撤销后重新核销失败: %v", err)
+	}
+	if response.Times != 9 {
+		t.Errorf("剩余次数 = %d, 期望 9", response.Times)
+	}
+	order := f.repo.findOrder(1, "午餐", monday)
+	if order == nil || order.Status != model.OrderStatusTemp || order.MealId != 11 {
+		t.Errorf("重新核销订单不符: %+v", order)
+	}
+}
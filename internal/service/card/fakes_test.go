@@ -0,0 +1,350 @@
+package card
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"canteen/internal/model"
+	"canteen/internal/repository/calendar"
+	"canteen/internal/repository/card"
+	"canteen/internal/repository/meal_period"
+	"canteen/internal/service/device"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// fakeCardRepo 内存实现的 CardRepository，同时充当交易流水仓储
+type fakeCardRepo struct {
+	users          map[int]*model.UserVo
+	cardStatus     map[string]string
+	orders         []*model.OrderRecord
+	windowSetmeals map[string]int
+	configs        [4]string
+	txLogs         map[string]*model.TransactionLog
+	refunds        []*model.RefundLog
+	ledger         []model.CountLedger
+	nextOrderId    int
+	// claimLost 模拟订单在加锁前已被并发请求领取
+	claimLost bool
+}
+
+func newFakeCardRepo() *fakeCardRepo {
+	return &fakeCardRepo{
+		users:          map[int]*model.UserVo{},
+		cardStatus:     map[string]string{},
+		windowSetmeals: map[string]int{},
+		txLogs:         map[string]*model.TransactionLog{},
+		nextOrderId:    1000,
+	}
+}
+
+func (r *fakeCardRepo) addUser(user model.UserVo) {
+	r.users[user.UserId] = &user
+}
+
+func (r *fakeCardRepo) addOrder(order model.OrderRecord) *model.OrderRecord {
+	r.orders = append(r.orders, &order)
+	return &order
+}
+
+func (r *fakeCardRepo) findOrder(userId int, mealType string, weekNumber string) *model.OrderRecord {
+	for _, o := range r.orders {
+		if o.UserId == userId && o.MealType == mealType && o.WeekNumber == weekNumber &&
+			o.Status != model.OrderStatusVoided && o.Status != model.OrderStatusCancelled {
+			return o
+		}
+	}
+	return nil
+}
+
+func (r *fakeCardRepo) FindUserByCardNo(cardNo string) (*model.UserVo, error) {
+	for _, u := range r.users {
+		if u.CardNo == cardNo {
+			user := *u
+			if status, ok := r.cardStatus[cardNo]; ok && status != model.CardStatusActive {
+				return &user, &card.CardBlockedError{Status: status}
+			}
+			return &user, nil
+		}
+	}
+	return &model.UserVo{}, sql.ErrNoRows
+}
+
+func (r *fakeCardRepo) FindOrderRecord(userId int, mealType string, weekNumber string, weekday string) (*model.OrderRecord, error) {
+	o := r.findOrder(userId, mealType, weekNumber)
+	if o == nil || o.Weekday != weekday {
+		return &model.OrderRecord{}, sql.ErrNoRows
+	}
+	order := *o
+	return &order, nil
+}
+
+func (r *fakeCardRepo) orderById(orderId int) *model.OrderRecord {
+	for _, o := range r.orders {
+		if o.Id == orderId {
+			return o
+		}
+	}
+	return nil
+}
+
+func (r *fakeCardRepo) FindOrderRecordById(orderId int) (*model.OrderRecord, error) {
+	o := r.orderById(orderId)
+	if o == nil {
+		return nil, sql.ErrNoRows
+	}
+	order := *o
+	return &order, nil
+}
+
+func (r *fakeCardRepo) FindWindowSetmealId(weekNumber string, mealType string, window string) (int, error) {
+	id, ok := r.windowSetmeals[weekNumber+"-"+mealType+"-"+window]
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+	return id, nil
+}
+
+func (r *fakeCardRepo) GetCanteenConfigs() (string, string, string, string, error) {
+	return r.configs[0], r.configs[1], r.configs[2], r.configs[3], nil
+}
+
+// WithTx 回调返回错误时恢复执行前的数据，模拟事务回滚
+func (r *fakeCardRepo) WithTx(fn func(tx card.CardTx) error) error {
+	users := map[int]*model.UserVo{}
+	for id, u := range r.users {
+		user := *u
+		users[id] = &user
+	}
+	orders := make([]*model.OrderRecord, len(r.orders))
+	for i, o := range r.orders {
+		order := *o
+		orders[i] = &order
+	}
+	txLogs := map[string]*model.TransactionLog{}
+	for k, v := range r.txLogs {
+		txLogs[k] = v
+	}
+	refunds := append([]*model.RefundLog{}, r.refunds...)
+	ledger := append([]model.CountLedger{}, r.ledger...)
+
+	if err := fn(&fakeCardTx{repo: r}); err != nil {
+		r.users, r.orders, r.txLogs, r.refunds, r.ledger = users, orders, txLogs, refunds, ledger
+		return err
+	}
+	return nil
+}
+
+func (r *fakeCardRepo) FindByDeviceOrder(deviceNo string, orderNo string) (*model.TransactionLog, error) {
+	txLog, ok := r.txLogs[deviceNo+"-"+orderNo]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return txLog, nil
+}
+
+func (r *fakeCardRepo) FindByOrderNo(orderNo string) ([]model.TransactionLog, error) {
+	txLogs := []model.TransactionLog{}
+	for _, txLog := range r.txLogs {
+		if txLog.OrderNo == orderNo {
+			txLogs = append(txLogs, *txLog)
+		}
+	}
+	return txLogs, nil
+}
+
+func (r *fakeCardRepo) FindLatestByOrderRecordId(orderRecordId int) (*model.TransactionLog, error) {
+	var latest *model.TransactionLog
+	for _, txLog := range r.txLogs {
+		if txLog.OrderRecordId == orderRecordId && (latest == nil || txLog.Id > latest.Id) {
+			latest = txLog
+		}
+	}
+	if latest == nil {
+		return nil, sql.ErrNoRows
+	}
+	return latest, nil
+}
+
+type fakeCardTx struct {
+	repo *fakeCardRepo
+}
+
+func (t *fakeCardTx) LockUser(userId int) (*model.UserVo, error) {
+	u, ok := t.repo.users[userId]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	user := *u
+	return &user, nil
+}
+
+func (t *fakeCardTx) FindOrderRecord(userId int, mealType string, weekNumber string) (*model.OrderRecord, error) {
+	o := t.repo.findOrder(userId, mealType, weekNumber)
+	if o == nil {
+		return nil, sql.ErrNoRows
+	}
+	order := *o
+	return &order, nil
+}
+
+func (t *fakeCardTx) ClaimOrder(orderId int) (bool, error) {
+	for _, o := range t.repo.orders {
+		if o.Id != orderId {
+			continue
+		}
+		if t.repo.claimLost {
+			o.Status = model.OrderStatusCollected
+		}
+		if o.Status != model.OrderStatusBooked {
+			return false, nil
+		}
+		o.Status = model.OrderStatusCollected
+		return true, nil
+	}
+	return false, nil
+}
+
+func (t *fakeCardTx) CreateOrderRecord(order *model.OrderRecord) (int, error) {
+	t.repo.nextOrderId++
+	created := *order
+	created.Id = t.repo.nextOrderId
+	t.repo.orders = append(t.repo.orders, &created)
+	return created.Id, nil
+}
+
+func (t *fakeCardTx) AdjustUserCount(entry *model.CountLedger) error {
+	u, ok := t.repo.users[entry.UserId]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	u.Count += entry.Change
+	saved := *entry
+	saved.Balance = u.Count
+	t.repo.ledger = append(t.repo.ledger, saved)
+	return nil
+}
+
+func (t *fakeCardTx) CreateTransactionLog(txLog *model.TransactionLog) error {
+	key := txLog.DeviceNo + "-" + txLog.OrderNo
+	if _, ok := t.repo.txLogs[key]; ok {
+		return card.ErrDuplicateTransaction
+	}
+	txLog.Id = len(t.repo.txLogs) + 1
+	t.repo.txLogs[key] = txLog
+	return nil
+}
+
+func (t *fakeCardTx) LockOrder(orderId int) (*model.OrderRecord, error) {
+	return t.repo.FindOrderRecordById(orderId)
+}
+
+func (t *fakeCardTx) RevertOrder(orderId int, fromStatus string, toStatus string) (bool, error) {
+	o := t.repo.orderById(orderId)
+	if o == nil || o.Status != fromStatus {
+		return false, nil
+	}
+	o.Status = toStatus
+	return true, nil
+}
+
+func (t *fakeCardTx) CreateRefundLog(refundLog *model.RefundLog) (int, error) {
+	saved := *refundLog
+	saved.Id = len(t.repo.refunds) + 1
+	t.repo.refunds = append(t.repo.refunds, &saved)
+	return saved.Id, nil
+}
+
+// fakeCache 内存实现的 Cache
+type fakeCache struct {
+	values map[string]string
+	hashes map[string]map[string]string
+}
+
+func newFakeCache() *fakeCache {
+	return &fakeCache{values: map[string]string{}, hashes: map[string]map[string]string{}}
+}
+
+func (c *fakeCache) Get(ctx context.Context, key string) *redis.StringCmd {
+	v, ok := c.values[key]
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(v, nil)
+}
+
+func (c *fakeCache) HGetAll(ctx context.Context, key string) *redis.StringStringMapCmd {
+	result := map[string]string{}
+	for k, v := range c.hashes[key] {
+		result[k] = v
+	}
+	return redis.NewStringStringMapResult(result, nil)
+}
+
+func (c *fakeCache) HSet(ctx context.Context, key string, values ...interface{}) *redis.IntCmd {
+	if c.hashes[key] == nil {
+		c.hashes[key] = map[string]string{}
+	}
+	for i := 0; i+1 < len(values); i += 2 {
+		c.hashes[key][fmt.Sprint(values[i])] = fmt.Sprint(values[i+1])
+	}
+	return redis.NewIntResult(int64(len(values)/2), nil)
+}
+
+func (c *fakeCache) Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd {
+	return redis.NewBoolResult(true, nil)
+}
+
+// fakeDeviceService 仅实现 ResolveDevice
+type fakeDeviceService struct {
+	device.DeviceService
+	devices map[string]*model.Device
+}
+
+func (s *fakeDeviceService) ResolveDevice(serialNo string) (*model.Device, error) {
+	d, ok := s.devices[serialNo]
+	if !ok {
+		return nil, device.ErrUnknownDevice
+	}
+	if !d.Enabled {
+		return nil, device.ErrDeviceDisabled
+	}
+	return d, nil
+}
+
+// fakeCalendarRepo 按 yyyyMMdd 登记的节假日及调休日期
+type fakeCalendarRepo struct {
+	calendar.CalendarRepository
+	days map[string]*model.CalendarDay
+}
+
+func (r *fakeCalendarRepo) FindByDate(day time.Time) (*model.CalendarDay, error) {
+	return r.days[day.Format("20060102")], nil
+}
+
+// fakePeriodRepo 仅实现 FindByCanteen
+type fakePeriodRepo struct {
+	meal_period.MealPeriodRepository
+	periods []model.MealPeriod
+}
+
+func (r *fakePeriodRepo) FindByCanteen(canteen string) ([]model.MealPeriod, error) {
+	result := []model.MealPeriod{}
+	for _, p := range r.periods {
+		if p.Canteen == canteen {
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
+
+// fakePublisher 记录发布的刷卡事件
+type fakePublisher struct {
+	events []model.SwipeEvent
+}
+
+func (p *fakePublisher) Publish(event model.SwipeEvent) {
+	p.events = append(p.events, event)
+}
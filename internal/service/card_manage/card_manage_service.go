@@ -0,0 +1,209 @@
+package card_manage
+
+import (
+	"canteen/internal/model"
+	"canteen/internal/repository/card_manage"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+type CardManageService interface {
+	ListCards(userId int, status string) ([]model.Card, error)
+	GetCard(cardNo string) (*model.Card, error)
+	GetCardLogs(cardNo string) ([]model.CardLog, error)
+	// ReportLoss 挂失，挂失后该卡刷卡将被拒绝
+	ReportLoss(req model.CardStatusRequest) (*model.Card, error)
+	Freeze(req model.CardStatusRequest) (*model.Card, error)
+	// Restore 解除挂失或冻结，已补办新卡的卡片不能恢复
+	Restore(req model.CardStatusRequest) (*model.Card, error)
+	Retire(req model.CardStatusRequest) (*model.Card, error)
+	// Replace 补卡：为原卡用户启用新卡，次数与订单按用户号归属，由新卡继承
+	Replace(req model.CardReplaceRequest) (*model.Card, error)
+}
+
+type cardManageService struct {
+	cardManageRepo card_manage.CardManageRepository
+}
+
+func NewCardManageService(cardManageRepo card_manage.CardManageRepository) CardManageService {
+	return &cardManageService{cardManageRepo: cardManageRepo}
+}
+
+func (s *cardManageService) ListCards(userId int, status string) ([]model.Card, error) {
+	return s.cardManageRepo.FindCards(userId, strings.TrimSpace(status))
+}
+
+func (s *cardManageService) GetCard(cardNo string) (*model.Card, error) {
+	return s.cardManageRepo.FindByCardNo(strings.TrimSpace(cardNo))
+}
+
+func (s *cardManageService) GetCardLogs(cardNo string) ([]model.CardLog, error) {
+	return s.cardManageRepo.FindLogs(strings.TrimSpace(cardNo))
+}
+
+func (s *cardManageService) ReportLoss(req model.CardStatusRequest) (*model.Card, error) {
+	return s.changeStatus(req, model.CardActionLoss, model.CardStatusLost, model.CardStatusActive, model.CardStatusFrozen)
+}
+
+func (s *cardManageService) Freeze(req model.CardStatusRequest) (*model.Card, error) {
+	return s.changeStatus(req, model.CardActionFreeze, model.CardStatusFrozen, model.CardStatusActive)
+}
+
+func (s *cardManageService) Restore(req model.CardStatusRequest) (*model.Card, error) {
+	return s.changeStatus(req, model.CardActionRestore, model.CardStatusActive, model.CardStatusLost, model.CardStatusFrozen)
+}
+
+func (s *cardManageService) Retire(req model.CardStatusRequest) (*model.Card, error) {
+	return s.changeStatus(req, model.CardActionRetire, model.CardStatusRetired, model.CardStatusActive, model.CardStatusLost, model.CardStatusFrozen)
+}
+
+// changeStatus 将卡片由 from 中的任一状态改为 to
+func (s *cardManageService) changeStatus(req model.CardStatusRequest, action string, to string, from ...string) (*model.Card, error) {
+	if err := normalizeRequest(&req.CardNo, &req.Operator, &req.Remark); err != nil {
+		return nil, err
+	}
+
+	var card *model.Card
+	err := s.cardManageRepo.WithTx(func(tx card_manage.CardManageTx) error {
+		var err error
+		if card, err = lockCard(tx, req.CardNo); err != nil {
+			return err
+		}
+		if !contains(from, card.Status) {
+			return fmt.Errorf("卡片当前状态为%s，无法%s", card.Status, action)
+		}
+		if to == model.CardStatusActive && card.ReplacedBy != "" {
+			return fmt.Errorf("该卡已补办新卡%s，无法%s", card.ReplacedBy, action)
+		}
+
+		if err := tx.UpdateStatus(card.Id, to, card.ReplacedBy, req.Remark); err != nil {
+			return err
+		}
+		if err := tx.CreateLog(&model.CardLog{
+			CardNo:     card.CardNo,
+			UserId:     card.UserId,
+			Action:     action,
+			FromStatus: card.Status,
+			ToStatus:   to,
+			Operator:   req.Operator,
+			Remark:     req.Remark,
+		}); err != nil {
+			return err
+		}
+		card, err = tx.LockCard(req.CardNo)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("卡片%s: 卡号=%s, user_id=%d, 状态=%s, 操作人=%s, 说明=%s", action, card.CardNo, card.UserId, to, req.Operator, req.Remark)
+	return card, nil
+}
+
+func (s *cardManageService) Replace(req model.CardReplaceRequest) (*model.Card, error) {
+	if err := normalizeRequest(&req.CardNo, &req.Operator, &req.Remark); err != nil {
+		return nil, err
+	}
+	req.NewCardNo = strings.TrimSpace(req.NewCardNo)
+	if req.NewCardNo == "" {
+		return nil, errors.New("新卡号不能为空")
+	}
+	if req.NewCardNo == req.CardNo {
+		return nil, errors.New("新卡号不能与原卡号相同")
+	}
+
+	var newCard *model.Card
+	err := s.cardManageRepo.WithTx(func(tx card_manage.CardManageTx) error {
+		old, err := lockCard(tx, req.CardNo)
+		if err != nil {
+			return err
+		}
+		if old.ReplacedBy != "" {
+			return fmt.Errorf("该卡已补办新卡%s", old.ReplacedBy)
+		}
+		if old.Status == model.CardStatusRetired {
+			return errors.New("卡片已注销，无法补卡")
+		}
+		inUse, err := tx.CardNoInUse(req.NewCardNo)
+		if err != nil {
+			return err
+		}
+		if inUse {
+			return fmt.Errorf("新卡号%s已被使用", req.NewCardNo)
+		}
+
+		// 挂失的原卡保持挂失状态，便于拾卡刷卡时提示；其余状态的原卡注销
+		oldStatus := old.Status
+		if oldStatus != model.CardStatusLost {
+			oldStatus = model.CardStatusRetired
+		}
+		if err := tx.UpdateStatus(old.Id, oldStatus, req.NewCardNo, req.Remark); err != nil {
+			return err
+		}
+		if _, err := tx.CreateCard(&model.Card{
+			CardNo: req.NewCardNo,
+			UserId: old.UserId,
+			Status: model.CardStatusActive,
+			Remark: req.Remark,
+		}); err != nil {
+			return err
+		}
+		if err := tx.SetUserCardNo(old.UserId, req.NewCardNo); err != nil {
+			return err
+		}
+
+		logs := []model.CardLog{
+			{CardNo: old.CardNo, UserId: old.UserId, Action: model.CardActionReplace, FromStatus: old.Status, ToStatus: oldStatus,
+				Operator: req.Operator, Remark: "补办新卡" + req.NewCardNo},
+			{CardNo: req.NewCardNo, UserId: old.UserId, Action: model.CardActionIssue, ToStatus: model.CardStatusActive,
+				Operator: req.Operator, Remark: "原卡" + old.CardNo},
+		}
+		for i := range logs {
+			if err := tx.CreateLog(&logs[i]); err != nil {
+				return err
+			}
+		}
+
+		newCard, err = tx.LockCard(req.NewCardNo)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("补卡: 原卡号=%s, 新卡号=%s, user_id=%d, 操作人=%s", req.CardNo, newCard.CardNo, newCard.UserId, req.Operator)
+	return newCard, nil
+}
+
+// lockCard 锁定卡片，仅登记在用户表中的卡号先补录到卡片表
+func lockCard(tx card_manage.CardManageTx, cardNo string) (*model.Card, error) {
+	if err := tx.EnsureCard(cardNo); err != nil {
+		return nil, err
+	}
+	return tx.LockCard(cardNo)
+}
+
+func normalizeRequest(cardNo, operator, remark *string) error {
+	*cardNo = strings.TrimSpace(*cardNo)
+	*operator = strings.TrimSpace(*operator)
+	*remark = strings.TrimSpace(*remark)
+	if *cardNo == "" {
+		return errors.New("卡号不能为空")
+	}
+	if *operator == "" {
+		return errors.New("操作人不能为空")
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package dashboard
+
+import (
+	"canteen/internal/infrastructure/clock"
+	"canteen/internal/model"
+	"canteen/internal/repository/dashboard"
+	"canteen/internal/service/meal_period"
+	"errors"
+	"sort"
+	"sync"
+)
+
+// recentLimit 看板保留的最近刷卡事件数
+const recentLimit = 20
+
+type DashboardService interface {
+	// Record 累计一次刷卡事件，未解析到食堂或餐别的事件不计入看板
+	Record(event model.SwipeEvent)
+	// Snapshot 返回 canteen 当前餐次的看板：刷卡及拒绝次数来自本实例收到的事件，订单数实时查询
+	Snapshot(canteen string) (*model.DashboardSnapshot, error)
+}
+
+// mealKey 一个食堂某日的某个餐次
+type mealKey struct {
+	date, canteen, mealType string
+}
+
+// board 一个餐次的事件计数
+type board struct {
+	windows map[string]*model.WindowCounter
+	recent  []model.SwipeEvent // 旧的在前
+}
+
+type dashboardService struct {
+	dashboardRepo dashboard.DashboardRepository
+	periodService meal_period.MealPeriodService
+	clock         clock.Clock
+
+	mu     sync.Mutex
+	boards map[mealKey]*board
+}
+
+func NewDashboardService(dashboardRepo dashboard.DashboardRepository, periodService meal_period.MealPeriodService, clk clock.Clock) DashboardService {
+	return &dashboardService{
+		dashboardRepo: dashboardRepo,
+		periodService: periodService,
+		clock:         clk,
+		boards:        map[mealKey]*board{},
+	}
+}
+
+func (s *dashboardService) Record(event model.SwipeEvent) {
+	if event.Canteen == "" || event.MealType == "" {
+		return
+	}
+	key := mealKey{date: event.Time.Format("20060102"), canteen: event.Canteen, mealType: event.MealType}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b := s.boards[key]
+	if b == nil {
+		// 丢弃更早日期的看板
+		for k := range s.boards {
+			if k.date < key.date {
+				delete(s.boards, k)
+			}
+		}
+		b = &board{windows: map[string]*model.WindowCounter{}}
+		s.boards[key] = b
+	}
+
+	w := b.windows[event.Window]
+	if w == nil {
+		w = &model.WindowCounter{Window: event.Window}
+		b.windows[event.Window] = w
+	}
+	w.Swipes++
+	switch event.Result {
+	case model.SwipeResultWrongWindow:
+		w.WrongWindow++
+	case model.SwipeResultRejected, model.SwipeResultError:
+		w.Rejected++
+	}
+
+	b.recent = append(b.recent, event)
+	if len(b.recent) > recentLimit {
+		b.recent = b.recent[len(b.recent)-recentLimit:]
+	}
+}
+
+func (s *dashboardService) Snapshot(canteen string) (*model.DashboardSnapshot, error) {
+	if canteen == "" {
+		return nil, errors.New("食堂(canteen)不能为空")
+	}
+	now := s.clock.Now()
+	date := now.Format("20060102")
+	snapshot := &model.DashboardSnapshot{
+		Canteen: canteen,
+		Date:    now.Format("2006-01-02"),
+		Windows: []model.WindowCounter{},
+		Recent:  []model.SwipeEvent{},
+	}
+
+	period, err := s.periodService.Resolve(canteen, now)
+	if errors.Is(err, meal_period.ErrNoMealPeriod) {
+		return snapshot, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	snapshot.MealType = period.Name
+
+	orders, err := s.dashboardRepo.CountOrdersByWindow(date, period.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	windows := map[string]*model.WindowCounter{}
+	s.mu.Lock()
+	if b := s.boards[mealKey{date: date, canteen: canteen, mealType: period.Name}]; b != nil {
+		for name, w := range b.windows {
+			counter := *w
+			windows[name] = &counter
+		}
+		for i := len(b.recent) - 1; i >= 0; i-- {
+			snapshot.Recent = append(snapshot.Recent, b.recent[i])
+		}
+	}
+	s.mu.Unlock()
+
+	for _, o := range orders {
+		w := windows[o.Window]
+		if w == nil {
+			w = &model.WindowCounter{Window: o.Window}
+			windows[o.Window] = w
+		}
+		w.Collected, w.Temp, w.Remaining = o.Collected, o.Temp, o.Remaining
+		snapshot.Remaining += o.Remaining
+	}
+	for _, w := range windows {
+		snapshot.Windows = append(snapshot.Windows, *w)
+	}
+	sort.Slice(snapshot.Windows, func(i, j int) bool {
+		return snapshot.Windows[i].Window < snapshot.Windows[j].Window
+	})
+	return snapshot, nil
+}
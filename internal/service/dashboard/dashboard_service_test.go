@@ -0,0 +1,136 @@
+package dashboard
+
+import (
+	"canteen/internal/infrastructure/clock"
+	"canteen/internal/model"
+	"canteen/internal/repository/calendar"
+	"canteen/internal/repository/dashboard"
+	repoPeriod "canteen/internal/repository/meal_period"
+	"canteen/internal/service/meal_period"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// fakeDashboardRepo 固定的各窗口订单数
+type fakeDashboardRepo struct {
+	dashboard.DashboardRepository
+	counters []model.WindowCounter
+	date     string
+	mealType string
+}
+
+func (r *fakeDashboardRepo) CountOrdersByWindow(date, mealType string) ([]model.WindowCounter, error) {
+	r.date, r.mealType = date, mealType
+	return r.counters, nil
+}
+
+// fakePeriodRepo main 食堂每天 11:00-14:00 为午餐
+type fakePeriodRepo struct {
+	repoPeriod.MealPeriodRepository
+}
+
+func (r *fakePeriodRepo) FindByCanteen(canteen string) ([]model.MealPeriod, error) {
+	if canteen != "main" {
+		return nil, nil
+	}
+	return []model.MealPeriod{{Canteen: "main", Name: "午餐", Code: "lunch", StartTime: "11:00", EndTime: "14:00", Weekdays: []int{1, 2, 3, 4, 5, 6, 7}, Enabled: true}}, nil
+}
+
+// fakeCalendarRepo 未登记任何节假日
+type fakeCalendarRepo struct {
+	calendar.CalendarRepository
+}
+
+func (r *fakeCalendarRepo) FindByDate(day time.Time) (*model.CalendarDay, error) {
+	return nil, nil
+}
+
+func newTestService(now time.Time, repo *fakeDashboardRepo) DashboardService {
+	periods := meal_period.NewMealPeriodService(&fakePeriodRepo{}, &fakeCalendarRepo{})
+	return NewDashboardService(repo, periods, clock.Fixed(now))
+}
+
+func TestSnapshotMergesEventsAndOrders(t *testing.T) {
+	now := time.Date(2025, 6, 2, 12, 0, 0, 0, time.Local)
+	repo := &fakeDashboardRepo{counters: []model.WindowCounter{
+		{Window: "A", Collected: 1, Remaining: 30},
+		{Window: "C", Remaining: 12, Temp: 2},
+	}}
+	s := newTestService(now, repo)
+
+	events := []model.SwipeEvent{
+		{Window: "A", Name: "张三", Result: model.SwipeResultCollected},
+		{Window: "A", Name: "李四", Result: model.SwipeResultWrongWindow},
+		{Window: "B", Name: "王五", Result: model.SwipeResultTemp},
+		{Window: "B", Name: "王五", Result: model.SwipeResultRejected},
+	}
+	for _, e := range events {
+		e.Time, e.Canteen, e.MealType = now.Add(-time.Minute), "main", "午餐"
+		s.Record(e)
+	}
+	// 其他食堂、其他餐次及前一天的事件不计入
+	s.Record(model.SwipeEvent{Time: now, Canteen: "east", MealType: "午餐", Window: "A"})
+	s.Record(model.SwipeEvent{Time: now, Canteen: "main", MealType: "早餐", Window: "A"})
+	s.Record(model.SwipeEvent{Time: now.AddDate(0, 0, -1), Canteen: "main", MealType: "午餐", Window: "A"})
+	s.Record(model.SwipeEvent{Time: now, Window: "A"})
+
+	snapshot, err := s.Snapshot("main")
+	if err != nil {
+		t.Fatalf("Snapshot() err = %v", err)
+	}
+	if repo.date != "20250602" || repo.mealType != "午餐" {
+		t.Errorf("查询订单 = %s/%s", repo.date, repo.mealType)
+	}
+	if snapshot.MealType != "午餐" || snapshot.Date != "2025-06-02" || snapshot.Remaining != 42 {
+		t.Errorf("Snapshot() = %+v", snapshot)
+	}
+	want := []string{"A:2/1/0/1/0/30", "B:2/0/1/0/0/0", "C:0/0/0/0/2/12"}
+	if len(snapshot.Windows) != len(want) {
+		t.Fatalf("窗口 = %+v", snapshot.Windows)
+	}
+	for i, w := range snapshot.Windows {
+		got := fmt.Sprintf("%s:%d/%d/%d/%d/%d/%d", w.Window, w.Swipes, w.WrongWindow, w.Rejected, w.Collected, w.Temp, w.Remaining)
+		if got != want[i] {
+			t.Errorf("窗口 %d = %s, 期望 %s", i+1, got, want[i])
+		}
+	}
+	if len(snapshot.Recent) != 4 || snapshot.Recent[0].Result != model.SwipeResultRejected || snapshot.Recent[3].Name != "张三" {
+		t.Errorf("最近事件 = %+v", snapshot.Recent)
+	}
+
+	if _, err := s.Snapshot(""); err == nil {
+		t.Errorf("食堂为空应报错")
+	}
+}
+
+func TestSnapshotKeepsRecentLimit(t *testing.T) {
+	now := time.Date(2025, 6, 2, 12, 0, 0, 0, time.Local)
+	s := newTestService(now, &fakeDashboardRepo{})
+	for i := 0; i < recentLimit+5; i++ {
+		s.Record(model.SwipeEvent{Time: now, Canteen: "main", MealType: "午餐", Window: "A", Name: fmt.Sprint(i)})
+	}
+
+	snapshot, err := s.Snapshot("main")
+	if err != nil {
+		t.Fatalf("Snapshot() err = %v", err)
+	}
+	if len(snapshot.Recent) != recentLimit || snapshot.Recent[0].Name != fmt.Sprint(recentLimit+4) {
+		t.Errorf("最近事件 = %d 条, 首条 %+v", len(snapshot.Recent), snapshot.Recent[0])
+	}
+	if snapshot.Windows[0].Swipes != recentLimit+5 {
+		t.Errorf("刷卡次数 = %d", snapshot.Windows[0].Swipes)
+	}
+}
+
+func TestSnapshotOutsideMealPeriod(t *testing.T) {
+	s := newTestService(time.Date(2025, 6, 2, 15, 0, 0, 0, time.Local), &fakeDashboardRepo{})
+
+	snapshot, err := s.Snapshot("main")
+	if err != nil {
+		t.Fatalf("Snapshot() err = %v", err)
+	}
+	if snapshot.MealType != "" || len(snapshot.Windows) != 0 {
+		t.Errorf("非就餐时段 Snapshot() = %+v", snapshot)
+	}
+}
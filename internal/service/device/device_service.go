@@ -0,0 +1,153 @@
+package device
+
+import (
+	"canteen/internal/model"
+	"canteen/internal/repository/device"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+var (
+	// ErrUnknownDevice 设备未登记
+	ErrUnknownDevice = errors.New("未登记的设备")
+	// ErrDeviceDisabled 设备已停用
+	ErrDeviceDisabled = errors.New("设备已停用")
+)
+
+type DeviceService interface {
+	ListDevices() ([]model.Device, error)
+	GetDevice(id int) (*model.Device, error)
+	CreateDevice(device *model.Device) error
+	UpdateDevice(device *model.Device) error
+	DeleteDevice(id int) error
+	ResolveDevice(serialNo string) (*model.Device, error)
+}
+
+type deviceService struct {
+	deviceRepo device.DeviceRepository
+}
+
+func NewDeviceService(deviceRepo device.DeviceRepository) DeviceService {
+	return &deviceService{deviceRepo: deviceRepo}
+}
+
+func (s *deviceService) ListDevices() ([]model.Device, error) {
+	return s.deviceRepo.FindAll()
+}
+
+func (s *deviceService) GetDevice(id int) (*model.Device, error) {
+	if id <= 0 {
+		return nil, errors.New("无效的设备ID")
+	}
+	return s.deviceRepo.FindById(id)
+}
+
+func (s *deviceService) CreateDevice(d *model.Device) error {
+	if err := normalizeDevice(d); err != nil {
+		return err
+	}
+
+	if _, err := s.deviceRepo.FindBySerialNo(d.SerialNo); err == nil {
+		return fmt.Errorf("设备序列号 %s 已存在", d.SerialNo)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	id, err := s.deviceRepo.Create(d)
+	if err != nil {
+		return err
+	}
+	d.Id = int(id)
+	return nil
+}
+
+func (s *deviceService) UpdateDevice(d *model.Device) error {
+	if d.Id <= 0 {
+		return errors.New("无效的设备ID")
+	}
+	if err := normalizeDevice(d); err != nil {
+		return err
+	}
+
+	if _, err := s.deviceRepo.FindById(d.Id); err != nil {
+		return err
+	}
+
+	existing, err := s.deviceRepo.FindBySerialNo(d.SerialNo)
+	if err == nil && existing.Id != d.Id {
+		return fmt.Errorf("设备序列号 %s 已存在", d.SerialNo)
+	} else if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	return s.deviceRepo.Update(d)
+}
+
+func (s *deviceService) DeleteDevice(id int) error {
+	if id <= 0 {
+		return errors.New("无效的设备ID")
+	}
+	return s.deviceRepo.Delete(id)
+}
+
+// ResolveDevice 根据终端序列号解析设备，未登记或已停用的设备将被拒绝
+func (s *deviceService) ResolveDevice(serialNo string) (*model.Device, error) {
+	if serialNo == "" {
+		log.Printf("TAG: 请求未携带设备号")
+		return nil, ErrUnknownDevice
+	}
+
+	d, err := s.deviceRepo.FindBySerialNo(serialNo)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Printf("TAG: 未登记设备请求, deviceID=%s", serialNo)
+			return nil, ErrUnknownDevice
+		}
+		log.Printf("TAG: 查询设备失败, deviceID=%s, err=%v", serialNo, err)
+		return nil, fmt.Errorf("查询设备失败: %v", err)
+	}
+
+	if !d.Enabled {
+		log.Printf("TAG: 已停用设备请求, deviceID=%s", serialNo)
+		return nil, ErrDeviceDisabled
+	}
+
+	if err := s.deviceRepo.UpdateLastSeen(d.Id); err != nil {
+		log.Printf("TAG: 更新设备通讯时间失败, deviceID=%s, err=%v", serialNo, err)
+	}
+
+	return d, nil
+}
+
+// normalizeDevice 校验并规范化设备信息
+func normalizeDevice(d *model.Device) error {
+	d.SerialNo = strings.TrimSpace(d.SerialNo)
+	d.Window = strings.ToUpper(strings.TrimSpace(d.Window))
+	d.Canteen = strings.TrimSpace(d.Canteen)
+
+	if d.SerialNo == "" {
+		return errors.New("设备序列号不能为空")
+	}
+	if d.Window == "" {
+		return errors.New("设备所属窗口不能为空")
+	}
+	if d.Canteen == "" {
+		d.Canteen = "main"
+	}
+
+	mealTypes := []string{}
+	for _, t := range d.MealTypes {
+		if t = strings.TrimSpace(t); t != "" {
+			mealTypes = append(mealTypes, t)
+		}
+	}
+	if len(mealTypes) == 0 {
+		return errors.New("设备供应餐别不能为空")
+	}
+	d.MealTypes = mealTypes
+
+	return nil
+}
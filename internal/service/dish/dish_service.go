@@ -0,0 +1,296 @@
+package dish
+
+import (
+	"canteen/internal/model"
+	"canteen/internal/repository/dish"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// reservedCategoryNames 周菜单的固定列名，不能用作分类名称
+var reservedCategoryNames = map[string]bool{
+	"日期": true, "星期": true, "餐别": true, "餐次": true, "窗口": true, "菜品": true,
+}
+
+// dietConflicts 饮食标签与过敏原的冲突，如素食菜品不能含猪肉
+var dietConflicts = map[string][]string{
+	model.DietVegetarian: {"猪肉", "鱼类", "甲壳类"},
+	model.DietHalal:      {"猪肉"},
+}
+
+type DishService interface {
+	// ListDishes 按名称/编码关键字、分类、状态分页查询菜品
+	ListDishes(query model.DishQuery) (*model.DishPage, error)
+	GetDish(id int) (*model.Dish, error)
+	CreateDish(dish *model.Dish) error
+	UpdateDish(dish *model.Dish) error
+	// DeleteDish 软删除菜品，已排入套餐的菜品不再出现在周菜单中
+	DeleteDish(id int) error
+
+	ListCategories() ([]model.DishCategory, error)
+	GetCategory(id int) (*model.DishCategory, error)
+	CreateCategory(category *model.DishCategory) error
+	UpdateCategory(category *model.DishCategory) error
+	// DeleteCategory 删除分类，分类下仍有菜品时拒绝
+	DeleteCategory(id int) error
+}
+
+type dishService struct {
+	dishRepo dish.DishRepository
+}
+
+func NewDishService(dishRepo dish.DishRepository) DishService {
+	return &dishService{dishRepo: dishRepo}
+}
+
+func (s *dishService) ListDishes(query model.DishQuery) (*model.DishPage, error) {
+	query.Keyword = strings.TrimSpace(query.Keyword)
+	if query.Page <= 0 {
+		query.Page = 1
+	}
+	if query.PageSize <= 0 || query.PageSize > 200 {
+		query.PageSize = 50
+	}
+
+	dishes, total, err := s.dishRepo.FindDishes(query, (query.Page-1)*query.PageSize, query.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	return &model.DishPage{Total: total, Dishes: dishes}, nil
+}
+
+func (s *dishService) GetDish(id int) (*model.Dish, error) {
+	if id <= 0 {
+		return nil, errors.New("无效的菜品ID")
+	}
+	return s.dishRepo.FindDishById(id)
+}
+
+func (s *dishService) CreateDish(d *model.Dish) error {
+	if err := s.validateDish(d); err != nil {
+		return err
+	}
+
+	id, err := s.dishRepo.CreateDish(d)
+	if err != nil {
+		return err
+	}
+	d.Id = int(id)
+	return nil
+}
+
+func (s *dishService) UpdateDish(d *model.Dish) error {
+	if d.Id <= 0 {
+		return errors.New("无效的菜品ID")
+	}
+	if _, err := s.dishRepo.FindDishById(d.Id); err != nil {
+		return err
+	}
+	if err := s.validateDish(d); err != nil {
+		return err
+	}
+	return s.dishRepo.UpdateDish(d)
+}
+
+func (s *dishService) DeleteDish(id int) error {
+	if id <= 0 {
+		return errors.New("无效的菜品ID")
+	}
+	return s.dishRepo.DeleteDish(id)
+}
+
+func (s *dishService) ListCategories() ([]model.DishCategory, error) {
+	return s.dishRepo.FindCategories()
+}
+
+func (s *dishService) GetCategory(id int) (*model.DishCategory, error) {
+	if id <= 0 {
+		return nil, errors.New("无效的分类ID")
+	}
+	return s.dishRepo.FindCategoryById(id)
+}
+
+func (s *dishService) CreateCategory(c *model.DishCategory) error {
+	if err := s.validateCategory(c); err != nil {
+		return err
+	}
+
+	id, err := s.dishRepo.CreateCategory(c)
+	if err != nil {
+		return err
+	}
+	c.Id = int(id)
+	return nil
+}
+
+func (s *dishService) UpdateCategory(c *model.DishCategory) error {
+	if c.Id <= 0 {
+		return errors.New("无效的分类ID")
+	}
+	if _, err := s.dishRepo.FindCategoryById(c.Id); err != nil {
+		return err
+	}
+	if err := s.validateCategory(c); err != nil {
+		return err
+	}
+	return s.dishRepo.UpdateCategory(c)
+}
+
+func (s *dishService) DeleteCategory(id int) error {
+	if id <= 0 {
+		return errors.New("无效的分类ID")
+	}
+	count, err := s.dishRepo.CountDishes(id)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("分类下还有 %d 个菜品，请先移除或删除菜品", count)
+	}
+	return s.dishRepo.DeleteCategory(id)
+}
+
+// validateDish 校验名称、编码唯一、分类存在及状态，状态为空时默认启用
+func (s *dishService) validateDish(d *model.Dish) error {
+	d.Name = strings.TrimSpace(d.Name)
+	d.Code = strings.TrimSpace(d.Code)
+	d.Image = strings.TrimSpace(d.Image)
+	if d.Name == "" {
+		return errors.New("菜品名称不能为空")
+	}
+	if utf8.RuneCountInString(d.Name) > 100 {
+		return fmt.Errorf("菜品名称过长: %s", d.Name)
+	}
+	if utf8.RuneCountInString(d.Code) > 50 {
+		return fmt.Errorf("菜品编码过长: %s", d.Code)
+	}
+	if len(d.Image) > 500 {
+		return errors.New("图片地址过长")
+	}
+	switch d.Status {
+	case "":
+		d.Status = model.DishStatusEnabled
+	case model.DishStatusEnabled, model.DishStatusDisabled:
+	default:
+		return fmt.Errorf("无效的菜品状态: %s", d.Status)
+	}
+
+	if err := validateTags(&d.DishTags); err != nil {
+		return err
+	}
+
+	if d.CategoryId != 0 {
+		category, err := s.dishRepo.FindCategoryById(d.CategoryId)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("菜品分类不存在: %d", d.CategoryId)
+		}
+		if err != nil {
+			return err
+		}
+		d.CategoryName = category.Name
+	}
+
+	if d.Code != "" {
+		inUse, err := s.dishRepo.DishCodeInUse(d.Code, d.Id)
+		if err != nil {
+			return err
+		}
+		if inUse {
+			return fmt.Errorf("菜品编码已存在: %s", d.Code)
+		}
+	}
+	return nil
+}
+
+// validateTags 校验过敏原、饮食标签在可选范围内且互不冲突，去重后按固定顺序排列
+func validateTags(t *model.DishTags) error {
+	allergens, err := normalizeTags(t.Allergens, model.Allergens, "过敏原")
+	if err != nil {
+		return err
+	}
+	diets, err := normalizeTags(t.Diets, model.DietTags, "饮食标签")
+	if err != nil {
+		return err
+	}
+	for _, diet := range diets {
+		for _, conflict := range dietConflicts[diet] {
+			for _, a := range allergens {
+				if a == conflict {
+					return fmt.Errorf("%s菜品不能含有%s", diet, a)
+				}
+			}
+		}
+	}
+	if n := t.Nutrition; n != nil &&
+		(n.EnergyKcal < 0 || n.ProteinG < 0 || n.FatG < 0 || n.CarbohydrateG < 0 || n.SodiumMg < 0) {
+		return errors.New("营养成分不能为负数")
+	}
+
+	t.Allergens, t.Diets = allergens, diets
+	return nil
+}
+
+// normalizeTags 按 vocabulary 的顺序返回去重后的标签，不在其中时报错
+func normalizeTags(tags []string, vocabulary []string, kind string) ([]string, error) {
+	selected := map[string]bool{}
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		known := false
+		for _, v := range vocabulary {
+			if v == tag {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("无效的%s: %s，可选 %s", kind, tag, strings.Join(vocabulary, "、"))
+		}
+		selected[tag] = true
+	}
+
+	result := []string{}
+	for _, v := range vocabulary {
+		if selected[v] {
+			result = append(result, v)
+		}
+	}
+	return result, nil
+}
+
+// validateCategory 校验分类名称及编码不重复，名称同时作为周菜单的列名
+func (s *dishService) validateCategory(c *model.DishCategory) error {
+	c.Name = strings.TrimSpace(c.Name)
+	c.Code = strings.TrimSpace(c.Code)
+	if c.Name == "" {
+		return errors.New("分类名称不能为空")
+	}
+	if utf8.RuneCountInString(c.Name) > 50 {
+		return fmt.Errorf("分类名称过长: %s", c.Name)
+	}
+	if reservedCategoryNames[c.Name] {
+		return fmt.Errorf("分类名称不能为周菜单固定列名: %s", c.Name)
+	}
+	if utf8.RuneCountInString(c.Code) > 50 {
+		return fmt.Errorf("分类编码过长: %s", c.Code)
+	}
+
+	categories, err := s.dishRepo.FindCategories()
+	if err != nil {
+		return err
+	}
+	for _, other := range categories {
+		if other.Id == c.Id {
+			continue
+		}
+		if other.Name == c.Name {
+			return fmt.Errorf("分类名称已存在: %s", c.Name)
+		}
+		if c.Code != "" && other.Code == c.Code {
+			return fmt.Errorf("分类编码已存在: %s", c.Code)
+		}
+	}
+	return nil
+}
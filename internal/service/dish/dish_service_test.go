@@ -0,0 +1,224 @@
+package dish
+
+import (
+	"canteen/internal/model"
+	"canteen/internal/repository/dish"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeDishRepo 内存菜品及分类，删除菜品为软删除
+type fakeDishRepo struct {
+	dish.DishRepository
+	dishes     map[int]*model.Dish
+	deleted    map[int]bool
+	categories []model.DishCategory
+	nextId     int
+}
+
+func newFakeDishRepo() *fakeDishRepo {
+	return &fakeDishRepo{
+		dishes:     map[int]*model.Dish{1: {Id: 1, Name: "米饭", CategoryId: 1, Code: "D001", Status: model.DishStatusEnabled}},
+		deleted:    map[int]bool{},
+		categories: []model.DishCategory{{Id: 1, Name: "主食", Code: "staple"}, {Id: 2, Name: "荤菜", Code: "meat"}},
+		nextId:     10,
+	}
+}
+
+func (r *fakeDishRepo) FindDishById(id int) (*model.Dish, error) {
+	d, ok := r.dishes[id]
+	if !ok || r.deleted[id] {
+		return nil, sql.ErrNoRows
+	}
+	copied := *d
+	return &copied, nil
+}
+
+func (r *fakeDishRepo) DishCodeInUse(code string, excludeId int) (bool, error) {
+	for id, d := range r.dishes {
+		if id != excludeId && !r.deleted[id] && d.Code == code {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeDishRepo) CreateDish(d *model.Dish) (int64, error) {
+	r.nextId++
+	copied := *d
+	copied.Id = r.nextId
+	r.dishes[r.nextId] = &copied
+	return int64(r.nextId), nil
+}
+
+func (r *fakeDishRepo) UpdateDish(d *model.Dish) error {
+	copied := *d
+	r.dishes[d.Id] = &copied
+	return nil
+}
+
+func (r *fakeDishRepo) DeleteDish(id int) error {
+	if _, ok := r.dishes[id]; !ok || r.deleted[id] {
+		return sql.ErrNoRows
+	}
+	r.deleted[id] = true
+	return nil
+}
+
+func (r *fakeDishRepo) FindCategories() ([]model.DishCategory, error) {
+	return r.categories, nil
+}
+
+func (r *fakeDishRepo) FindCategoryById(id int) (*model.DishCategory, error) {
+	for _, c := range r.categories {
+		if c.Id == id {
+			return &c, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *fakeDishRepo) CountDishes(categoryId int) (int, error) {
+	count := 0
+	for id, d := range r.dishes {
+		if !r.deleted[id] && d.CategoryId == categoryId {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *fakeDishRepo) CreateCategory(c *model.DishCategory) (int64, error) {
+	r.nextId++
+	copied := *c
+	copied.Id = r.nextId
+	r.categories = append(r.categories, copied)
+	return int64(r.nextId), nil
+}
+
+func (r *fakeDishRepo) UpdateCategory(c *model.DishCategory) error {
+	for i := range r.categories {
+		if r.categories[i].Id == c.Id {
+			r.categories[i] = *c
+		}
+	}
+	return nil
+}
+
+func (r *fakeDishRepo) DeleteCategory(id int) error {
+	for i, c := range r.categories {
+		if c.Id == id {
+			r.categories = append(r.categories[:i], r.categories[i+1:]...)
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func TestCreateDishValidation(t *testing.T) {
+	repo := newFakeDishRepo()
+	s := NewDishService(repo)
+
+	tests := []struct {
+		dish model.Dish
+		want string
+	}{
+		{model.Dish{Name: "  "}, "菜品名称不能为空"},
+		{model.Dish{Name: "红烧肉", CategoryId: 9}, "菜品分类不存在"},
+		{model.Dish{Name: "红烧肉", Code: " D001 "}, "菜品编码已存在"},
+		{model.Dish{Name: "红烧肉", Status: "下架"}, "无效的菜品状态"},
+		{model.Dish{Name: strings.Repeat("菜", 101)}, "菜品名称过长"},
+	}
+	for _, tt := range tests {
+		d := tt.dish
+		if err := s.CreateDish(&d); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("CreateDish(%+v) err = %v, 期望包含 %s", tt.dish, err, tt.want)
+		}
+	}
+
+	d := &model.Dish{Name: " 红烧肉 ", CategoryId: 2, Code: "D002"}
+	if err := s.CreateDish(d); err != nil {
+		t.Fatalf("CreateDish() err = %v", err)
+	}
+	if d.Id == 0 || d.Name != "红烧肉" || d.Status != model.DishStatusEnabled || d.CategoryName != "荤菜" {
+		t.Errorf("新增菜品 = %+v", d)
+	}
+
+	// 修改时自身编码不算重复；已删除菜品的编码可复用
+	d.Status = model.DishStatusDisabled
+	if err := s.UpdateDish(d); err != nil {
+		t.Errorf("UpdateDish() err = %v", err)
+	}
+	if err := s.DeleteDish(1); err != nil {
+		t.Fatalf("DeleteDish() err = %v", err)
+	}
+	if err := s.CreateDish(&model.Dish{Name: "米饭", Code: "D001"}); err != nil {
+		t.Errorf("已删除菜品的编码应可复用: %v", err)
+	}
+	if _, err := s.GetDish(1); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("已删除菜品应查询不到，实际 %v", err)
+	}
+	if err := s.UpdateDish(&model.Dish{Id: 1, Name: "米饭"}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("修改已删除菜品应返回不存在，实际 %v", err)
+	}
+}
+
+func TestDishTagValidation(t *testing.T) {
+	s := NewDishService(newFakeDishRepo())
+
+	tests := []struct {
+		tags model.DishTags
+		want string
+	}{
+		{model.DishTags{Allergens: []string{"香菜"}}, "无效的过敏原: 香菜"},
+		{model.DishTags{Diets: []string{"低脂"}}, "无效的饮食标签: 低脂"},
+		{model.DishTags{Allergens: []string{"鱼类"}, Diets: []string{"素食"}}, "素食菜品不能含有鱼类"},
+		{model.DishTags{Allergens: []string{"猪肉"}, Diets: []string{"清真"}}, "清真菜品不能含有猪肉"},
+		{model.DishTags{Nutrition: &model.Nutrition{FatG: -1}}, "营养成分不能为负数"},
+	}
+	for _, tt := range tests {
+		if err := s.CreateDish(&model.Dish{Name: "测试", DishTags: tt.tags}); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("CreateDish(%+v) err = %v, 期望包含 %s", tt.tags, err, tt.want)
+		}
+	}
+
+	d := &model.Dish{Name: "宫保鸡丁", DishTags: model.DishTags{Allergens: []string{"猪肉", " 花生", "花生"}, Diets: []string{"辣"}}}
+	if err := s.CreateDish(d); err != nil {
+		t.Fatalf("CreateDish() err = %v", err)
+	}
+	if strings.Join(d.Allergens, ",") != "花生,猪肉" || d.Diets[0] != "辣" {
+		t.Errorf("标签应去重并按固定顺序排列: %+v", d.DishTags)
+	}
+}
+
+func TestCategoryValidationAndDelete(t *testing.T) {
+	repo := newFakeDishRepo()
+	s := NewDishService(repo)
+
+	for _, c := range []model.DishCategory{
+		{Name: "窗口"},
+		{Name: " 主食 "},
+		{Name: "素菜", Code: "meat"},
+	} {
+		category := c
+		if err := s.CreateCategory(&category); err == nil {
+			t.Errorf("CreateCategory(%+v) 应校验失败", c)
+		}
+	}
+	// 修改时名称与自身相同不算重复
+	if err := s.UpdateCategory(&model.DishCategory{Id: 2, Name: "荤菜", Code: "meat", Sort: 2}); err != nil {
+		t.Errorf("UpdateCategory() err = %v", err)
+	}
+
+	if err := s.DeleteCategory(1); err == nil || !strings.Contains(err.Error(), "1 个菜品") {
+		t.Errorf("分类下有菜品时应拒绝删除，实际 %v", err)
+	}
+	if err := s.DeleteCategory(2); err != nil {
+		t.Errorf("DeleteCategory() err = %v", err)
+	}
+	if err := s.DeleteCategory(2); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("重复删除应返回不存在，实际 %v", err)
+	}
+}
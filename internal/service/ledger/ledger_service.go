@@ -0,0 +1,169 @@
+package ledger
+
+import (
+	"canteen/internal/model"
+	"canteen/internal/repository/ledger"
+	"canteen/internal/repository/user"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// UserLedger 用户次数流水及对账结果
+type UserLedger struct {
+	User      *model.UserVo       `json:"user"`
+	LedgerSum int                 `json:"ledgerSum"` // 流水合计
+	Drift     int                 `json:"drift"`     // count - 流水合计，0 表示一致
+	Total     int                 `json:"total"`     // 流水总条数
+	Entries   []model.CountLedger `json:"entries"`
+}
+
+type LedgerService interface {
+	GetUserLedger(userId int, startDate, endDate string, page, pageSize int) (*UserLedger, error)
+	// AdjustCount 手工调整用户次数
+	AdjustCount(userId int, change int, operator string, remark string) (*model.CountLedger, error)
+	// ReconcileUser 以 sys_user.count 为准写入对账调整流水
+	ReconcileUser(userId int, operator string, remark string) (*model.CountLedger, error)
+	ListDrifts() ([]model.CountDrift, error)
+	// SeedOpeningBalances 为尚无期初余额的用户补写期初余额，启动时及每日对账前调用
+	SeedOpeningBalances() (int64, error)
+	// CheckDrifts 补齐期初余额后核对所有用户，记录并返回不一致的用户
+	CheckDrifts() ([]model.CountDrift, error)
+}
+
+type ledgerService struct {
+	ledgerRepo ledger.LedgerRepository
+	userRepo   user.UserRepository
+}
+
+func NewLedgerService(ledgerRepo ledger.LedgerRepository, userRepo user.UserRepository) LedgerService {
+	return &ledgerService{ledgerRepo: ledgerRepo, userRepo: userRepo}
+}
+
+func (s *ledgerService) GetUserLedger(userId int, startDate, endDate string, page, pageSize int) (*UserLedger, error) {
+	if userId <= 0 {
+		return nil, errors.New("无效的用户ID")
+	}
+	for _, d := range []string{startDate, endDate} {
+		if d == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", d); err != nil {
+			return nil, fmt.Errorf("日期格式错误: %s，请使用 yyyy-MM-dd 格式", d)
+		}
+	}
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 200 {
+		pageSize = 50
+	}
+
+	u, err := s.userRepo.FindById(userId)
+	if err != nil {
+		return nil, err
+	}
+	sum, err := s.ledgerRepo.SumByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	entries, total, err := s.ledgerRepo.FindByUser(userId, startDate, endDate, (page-1)*pageSize, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UserLedger{
+		User:      u,
+		LedgerSum: sum,
+		Drift:     u.Count - sum,
+		Total:     total,
+		Entries:   entries,
+	}, nil
+}
+
+func (s *ledgerService) AdjustCount(userId int, change int, operator string, remark string) (*model.CountLedger, error) {
+	operator = strings.TrimSpace(operator)
+	remark = strings.TrimSpace(remark)
+	if userId <= 0 {
+		return nil, errors.New("无效的用户ID")
+	}
+	if change == 0 {
+		return nil, errors.New("调整次数不能为0")
+	}
+	if operator == "" {
+		return nil, errors.New("操作人不能为空")
+	}
+	if remark == "" {
+		return nil, errors.New("调整原因不能为空")
+	}
+
+	entry := &model.CountLedger{
+		UserId:     userId,
+		Change:     change,
+		ChangeType: model.LedgerTypeManual,
+		Operator:   operator,
+		Remark:     remark,
+	}
+	if err := s.ledgerRepo.Adjust(entry); err != nil {
+		return nil, err
+	}
+
+	log.Printf("手工调整用餐次数: user_id=%d, 变动=%d, 余额=%d, 操作人=%s, 原因=%s", userId, change, entry.Balance, operator, remark)
+	return entry, nil
+}
+
+func (s *ledgerService) ReconcileUser(userId int, operator string, remark string) (*model.CountLedger, error) {
+	operator = strings.TrimSpace(operator)
+	if userId <= 0 {
+		return nil, errors.New("无效的用户ID")
+	}
+	if operator == "" {
+		return nil, errors.New("操作人不能为空")
+	}
+
+	entry, err := s.ledgerRepo.Correct(userId, operator, strings.TrimSpace(remark))
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("次数对账调整: user_id=%d, 调整=%d, 余额=%d, 操作人=%s", userId, entry.Change, entry.Balance, operator)
+	return entry, nil
+}
+
+func (s *ledgerService) ListDrifts() ([]model.CountDrift, error) {
+	return s.ledgerRepo.FindDrifts()
+}
+
+func (s *ledgerService) SeedOpeningBalances() (int64, error) {
+	seeded, err := s.ledgerRepo.SeedOpeningBalances()
+	if err != nil {
+		return 0, fmt.Errorf("写入期初余额失败: %w", err)
+	}
+	if seeded > 0 {
+		log.Printf("Seeded opening count balance for %d users", seeded)
+	}
+	return seeded, nil
+}
+
+func (s *ledgerService) CheckDrifts() ([]model.CountDrift, error) {
+	if _, err := s.SeedOpeningBalances(); err != nil {
+		return nil, err
+	}
+
+	drifts, err := s.ledgerRepo.FindDrifts()
+	if err != nil {
+		return nil, fmt.Errorf("核对次数流水失败: %w", err)
+	}
+	if err := s.ledgerRepo.SaveDrifts(drifts); err != nil {
+		return nil, fmt.Errorf("记录对账结果失败: %w", err)
+	}
+
+	for _, d := range drifts {
+		log.Printf("WARNING: count drift user_id=%d nick_name=%s ledger_sum=%d count=%d drift=%d",
+			d.UserId, d.NickName, d.LedgerSum, d.Count, d.Drift)
+	}
+	log.Printf("Count ledger check finished, %d users drifted", len(drifts))
+	return drifts, nil
+}
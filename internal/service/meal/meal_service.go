@@ -4,68 +4,85 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"canteen/internal/infrastructure/clock"
 	"canteen/internal/model"
+	"canteen/internal/repository/device"
 	"canteen/internal/repository/meal"
+	"canteen/internal/repository/setmeal_template"
+	"canteen/internal/service/meal_period"
 	"github.com/go-redis/redis/v8"
 )
 
 type MealService interface {
-	GetDishesByIds(dishIds []int16) ([]model.Dish, error)
 	GetSetmealsByWeekNumber(weekNumber string) ([]model.WeekMeal, error)
 	UpdateDailyMealCache() error
-	GenerateWeeklySetmeals() error
+	// GenerateWeek 按套餐模板重新生成 date（yyyyMMdd，为空表示下周）所在周的周套餐：该周已有未取消、未作废的订单时拒绝，
+	// 否则删除该周已有的周套餐后重新生成，导入或组合菜单时关联的套餐（setmeal_id）将一并丢失
+	GenerateWeek(date string, createUser int) (*model.SetmealGeneration, error)
 	GenerateNextWeekSetmeals() error
 	CheckIfWeeklySetmealGenerated() bool
 }
 
 type mealService struct {
-	mealRepo meal.MealRepository
-	redis    *redis.Client
+	mealRepo      meal.MealRepository
+	deviceRepo    device.DeviceRepository
+	templateRepo  setmeal_template.SetmealTemplateRepository
+	periodService meal_period.MealPeriodService
+	redis         *redis.Client
+	clock         clock.Clock
 }
 
-func NewMealService(mealRepo meal.MealRepository, redisClient *redis.Client) MealService {
+func NewMealService(mealRepo meal.MealRepository, deviceRepo device.DeviceRepository, templateRepo setmeal_template.SetmealTemplateRepository, periodService meal_period.MealPeriodService, redisClient *redis.Client, clk clock.Clock) MealService {
 	return &mealService{
-		mealRepo: mealRepo,
-		redis:    redisClient,
+		mealRepo:      mealRepo,
+		deviceRepo:    deviceRepo,
+		templateRepo:  templateRepo,
+		periodService: periodService,
+		redis:         redisClient,
+		clock:         clk,
 	}
 }
 
-func (s *mealService) GetDishesByIds(dishIds []int16) ([]model.Dish, error) {
-	return s.mealRepo.FindDishesByIds(dishIds)
-}
-
 func (s *mealService) GetSetmealsByWeekNumber(weekNumber string) ([]model.WeekMeal, error) {
 	return s.mealRepo.FindSetmealsByWeekNumber(weekNumber)
 }
 
+// UpdateDailyMealCache 将当日各餐次、窗口对应的周套餐ID写入缓存，键格式 yyyyMMdd-<餐次编码>-<窗口>
 func (s *mealService) UpdateDailyMealCache() error {
 	ctx := context.Background()
-	dateStr := time.Now().Format("20060102")
+	now := s.clock.Now()
+	dateStr := now.Format("20060102")
+
+	periods, err := s.periodService.PeriodsOn("", now)
+	if err != nil {
+		return fmt.Errorf("query meal periods failed: %w", err)
+	}
+	codes := make(map[string]string)
+	for _, p := range periods {
+		codes[p.Name] = p.Code
+	}
 
 	setmeals, err := s.mealRepo.FindSetmealsByWeekNumber(dateStr)
 	if err != nil {
 		return fmt.Errorf("db query failed: %w", err)
 	}
 
-	mealTypeMap := map[string]string{"午餐": "lunch", "晚餐": "dinner"}
-	remarkMap := map[string]string{"套餐A": "A", "套餐B": "B", "套餐C": "C"}
-
 	found := make(map[string]bool)
 
 	for _, setmeal := range setmeals {
-		// 这里需要从数据库查询获取remark，简化处理
-		remark := "套餐A" // 假设值，实际应从数据库获取
-		mealTypeEn, ok1 := mealTypeMap[setmeal.MealType]
-		remarkEn, ok2 := remarkMap[remark]
-
-		if !ok1 || !ok2 {
-			log.Printf("Unknown mealType or remark: %s, %s", setmeal.MealType, remark)
+		code, ok := codes[setmeal.MealType]
+		window := strings.TrimPrefix(setmeal.Remark, "套餐")
+		if !ok || window == "" || window == setmeal.Remark {
+			log.Printf("Unknown mealType or remark: %s, %s", setmeal.MealType, setmeal.Remark)
 			continue
 		}
 
-		key := fmt.Sprintf("%s-%s-%s", dateStr, mealTypeEn, remarkEn)
+		key := fmt.Sprintf("%s-%s-%s", dateStr, code, window)
 		if err := s.redis.Set(ctx, key, setmeal.MealId, 24*time.Hour).Err(); err != nil {
 			log.Printf("Redis SET failed for key %s: %v", key, err)
 		} else {
@@ -74,16 +91,17 @@ func (s *mealService) UpdateDailyMealCache() error {
 		}
 	}
 
-	defaultKeys := []string{
-		fmt.Sprintf("%s-lunch-A", dateStr),
-		fmt.Sprintf("%s-lunch-B", dateStr),
-		fmt.Sprintf("%s-lunch-C", dateStr),
-		fmt.Sprintf("%s-dinner-A", dateStr),
-		fmt.Sprintf("%s-dinner-C", dateStr),
+	// 为当日供餐的每个设备窗口设置默认值
+	windows, err := s.servingWindows(periods)
+	if err != nil {
+		return fmt.Errorf("query devices failed: %w", err)
 	}
-
-	for _, key := range defaultKeys {
-		if !found[key] {
+	for _, p := range periods {
+		for _, window := range windows[p.Name] {
+			key := fmt.Sprintf("%s-%s-%s", dateStr, p.Code, window)
+			if found[key] {
+				continue
+			}
 			if err := s.redis.Set(ctx, key, 1, 24*time.Hour).Err(); err != nil {
 				log.Printf("Redis SET default failed for %s: %v", key, err)
 			} else {
@@ -95,30 +113,132 @@ func (s *mealService) UpdateDailyMealCache() error {
 	return nil
 }
 
-func (s *mealService) GenerateWeeklySetmeals() error {
-	nextMonday := getNextMonday(time.Now())
-	dates := make([]time.Time, 6)
-	for i := 0; i < 6; i++ {
-		dates[i] = nextMonday.AddDate(0, 0, i)
+func (s *mealService) GenerateWeek(date string, createUser int) (*model.SetmealGeneration, error) {
+	now := s.clock.Now()
+	monday := getNextMonday(now)
+	if date != "" {
+		day, err := time.ParseInLocation("20060102", date, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("日期格式错误: %s，请使用 yyyyMMdd 格式", date)
+		}
+		monday = day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
 	}
+	dates := weekDates(monday)
+	startWeek := dates[0].Format("20060102")
+	endWeek := dates[len(dates)-1].Format("20060102")
 
-	return s.mealRepo.GenerateWeeklySetmeals(dates)
+	if endWeek < now.Format("20060102") {
+		return nil, fmt.Errorf("不能生成已过去的周套餐: %s - %s", startWeek, endWeek)
+	}
+	// 重新生成会删除周套餐，已有订单引用时拒绝
+	active, err := s.mealRepo.CountActiveOrders(startWeek, endWeek)
+	if err != nil {
+		return nil, err
+	}
+	if active > 0 {
+		return nil, fmt.Errorf("%s - %s 已有 %d 条报餐订单，不能重新生成", startWeek, endWeek, active)
+	}
+
+	slots, err := s.buildSlots(dates)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mealRepo.GenerateWeeklySetmeals(startWeek, endWeek, slots, createUser); err != nil {
+		return nil, err
+	}
+	log.Printf("Generated %d setmeals for week starting %s, create_user=%d", len(slots), dates[0].Format("2006-01-02"), createUser)
+	return &model.SetmealGeneration{StartWeek: startWeek, EndWeek: endWeek, Setmeals: slots}, nil
 }
 
+// GenerateNextWeekSetmeals 定时生成下周的周套餐，已生成时跳过；修改模板后可通过 GenerateWeek 重新生成
 func (s *mealService) GenerateNextWeekSetmeals() error {
-	return s.GenerateWeeklySetmeals()
+	if s.CheckIfWeeklySetmealGenerated() {
+		log.Printf("Setmeals for next week already generated, skip")
+		return nil
+	}
+	_, err := s.GenerateWeek("", 0)
+	return err
 }
 
-func (s *mealService) CheckIfWeeklySetmealGenerated() bool {
-	// 获取下周一的日期
-	nextMonday := getNextMonday(time.Now())
-	dates := make([]time.Time, 6)
-	for i := 0; i < 6; i++ {
-		dates[i] = nextMonday.AddDate(0, 0, i)
+// buildSlots 按日期生成周套餐：餐次配置了模板时取当日星期的启用模板，未配置任何模板时取供餐设备的窗口；
+// 节假日不生成，调休上班日按对应星期的模板生成
+func (s *mealService) buildSlots(dates []time.Time) ([]model.WeekMeal, error) {
+	templates, err := s.templateRepo.FindAll(0)
+	if err != nil {
+		return nil, err
+	}
+	byPeriod := make(map[int][]model.SetmealTemplate)
+	for _, t := range templates {
+		byPeriod[t.MealPeriodId] = append(byPeriod[t.MealPeriodId], t)
 	}
 
-	// 查询是否已有记录
+	slots := []model.WeekMeal{}
 	for _, date := range dates {
+		weekday, open, err := s.periodService.ScheduleOn(date)
+		if err != nil {
+			return nil, err
+		}
+		if !open {
+			continue
+		}
+		periods, err := s.periodService.PeriodsOn("", date)
+		if err != nil {
+			return nil, err
+		}
+		windows, err := s.servingWindows(periods)
+		if err != nil {
+			return nil, err
+		}
+
+		day := int(weekday)
+		if day == 0 {
+			day = 7
+		}
+		weekNumber, _ := strconv.Atoi(date.Format("20060102"))
+		generated := make(map[string]bool)
+		for _, p := range periods {
+			remarks := []string{}
+			if configured, ok := byPeriod[p.Id]; ok {
+				for _, t := range configured {
+					if t.Enabled && t.Weekday == day {
+						remarks = append(remarks, t.SetmealRemark())
+					}
+				}
+			} else {
+				for _, window := range windows[p.Name] {
+					remarks = append(remarks, "套餐"+window)
+				}
+			}
+
+			for _, remark := range remarks {
+				if generated[p.Name+remark] {
+					continue
+				}
+				generated[p.Name+remark] = true
+				slots = append(slots, model.WeekMeal{
+					WeekNumber: int32(weekNumber),
+					WeekDay:    weekdayZh(date),
+					MealType:   p.Name,
+					Remark:     remark,
+				})
+			}
+		}
+	}
+	return slots, nil
+}
+
+func (s *mealService) CheckIfWeeklySetmealGenerated() bool {
+	// 查询下周每个供餐日是否已有记录
+	for _, date := range nextWeekDates(s.clock.Now()) {
+		periods, err := s.periodService.PeriodsOn("", date)
+		if err != nil {
+			log.Printf("Failed to check meal periods: %v", err)
+			return false
+		}
+		if len(periods) == 0 {
+			continue
+		}
+
 		weekNumber := date.Format("20060102")
 		setmeals, err := s.mealRepo.FindSetmealsByWeekNumber(weekNumber)
 		if err != nil {
@@ -133,8 +253,51 @@ func (s *mealService) CheckIfWeeklySetmealGenerated() bool {
 	return true
 }
 
-// getNextMonday 获取下周一的日期
+// servingWindows 返回各餐次（按名称）有启用设备供餐的窗口，窗口按字母排序
+func (s *mealService) servingWindows(periods []model.MealPeriod) (map[string][]string, error) {
+	devices, err := s.deviceRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	windows := make(map[string][]string)
+	for _, p := range periods {
+		seen := make(map[string]bool)
+		for _, w := range windows[p.Name] {
+			seen[w] = true
+		}
+		for _, d := range devices {
+			if !d.Enabled || d.Canteen != p.Canteen || !d.ServesMealType(p.Name) || seen[d.Window] {
+				continue
+			}
+			seen[d.Window] = true
+			windows[p.Name] = append(windows[p.Name], d.Window)
+		}
+		sort.Strings(windows[p.Name])
+	}
+	return windows, nil
+}
+
+// nextWeekDates 返回下周一至周日的日期
+func nextWeekDates(t time.Time) []time.Time {
+	return weekDates(getNextMonday(t))
+}
+
+// weekDates 返回自 monday 起的七天
+func weekDates(monday time.Time) []time.Time {
+	dates := make([]time.Time, 7)
+	for i := 0; i < 7; i++ {
+		dates[i] = monday.AddDate(0, 0, i)
+	}
+	return dates
+}
+
+func weekdayZh(t time.Time) string {
+	return [...]string{"周日", "周一", "周二", "周三", "周四", "周五", "周六"}[t.Weekday()]
+}
+
+// getNextMonday 获取下周一的日期，t 为周一时返回 7 天后而非当天
 func getNextMonday(t time.Time) time.Time {
-	daysUntilMonday := (time.Monday - t.Weekday() + 7) % 7
-	return t.AddDate(0, 0, int(daysUntilMonday))
+	thisMonday := t.AddDate(0, 0, -(int(t.Weekday())+6)%7)
+	return thisMonday.AddDate(0, 0, 7)
 }
\ No newline at end of file
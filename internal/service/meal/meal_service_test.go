@@ -0,0 +1,204 @@
+package meal
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"canteen/internal/infrastructure/clock"
+	"canteen/internal/model"
+	"canteen/internal/repository/device"
+	"canteen/internal/repository/meal"
+	"canteen/internal/repository/setmeal_template"
+	"canteen/internal/service/meal_period"
+)
+
+// fakeMealRepo 记录最近一次生成的周套餐
+type fakeMealRepo struct {
+	meal.MealRepository
+	activeOrders int
+	generated    []model.WeekMeal
+	createUser   int
+}
+
+func (r *fakeMealRepo) CountActiveOrders(startWeek, endWeek string) (int, error) {
+	return r.activeOrders, nil
+}
+
+func (r *fakeMealRepo) GenerateWeeklySetmeals(startWeek, endWeek string, slots []model.WeekMeal, createUser int) error {
+	r.generated = slots
+	r.createUser = createUser
+	return nil
+}
+
+type fakeDeviceRepo struct {
+	device.DeviceRepository
+	devices []model.Device
+}
+
+func (r *fakeDeviceRepo) FindAll() ([]model.Device, error) {
+	return r.devices, nil
+}
+
+type fakeTemplateRepo struct {
+	setmeal_template.SetmealTemplateRepository
+	templates []model.SetmealTemplate
+}
+
+func (r *fakeTemplateRepo) FindAll(mealPeriodId int) ([]model.SetmealTemplate, error) {
+	return r.templates, nil
+}
+
+// fakePeriodService 午餐(1)周一至周六、晚餐(2)周一至周五；closed 中的日期停餐，workdays 中的日期按周一供餐
+type fakePeriodService struct {
+	meal_period.MealPeriodService
+	closed   map[string]bool
+	workdays map[string]bool
+}
+
+func (s *fakePeriodService) ScheduleOn(day time.Time) (time.Weekday, bool, error) {
+	date := day.Format("20060102")
+	if s.closed[date] {
+		return day.Weekday(), false, nil
+	}
+	if s.workdays[date] {
+		return time.Monday, true, nil
+	}
+	return day.Weekday(), true, nil
+}
+
+func (s *fakePeriodService) PeriodsOn(canteen string, day time.Time) ([]model.MealPeriod, error) {
+	weekday, open, _ := s.ScheduleOn(day)
+	if !open {
+		return []model.MealPeriod{}, nil
+	}
+	result := []model.MealPeriod{}
+	for _, p := range []model.MealPeriod{
+		{Id: 1, Canteen: "main", Name: "午餐", Code: "lunch", Weekdays: []int{1, 2, 3, 4, 5, 6}, Enabled: true},
+		{Id: 2, Canteen: "main", Name: "晚餐", Code: "dinner", Weekdays: []int{1, 2, 3, 4, 5}, Enabled: true},
+	} {
+		if p.AppliesOn(weekday) {
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
+
+// 2025-06-05 为周四，下周为 2025-06-09 至 2025-06-15
+func newTestService() (*mealService, *fakeMealRepo, *fakeTemplateRepo, *fakePeriodService) {
+	mealRepo := &fakeMealRepo{}
+	templateRepo := &fakeTemplateRepo{}
+	periods := &fakePeriodService{closed: map[string]bool{}, workdays: map[string]bool{}}
+	devices := &fakeDeviceRepo{devices: []model.Device{
+		{Window: "B", Canteen: "main", MealTypes: []string{"午餐", "晚餐"}, Enabled: true},
+		{Window: "A", Canteen: "main", MealTypes: []string{"午餐", "晚餐"}, Enabled: true},
+		{Window: "C", Canteen: "main", MealTypes: []string{"午餐"}, Enabled: true},
+	}}
+	s := &mealService{
+		mealRepo:      mealRepo,
+		deviceRepo:    devices,
+		templateRepo:  templateRepo,
+		periodService: periods,
+		clock:         clock.Fixed(time.Date(2025, 6, 5, 10, 0, 0, 0, time.Local)),
+	}
+	return s, mealRepo, templateRepo, periods
+}
+
+// remarksByDay 统计每天、每个餐别生成的套餐备注
+func remarksByDay(slots []model.WeekMeal) map[string][]string {
+	result := map[string][]string{}
+	for _, slot := range slots {
+		key := strconv.Itoa(int(slot.WeekNumber))[4:] + slot.MealType
+		result[key] = append(result[key], slot.Remark)
+	}
+	return result
+}
+
+func TestGenerateWeekFallsBackToDeviceWindows(t *testing.T) {
+	s, repo, _, _ := newTestService()
+
+	generation, err := s.GenerateWeek("", 0)
+	if err != nil {
+		t.Fatalf("GenerateWeek() err = %v", err)
+	}
+	if generation.StartWeek != "20250609" || generation.EndWeek != "20250615" {
+		t.Errorf("生成范围 = %s - %s", generation.StartWeek, generation.EndWeek)
+	}
+	// 周一至周五午餐 3 个窗口、晚餐 2 个窗口，周六仅午餐
+	if len(repo.generated) != 5*5+3 {
+		t.Errorf("生成数 = %d, 期望 28", len(repo.generated))
+	}
+	got := remarksByDay(repo.generated)["0609午餐"]
+	if len(got) != 3 || got[0] != "套餐A" || got[2] != "套餐C" {
+		t.Errorf("周一午餐 = %v, 期望按窗口排序的 套餐A/B/C", got)
+	}
+}
+
+func TestGenerateWeekOnMondayTargetsNextWeek(t *testing.T) {
+	s, _, _, _ := newTestService()
+	// 2025-06-09 为周一，为空时应生成下周而不是覆盖本周
+	s.clock = clock.Fixed(time.Date(2025, 6, 9, 8, 0, 0, 0, time.Local))
+
+	generation, err := s.GenerateWeek("", 0)
+	if err != nil {
+		t.Fatalf("GenerateWeek() err = %v", err)
+	}
+	if generation.StartWeek != "20250616" || generation.EndWeek != "20250622" {
+		t.Errorf("生成范围 = %s - %s, 期望 20250616 - 20250622", generation.StartWeek, generation.EndWeek)
+	}
+	if dates := nextWeekDates(s.clock.Now()); dates[0].Format("20060102") != "20250616" {
+		t.Errorf("检查是否已生成的下周从 %s 开始, 期望 20250616", dates[0].Format("20060102"))
+	}
+}
+
+func TestGenerateWeekUsesTemplates(t *testing.T) {
+	s, repo, templates, periods := newTestService()
+	// 午餐配置模板：周一至周五 A、B，周六仅 A；晚餐未配置模板，按设备窗口生成
+	for day := 1; day <= 5; day++ {
+		templates.templates = append(templates.templates,
+			model.SetmealTemplate{MealPeriodId: 1, Weekday: day, Window: "A", Enabled: true},
+			model.SetmealTemplate{MealPeriodId: 1, Weekday: day, Window: "B", Enabled: true},
+		)
+	}
+	templates.templates = append(templates.templates,
+		model.SetmealTemplate{MealPeriodId: 1, Weekday: 6, Window: "A", Enabled: true},
+		model.SetmealTemplate{MealPeriodId: 1, Weekday: 6, Window: "B", Enabled: false},
+	)
+	periods.closed["20250610"] = true
+	periods.workdays["20250615"] = true
+
+	if _, err := s.GenerateWeek("20250612", 7); err != nil {
+		t.Fatalf("GenerateWeek() err = %v", err)
+	}
+	if repo.createUser != 7 {
+		t.Errorf("create_user = %d, 期望 7", repo.createUser)
+	}
+
+	byDay := remarksByDay(repo.generated)
+	tests := map[string]int{
+		"0609午餐": 2, "0609晚餐": 2,
+		"0610午餐": 0, "0610晚餐": 0, // 节假日
+		"0614午餐": 1, "0614晚餐": 0,
+		"0615午餐": 2, "0615晚餐": 2, // 周日调休按周一供餐
+	}
+	for key, want := range tests {
+		if len(byDay[key]) != want {
+			t.Errorf("%s = %v, 期望 %d 个套餐", key, byDay[key], want)
+		}
+	}
+}
+
+func TestGenerateWeekRejectsWeekWithOrders(t *testing.T) {
+	s, repo, _, _ := newTestService()
+
+	if _, err := s.GenerateWeek("20250530", 0); err == nil {
+		t.Errorf("已过去的周应拒绝生成")
+	}
+	repo.activeOrders = 3
+	if _, err := s.GenerateWeek("", 0); err == nil {
+		t.Errorf("已有订单的周应拒绝重新生成")
+	}
+	if repo.generated != nil {
+		t.Errorf("拒绝时不应生成周套餐")
+	}
+}
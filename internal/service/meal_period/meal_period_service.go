@@ -0,0 +1,261 @@
+package meal_period
+
+import (
+	"canteen/internal/model"
+	"canteen/internal/repository/calendar"
+	"canteen/internal/repository/meal_period"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrNoMealPeriod 当前时刻不在任何餐次时段内
+var ErrNoMealPeriod = errors.New("不在就餐时间范围内")
+
+type MealPeriodService interface {
+	ListPeriods(canteen string) ([]model.MealPeriod, error)
+	GetPeriod(id int) (*model.MealPeriod, error)
+	CreatePeriod(period *model.MealPeriod) error
+	UpdatePeriod(period *model.MealPeriod) error
+	DeletePeriod(id int) error
+	// Resolve 解析指定食堂在时刻 t 所处的餐次，不在任何时段内时返回 ErrNoMealPeriod；调休上班日按对应星期的餐次判断
+	Resolve(canteen string, t time.Time) (*model.MealPeriod, error)
+	// PeriodsOn 返回指定日期适用的已启用餐次，canteen 为空时返回所有食堂的餐次；节假日不供餐，调休上班日按对应星期供餐
+	PeriodsOn(canteen string, day time.Time) ([]model.MealPeriod, error)
+	// ScheduleOn 返回 day 按哪个星期供餐，节假日返回 false
+	ScheduleOn(day time.Time) (time.Weekday, bool, error)
+	// MealCodes 返回餐别名称到餐次编码的映射，多个食堂同名时以主食堂为准
+	MealCodes() (map[string]string, error)
+}
+
+type mealPeriodService struct {
+	periodRepo   meal_period.MealPeriodRepository
+	calendarRepo calendar.CalendarRepository
+}
+
+func NewMealPeriodService(periodRepo meal_period.MealPeriodRepository, calendarRepo calendar.CalendarRepository) MealPeriodService {
+	return &mealPeriodService{periodRepo: periodRepo, calendarRepo: calendarRepo}
+}
+
+func (s *mealPeriodService) ListPeriods(canteen string) ([]model.MealPeriod, error) {
+	if canteen == "" {
+		return s.periodRepo.FindAll()
+	}
+	return s.periodRepo.FindByCanteen(canteen)
+}
+
+func (s *mealPeriodService) GetPeriod(id int) (*model.MealPeriod, error) {
+	if id <= 0 {
+		return nil, errors.New("无效的餐次ID")
+	}
+	return s.periodRepo.FindById(id)
+}
+
+func (s *mealPeriodService) CreatePeriod(p *model.MealPeriod) error {
+	if err := normalizePeriod(p); err != nil {
+		return err
+	}
+	if err := s.checkConflicts(p); err != nil {
+		return err
+	}
+
+	id, err := s.periodRepo.Create(p)
+	if err != nil {
+		return err
+	}
+	p.Id = int(id)
+	return nil
+}
+
+func (s *mealPeriodService) UpdatePeriod(p *model.MealPeriod) error {
+	if p.Id <= 0 {
+		return errors.New("无效的餐次ID")
+	}
+	if err := normalizePeriod(p); err != nil {
+		return err
+	}
+	if _, err := s.periodRepo.FindById(p.Id); err != nil {
+		return err
+	}
+	if err := s.checkConflicts(p); err != nil {
+		return err
+	}
+	return s.periodRepo.Update(p)
+}
+
+func (s *mealPeriodService) DeletePeriod(id int) error {
+	if id <= 0 {
+		return errors.New("无效的餐次ID")
+	}
+	return s.periodRepo.Delete(id)
+}
+
+func (s *mealPeriodService) Resolve(canteen string, t time.Time) (*model.MealPeriod, error) {
+	periods, err := s.periodRepo.FindByCanteen(canteen)
+	if err != nil {
+		log.Printf("TAG: 查询餐次配置失败, canteen=%s, err=%v", canteen, err)
+		return nil, fmt.Errorf("查询餐次配置失败: %v", err)
+	}
+
+	// 节假日仍按原星期判断，值班人员可正常刷卡
+	weekday := t.Weekday()
+	if day, err := s.calendarRepo.FindByDate(t); err != nil {
+		log.Printf("TAG: 查询日历失败, date=%s, err=%v", t.Format("2006-01-02"), err)
+	} else if w, open := day.ScheduleOn(t); open {
+		weekday = w
+	}
+
+	for i := range periods {
+		if periods[i].ContainsOn(t, weekday) {
+			return &periods[i], nil
+		}
+	}
+	return nil, ErrNoMealPeriod
+}
+
+func (s *mealPeriodService) PeriodsOn(canteen string, day time.Time) ([]model.MealPeriod, error) {
+	weekday, open, err := s.ScheduleOn(day)
+	if err != nil {
+		return nil, err
+	}
+	if !open {
+		return []model.MealPeriod{}, nil
+	}
+
+	periods, err := s.ListPeriods(canteen)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []model.MealPeriod{}
+	for _, p := range periods {
+		if p.Enabled && p.AppliesOn(weekday) {
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
+
+func (s *mealPeriodService) ScheduleOn(day time.Time) (time.Weekday, bool, error) {
+	calendarDay, err := s.calendarRepo.FindByDate(day)
+	if err != nil {
+		return day.Weekday(), false, err
+	}
+	weekday, open := calendarDay.ScheduleOn(day)
+	return weekday, open, nil
+}
+
+func (s *mealPeriodService) MealCodes() (map[string]string, error) {
+	periods, err := s.periodRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	codes := map[string]string{}
+	for _, p := range periods {
+		if _, ok := codes[p.Name]; !ok || p.Canteen == "main" {
+			codes[p.Name] = p.Code
+		}
+	}
+	return codes, nil
+}
+
+// checkConflicts 校验同一食堂内餐别不重复、时段不重叠
+func (s *mealPeriodService) checkConflicts(p *model.MealPeriod) error {
+	periods, err := s.periodRepo.FindByCanteen(p.Canteen)
+	if err != nil {
+		return err
+	}
+
+	for _, other := range periods {
+		if other.Id == p.Id {
+			continue
+		}
+		if other.Name == p.Name || other.Code == p.Code {
+			return fmt.Errorf("食堂 %s 已存在餐次 %s(%s)", p.Canteen, other.Name, other.Code)
+		}
+		if !p.Enabled || !other.Enabled || !sharesWeekday(p, &other) {
+			continue
+		}
+		if p.StartTime < other.EndTime && other.StartTime < p.EndTime {
+			return fmt.Errorf("时段 %s-%s 与餐次 %s(%s-%s) 重叠", p.StartTime, p.EndTime, other.Name, other.StartTime, other.EndTime)
+		}
+	}
+	return nil
+}
+
+func sharesWeekday(a, b *model.MealPeriod) bool {
+	for _, d := range a.Weekdays {
+		for _, e := range b.Weekdays {
+			if d == e {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// normalizePeriod 校验并规范化餐次配置
+func normalizePeriod(p *model.MealPeriod) error {
+	p.Canteen = strings.TrimSpace(p.Canteen)
+	p.Name = strings.TrimSpace(p.Name)
+	p.Code = strings.ToLower(strings.TrimSpace(p.Code))
+
+	if p.Canteen == "" {
+		p.Canteen = "main"
+	}
+	if p.Name == "" {
+		return errors.New("餐次名称不能为空")
+	}
+	if p.Code == "" {
+		return errors.New("餐次编码不能为空")
+	}
+
+	start, err := parseClock(p.StartTime)
+	if err != nil {
+		return fmt.Errorf("开始时间格式错误: %s", p.StartTime)
+	}
+	end, err := parseClock(p.EndTime)
+	if err != nil {
+		return fmt.Errorf("结束时间格式错误: %s", p.EndTime)
+	}
+	if start >= end {
+		return errors.New("开始时间必须早于结束时间")
+	}
+	p.StartTime, p.EndTime = start, end
+
+	seen := map[int]bool{}
+	weekdays := []int{}
+	for _, d := range p.Weekdays {
+		if d < 1 || d > 7 {
+			return fmt.Errorf("无效的星期: %d", d)
+		}
+		if !seen[d] {
+			seen[d] = true
+			weekdays = append(weekdays, d)
+		}
+	}
+	if len(weekdays) == 0 {
+		return errors.New("适用星期不能为空")
+	}
+	sort.Ints(weekdays)
+	p.Weekdays = weekdays
+
+	return nil
+}
+
+// parseClock 将 H:MM / HH:MM 规范为 HH:MM，允许 24:00 表示当日结束
+func parseClock(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "24:00" {
+		return value, nil
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return "", err
+	}
+	return t.Format("15:04"), nil
+}
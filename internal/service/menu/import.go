@@ -0,0 +1,211 @@
+package menu
+
+import (
+	"canteen/internal/model"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// menuHeaders 周菜单固定列，其余列的表头为菜品分类名称
+var menuHeaders = map[string]string{
+	"日期": "day",
+	"星期": "day",
+	"餐别": "mealType",
+	"餐次": "mealType",
+	"窗口": "window",
+}
+
+// uncategorizedHeader 不归属任何分类的菜品列
+const uncategorizedHeader = "菜品"
+
+var weekdayNames = map[string]int{
+	"周一": 1, "周二": 2, "周三": 3, "周四": 4, "周五": 5, "周六": 6, "周日": 7,
+	"星期一": 1, "星期二": 2, "星期三": 3, "星期四": 4, "星期五": 5, "星期六": 6, "星期日": 7, "星期天": 7,
+}
+
+// dishSeparator 同一单元格内多个菜品的分隔符
+var dishSeparator = regexp.MustCompile(`[、，,;；/\n]+`)
+
+// maxDishName 菜品名称最大长度，与 dish.name 一致
+const maxDishName = 100
+
+// menuColumn 菜品分类列
+type menuColumn struct {
+	index      int
+	categoryId int
+	category   string
+}
+
+// parseMenuSheet 解析周菜单第一个工作表，首行为表头：日期 / 餐别 / 窗口 / 各菜品分类。
+// 日期列可填写日期或星期，填写星期时按 monday 所在周换算；单元格错误收集在 errs 中，表头错误直接返回
+func parseMenuSheet(r io.Reader, categories map[string]int, monday time.Time) (menus []model.WeekMenu, errs []string, err error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("解析Excel文件失败: %w", err)
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, nil, errors.New("Excel文件中没有工作表")
+	}
+	// 读取原始值，日期单元格为序列号
+	rows, err := f.GetRows(sheets[0], excelize.Options{RawCellValue: true})
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取工作表数据失败: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil, errors.New("周菜单为空")
+	}
+
+	fixed := map[string]int{}
+	dishColumns := []menuColumn{}
+	for i, cell := range rows[0] {
+		header := strings.TrimSpace(cell)
+		if header == "" {
+			continue
+		}
+		if key, ok := menuHeaders[header]; ok {
+			if _, exists := fixed[key]; !exists {
+				fixed[key] = i
+			}
+			continue
+		}
+		categoryId, ok := categories[header]
+		if !ok && header != uncategorizedHeader {
+			return nil, nil, fmt.Errorf("第1行%s列: 未知的菜品分类 %s", columnName(i), header)
+		}
+		dishColumns = append(dishColumns, menuColumn{index: i, categoryId: categoryId, category: header})
+	}
+	for _, required := range [][2]string{{"day", "日期"}, {"mealType", "餐别"}, {"window", "窗口"}} {
+		if _, ok := fixed[required[0]]; !ok {
+			return nil, nil, fmt.Errorf("周菜单首行须包含 %s 列", required[1])
+		}
+	}
+	if len(dishColumns) == 0 {
+		return nil, nil, errors.New("周菜单首行须包含至少一个菜品分类列")
+	}
+
+	cell := func(row []string, i int) string {
+		if i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	menus = []model.WeekMenu{}
+	errs = []string{}
+	for i, row := range rows[1:] {
+		rowNo := i + 2
+		blank := true
+		for _, v := range row {
+			if strings.TrimSpace(v) != "" {
+				blank = false
+				break
+			}
+		}
+		if blank {
+			continue
+		}
+
+		fail := func(col int, format string, args ...interface{}) {
+			errs = append(errs, fmt.Sprintf("第%d行%s列: %s", rowNo, columnName(col), fmt.Sprintf(format, args...)))
+		}
+		menu := model.WeekMenu{Row: rowNo, Dishes: []model.MenuDish{}}
+		valid := true
+
+		dayCol := fixed["day"]
+		if day, err := parseMenuDay(cell(row, dayCol), monday); err != nil {
+			fail(dayCol, "%v", err)
+			valid = false
+		} else {
+			menu.Day = day.Format("20060102")
+		}
+
+		mealCol := fixed["mealType"]
+		if menu.MealType = cell(row, mealCol); menu.MealType == "" {
+			fail(mealCol, "餐别不能为空")
+			valid = false
+		}
+
+		windowCol := fixed["window"]
+		menu.Window = strings.ToUpper(strings.TrimPrefix(cell(row, windowCol), "套餐"))
+		if menu.Window == "" {
+			fail(windowCol, "窗口不能为空")
+			valid = false
+		}
+
+		seen := map[string]bool{}
+		for _, col := range dishColumns {
+			for _, name := range dishSeparator.Split(cell(row, col.index), -1) {
+				name = strings.TrimSpace(name)
+				switch {
+				case name == "":
+					continue
+				case utf8.RuneCountInString(name) > maxDishName:
+					fail(col.index, "菜品名称过长: %s", name)
+					valid = false
+				case seen[name]:
+					fail(col.index, "菜品重复: %s", name)
+					valid = false
+				default:
+					seen[name] = true
+					menu.Dishes = append(menu.Dishes, model.MenuDish{CategoryId: col.categoryId, Category: col.category, Name: name})
+				}
+			}
+		}
+		if valid && len(menu.Dishes) == 0 {
+			errs = append(errs, fmt.Sprintf("第%d行: 没有菜品", rowNo))
+			valid = false
+		}
+
+		if valid {
+			menus = append(menus, menu)
+		}
+	}
+
+	return menus, errs, nil
+}
+
+// parseMenuDay 解析 Excel 日期序列号、常见日期文本或星期
+func parseMenuDay(value string, monday time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, errors.New("日期不能为空")
+	}
+	if d, ok := weekdayNames[value]; ok {
+		if monday.IsZero() {
+			return time.Time{}, fmt.Errorf("日期填写星期时须指定所在周: %s", value)
+		}
+		return monday.AddDate(0, 0, d-1), nil
+	}
+	if serial, err := strconv.ParseFloat(value, 64); err == nil && len(value) != 8 {
+		t, err := excelize.ExcelDateToTime(serial, false)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("日期格式错误: %s", value)
+		}
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local), nil
+	}
+	for _, layout := range []string{"2006-01-02", "2006-1-2", "2006/1/2", "2006.1.2", "20060102", "2006年1月2日"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("日期格式错误: %s", value)
+}
+
+// columnName 返回从 0 开始的列序号对应的列名，如 0 为 A
+func columnName(index int) string {
+	name, err := excelize.ColumnNumberToName(index + 1)
+	if err != nil {
+		return strconv.Itoa(index + 1)
+	}
+	return name
+}
@@ -0,0 +1,224 @@
+package menu
+
+import (
+	"canteen/internal/infrastructure/clock"
+	"canteen/internal/model"
+	"canteen/internal/repository/menu"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ErrInvalidMenu 周菜单存在校验错误，明细见导入结果的 Errors
+var ErrInvalidMenu = errors.New("周菜单校验未通过")
+
+type MenuService interface {
+	// ImportWeekMenu 导入一周的菜单：按 日期+餐别+窗口 对应周套餐，新建或更新导入生成的套餐及菜品，未知菜品自动新建；
+	// 任一单元格有误时整体不导入，重复导入同一周时内容未变化的套餐不做修改
+	ImportWeekMenu(r io.Reader, req model.MenuImportRequest) (*model.MenuImportResult, error)
+	// BuildTemplate 生成 date（yyyyMMdd，为空时为下周）所在周的空白周菜单，行为已生成的周套餐，菜品列带菜品下拉
+	BuildTemplate(date string) (*excelize.File, error)
+	// ExportWeek 按导入格式导出 date 所在周已发布的周菜单（仅含已关联套餐的周套餐），可修改后重新导入
+	ExportWeek(date string) (*excelize.File, error)
+}
+
+type menuService struct {
+	menuRepo menu.MenuRepository
+	clock    clock.Clock
+}
+
+func NewMenuService(menuRepo menu.MenuRepository, clk clock.Clock) MenuService {
+	return &menuService{menuRepo: menuRepo, clock: clk}
+}
+
+func (s *menuService) ImportWeekMenu(r io.Reader, req model.MenuImportRequest) (*model.MenuImportResult, error) {
+	var monday time.Time
+	if req.Date != "" {
+		day, err := time.ParseInLocation("20060102", req.Date, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("日期格式错误: %s，请使用 yyyyMMdd 格式", req.Date)
+		}
+		monday = mondayOf(day)
+	}
+
+	categories, err := s.menuRepo.FindCategories()
+	if err != nil {
+		return nil, err
+	}
+	categoryIds := map[string]int{}
+	for _, c := range categories {
+		categoryIds[c.Name] = c.Id
+	}
+	menus, errs, err := parseMenuSheet(r, categoryIds, monday)
+	if err != nil {
+		return nil, err
+	}
+	if len(menus) == 0 && len(errs) == 0 {
+		return nil, errors.New("周菜单中没有数据")
+	}
+
+	result := &model.MenuImportResult{DryRun: req.DryRun, NewDishes: []string{}, Errors: errs}
+	if len(menus) == 0 {
+		result.Menus = menus
+		return result, ErrInvalidMenu
+	}
+
+	// 一次导入一周，以第一行所在周为准
+	if monday.IsZero() {
+		first, _ := time.ParseInLocation("20060102", menus[0].Day, time.Local)
+		monday = mondayOf(first)
+	}
+	result.StartDate = monday.Format("20060102")
+	result.EndDate = monday.AddDate(0, 0, 6).Format("20060102")
+
+	slots, err := s.menuRepo.FindWeeklySlots(result.StartDate, result.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	slotIndex := map[string]model.WeeklySlot{}
+	for _, slot := range slots {
+		slotIndex[slot.WeekNumber+slot.MealType+slot.Remark] = slot
+	}
+
+	valid := []model.WeekMenu{}
+	rows := map[string]int{}
+	for _, m := range menus {
+		key := m.Day + m.MealType + "套餐" + m.Window
+		if m.Day < result.StartDate || m.Day > result.EndDate {
+			result.Errors = append(result.Errors, fmt.Sprintf("第%d行: %s 不在 %s - %s 这一周", m.Row, m.Day, result.StartDate, result.EndDate))
+			continue
+		}
+		if row, ok := rows[key]; ok {
+			result.Errors = append(result.Errors, fmt.Sprintf("第%d行: 与第%d行重复", m.Row, row))
+			continue
+		}
+		rows[key] = m.Row
+
+		slot, ok := slotIndex[key]
+		if !ok {
+			result.Errors = append(result.Errors, fmt.Sprintf("第%d行: %s %s 没有窗口 %s 的周套餐，请先生成周套餐", m.Row, m.Day, m.MealType, m.Window))
+			continue
+		}
+		m.WeeklySetmealId = slot.Id
+		m.SetmealCode = model.SetmealCode(m.Day, m.MealType, m.Window)
+		m.SetmealId = slot.SetmealId
+		valid = append(valid, m)
+	}
+	result.Menus = valid
+	if len(result.Errors) > 0 {
+		return result, ErrInvalidMenu
+	}
+
+	newDishes, err := s.resolveDishes(result.Menus)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range newDishes {
+		result.NewDishes = append(result.NewDishes, d.Name)
+	}
+	if err := s.resolveActions(result); err != nil {
+		return nil, err
+	}
+
+	if req.DryRun {
+		return result, nil
+	}
+	if err := s.menuRepo.SaveMenus(result.Menus, newDishes); err != nil {
+		return nil, err
+	}
+	log.Printf("导入周菜单: %s - %s, 新增套餐=%d, 更新套餐=%d, 未变化=%d, 新建菜品=%d",
+		result.StartDate, result.EndDate, result.Created, result.Updated, result.Unchanged, len(newDishes))
+	return result, nil
+}
+
+// resolveDishes 按名称匹配已有菜品，返回需新建的菜品（按首次出现的列归类）
+func (s *menuService) resolveDishes(menus []model.WeekMenu) ([]model.MenuDish, error) {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, m := range menus {
+		for _, d := range m.Dishes {
+			if !seen[d.Name] {
+				seen[d.Name] = true
+				names = append(names, d.Name)
+			}
+		}
+	}
+	ids, err := s.menuRepo.FindDishIds(names)
+	if err != nil {
+		return nil, err
+	}
+
+	newDishes := []model.MenuDish{}
+	added := map[string]bool{}
+	for i := range menus {
+		for j := range menus[i].Dishes {
+			d := &menus[i].Dishes[j]
+			d.DishId = ids[d.Name]
+			if d.DishId == 0 && !added[d.Name] {
+				added[d.Name] = true
+				newDishes = append(newDishes, *d)
+			}
+		}
+	}
+	return newDishes, nil
+}
+
+// resolveActions 查找各周套餐此前导入生成的套餐，判断新增、更新或未变化
+func (s *menuService) resolveActions(result *model.MenuImportResult) error {
+	codes := make([]string, len(result.Menus))
+	for i, m := range result.Menus {
+		codes[i] = m.SetmealCode
+	}
+	owned, err := s.menuRepo.FindSetmealIdsByCodes(codes)
+	if err != nil {
+		return err
+	}
+	ownedIds := []int{}
+	for _, id := range owned {
+		ownedIds = append(ownedIds, id)
+	}
+	dishes, err := s.menuRepo.FindSetmealDishes(ownedIds)
+	if err != nil {
+		return err
+	}
+
+	for i := range result.Menus {
+		m := &result.Menus[i]
+		linked := m.SetmealId
+		m.SetmealId = owned[m.SetmealCode]
+		switch {
+		case m.SetmealId == 0:
+			m.Action = model.MenuActionCreate
+			result.Created++
+		case linked == m.SetmealId && sameDishes(dishes[m.SetmealId], m.Dishes):
+			m.Action = model.MenuActionUnchanged
+			result.Unchanged++
+		default:
+			m.Action = model.MenuActionUpdate
+			result.Updated++
+		}
+	}
+	return nil
+}
+
+// sameDishes 判断套餐已有菜品与菜单菜品（含顺序）是否一致，菜单中有新建菜品时视为不一致
+func sameDishes(existing []model.MenuDish, dishes []model.MenuDish) bool {
+	if len(existing) != len(dishes) {
+		return false
+	}
+	for i, d := range dishes {
+		if d.DishId == 0 || d.DishId != existing[i].DishId {
+			return false
+		}
+	}
+	return true
+}
+
+// mondayOf 返回 day 所在周的周一
+func mondayOf(day time.Time) time.Time {
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
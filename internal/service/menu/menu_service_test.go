@@ -0,0 +1,368 @@
+package menu
+
+import (
+	"bytes"
+	"canteen/internal/infrastructure/clock"
+	"canteen/internal/model"
+	"canteen/internal/repository/menu"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// fakeMenuRepo 内存菜品、套餐及周套餐，SaveMenus 直接作用于内存数据
+type fakeMenuRepo struct {
+	menu.MenuRepository
+	categories   []model.DishCategory
+	dishes       map[string]int
+	dishCategory map[string]int
+	slots        []model.WeeklySlot
+	setmeals     map[string]int
+	setmealDish  map[int][]int
+	nextId       int
+	saveRequests int
+}
+
+func newFakeMenuRepo() *fakeMenuRepo {
+	repo := &fakeMenuRepo{
+		categories:   []model.DishCategory{{Id: 1, Name: "主食"}, {Id: 2, Name: "荤菜"}, {Id: 3, Name: "素菜"}},
+		dishes:       map[string]int{"米饭": 1, "红烧肉": 2},
+		dishCategory: map[string]int{"米饭": 1, "红烧肉": 2},
+		setmeals:     map[string]int{},
+		setmealDish:  map[int][]int{},
+		nextId:       100,
+	}
+	// 2025-06-09 为周一：周一午餐 A/B 窗口、晚餐 A 窗口，周二午餐 A 窗口
+	for i, s := range []struct{ date, meal, window string }{
+		{"20250609", "午餐", "A"}, {"20250609", "午餐", "B"}, {"20250609", "晚餐", "A"}, {"20250610", "午餐", "A"},
+	} {
+		repo.slots = append(repo.slots, model.WeeklySlot{Id: i + 1, WeekNumber: s.date, MealType: s.meal, Remark: "套餐" + s.window})
+	}
+	return repo
+}
+
+func (r *fakeMenuRepo) FindCategories() ([]model.DishCategory, error) {
+	return r.categories, nil
+}
+
+func (r *fakeMenuRepo) dish(name string) model.MenuDish {
+	d := model.MenuDish{DishId: r.dishes[name], Name: name, CategoryId: r.dishCategory[name]}
+	for _, c := range r.categories {
+		if c.Id == d.CategoryId {
+			d.Category = c.Name
+		}
+	}
+	return d
+}
+
+func (r *fakeMenuRepo) FindDishes() ([]model.MenuDish, error) {
+	dishes := []model.MenuDish{}
+	for name := range r.dishes {
+		dishes = append(dishes, r.dish(name))
+	}
+	sort.Slice(dishes, func(i, j int) bool { return dishes[i].DishId < dishes[j].DishId })
+	return dishes, nil
+}
+
+func (r *fakeMenuRepo) FindDishIds(names []string) (map[string]int, error) {
+	ids := map[string]int{}
+	for _, name := range names {
+		if id, ok := r.dishes[name]; ok {
+			ids[name] = id
+		}
+	}
+	return ids, nil
+}
+
+func (r *fakeMenuRepo) FindWeeklySlots(startDate, endDate string) ([]model.WeeklySlot, error) {
+	slots := []model.WeeklySlot{}
+	for _, s := range r.slots {
+		if s.WeekNumber >= startDate && s.WeekNumber <= endDate {
+			slots = append(slots, s)
+		}
+	}
+	return slots, nil
+}
+
+func (r *fakeMenuRepo) FindSetmealIdsByCodes(codes []string) (map[string]int, error) {
+	ids := map[string]int{}
+	for _, code := range codes {
+		if id, ok := r.setmeals[code]; ok {
+			ids[code] = id
+		}
+	}
+	return ids, nil
+}
+
+func (r *fakeMenuRepo) FindSetmealDishes(setmealIds []int) (map[int][]model.MenuDish, error) {
+	names := map[int]string{}
+	for name, id := range r.dishes {
+		names[id] = name
+	}
+	result := map[int][]model.MenuDish{}
+	for _, id := range setmealIds {
+		for _, dishId := range r.setmealDish[id] {
+			result[id] = append(result[id], r.dish(names[dishId]))
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeMenuRepo) SaveMenus(menus []model.WeekMenu, newDishes []model.MenuDish) error {
+	r.saveRequests++
+	for _, d := range newDishes {
+		r.nextId++
+		r.dishes[d.Name] = r.nextId
+		r.dishCategory[d.Name] = d.CategoryId
+	}
+	for _, m := range menus {
+		if m.Action == model.MenuActionUnchanged {
+			continue
+		}
+		setmealId := m.SetmealId
+		if setmealId == 0 {
+			r.nextId++
+			setmealId = r.nextId
+			r.setmeals[m.SetmealCode] = setmealId
+		}
+		ids := []int{}
+		for _, d := range m.Dishes {
+			ids = append(ids, r.dishes[d.Name])
+		}
+		r.setmealDish[setmealId] = ids
+		for i := range r.slots {
+			if r.slots[i].Id == m.WeeklySetmealId {
+				r.slots[i].SetmealId = setmealId
+			}
+		}
+	}
+	return nil
+}
+
+// 2025-06-05 为周四，下周为 2025-06-09 至 2025-06-15
+func newTestService(repo *fakeMenuRepo) MenuService {
+	return NewMenuService(repo, clock.Fixed(time.Date(2025, 6, 5, 10, 0, 0, 0, time.Local)))
+}
+
+func buildSheet(t *testing.T, rows [][]interface{}) *bytes.Reader {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := f.SetSheetRow("Sheet1", cell, &row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+var weekMenu = [][]interface{}{
+	{"日期", "餐别", "窗口", "主食", "荤菜", "素菜"},
+	{"2025-06-09", "午餐", "A", "米饭", "红烧肉", "清炒时蔬、麻婆豆腐"},
+	{"2025-06-09", "午餐", "套餐B", "米饭", "糖醋排骨", "清炒时蔬"},
+	{"2025-06-09", "晚餐", "a", "馒头", "", "麻婆豆腐"},
+}
+
+func TestImportWeekMenuDryRunThenIdempotent(t *testing.T) {
+	repo := newFakeMenuRepo()
+	s := newTestService(repo)
+
+	preview, err := s.ImportWeekMenu(buildSheet(t, weekMenu), model.MenuImportRequest{DryRun: true})
+	if err != nil {
+		t.Fatalf("预览失败: %v, %+v", err, preview)
+	}
+	if repo.saveRequests != 0 {
+		t.Errorf("预览不应写入")
+	}
+	if preview.Created != 3 || preview.StartDate != "20250609" || preview.EndDate != "20250615" {
+		t.Errorf("预览结果不符: %+v", preview)
+	}
+	if strings.Join(preview.NewDishes, ",") != "清炒时蔬,麻婆豆腐,糖醋排骨,馒头" {
+		t.Errorf("新建菜品 = %v", preview.NewDishes)
+	}
+	if preview.Menus[0].SetmealCode != "M20250609-午餐-A" {
+		t.Errorf("套餐编码 = %s", preview.Menus[0].SetmealCode)
+	}
+
+	if _, err := s.ImportWeekMenu(buildSheet(t, weekMenu), model.MenuImportRequest{}); err != nil {
+		t.Fatalf("导入失败: %v", err)
+	}
+	lunchA := repo.slots[0].SetmealId
+	if lunchA == 0 || len(repo.setmealDish[lunchA]) != 4 || repo.setmealDish[lunchA][0] != 1 {
+		t.Errorf("周一午餐A 套餐不符: setmeal=%d, dishes=%v", lunchA, repo.setmealDish[lunchA])
+	}
+	dishCount := len(repo.dishes)
+
+	// 重复导入同一周：不新建菜品及套餐，内容未变化的套餐不修改
+	again, err := s.ImportWeekMenu(buildSheet(t, weekMenu), model.MenuImportRequest{})
+	if err != nil {
+		t.Fatalf("重复导入失败: %v", err)
+	}
+	if again.Unchanged != 3 || again.Created != 0 || len(again.NewDishes) != 0 || len(repo.dishes) != dishCount {
+		t.Errorf("重复导入结果不符: %+v", again)
+	}
+	if repo.slots[0].SetmealId != lunchA {
+		t.Errorf("重复导入不应更换套餐")
+	}
+
+	// 修改一格后仅该套餐更新
+	changed := append([][]interface{}{}, weekMenu...)
+	changed[3] = []interface{}{"2025-06-09", "晚餐", "A", "馒头", "红烧肉", "麻婆豆腐"}
+	updated, err := s.ImportWeekMenu(buildSheet(t, changed), model.MenuImportRequest{})
+	if err != nil {
+		t.Fatalf("修改后导入失败: %v", err)
+	}
+	if updated.Updated != 1 || updated.Unchanged != 2 {
+		t.Errorf("修改后导入结果不符: %+v", updated)
+	}
+}
+
+func TestImportWeekMenuWeekdayColumn(t *testing.T) {
+	repo := newFakeMenuRepo()
+	s := newTestService(repo)
+	sheet := [][]interface{}{
+		{"星期", "餐别", "窗口", "菜品"},
+		{"周二", "午餐", "A", "米饭/红烧肉"},
+	}
+
+	if _, err := s.ImportWeekMenu(buildSheet(t, sheet), model.MenuImportRequest{}); !errors.Is(err, ErrInvalidMenu) {
+		t.Errorf("未指定所在周时应校验失败，实际 %v", err)
+	}
+	result, err := s.ImportWeekMenu(buildSheet(t, sheet), model.MenuImportRequest{Date: "20250612"})
+	if err != nil {
+		t.Fatalf("导入失败: %v", err)
+	}
+	if result.Menus[0].Day != "20250610" || len(result.Menus[0].Dishes) != 2 || repo.slots[3].SetmealId == 0 {
+		t.Errorf("周二午餐不符: %+v", result.Menus[0])
+	}
+}
+
+func TestImportWeekMenuReportsCellErrors(t *testing.T) {
+	repo := newFakeMenuRepo()
+	s := newTestService(repo)
+	sheet := [][]interface{}{
+		{"日期", "餐别", "窗口", "主食", "荤菜"},
+		{"2025-06-09", "午餐", "A", "米饭", "红烧肉"},
+		{"2025-06-32", "午餐", "B", "米饭", ""},
+		{"2025-06-09", "", "B", "米饭", "米饭"},
+		{"2025-06-09", "午餐", "A", "米饭", ""},
+		{"2025-06-10", "晚餐", "A", "米饭", ""},
+		{"2025-06-16", "午餐", "A", "米饭", ""},
+		{"2025-06-09", "晚餐", "A", "", ""},
+	}
+
+	result, err := s.ImportWeekMenu(buildSheet(t, sheet), model.MenuImportRequest{})
+	if !errors.Is(err, ErrInvalidMenu) {
+		t.Fatalf("err = %v, 期望校验失败", err)
+	}
+	want := []string{
+		"第3行A列: 日期格式错误",
+		"第4行B列: 餐别不能为空",
+		"第4行E列: 菜品重复: 米饭",
+		"第8行: 没有菜品",
+		"第5行: 与第2行重复",
+		"第6行: 20250610 晚餐 没有窗口 A 的周套餐",
+		"第7行: 20250616 不在",
+	}
+	if len(result.Errors) != len(want) {
+		t.Fatalf("错误数 = %d, 期望 %d: %v", len(result.Errors), len(want), result.Errors)
+	}
+	for i, prefix := range want {
+		if !strings.HasPrefix(result.Errors[i], prefix) {
+			t.Errorf("第%d个错误 = %s, 期望以 %s 开头", i+1, result.Errors[i], prefix)
+		}
+	}
+	if repo.saveRequests != 0 {
+		t.Errorf("校验失败时不应写入")
+	}
+
+	if _, err := s.ImportWeekMenu(buildSheet(t, [][]interface{}{{"日期", "餐别", "窗口", "甜点"}}), model.MenuImportRequest{}); err == nil || !strings.Contains(err.Error(), "D列") {
+		t.Errorf("未知分类应指出列，实际 %v", err)
+	}
+}
+
+func TestBuildTemplateLayout(t *testing.T) {
+	repo := newFakeMenuRepo()
+	s := newTestService(repo)
+
+	if _, err := s.BuildTemplate("20250616"); err == nil {
+		t.Errorf("未生成周套餐的周应返回错误")
+	}
+	f, err := s.BuildTemplate("")
+	if err != nil {
+		t.Fatalf("BuildTemplate() err = %v", err)
+	}
+	defer f.Close()
+
+	if sheets := f.GetSheetList(); len(sheets) != 2 || sheets[0] != menuSheet {
+		t.Fatalf("工作表 = %v", sheets)
+	}
+	if visible, _ := f.GetSheetVisible(dishLibrarySheet); visible {
+		t.Errorf("菜品库应隐藏")
+	}
+	rows, _ := f.GetRows(menuSheet)
+	if strings.Join(rows[0], ",") != "日期,星期,餐别,窗口,主食,荤菜,素菜,菜品" || len(rows) != 5 {
+		t.Fatalf("模板 = %v", rows)
+	}
+	if strings.Join(rows[4], ",") != "2025-06-10,周二,午餐,A" {
+		t.Errorf("周二午餐行 = %v", rows[4])
+	}
+
+	validations, _ := f.GetDataValidations(menuSheet)
+	lists := map[string]string{}
+	for _, dv := range validations {
+		lists[dv.Sqref] = dv.Formula1
+	}
+	// 素菜分类下没有菜品，不加下拉
+	if len(lists) != 3 || lists["E2:E5"] != "'菜品库'!$A$2:$A$2" || lists["H2:H5"] != "'菜品库'!$D$2:$D$3" {
+		t.Errorf("菜品下拉 = %v", lists)
+	}
+
+	// 空白模板直接导入时每行都缺少菜品
+	buf, _ := f.WriteToBuffer()
+	result, err := s.ImportWeekMenu(bytes.NewReader(buf.Bytes()), model.MenuImportRequest{})
+	if !errors.Is(err, ErrInvalidMenu) || len(result.Errors) != 4 {
+		t.Errorf("空白模板导入应逐行报错，实际 %v, %+v", err, result)
+	}
+}
+
+func TestExportWeekRoundTrip(t *testing.T) {
+	repo := newFakeMenuRepo()
+	s := newTestService(repo)
+
+	if _, err := s.ExportWeek("20250609"); err == nil {
+		t.Errorf("未发布的周应返回错误")
+	}
+	if _, err := s.ImportWeekMenu(buildSheet(t, weekMenu), model.MenuImportRequest{}); err != nil {
+		t.Fatalf("导入失败: %v", err)
+	}
+
+	f, err := s.ExportWeek("20250611")
+	if err != nil {
+		t.Fatalf("ExportWeek() err = %v", err)
+	}
+	defer f.Close()
+	rows, _ := f.GetRows(menuSheet)
+	if strings.Join(rows[1], ",") != "2025-06-09,周一,午餐,A,米饭,红烧肉,清炒时蔬、麻婆豆腐" {
+		t.Errorf("周一午餐A = %v", rows[1])
+	}
+
+	// 导出的周菜单原样导入，所有套餐均未变化
+	buf, _ := f.WriteToBuffer()
+	again, err := s.ImportWeekMenu(bytes.NewReader(buf.Bytes()), model.MenuImportRequest{DryRun: true})
+	if err != nil {
+		t.Fatalf("重新导入失败: %v, %+v", err, again)
+	}
+	if again.Unchanged != 3 || again.Created != 0 || again.Updated != 0 || len(again.NewDishes) != 0 {
+		t.Errorf("重新导入结果不符: %+v", again)
+	}
+}
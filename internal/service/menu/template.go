@@ -0,0 +1,217 @@
+package menu
+
+import (
+	"canteen/internal/model"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	// menuSheet 周菜单工作表，须为第一个工作表以便导入
+	menuSheet = "周菜单"
+	// dishLibrarySheet 菜品下拉的数据来源，隐藏
+	dishLibrarySheet = "菜品库"
+	// allDishesHeader 菜品库中全部菜品列
+	allDishesHeader = "全部菜品"
+)
+
+var weekdayShortNames = []string{"周日", "周一", "周二", "周三", "周四", "周五", "周六"}
+
+func (s *menuService) BuildTemplate(date string) (*excelize.File, error) {
+	return s.buildWorkbook(date, false)
+}
+
+func (s *menuService) ExportWeek(date string) (*excelize.File, error) {
+	return s.buildWorkbook(date, true)
+}
+
+// buildWorkbook 按导入格式生成周菜单：日期 / 星期 / 餐别 / 窗口 / 各菜品分类 / 菜品，每个周套餐一行；
+// filled 为 true 时仅导出已关联套餐的周套餐并填入其菜品
+func (s *menuService) buildWorkbook(date string, filled bool) (*excelize.File, error) {
+	monday, err := s.weekOf(date)
+	if err != nil {
+		return nil, err
+	}
+	startDate := monday.Format("20060102")
+	endDate := monday.AddDate(0, 0, 6).Format("20060102")
+
+	slots, err := s.menuRepo.FindWeeklySlots(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	if len(slots) == 0 {
+		return nil, fmt.Errorf("%s - %s 尚未生成周套餐", startDate, endDate)
+	}
+	categories, err := s.menuRepo.FindCategories()
+	if err != nil {
+		return nil, err
+	}
+	dishes, err := s.menuRepo.FindDishes()
+	if err != nil {
+		return nil, err
+	}
+
+	// 导出时仅保留已发布的周套餐，避免空行导致重新导入失败
+	setmealDishes := map[int][]model.MenuDish{}
+	if filled {
+		published := []model.WeeklySlot{}
+		setmealIds := []int{}
+		for _, slot := range slots {
+			if slot.SetmealId != 0 {
+				published = append(published, slot)
+				setmealIds = append(setmealIds, slot.SetmealId)
+			}
+		}
+		if len(published) == 0 {
+			return nil, fmt.Errorf("%s - %s 尚未发布周菜单", startDate, endDate)
+		}
+		if setmealDishes, err = s.menuRepo.FindSetmealDishes(setmealIds); err != nil {
+			return nil, err
+		}
+		slots = published
+	}
+
+	// 分类列在前，菜品列收纳未分类或分类已删除的菜品
+	columnOf := map[int]int{}
+	headers := []interface{}{"日期", "星期", "餐别", "窗口"}
+	for _, c := range categories {
+		columnOf[c.Id] = len(headers)
+		headers = append(headers, c.Name)
+	}
+	uncategorized := len(headers)
+	headers = append(headers, uncategorizedHeader)
+
+	f := excelize.NewFile()
+	if err := f.SetSheetName("Sheet1", menuSheet); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.SetSheetRow(menuSheet, "A1", &headers); err != nil {
+		f.Close()
+		return nil, err
+	}
+	for i, slot := range slots {
+		day, err := time.ParseInLocation("20060102", slot.WeekNumber, time.Local)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("周套餐 %d 日期错误: %s", slot.Id, slot.WeekNumber)
+		}
+		row := make([]interface{}, len(headers))
+		row[0] = day.Format("2006-01-02")
+		row[1] = weekdayShortNames[day.Weekday()]
+		row[2] = slot.MealType
+		row[3] = slot.Window()
+
+		cells := make([][]string, len(headers))
+		for _, d := range setmealDishes[slot.SetmealId] {
+			col, ok := columnOf[d.CategoryId]
+			if !ok {
+				col = uncategorized
+			}
+			cells[col] = append(cells[col], d.Name)
+		}
+		for col := 4; col < len(headers); col++ {
+			row[col] = strings.Join(cells[col], "、")
+		}
+
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		if err := f.SetSheetRow(menuSheet, cell, &row); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+
+	if err := addDishDropdowns(f, categories, dishes, 4, len(slots)+1); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := styleMenuSheet(f, len(headers)); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// addDishDropdowns 在隐藏的菜品库中按分类列出菜品，并为周菜单各菜品列添加下拉；
+// 下拉仅作提示，允许填写新菜品或同一单元格填写多个菜品
+func addDishDropdowns(f *excelize.File, categories []model.DishCategory, dishes []model.MenuDish, firstCol, lastRow int) error {
+	if _, err := f.NewSheet(dishLibrarySheet); err != nil {
+		return err
+	}
+
+	columns := make([][]string, len(categories)+1)
+	index := map[int]int{}
+	for i, c := range categories {
+		index[c.Id] = i
+	}
+	for _, d := range dishes {
+		if i, ok := index[d.CategoryId]; ok {
+			columns[i] = append(columns[i], d.Name)
+		}
+		columns[len(categories)] = append(columns[len(categories)], d.Name)
+	}
+
+	for i, names := range columns {
+		header := allDishesHeader
+		if i < len(categories) {
+			header = categories[i].Name
+		}
+		values := []interface{}{header}
+		for _, name := range names {
+			values = append(values, name)
+		}
+		col := columnName(i)
+		if err := f.SetSheetCol(dishLibrarySheet, col+"1", &values); err != nil {
+			return err
+		}
+		if len(names) == 0 {
+			continue
+		}
+
+		target := columnName(firstCol + i)
+		dv := excelize.NewDataValidation(true)
+		dv.Sqref = fmt.Sprintf("%s2:%s%d", target, target, lastRow)
+		dv.SetSqrefDropList(fmt.Sprintf("'%s'!$%s$2:$%s$%d", dishLibrarySheet, col, col, len(names)+1))
+		dv.SetError(excelize.DataValidationErrorStyleWarning, "菜品库中没有该菜品", "导入时将自动新建菜品；多个菜品用、分隔")
+		if err := f.AddDataValidation(menuSheet, dv); err != nil {
+			return err
+		}
+	}
+	return f.SetSheetVisible(dishLibrarySheet, false)
+}
+
+// styleMenuSheet 表头加粗并冻结首行
+func styleMenuSheet(f *excelize.File, columns int) error {
+	style, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return err
+	}
+	last := columnName(columns - 1)
+	if err := f.SetCellStyle(menuSheet, "A1", last+"1", style); err != nil {
+		return err
+	}
+	if err := f.SetColWidth(menuSheet, "A", "A", 12); err != nil {
+		return err
+	}
+	if err := f.SetColWidth(menuSheet, "E", last, 24); err != nil {
+		return err
+	}
+	return f.SetPanes(menuSheet, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"})
+}
+
+// weekOf 返回 date（yyyyMMdd）所在周的周一，date 为空时为下周一
+func (s *menuService) weekOf(date string) (time.Time, error) {
+	if date == "" {
+		now := s.clock.Now()
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+		return mondayOf(today).AddDate(0, 0, 7), nil
+	}
+	day, err := time.ParseInLocation("20060102", date, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("日期格式错误: %s，请使用 yyyyMMdd 格式", date)
+	}
+	return mondayOf(day), nil
+}
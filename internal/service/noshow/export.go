@@ -0,0 +1,116 @@
+package noshow
+
+import (
+	"fmt"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	userSheet = "人员爽约"
+	deptSheet = "部门爽约"
+)
+
+var (
+	userHeaders = []interface{}{"工号", "姓名", "部门", "报餐", "爽约", "爽约率", "达到阈值", "暂停报餐至"}
+	deptHeaders = []interface{}{"部门", "报餐人数", "达到阈值人数", "报餐", "爽约", "爽约率"}
+)
+
+func (s *noShowService) ExportReport(startDate, endDate string) (*excelize.File, error) {
+	report, err := s.GetReport(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	f := excelize.NewFile()
+	f.SetSheetName("Sheet1", userSheet)
+	if _, err := f.NewSheet(deptSheet); err != nil {
+		return nil, err
+	}
+	bold, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return nil, err
+	}
+	// 内置格式 10 为 0.00%
+	percent, err := f.NewStyle(&excelize.Style{NumFmt: 10})
+	if err != nil {
+		return nil, err
+	}
+	fill := excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"FCE4D6"}}
+	highlight, err := f.NewStyle(&excelize.Style{Fill: fill})
+	if err != nil {
+		return nil, err
+	}
+	highlightPercent, err := f.NewStyle(&excelize.Style{Fill: fill, NumFmt: 10})
+	if err != nil {
+		return nil, err
+	}
+
+	// 人员爽约：期间及阈值说明、表头，达到阈值的人员整行标色
+	t := report.Thresholds
+	rows := [][]interface{}{
+		{fmt.Sprintf("统计期间：%s 至 %s，共报餐 %d 次，爽约 %d 次", displayDate(report.StartDate), displayDate(report.EndDate), report.Booked, report.NoShow)},
+		{fmt.Sprintf("标记规则：爽约不少于 %d 次且爽约率不低于 %g%%", t.MinCount, t.MinRate*100)},
+		userHeaders,
+	}
+	highlighted := []int{}
+	for _, u := range report.Users {
+		mark := ""
+		if u.Flagged {
+			mark = "是"
+		}
+		rows = append(rows, []interface{}{u.WorkNo, u.Name, u.DeptName, u.Booked, u.NoShow, u.Rate, mark, u.BlockedUntil})
+		if u.Flagged {
+			highlighted = append(highlighted, len(rows))
+		}
+	}
+	if err := writeRows(f, userSheet, rows); err != nil {
+		return nil, err
+	}
+	f.SetCellStyle(userSheet, "A3", "H3", bold)
+	if len(rows) > 3 {
+		f.SetCellStyle(userSheet, "F4", fmt.Sprintf("F%d", len(rows)), percent)
+	}
+	for _, row := range highlighted {
+		f.SetCellStyle(userSheet, fmt.Sprintf("A%d", row), fmt.Sprintf("H%d", row), highlight)
+		f.SetCellStyle(userSheet, fmt.Sprintf("F%d", row), fmt.Sprintf("F%d", row), highlightPercent)
+	}
+	f.SetColWidth(userSheet, "C", "C", 20)
+	f.SetColWidth(userSheet, "H", "H", 12)
+	f.SetPanes(userSheet, &excelize.Panes{Freeze: true, YSplit: 3, TopLeftCell: "A4", ActivePane: "bottomLeft"})
+
+	// 部门爽约
+	rows = [][]interface{}{deptHeaders}
+	for _, d := range report.Depts {
+		rows = append(rows, []interface{}{d.DeptName, d.Users, d.Flagged, d.Booked, d.NoShow, d.Rate})
+	}
+	rows = append(rows, []interface{}{"合计", "", "", report.Booked, report.NoShow, report.Rate})
+	if err := writeRows(f, deptSheet, rows); err != nil {
+		return nil, err
+	}
+	f.SetCellStyle(deptSheet, "A1", "F1", bold)
+	f.SetCellStyle(deptSheet, "F2", fmt.Sprintf("F%d", len(rows)), percent)
+	f.SetCellStyle(deptSheet, fmt.Sprintf("A%d", len(rows)), fmt.Sprintf("E%d", len(rows)), bold)
+	f.SetColWidth(deptSheet, "A", "A", 20)
+
+	return f, nil
+}
+
+// writeRows 从第一行起逐行写入
+func writeRows(f *excelize.File, sheet string, rows [][]interface{}) error {
+	for i, row := range rows {
+		cell, err := excelize.CoordinatesToCellName(1, i+1)
+		if err != nil {
+			return err
+		}
+		if err := f.SetSheetRow(sheet, cell, &row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// displayDate yyyyMMdd 转换为 yyyy-MM-dd
+func displayDate(date string) string {
+	return date[:4] + "-" + date[4:6] + "-" + date[6:]
+}
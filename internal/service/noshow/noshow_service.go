@@ -0,0 +1,209 @@
+package noshow
+
+import (
+	"canteen/internal/infrastructure/clock"
+	"canteen/internal/infrastructure/daterange"
+	"canteen/internal/model"
+	"canteen/internal/repository/noshow"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// maxReportDays 单次统计的最大天数
+const maxReportDays = 366
+
+type NoShowService interface {
+	// GetReport 统计 [startDate, endDate]（yyyyMMdd）内各部门、人员的爽约次数及爽约率，并标记达到阈值的人员
+	GetReport(startDate, endDate string) (*model.NoShowReport, error)
+	// ExportReport 导出爽约报表，包含人员爽约及部门爽约两个工作表
+	ExportReport(startDate, endDate string) (*excelize.File, error)
+	// BlockRepeatOffenders 按截至今天最近 window_days 天的订单，暂停达到阈值的人员自明天起报餐 block_days 天，
+	// block_days 为 0 时不处理；返回新暂停的人数
+	BlockRepeatOffenders() (int, error)
+	// GetActiveBlocks 查询今天生效的暂停报餐记录
+	GetActiveBlocks() ([]model.BookingBlock, error)
+	// LiftBlock 提前解除暂停报餐
+	LiftBlock(id int) error
+}
+
+type noShowService struct {
+	noShowRepo noshow.NoShowRepository
+	clock      clock.Clock
+}
+
+func NewNoShowService(noShowRepo noshow.NoShowRepository, clk clock.Clock) NoShowService {
+	return &noShowService{noShowRepo: noShowRepo, clock: clk}
+}
+
+func (s *noShowService) GetReport(startDate, endDate string) (*model.NoShowReport, error) {
+	if err := daterange.Validate(startDate, endDate, maxReportDays); err != nil {
+		return nil, err
+	}
+	thresholds, err := s.loadThresholds()
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.noShowRepo.FindBookingCounts(startDate, endDate, false)
+	if err != nil {
+		return nil, err
+	}
+	blocks, err := s.noShowRepo.FindActiveBlocks(s.clock.Now().Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	blockedUntil := map[int]string{}
+	for _, b := range blocks {
+		if b.EndDate > blockedUntil[b.UserId] {
+			blockedUntil[b.UserId] = b.EndDate
+		}
+	}
+
+	report := &model.NoShowReport{
+		StartDate: startDate, EndDate: endDate, Thresholds: thresholds,
+		Depts: []model.NoShowDept{}, Users: []model.NoShowUser{},
+	}
+	depts := map[int]*model.NoShowDept{}
+	for _, u := range counts {
+		if u.DeptName == "" {
+			u.DeptName = "未分配部门"
+		}
+		u.Rate = ratio(u.NoShow, u.Booked)
+		u.Flagged = flagged(thresholds, u)
+		u.BlockedUntil = blockedUntil[u.UserId]
+
+		dept := depts[u.DeptId]
+		if dept == nil {
+			dept = &model.NoShowDept{DeptId: u.DeptId, DeptName: u.DeptName}
+			depts[u.DeptId] = dept
+		}
+		dept.Users++
+		dept.Booked += u.Booked
+		dept.NoShow += u.NoShow
+		if u.Flagged {
+			dept.Flagged++
+		}
+
+		report.Booked += u.Booked
+		report.NoShow += u.NoShow
+		if u.NoShow > 0 {
+			report.Users = append(report.Users, u)
+		}
+	}
+	report.Rate = ratio(report.NoShow, report.Booked)
+
+	for _, dept := range depts {
+		dept.Rate = ratio(dept.NoShow, dept.Booked)
+		report.Depts = append(report.Depts, *dept)
+	}
+	sort.Slice(report.Depts, func(i, j int) bool {
+		a, b := report.Depts[i], report.Depts[j]
+		if a.NoShow != b.NoShow {
+			return a.NoShow > b.NoShow
+		}
+		if a.Rate != b.Rate {
+			return a.Rate > b.Rate
+		}
+		return a.DeptName < b.DeptName
+	})
+	sort.Slice(report.Users, func(i, j int) bool {
+		a, b := report.Users[i], report.Users[j]
+		if a.NoShow != b.NoShow {
+			return a.NoShow > b.NoShow
+		}
+		if a.Rate != b.Rate {
+			return a.Rate > b.Rate
+		}
+		if a.WorkNo != b.WorkNo {
+			return a.WorkNo < b.WorkNo
+		}
+		return a.UserId < b.UserId
+	})
+	return report, nil
+}
+
+func (s *noShowService) BlockRepeatOffenders() (int, error) {
+	thresholds, err := s.loadThresholds()
+	if err != nil {
+		return 0, err
+	}
+	if thresholds.BlockDays == 0 {
+		return 0, nil
+	}
+
+	today := s.clock.Now()
+	start := today.AddDate(0, 0, 1-thresholds.WindowDays)
+	tomorrow := today.AddDate(0, 0, 1)
+	counts, err := s.noShowRepo.FindBookingCounts(start.Format("20060102"), today.Format("20060102"), true)
+	if err != nil {
+		return 0, err
+	}
+	blocks, err := s.noShowRepo.FindActiveBlocks(tomorrow.Format("2006-01-02"))
+	if err != nil {
+		return 0, err
+	}
+	blocked := map[int]bool{}
+	for _, b := range blocks {
+		blocked[b.UserId] = true
+	}
+
+	created := 0
+	for _, u := range counts {
+		u.Rate = ratio(u.NoShow, u.Booked)
+		if blocked[u.UserId] || !flagged(thresholds, u) {
+			continue
+		}
+		block := model.BookingBlock{
+			UserId:    u.UserId,
+			StartDate: tomorrow.Format("2006-01-02"),
+			EndDate:   tomorrow.AddDate(0, 0, thresholds.BlockDays-1).Format("2006-01-02"),
+			NoShow:    u.NoShow,
+			Reason: fmt.Sprintf("%s 至 %s 报餐 %d 次，未就餐 %d 次",
+				start.Format("2006-01-02"), today.Format("2006-01-02"), u.Booked, u.NoShow),
+		}
+		id, err := s.noShowRepo.CreateBlock(block)
+		if err != nil {
+			return created, err
+		}
+		created++
+		log.Printf("爽约暂停报餐: ID=%d, user_id=%d, 工号=%s, %s 至 %s, 原因=%s",
+			id, u.UserId, u.WorkNo, block.StartDate, block.EndDate, block.Reason)
+	}
+	return created, nil
+}
+
+func (s *noShowService) GetActiveBlocks() ([]model.BookingBlock, error) {
+	return s.noShowRepo.FindActiveBlocks(s.clock.Now().Format("2006-01-02"))
+}
+
+func (s *noShowService) LiftBlock(id int) error {
+	ok, err := s.noShowRepo.RevokeBlock(id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("暂停报餐记录不存在或已解除")
+	}
+	log.Printf("解除暂停报餐: ID=%d", id)
+	return nil
+}
+
+func (s *noShowService) loadThresholds() (model.NoShowThresholds, error) {
+	configs, err := s.noShowRepo.FindNoShowConfigs()
+	if err != nil {
+		return model.NoShowThresholds{}, err
+	}
+	return parseThresholds(configs)
+}
+
+// ratio 返回 n / d，保留 4 位小数，d 为 0 时返回 0
+func ratio(n, d int) float64 {
+	if d == 0 {
+		return 0
+	}
+	return math.Round(float64(n)/float64(d)*10000) / 10000
+}
@@ -0,0 +1,176 @@
+package noshow
+
+import (
+	"canteen/internal/infrastructure/clock"
+	"canteen/internal/model"
+	"canteen/internal/repository/noshow"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeNoShowRepo 固定的报餐统计及配置，记录查询参数和新建的暂停记录
+type fakeNoShowRepo struct {
+	noshow.NoShowRepository
+	counts  []model.NoShowUser
+	configs map[string]string
+	blocks  []model.BookingBlock
+
+	start, end     string
+	sinceLastBlock bool
+	created        []model.BookingBlock
+}
+
+func (r *fakeNoShowRepo) FindBookingCounts(startDate, endDate string, sinceLastBlock bool) ([]model.NoShowUser, error) {
+	r.start, r.end, r.sinceLastBlock = startDate, endDate, sinceLastBlock
+	return r.counts, nil
+}
+
+func (r *fakeNoShowRepo) FindNoShowConfigs() (map[string]string, error) {
+	return r.configs, nil
+}
+
+func (r *fakeNoShowRepo) FindActiveBlocks(date string) ([]model.BookingBlock, error) {
+	blocks := []model.BookingBlock{}
+	for _, b := range r.blocks {
+		if b.StartDate <= date && b.EndDate >= date {
+			blocks = append(blocks, b)
+		}
+	}
+	return blocks, nil
+}
+
+func (r *fakeNoShowRepo) CreateBlock(block model.BookingBlock) (int, error) {
+	r.created = append(r.created, block)
+	return len(r.created), nil
+}
+
+func newFakeNoShowRepo() *fakeNoShowRepo {
+	return &fakeNoShowRepo{
+		counts: []model.NoShowUser{
+			{UserId: 1, WorkNo: "E001", Name: "张三", DeptId: 10, DeptName: "财务部", Booked: 10, NoShow: 4},
+			{UserId: 2, WorkNo: "E002", Name: "李四", DeptId: 10, DeptName: "财务部", Booked: 20, NoShow: 4},
+			{UserId: 3, WorkNo: "E003", Name: "王五", DeptId: 20, DeptName: "行政部", Booked: 5, NoShow: 0},
+			{UserId: 4, WorkNo: "E004", Name: "赵六", DeptId: 20, DeptName: "行政部", Booked: 2, NoShow: 2},
+		},
+		configs: map[string]string{"no_show.min_count": "2", "no_show.min_rate": "0.3"},
+		blocks: []model.BookingBlock{
+			{Id: 1, UserId: 4, StartDate: "2025-06-28", EndDate: "2025-07-04"},
+		},
+	}
+}
+
+// 2025-06-30 23:00 为每日过期任务执行时间
+func newTestService(repo *fakeNoShowRepo) NoShowService {
+	return NewNoShowService(repo, clock.Fixed(time.Date(2025, 6, 30, 23, 0, 0, 0, time.Local)))
+}
+
+func TestGetReportFlagsRepeatOffenders(t *testing.T) {
+	repo := newFakeNoShowRepo()
+	report, err := newTestService(repo).GetReport("20250601", "20250630")
+	if err != nil {
+		t.Fatalf("GetReport() err = %v", err)
+	}
+	if repo.sinceLastBlock {
+		t.Errorf("报表应统计期间内全部订单")
+	}
+	if report.Booked != 37 || report.NoShow != 10 || report.Rate != 0.2703 {
+		t.Errorf("合计 = %d/%d %.4f, 期望 37/10 0.2703", report.Booked, report.NoShow, report.Rate)
+	}
+
+	// 按爽约次数、爽约率降序，无爽约的人员不列出；李四爽约率 0.2 未达阈值
+	var got []string
+	for _, u := range report.Users {
+		got = append(got, u.WorkNo)
+	}
+	if strings.Join(got, ",") != "E001,E002,E004" {
+		t.Fatalf("人员顺序 = %v", got)
+	}
+	if !report.Users[0].Flagged || report.Users[1].Flagged || !report.Users[2].Flagged {
+		t.Errorf("标记 = %+v", report.Users)
+	}
+	if report.Users[2].Rate != 1 || report.Users[2].BlockedUntil != "2025-07-04" {
+		t.Errorf("赵六 = %+v", report.Users[2])
+	}
+
+	if len(report.Depts) != 2 || report.Depts[0].DeptName != "财务部" {
+		t.Fatalf("部门 = %+v", report.Depts)
+	}
+	admin := report.Depts[1]
+	if admin.Users != 2 || admin.Flagged != 1 || admin.Booked != 7 || admin.NoShow != 2 || admin.Rate != 0.2857 {
+		t.Errorf("行政部 = %+v", admin)
+	}
+}
+
+func TestGetReportValidation(t *testing.T) {
+	repo := newFakeNoShowRepo()
+	s := newTestService(repo)
+
+	if _, err := s.GetReport("20250630", "20250601"); err == nil || !strings.Contains(err.Error(), "不能早于开始日期") {
+		t.Errorf("起止日期颠倒应报错，实际 %v", err)
+	}
+
+	for _, configs := range []map[string]string{
+		{"no_show.min_count": "0"},
+		{"no_show.min_rate": "30%"},
+		{"no_show.block_days": "-1"},
+	} {
+		repo.configs = configs
+		if _, err := s.GetReport("20250601", "20250630"); err == nil || !strings.Contains(err.Error(), "无效") {
+			t.Errorf("配置 %v 应报错，实际 %v", configs, err)
+		}
+	}
+}
+
+func TestBlockRepeatOffenders(t *testing.T) {
+	repo := newFakeNoShowRepo()
+	s := newTestService(repo)
+
+	if n, err := s.BlockRepeatOffenders(); err != nil || n != 0 || len(repo.created) != 0 {
+		t.Fatalf("未配置暂停天数时不应暂停, n=%d err=%v", n, err)
+	}
+
+	repo.configs["no_show.block_days"] = "7"
+	repo.configs["no_show.window_days"] = "14"
+	n, err := s.BlockRepeatOffenders()
+	if err != nil {
+		t.Fatalf("BlockRepeatOffenders() err = %v", err)
+	}
+	if repo.start != "20250617" || repo.end != "20250630" || !repo.sinceLastBlock {
+		t.Errorf("统计范围 = %s-%s sinceLastBlock=%v", repo.start, repo.end, repo.sinceLastBlock)
+	}
+	// 赵六暂停中不重复暂停
+	if n != 1 || len(repo.created) != 1 {
+		t.Fatalf("新暂停 %d 人: %+v", n, repo.created)
+	}
+	block := repo.created[0]
+	if block.UserId != 1 || block.StartDate != "2025-07-01" || block.EndDate != "2025-07-07" || block.NoShow != 4 {
+		t.Errorf("暂停记录 = %+v", block)
+	}
+}
+
+func TestExportReport(t *testing.T) {
+	f, err := newTestService(newFakeNoShowRepo()).ExportReport("20250601", "20250630")
+	if err != nil {
+		t.Fatalf("ExportReport() err = %v", err)
+	}
+	rows, err := f.GetRows(userSheet)
+	if err != nil {
+		t.Fatalf("GetRows(%s) err = %v", userSheet, err)
+	}
+	// 期间、规则、表头及 3 名有爽约的人员
+	if len(rows) != 6 || rows[2][0] != "工号" || !strings.Contains(rows[1][0], "不低于 30%") {
+		t.Fatalf("人员爽约 = %v", rows)
+	}
+	if rows[3][0] != "E001" || rows[3][5] != "40.00%" || rows[3][6] != "是" {
+		t.Errorf("张三 = %v", rows[3])
+	}
+
+	depts, err := f.GetRows(deptSheet)
+	if err != nil {
+		t.Fatalf("GetRows(%s) err = %v", deptSheet, err)
+	}
+	if len(depts) != 4 || depts[3][0] != "合计" || depts[3][4] != "10" {
+		t.Errorf("部门爽约 = %v", depts)
+	}
+}
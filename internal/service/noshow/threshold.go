@@ -0,0 +1,60 @@
+package noshow
+
+import (
+	"canteen/internal/model"
+	"fmt"
+	"strconv"
+)
+
+// 爽约规则配置键（canteen_config），未配置时使用默认值：
+//
+//	no_show.min_count    爽约次数不少于该值，默认 3
+//	no_show.min_rate     且爽约率（0-1）不低于该值时标记，默认 0.3
+//	no_show.block_days   标记后暂停报餐的天数，默认 0 即不暂停
+//	no_show.window_days  自动暂停时统计最近的天数，默认 30
+const (
+	configMinCount   = "no_show.min_count"
+	configMinRate    = "no_show.min_rate"
+	configBlockDays  = "no_show.block_days"
+	configWindowDays = "no_show.window_days"
+)
+
+var defaultThresholds = model.NoShowThresholds{MinCount: 3, MinRate: 0.3, BlockDays: 0, WindowDays: 30}
+
+// parseThresholds 解析爽约规则，配置无效时返回错误而不是静默使用默认值
+func parseThresholds(configs map[string]string) (model.NoShowThresholds, error) {
+	t := defaultThresholds
+	ints := []struct {
+		key string
+		min int
+		dst *int
+	}{
+		{configMinCount, 1, &t.MinCount},
+		{configBlockDays, 0, &t.BlockDays},
+		{configWindowDays, 1, &t.WindowDays},
+	}
+	for _, c := range ints {
+		value, ok := configs[c.key]
+		if !ok || value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < c.min {
+			return model.NoShowThresholds{}, fmt.Errorf("配置 %s 无效: %s", c.key, value)
+		}
+		*c.dst = n
+	}
+	if value := configs[configMinRate]; value != "" {
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return model.NoShowThresholds{}, fmt.Errorf("配置 %s 无效: %s，应为 0-1 之间的小数", configMinRate, value)
+		}
+		t.MinRate = rate
+	}
+	return t, nil
+}
+
+// flagged 判断用户是否达到爽约标记阈值
+func flagged(t model.NoShowThresholds, u model.NoShowUser) bool {
+	return u.NoShow >= t.MinCount && u.Rate >= t.MinRate
+}
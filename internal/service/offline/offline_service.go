@@ -0,0 +1,45 @@
+package offline
+
+import (
+	"canteen/internal/model"
+	"canteen/internal/repository/offline"
+	"errors"
+	"strings"
+)
+
+type OfflineService interface {
+	ListReconciliations(status string) ([]model.OfflineTransaction, error)
+	ResolveReconciliation(id int, operator string, remark string) (*model.OfflineTransaction, error)
+}
+
+type offlineService struct {
+	offlineRepo offline.OfflineRepository
+}
+
+func NewOfflineService(offlineRepo offline.OfflineRepository) OfflineService {
+	return &offlineService{offlineRepo: offlineRepo}
+}
+
+// ListReconciliations 查询脱机记录，默认返回待对账列表
+func (s *offlineService) ListReconciliations(status string) ([]model.OfflineTransaction, error) {
+	if status == "" {
+		status = model.OfflineStatusReconcile
+	}
+	return s.offlineRepo.FindByStatus(status)
+}
+
+// ResolveReconciliation 管理员处理待对账记录
+func (s *offlineService) ResolveReconciliation(id int, operator string, remark string) (*model.OfflineTransaction, error) {
+	if id <= 0 {
+		return nil, errors.New("无效的记录ID")
+	}
+	operator = strings.TrimSpace(operator)
+	if operator == "" {
+		return nil, errors.New("处理人不能为空")
+	}
+
+	if err := s.offlineRepo.Resolve(id, operator, remark); err != nil {
+		return nil, err
+	}
+	return s.offlineRepo.FindById(id)
+}
@@ -0,0 +1,211 @@
+package order
+
+import (
+	"canteen/internal/model"
+	"canteen/internal/repository/order"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	recordSheet      = "报餐记录"
+	deptSummarySheet = "部门汇总"
+	userSummarySheet = "人员汇总"
+)
+
+// recordHeaders 报餐记录表头，与按日导出一致
+var recordHeaders = []interface{}{"工号", "姓名", "部门", "餐别", "日期", "星期", "状态"}
+
+// summaryStatuses 汇总表的状态列，其他状态按出现顺序追加
+var summaryStatuses = []string{
+	model.OrderStatusBooked,
+	model.OrderStatusCollected,
+	model.OrderStatusTemp,
+	model.OrderStatusExpired,
+	model.OrderStatusCancelled,
+	model.OrderStatusVoided,
+}
+
+// tally 按状态及餐别计数
+type tally struct {
+	total      int
+	byStatus   map[string]int
+	byMealType map[string]int
+}
+
+func newTally() *tally {
+	return &tally{byStatus: map[string]int{}, byMealType: map[string]int{}}
+}
+
+func (t *tally) add(o order.ExportOrderRecord) {
+	t.total++
+	t.byStatus[o.Status]++
+	t.byMealType[o.MealType]++
+}
+
+// userTally 人员汇总
+type userTally struct {
+	workNo, name, dept string
+	*tally
+}
+
+// columns 按出现顺序记录汇总表的动态列
+type columns struct {
+	names []string
+	seen  map[string]bool
+}
+
+func newColumns(initial []string) *columns {
+	c := &columns{seen: map[string]bool{}}
+	for _, name := range initial {
+		c.add(name)
+	}
+	return c
+}
+
+func (c *columns) add(name string) {
+	if !c.seen[name] {
+		c.seen[name] = true
+		c.names = append(c.names, name)
+	}
+}
+
+func (s *orderService) ExportOrdersByMonth(month string) (file *excelize.File, err error) {
+	if _, err := time.Parse("200601", month); err != nil {
+		return nil, fmt.Errorf("月份格式错误: %s，请使用 yyyyMM 格式", month)
+	}
+
+	f := excelize.NewFile()
+	// 出错时关闭工作簿，清理流式写入产生的临时文件
+	defer func() {
+		if err != nil {
+			f.Close()
+		}
+	}()
+	f.SetSheetName("Sheet1", recordSheet)
+	// 流式写入前需建好全部工作表
+	if _, err := f.NewSheet(deptSummarySheet); err != nil {
+		return nil, err
+	}
+	if _, err := f.NewSheet(userSummarySheet); err != nil {
+		return nil, err
+	}
+
+	sw, err := f.NewStreamWriter(recordSheet)
+	if err != nil {
+		return nil, err
+	}
+	if err := sw.SetRow("A1", recordHeaders); err != nil {
+		return nil, err
+	}
+
+	statuses, mealTypes := newColumns(summaryStatuses), newColumns(nil)
+	depts := map[string]*tally{}
+	users := map[int]*userTally{}
+	row := 1
+	err = s.orderRepo.EachOrderForMonth(month, func(o order.ExportOrderRecord) error {
+		row++
+		cell, err := excelize.CoordinatesToCellName(1, row)
+		if err != nil {
+			return err
+		}
+		if err := sw.SetRow(cell, []interface{}{o.WorkNo, o.Name, o.Dept, o.MealType, o.Date, o.Weekday, o.Status}); err != nil {
+			return err
+		}
+
+		statuses.add(o.Status)
+		mealTypes.add(o.MealType)
+		if depts[o.Dept] == nil {
+			depts[o.Dept] = newTally()
+		}
+		depts[o.Dept].add(o)
+		if users[o.UserId] == nil {
+			users[o.UserId] = &userTally{workNo: o.WorkNo, name: o.Name, dept: o.Dept, tally: newTally()}
+		}
+		users[o.UserId].add(o)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("查询失败: %v", err)
+	}
+	if err := sw.Flush(); err != nil {
+		return nil, err
+	}
+
+	deptNames := make([]string, 0, len(depts))
+	for name := range depts {
+		deptNames = append(deptNames, name)
+	}
+	sort.Strings(deptNames)
+	deptRows := make([][]interface{}, len(deptNames))
+	for i, name := range deptNames {
+		deptRows[i] = summaryRow([]interface{}{name}, depts[name], statuses, mealTypes)
+	}
+	if err := writeSummary(f, deptSummarySheet, []interface{}{"部门"}, deptRows, statuses, mealTypes); err != nil {
+		return nil, err
+	}
+
+	userList := make([]*userTally, 0, len(users))
+	for _, u := range users {
+		userList = append(userList, u)
+	}
+	sort.Slice(userList, func(i, j int) bool {
+		if userList[i].dept != userList[j].dept {
+			return userList[i].dept < userList[j].dept
+		}
+		return userList[i].workNo < userList[j].workNo
+	})
+	userRows := make([][]interface{}, len(userList))
+	for i, u := range userList {
+		userRows[i] = summaryRow([]interface{}{u.workNo, u.name, u.dept}, u.tally, statuses, mealTypes)
+	}
+	if err := writeSummary(f, userSummarySheet, []interface{}{"工号", "姓名", "部门"}, userRows, statuses, mealTypes); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// summaryRow 汇总行：标识列、合计、各状态、各餐别
+func summaryRow(keys []interface{}, t *tally, statuses, mealTypes *columns) []interface{} {
+	row := append(keys, t.total)
+	for _, status := range statuses.names {
+		row = append(row, t.byStatus[status])
+	}
+	for _, mealType := range mealTypes.names {
+		row = append(row, t.byMealType[mealType])
+	}
+	return row
+}
+
+// writeSummary 流式写入汇总表，表头为 标识列 / 合计 / 各状态 / 各餐别
+func writeSummary(f *excelize.File, sheet string, keyHeaders []interface{}, rows [][]interface{}, statuses, mealTypes *columns) error {
+	sw, err := f.NewStreamWriter(sheet)
+	if err != nil {
+		return err
+	}
+
+	headers := append(keyHeaders, "合计")
+	for _, status := range statuses.names {
+		headers = append(headers, status)
+	}
+	for _, mealType := range mealTypes.names {
+		headers = append(headers, mealType)
+	}
+	if err := sw.SetRow("A1", headers); err != nil {
+		return err
+	}
+	for i, row := range rows {
+		cell, err := excelize.CoordinatesToCellName(1, i+2)
+		if err != nil {
+			return err
+		}
+		if err := sw.SetRow(cell, row); err != nil {
+			return err
+		}
+	}
+	return sw.Flush()
+}
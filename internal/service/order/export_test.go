@@ -0,0 +1,83 @@
+package order
+
+import (
+	"canteen/internal/repository/order"
+	"strings"
+	"testing"
+)
+
+// fakeOrderRepo 按顺序回放月订单
+type fakeOrderRepo struct {
+	order.OrderRepository
+	records []order.ExportOrderRecord
+	month   string
+}
+
+func (r *fakeOrderRepo) EachOrderForMonth(month string, fn func(order.ExportOrderRecord) error) error {
+	r.month = month
+	for _, o := range r.records {
+		if err := fn(o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestExportOrdersByMonth(t *testing.T) {
+	repo := &fakeOrderRepo{records: []order.ExportOrderRecord{
+		{UserId: 2, WorkNo: "E002", Name: "李四", Dept: "财务部", MealType: "午餐", Date: "2025/6/3", Weekday: "周二", Status: "已领取"},
+		{UserId: 1, WorkNo: "E001", Name: "张三", Dept: "财务部", MealType: "午餐", Date: "2025/6/3", Weekday: "周二", Status: "已过期"},
+		{UserId: 3, WorkNo: "E003", Name: "王五", Dept: "信息部", MealType: "晚餐", Date: "2025/6/3", Weekday: "周二", Status: "已领取"},
+		{UserId: 2, WorkNo: "E002", Name: "李四", Dept: "财务部", MealType: "晚餐", Date: "2025/6/4", Weekday: "周三", Status: "待确认"},
+	}}
+	s := &orderService{orderRepo: repo}
+
+	if _, err := s.ExportOrdersByMonth("20250601"); err == nil {
+		t.Errorf("非 yyyyMM 格式应报错")
+	}
+	f, err := s.ExportOrdersByMonth("202506")
+	if err != nil {
+		t.Fatalf("ExportOrdersByMonth() err = %v", err)
+	}
+	if repo.month != "202506" {
+		t.Errorf("查询月份 = %s", repo.month)
+	}
+	if got := strings.Join(f.GetSheetList(), ","); got != "报餐记录,部门汇总,人员汇总" {
+		t.Errorf("工作表 = %s", got)
+	}
+
+	records, err := f.GetRows(recordSheet)
+	if err != nil {
+		t.Fatalf("GetRows(%s) err = %v", recordSheet, err)
+	}
+	if len(records) != 5 || records[4][6] != "待确认" {
+		t.Errorf("报餐记录 = %v", records)
+	}
+
+	// 未知状态追加在固定状态列之后，餐别按出现顺序
+	depts, err := f.GetRows(deptSummarySheet)
+	if err != nil {
+		t.Fatalf("GetRows(%s) err = %v", deptSummarySheet, err)
+	}
+	want := []string{
+		"部门,合计,已报餐,已领取,临时用餐,已过期,已取消,已作废,待确认,午餐,晚餐",
+		"信息部,1,0,1,0,0,0,0,0,0,1",
+		"财务部,3,0,1,0,1,0,0,1,2,1",
+	}
+	if len(depts) != len(want) {
+		t.Fatalf("部门汇总 = %v", depts)
+	}
+	for i, row := range depts {
+		if got := strings.Join(row, ","); got != want[i] {
+			t.Errorf("部门汇总第%d行 = %s, 期望 %s", i+1, got, want[i])
+		}
+	}
+
+	users, err := f.GetRows(userSummarySheet)
+	if err != nil {
+		t.Fatalf("GetRows(%s) err = %v", userSummarySheet, err)
+	}
+	if len(users) != 4 || strings.Join(users[1][:4], ",") != "E003,王五,信息部,1" || strings.Join(users[3][:4], ",") != "E002,李四,财务部,2" {
+		t.Errorf("人员汇总 = %v", users)
+	}
+}
@@ -1,12 +1,11 @@
 package order
 
 import (
-	"canteen/internal/model"
+	"canteen/internal/infrastructure/clock"
+	"canteen/internal/repository/calendar"
 	"canteen/internal/repository/order"
 	"canteen/internal/repository/user"
-	"errors"
 	"log"
-	"time"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/xuri/excelize/v2"
@@ -14,22 +13,26 @@ import (
 
 type OrderService interface {
 	ExportOrdersByDate(date string) (*excelize.File, error)
-	ExportOrdersByMonth(date string) (*excelize.File, error)
+	// ExportOrdersByMonth 导出 yyyyMM 月的报餐记录及按部门、人员的状态和餐别汇总
+	ExportOrdersByMonth(month string) (*excelize.File, error)
 	ProcessExpiredOrders() error
-	CreateOrder(order *model.OrderRecord) error
 }
 
 type orderService struct {
-	orderRepo order.OrderRepository
-	userRepo  user.UserRepository
-	redis     *redis.Client
+	orderRepo    order.OrderRepository
+	userRepo     user.UserRepository
+	calendarRepo calendar.CalendarRepository
+	redis        *redis.Client
+	clock        clock.Clock
 }
 
-func NewOrderService(orderRepo order.OrderRepository, userRepo user.UserRepository, redisClient *redis.Client) OrderService {
+func NewOrderService(orderRepo order.OrderRepository, userRepo user.UserRepository, calendarRepo calendar.CalendarRepository, redisClient *redis.Client, clk clock.Clock) OrderService {
 	return &orderService{
-		orderRepo: orderRepo,
-		userRepo:  userRepo,
-		redis:     redisClient,
+		orderRepo:    orderRepo,
+		userRepo:     userRepo,
+		calendarRepo: calendarRepo,
+		redis:        redisClient,
+		clock:        clk,
 	}
 }
 
@@ -38,53 +41,48 @@ func (s *orderService) ExportOrdersByDate(date string) (*excelize.File, error) {
 	return s.orderRepo.ExportToExcel(date)
 }
 
-func (s *orderService) ExportOrdersByMonth(date string) (*excelize.File, error) {
-	// 类似ExportOrdersByDate的实现，但按月查询
-	return s.ExportOrdersByDate(date) // 简化实现
-}
-
+// ProcessExpiredOrders 将当日未领取的报餐订单置为已过期，每个有过期订单的用户扣减一次次数；
+// 节假日停餐，当日报餐订单直接取消且不扣次数
 func (s *orderService) ProcessExpiredOrders() error {
-	todayStr := time.Now().Format("20060102")
-	
-	// 使用事务处理过期订单
-	// 注意：这里应该使用数据库事务，但为了简化，我们分步执行
-	
-	// 1. 更新订单记录并获取受影响的 user_id 列表
-	err := s.orderRepo.UpdateStatusToExpired(todayStr)
+	today := s.clock.Now()
+	todayStr := today.Format("20060102")
+
+	day, err := s.calendarRepo.FindByDate(today)
 	if err != nil {
-		log.Printf("Failed to update expired orders: %v", err)
+		log.Printf("Failed to check calendar for %s: %v", todayStr, err)
 		return err
 	}
+	if _, open := day.ScheduleOn(today); !open {
+		cancelled, err := s.orderRepo.CancelBookedOrders(todayStr)
+		if err != nil {
+			log.Printf("Failed to cancel booked orders on closed day %s: %v", todayStr, err)
+			return err
+		}
+		log.Printf("Day %s is closed (%s), cancelled %d booked orders without charge", todayStr, day.Name, cancelled)
+		return nil
+	}
 	
-	// 2. 获取受影响的用户ID
-	userIds, err := s.orderRepo.FindExpiredOrdersByWeekNumber(todayStr)
+	userIds, err := s.orderRepo.FindUsersWithBookedOrders(todayStr)
 	if err != nil {
 		log.Printf("Failed to get affected user IDs: %v", err)
 		return err
 	}
 	
-	// 3. 将对应的 sys_user.count -1
+	// 逐个用户在独立事务中处理，单个用户失败不影响其他用户
+	var totalExpired int64
+	charged := 0
 	for _, userId := range userIds {
-		if err := s.userRepo.DecreaseCountByUserId(userId); err != nil {
-			log.Printf("Failed to decrement user count for user %d: %v", userId, err)
+		expired, ok, err := s.orderRepo.ExpireUserOrders(userId, todayStr)
+		if err != nil {
+			log.Printf("Failed to expire orders for user %d: %v", userId, err)
 			continue
 		}
+		totalExpired += expired
+		if ok {
+			charged++
+		}
 	}
 	
-	log.Printf("Successfully processed expired orders for day %s", todayStr)
+	log.Printf("Marked %d orders as expired for day %s, decremented count for %d users", totalExpired, todayStr, charged)
 	return nil
 }
-
-func (s *orderService) CreateOrder(order *model.OrderRecord) error {
-	if order.UserId <= 0 {
-		return errors.New("无效的用户ID")
-	}
-	if order.MealId <= 0 {
-		return errors.New("无效的餐食ID")
-	}
-	if order.Status == "" {
-		order.Status = "已报餐" // 默认状态
-	}
-	
-	return s.orderRepo.CreateOrder(order)
-}
\ No newline at end of file
@@ -0,0 +1,512 @@
+package recharge
+
+import (
+	"canteen/internal/infrastructure/clock"
+	"canteen/internal/model"
+	"canteen/internal/repository/ledger"
+	"canteen/internal/repository/recharge"
+	"canteen/internal/repository/user"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// maxRechargeAmount 单次充值次数上限，防止误填
+const maxRechargeAmount = 1000
+
+type RechargeService interface {
+	// Recharge 为单个用户（userId 或 cardNo）或整个部门（deptId）充值
+	Recharge(req model.RechargeRequest) (*model.RechargeBatch, error)
+	// RechargeSheet 按 Excel 名单充值，名单中次数为空的行使用 req.Amount
+	RechargeSheet(r io.Reader, req model.RechargeRequest) (*model.RechargeBatch, error)
+
+	ListRules() ([]model.AllotmentRule, error)
+	GetRule(id int) (*model.AllotmentRule, error)
+	CreateRule(rule model.AllotmentRule) (*model.AllotmentRule, error)
+	UpdateRule(rule model.AllotmentRule) (*model.AllotmentRule, error)
+	DeleteRule(id int) error
+	// RunRule 执行配额规则，当月已发放的用户跳过
+	RunRule(id int, operator string, dryRun bool) (*model.RechargeBatch, error)
+	// RunDueRules 执行本月已到执行日且尚未执行的规则
+	RunDueRules() error
+}
+
+type rechargeService struct {
+	rechargeRepo recharge.RechargeRepository
+	userRepo     user.UserRepository
+	clock        clock.Clock
+}
+
+func NewRechargeService(rechargeRepo recharge.RechargeRepository, userRepo user.UserRepository, clk clock.Clock) RechargeService {
+	return &rechargeService{rechargeRepo: rechargeRepo, userRepo: userRepo, clock: clk}
+}
+
+// rechargeTarget 待充值的用户
+type rechargeTarget struct {
+	row    int
+	user   *model.UserVo
+	amount int
+	err    string
+}
+
+func (s *rechargeService) Recharge(req model.RechargeRequest) (*model.RechargeBatch, error) {
+	mode, err := normalizeRequest(&req)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAmount(mode, req.Amount); err != nil {
+		return nil, err
+	}
+
+	var targets []rechargeTarget
+	switch {
+	case req.UserId > 0 || req.CardNo != "":
+		var u *model.UserVo
+		if req.UserId > 0 {
+			u, err = s.userRepo.FindById(req.UserId)
+		} else {
+			u, err = s.userRepo.FindByCardNo(req.CardNo)
+		}
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, rechargeTarget{row: 1, user: u, amount: req.Amount})
+	case req.DeptId > 0:
+		users, err := s.rechargeRepo.FindUsersByDept(req.DeptId)
+		if err != nil {
+			return nil, err
+		}
+		if len(users) == 0 {
+			return nil, fmt.Errorf("部门 %d 下没有用户", req.DeptId)
+		}
+		for i := range users {
+			targets = append(targets, rechargeTarget{row: i + 1, user: &users[i], amount: req.Amount})
+		}
+	default:
+		return nil, errors.New("请指定充值用户或部门")
+	}
+
+	return s.apply(targets, mode, req, s.topUpEntry(req))
+}
+
+func (s *rechargeService) RechargeSheet(r io.Reader, req model.RechargeRequest) (*model.RechargeBatch, error) {
+	mode, err := normalizeRequest(&req)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := parseRechargeSheet(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, errors.New("名单中没有数据")
+	}
+
+	targets := make([]rechargeTarget, 0, len(items))
+	seen := map[int]int{}
+	for _, item := range items {
+		target := rechargeTarget{row: item.Row, amount: item.Amount}
+		if item.Amount < 0 {
+			target.amount = req.Amount
+		}
+		target.user, target.err = s.resolveItem(item)
+		if target.err == "" {
+			if err := checkAmount(mode, target.amount); err != nil {
+				target.err = err.Error()
+			} else if row, ok := seen[target.user.UserId]; ok {
+				target.err = fmt.Sprintf("与第%d行用户重复", row)
+			} else {
+				seen[target.user.UserId] = item.Row
+			}
+		}
+		targets = append(targets, target)
+	}
+
+	return s.apply(targets, mode, req, s.topUpEntry(req))
+}
+
+// resolveItem 按用户号、卡号、姓名的顺序查找名单中的用户
+func (s *rechargeService) resolveItem(item model.RechargeItem) (*model.UserVo, string) {
+	if item.Error != "" {
+		return nil, item.Error
+	}
+
+	var u *model.UserVo
+	var err error
+	switch {
+	case item.UserId > 0:
+		u, err = s.userRepo.FindById(item.UserId)
+	case item.CardNo != "":
+		u, err = s.userRepo.FindByCardNo(item.CardNo)
+	case item.NickName != "":
+		u, err = s.userRepo.FindByNickName(item.NickName)
+	default:
+		return nil, "未填写用户号、卡号或姓名"
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, "用户不存在"
+	}
+	if err != nil {
+		return nil, "查询用户失败: " + err.Error()
+	}
+	if item.NickName != "" && u.NickName != item.NickName {
+		return nil, fmt.Sprintf("姓名与系统记录（%s）不符", u.NickName)
+	}
+	return u, ""
+}
+
+// topUpEntry 生成手工充值的流水模板，同一批次共用批次号
+func (s *rechargeService) topUpEntry(req model.RechargeRequest) model.CountLedger {
+	now := s.clock.Now()
+	return model.CountLedger{
+		ChangeType: model.LedgerTypeTopUp,
+		RefType:    model.LedgerRefRecharge,
+		RefId:      fmt.Sprintf("R%s%03d", now.Format("20060102150405"), now.Nanosecond()/1e6),
+		Operator:   req.Operator,
+		Remark:     req.Remark,
+	}
+}
+
+// apply 逐个用户充值，单个用户失败不影响其他用户；dryRun 时只计算结果
+func (s *rechargeService) apply(targets []rechargeTarget, mode string, req model.RechargeRequest, template model.CountLedger) (*model.RechargeBatch, error) {
+	batch := &model.RechargeBatch{
+		BatchNo: template.RefId,
+		DryRun:  req.DryRun,
+		Mode:    mode,
+		Total:   len(targets),
+		Results: make([]model.RechargeResult, 0, len(targets)),
+	}
+	if req.DryRun {
+		batch.BatchNo = ""
+	}
+
+	// 月度配额按规则与月份记账，预览时同样标出已发放的用户
+	applied := map[int]bool{}
+	if req.DryRun && template.RefType == model.LedgerRefAllotment {
+		var err error
+		if applied, err = s.rechargeRepo.FindAppliedUsers(template.RefType, template.RefId); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, target := range targets {
+		result := model.RechargeResult{Row: target.row}
+		if target.user != nil {
+			result.UserId = target.user.UserId
+			result.NickName = target.user.NickName
+			result.CardNo = target.user.CardNo
+			result.Before = target.user.Count
+		}
+
+		switch {
+		case target.err != "":
+			result.Status, result.Message = model.RechargeStatusFailed, target.err
+		case req.DryRun:
+			result.Change = target.amount
+			if mode == model.RechargeModeSet {
+				result.Change = target.amount - result.Before
+			}
+			result.After = result.Before + result.Change
+			switch {
+			case applied[result.UserId]:
+				result.Status, result.Message = model.RechargeStatusSkipped, recharge.ErrAlreadyApplied.Error()
+			case result.Change == 0:
+				result.Status, result.Message = model.RechargeStatusSkipped, "次数未变化"
+			default:
+				result.Status = model.RechargeStatusPreview
+			}
+		default:
+			entry := template
+			entry.UserId = target.user.UserId
+			before, err := s.rechargeRepo.Recharge(&entry, mode, target.amount)
+			result.Before = before
+			result.Change = entry.Change
+			result.After = before + entry.Change
+			switch {
+			case errors.Is(err, recharge.ErrAlreadyApplied):
+				result.Change, result.After = 0, before
+				result.Status, result.Message = model.RechargeStatusSkipped, err.Error()
+			case errors.Is(err, sql.ErrNoRows):
+				result.Status, result.Message = model.RechargeStatusFailed, "用户不存在"
+			case errors.Is(err, ledger.ErrInsufficientCount):
+				result.Status, result.Message = model.RechargeStatusFailed, err.Error()
+			case err != nil:
+				log.Printf("充值失败: user_id=%d, err=%v", entry.UserId, err)
+				result.Status, result.Message = model.RechargeStatusFailed, "充值失败: "+err.Error()
+			case entry.Change == 0:
+				result.Status, result.Message = model.RechargeStatusSkipped, "次数未变化"
+			default:
+				result.Status = model.RechargeStatusSuccess
+			}
+		}
+
+		switch result.Status {
+		case model.RechargeStatusFailed:
+			batch.Failed++
+		case model.RechargeStatusSkipped:
+			batch.Skipped++
+		default:
+			batch.Succeeded++
+		}
+		batch.Results = append(batch.Results, result)
+	}
+
+	if !req.DryRun {
+		log.Printf("次数充值完成: 批次=%s, 类型=%s, 方式=%s, 成功=%d, 跳过=%d, 失败=%d, 操作人=%s",
+			template.RefId, template.ChangeType, mode, batch.Succeeded, batch.Skipped, batch.Failed, template.Operator)
+	}
+	return batch, nil
+}
+
+func (s *rechargeService) ListRules() ([]model.AllotmentRule, error) {
+	return s.rechargeRepo.FindRules()
+}
+
+func (s *rechargeService) GetRule(id int) (*model.AllotmentRule, error) {
+	return s.rechargeRepo.FindRuleById(id)
+}
+
+func (s *rechargeService) CreateRule(rule model.AllotmentRule) (*model.AllotmentRule, error) {
+	if err := normalizeRule(&rule); err != nil {
+		return nil, err
+	}
+
+	// 创建当月已过执行日的规则从下月开始生效，本月可手动执行
+	now := s.clock.Now()
+	if rule.DayOfMonth <= now.Day() {
+		rule.LastRunMonth = now.Format("200601")
+	}
+
+	id, err := s.rechargeRepo.CreateRule(&rule)
+	if err != nil {
+		return nil, err
+	}
+	return s.rechargeRepo.FindRuleById(int(id))
+}
+
+func (s *rechargeService) UpdateRule(rule model.AllotmentRule) (*model.AllotmentRule, error) {
+	if _, err := s.rechargeRepo.FindRuleById(rule.Id); err != nil {
+		return nil, err
+	}
+	if err := normalizeRule(&rule); err != nil {
+		return nil, err
+	}
+	if err := s.rechargeRepo.UpdateRule(&rule); err != nil {
+		return nil, err
+	}
+	return s.rechargeRepo.FindRuleById(rule.Id)
+}
+
+func (s *rechargeService) DeleteRule(id int) error {
+	return s.rechargeRepo.DeleteRule(id)
+}
+
+func (s *rechargeService) RunRule(id int, operator string, dryRun bool) (*model.RechargeBatch, error) {
+	rule, err := s.rechargeRepo.FindRuleById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := s.rechargeRepo.FindUsersByDept(rule.DeptId)
+	if err != nil {
+		return nil, err
+	}
+	targets := make([]rechargeTarget, 0, len(users))
+	for i := range users {
+		targets = append(targets, rechargeTarget{row: i + 1, user: &users[i], amount: rule.Amount})
+	}
+
+	month := s.clock.Now().Format("200601")
+	template := model.CountLedger{
+		ChangeType: model.LedgerTypeAllotment,
+		RefType:    model.LedgerRefAllotment,
+		RefId:      fmt.Sprintf("%d-%s", rule.Id, month),
+		Operator:   strings.TrimSpace(operator),
+		Remark:     rule.Name,
+	}
+	req := model.RechargeRequest{Mode: rule.Mode, Amount: rule.Amount, Operator: template.Operator, DryRun: dryRun}
+	batch, err := s.apply(targets, rule.Mode, req, template)
+	if err != nil {
+		return nil, err
+	}
+
+	// 存在失败用户时不标记执行月份，下次执行时已发放的用户会被跳过
+	if !dryRun && batch.Failed == 0 {
+		if err := s.rechargeRepo.MarkRuleRun(rule.Id, month); err != nil {
+			return nil, err
+		}
+	}
+	return batch, nil
+}
+
+func (s *rechargeService) RunDueRules() error {
+	rules, err := s.rechargeRepo.FindRules()
+	if err != nil {
+		return fmt.Errorf("查询月度配额规则失败: %w", err)
+	}
+
+	now := s.clock.Now()
+	month := now.Format("200601")
+	for _, rule := range rules {
+		if !rule.Enabled || rule.DayOfMonth > now.Day() || rule.LastRunMonth == month {
+			continue
+		}
+		batch, err := s.RunRule(rule.Id, "", false)
+		if err != nil {
+			log.Printf("Allotment rule %d (%s) failed: %v", rule.Id, rule.Name, err)
+			continue
+		}
+		log.Printf("Allotment rule %d (%s) applied: succeeded=%d skipped=%d failed=%d",
+			rule.Id, rule.Name, batch.Succeeded, batch.Skipped, batch.Failed)
+	}
+	return nil
+}
+
+// normalizeRequest 校验充值请求并返回充值方式
+func normalizeRequest(req *model.RechargeRequest) (string, error) {
+	req.Operator = strings.TrimSpace(req.Operator)
+	req.Remark = strings.TrimSpace(req.Remark)
+	req.CardNo = strings.TrimSpace(req.CardNo)
+	if req.Operator == "" {
+		return "", errors.New("操作人不能为空")
+	}
+	return normalizeMode(req.Mode)
+}
+
+func normalizeMode(mode string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "", model.RechargeModeAdd:
+		return model.RechargeModeAdd, nil
+	case model.RechargeModeSet:
+		return model.RechargeModeSet, nil
+	}
+	return "", fmt.Errorf("充值方式错误: %s，可选 add 或 set", mode)
+}
+
+// checkAmount 增加方式次数须大于 0，重置方式次数不能小于 0
+func checkAmount(mode string, amount int) error {
+	if amount > maxRechargeAmount {
+		return fmt.Errorf("充值次数不能超过%d", maxRechargeAmount)
+	}
+	if mode == model.RechargeModeAdd && amount <= 0 {
+		return errors.New("充值次数必须大于0")
+	}
+	if amount < 0 {
+		return errors.New("重置次数不能小于0")
+	}
+	return nil
+}
+
+func normalizeRule(rule *model.AllotmentRule) error {
+	rule.Name = strings.TrimSpace(rule.Name)
+	rule.Remark = strings.TrimSpace(rule.Remark)
+	if rule.Name == "" {
+		return errors.New("规则名称不能为空")
+	}
+	if rule.DeptId < 0 {
+		return errors.New("无效的部门ID")
+	}
+	mode, err := normalizeMode(rule.Mode)
+	if err != nil {
+		return err
+	}
+	rule.Mode = mode
+	if err := checkAmount(rule.Mode, rule.Amount); err != nil {
+		return err
+	}
+	// 限制为 28 日以内，保证每月都能执行
+	if rule.DayOfMonth < 1 || rule.DayOfMonth > 28 {
+		return errors.New("执行日必须在1-28之间")
+	}
+	return nil
+}
+
+// 名单表头别名
+var rechargeHeaders = map[string]string{
+	"用户id": "userId",
+	"用户号":  "userId",
+	"卡号":   "cardNo",
+	"姓名":   "nickName",
+	"次数":   "amount",
+	"充值次数": "amount",
+}
+
+// parseRechargeSheet 读取名单第一个工作表，首行为表头，可包含 用户ID/卡号/姓名/次数 列。
+// 次数为空的行 Amount 为 -1
+func parseRechargeSheet(r io.Reader) ([]model.RechargeItem, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("解析Excel文件失败: %w", err)
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, errors.New("Excel文件中没有工作表")
+	}
+	rows, err := f.GetRows(sheets[0])
+	if err != nil {
+		return nil, fmt.Errorf("读取工作表数据失败: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("名单为空")
+	}
+
+	columns := map[string]int{}
+	for i, cell := range rows[0] {
+		if key, ok := rechargeHeaders[strings.ToLower(strings.TrimSpace(cell))]; ok {
+			if _, exists := columns[key]; !exists {
+				columns[key] = i
+			}
+		}
+	}
+	_, hasId := columns["userId"]
+	_, hasCard := columns["cardNo"]
+	_, hasName := columns["nickName"]
+	if !hasId && !hasCard && !hasName {
+		return nil, errors.New("名单首行须包含 用户ID、卡号 或 姓名 列")
+	}
+
+	cell := func(row []string, key string) string {
+		i, ok := columns[key]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	items := []model.RechargeItem{}
+	for i, row := range rows[1:] {
+		item := model.RechargeItem{
+			Row:      i + 2,
+			CardNo:   cell(row, "cardNo"),
+			NickName: cell(row, "nickName"),
+			Amount:   -1,
+		}
+		userId, amount := cell(row, "userId"), cell(row, "amount")
+		if userId == "" && item.CardNo == "" && item.NickName == "" && amount == "" {
+			continue
+		}
+		if userId != "" {
+			if item.UserId, err = strconv.Atoi(userId); err != nil {
+				item.Error = "用户ID格式错误: " + userId
+			}
+		}
+		if amount != "" && item.Error == "" {
+			if item.Amount, err = strconv.Atoi(amount); err != nil || item.Amount < 0 {
+				item.Amount = -1
+				item.Error = "次数格式错误: " + amount
+			}
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
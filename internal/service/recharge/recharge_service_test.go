@@ -0,0 +1,161 @@
+package recharge
+
+import (
+	"bytes"
+	"canteen/internal/infrastructure/clock"
+	"canteen/internal/model"
+	"canteen/internal/repository/recharge"
+	"canteen/internal/repository/user"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// fakeUserRepo 内存用户表
+type fakeUserRepo struct {
+	user.UserRepository
+	users map[int]*model.UserVo
+}
+
+func (r *fakeUserRepo) FindById(userId int) (*model.UserVo, error) {
+	if u, ok := r.users[userId]; ok {
+		copied := *u
+		return &copied, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *fakeUserRepo) FindByCardNo(cardNo string) (*model.UserVo, error) {
+	for _, u := range r.users {
+		if u.CardNo == cardNo {
+			copied := *u
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// fakeRechargeRepo 直接修改 fakeUserRepo 中的次数并记录流水
+type fakeRechargeRepo struct {
+	recharge.RechargeRepository
+	users  *fakeUserRepo
+	ledger []model.CountLedger
+}
+
+func (r *fakeRechargeRepo) Recharge(entry *model.CountLedger, mode string, amount int) (int, error) {
+	u, ok := r.users.users[entry.UserId]
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+	before := u.Count
+	entry.Change = amount
+	if mode == model.RechargeModeSet {
+		entry.Change = amount - before
+	}
+	entry.Balance = before + entry.Change
+	if entry.Change != 0 {
+		u.Count = entry.Balance
+		r.ledger = append(r.ledger, *entry)
+	}
+	return before, nil
+}
+
+func newTestService() (*rechargeService, *fakeRechargeRepo) {
+	users := &fakeUserRepo{users: map[int]*model.UserVo{
+		1: {UserId: 1, NickName: "张三", CardNo: "E001", Count: 5},
+		2: {UserId: 2, NickName: "李四", CardNo: "E002", Count: 0},
+	}}
+	repo := &fakeRechargeRepo{users: users}
+	clk := clock.Fixed(time.Date(2025, 6, 2, 9, 0, 0, 0, time.Local))
+	return &rechargeService{rechargeRepo: repo, userRepo: users, clock: clk}, repo
+}
+
+func buildSheet(t *testing.T, rows [][]interface{}) *bytes.Buffer {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := f.SetSheetRow("Sheet1", cell, &row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+func TestRechargeSheet(t *testing.T) {
+	s, repo := newTestService()
+	sheet := buildSheet(t, [][]interface{}{
+		{"用户ID", "卡号", "姓名", "次数"},
+		{1, "", "张三", 10},
+		{"", "E002", "", ""},
+		{"", "", "", ""},
+		{"", "E001", "", 3},
+		{"", "E404", "", 3},
+		{"abc", "", "", 3},
+		{2, "", "王五", 3},
+	})
+
+	batch, err := s.RechargeSheet(sheet, model.RechargeRequest{Amount: 4, Operator: "admin"})
+	if err != nil {
+		t.Fatalf("RechargeSheet() error = %v", err)
+	}
+
+	want := []struct {
+		row    int
+		status string
+		after  int
+	}{
+		{2, model.RechargeStatusSuccess, 15},
+		{3, model.RechargeStatusSuccess, 4},
+		{5, model.RechargeStatusFailed, 0},
+		{6, model.RechargeStatusFailed, 0},
+		{7, model.RechargeStatusFailed, 0},
+		{8, model.RechargeStatusFailed, 0},
+	}
+	if len(batch.Results) != len(want) {
+		t.Fatalf("got %d results, want %d: %+v", len(batch.Results), len(want), batch.Results)
+	}
+	for i, w := range want {
+		got := batch.Results[i]
+		if got.Row != w.row || got.Status != w.status || got.After != w.after {
+			t.Errorf("result %d = %+v, want row=%d status=%s after=%d", i, got, w.row, w.status, w.after)
+		}
+	}
+	if batch.Succeeded != 2 || batch.Failed != 4 {
+		t.Errorf("batch counts = %d/%d, want 2/4", batch.Succeeded, batch.Failed)
+	}
+	if len(repo.ledger) != 2 || repo.ledger[0].RefId != batch.BatchNo || repo.ledger[0].ChangeType != model.LedgerTypeTopUp {
+		t.Errorf("ledger = %+v", repo.ledger)
+	}
+}
+
+func TestRechargeDryRunDoesNotWrite(t *testing.T) {
+	s, repo := newTestService()
+
+	batch, err := s.Recharge(model.RechargeRequest{UserId: 1, Mode: "set", Amount: 20, Operator: "admin", DryRun: true})
+	if err != nil {
+		t.Fatalf("Recharge() error = %v", err)
+	}
+	got := batch.Results[0]
+	if got.Status != model.RechargeStatusPreview || got.Before != 5 || got.Change != 15 || got.After != 20 {
+		t.Errorf("result = %+v", got)
+	}
+	if len(repo.ledger) != 0 || repo.users.users[1].Count != 5 {
+		t.Errorf("dry run wrote changes: ledger=%+v count=%d", repo.ledger, repo.users.users[1].Count)
+	}
+
+	batch, err = s.Recharge(model.RechargeRequest{UserId: 1, Mode: "set", Amount: 5, Operator: "admin"})
+	if err != nil {
+		t.Fatalf("Recharge() error = %v", err)
+	}
+	if batch.Results[0].Status != model.RechargeStatusSkipped || len(repo.ledger) != 0 {
+		t.Errorf("reset to same count = %+v, ledger=%+v", batch.Results[0], repo.ledger)
+	}
+}
@@ -0,0 +1,167 @@
+package setmeal
+
+import (
+	"canteen/internal/model"
+	"canteen/internal/repository/dish"
+	"canteen/internal/repository/setmeal"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+)
+
+type SetmealService interface {
+	// GetSetmeal 获取套餐及其菜品，附带各菜品标签及套餐汇总
+	GetSetmeal(id int) (*model.Setmeal, error)
+	// CreateSetmeal 为周套餐新建套餐并关联，编码为 M<yyyyMMdd>-<餐别>-<窗口>，同一周套餐仅允许一个套餐
+	CreateSetmeal(req model.SetmealRequest) (*model.Setmeal, error)
+	// UpdateSetmeal 修改套餐名称及菜品，编码保持不变
+	UpdateSetmeal(id int, req model.SetmealRequest) (*model.Setmeal, error)
+	// CloneSetmeal 按套餐的菜品为另一个周套餐新建套餐
+	CloneSetmeal(id int, weeklySetmealId int) (*model.Setmeal, error)
+}
+
+type setmealService struct {
+	setmealRepo setmeal.SetmealRepository
+	dishRepo    dish.DishRepository
+}
+
+func NewSetmealService(setmealRepo setmeal.SetmealRepository, dishRepo dish.DishRepository) SetmealService {
+	return &setmealService{setmealRepo: setmealRepo, dishRepo: dishRepo}
+}
+
+func (s *setmealService) GetSetmeal(id int) (*model.Setmeal, error) {
+	if id <= 0 {
+		return nil, errors.New("无效的套餐ID")
+	}
+	meal, err := s.setmealRepo.FindById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	dishIds := make([]int, len(meal.Dishes))
+	for i, d := range meal.Dishes {
+		dishIds[i] = d.DishId
+	}
+	tags, err := s.dishRepo.FindTags(dishIds)
+	if err != nil {
+		return nil, err
+	}
+	dishTags := make([]model.DishTags, len(meal.Dishes))
+	for i := range meal.Dishes {
+		meal.Dishes[i].DishTags = tags[meal.Dishes[i].DishId]
+		dishTags[i] = meal.Dishes[i].DishTags
+	}
+	meal.Tags = model.AggregateTags(dishTags)
+	return meal, nil
+}
+
+func (s *setmealService) CreateSetmeal(req model.SetmealRequest) (*model.Setmeal, error) {
+	if req.WeeklySetmealId <= 0 {
+		return nil, errors.New("周套餐ID不能为空")
+	}
+	slot, err := s.setmealRepo.FindSlot(req.WeeklySetmealId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("周套餐不存在: %d", req.WeeklySetmealId)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	code := model.SetmealCode(slot.WeekNumber, slot.MealType, slot.Window())
+	existing, err := s.setmealRepo.FindIdByCode(code)
+	if err != nil {
+		return nil, err
+	}
+	if existing != 0 {
+		return nil, fmt.Errorf("%s %s %s 已有套餐 %d，请直接修改", slot.WeekNumber, slot.MealType, slot.Remark, existing)
+	}
+
+	meal := &model.Setmeal{Name: strings.TrimSpace(req.Name), Code: code}
+	if meal.Name == "" {
+		meal.Name = slot.WeekNumber + " " + slot.MealType + " " + slot.Remark
+	}
+	if err := s.compose(meal, req.DishIds); err != nil {
+		return nil, err
+	}
+
+	id, err := s.setmealRepo.Create(meal, slot.Id)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("新建套餐: id=%d, 编码=%s, 周套餐=%d, 菜品=%s", id, code, slot.Id, meal.Description)
+	return s.GetSetmeal(int(id))
+}
+
+func (s *setmealService) UpdateSetmeal(id int, req model.SetmealRequest) (*model.Setmeal, error) {
+	meal, err := s.GetSetmeal(id)
+	if err != nil {
+		return nil, err
+	}
+	if name := strings.TrimSpace(req.Name); name != "" {
+		meal.Name = name
+	}
+	if err := s.compose(meal, req.DishIds); err != nil {
+		return nil, err
+	}
+
+	if err := s.setmealRepo.Update(meal); err != nil {
+		return nil, err
+	}
+	log.Printf("修改套餐: id=%d, 编码=%s, 菜品=%s", id, meal.Code, meal.Description)
+	return s.GetSetmeal(id)
+}
+
+func (s *setmealService) CloneSetmeal(id int, weeklySetmealId int) (*model.Setmeal, error) {
+	source, err := s.GetSetmeal(id)
+	if err != nil {
+		return nil, err
+	}
+	dishIds := make([]int, len(source.Dishes))
+	for i, d := range source.Dishes {
+		dishIds[i] = d.DishId
+	}
+	return s.CreateSetmeal(model.SetmealRequest{WeeklySetmealId: weeklySetmealId, DishIds: dishIds})
+}
+
+// compose 校验菜品存在、已启用且不重复，按请求顺序设置套餐菜品及描述
+func (s *setmealService) compose(meal *model.Setmeal, dishIds []int) error {
+	if utf8.RuneCountInString(meal.Name) > 100 {
+		return fmt.Errorf("套餐名称过长: %s", meal.Name)
+	}
+	if len(dishIds) == 0 {
+		return errors.New("菜品列表不能为空")
+	}
+
+	dishes, err := s.setmealRepo.FindDishes(dishIds)
+	if err != nil {
+		return err
+	}
+	meal.Dishes = make([]model.SetmealDish, 0, len(dishIds))
+	names := make([]string, 0, len(dishIds))
+	seen := map[int]bool{}
+	for i, id := range dishIds {
+		d, ok := dishes[id]
+		switch {
+		case !ok:
+			return fmt.Errorf("菜品不存在: %d", id)
+		case seen[id]:
+			return fmt.Errorf("菜品重复: %s", d.Name)
+		case d.Status == model.DishStatusDisabled:
+			return fmt.Errorf("菜品已停用: %s", d.Name)
+		}
+		seen[id] = true
+		meal.Dishes = append(meal.Dishes, model.SetmealDish{
+			DishId:       id,
+			Name:         d.Name,
+			CategoryId:   d.CategoryId,
+			CategoryName: d.CategoryName,
+			Sort:         i + 1,
+		})
+		names = append(names, d.Name)
+	}
+	meal.Description = strings.Join(names, "+")
+	return nil
+}
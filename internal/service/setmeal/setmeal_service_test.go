@@ -0,0 +1,187 @@
+package setmeal
+
+import (
+	"canteen/internal/model"
+	"canteen/internal/repository/dish"
+	"canteen/internal/repository/setmeal"
+	"database/sql"
+	"strings"
+	"testing"
+)
+
+// fakeSetmealRepo 内存套餐、周套餐及菜品
+type fakeSetmealRepo struct {
+	setmeal.SetmealRepository
+	setmeals map[int]*model.Setmeal
+	slots    map[int]*model.WeeklySlot
+	dishes   map[int]model.Dish
+	nextId   int
+}
+
+func newFakeSetmealRepo() *fakeSetmealRepo {
+	return &fakeSetmealRepo{
+		setmeals: map[int]*model.Setmeal{},
+		slots: map[int]*model.WeeklySlot{
+			1: {Id: 1, WeekNumber: "20250609", MealType: "午餐", Remark: "套餐A"},
+			2: {Id: 2, WeekNumber: "20250610", MealType: "午餐", Remark: "套餐C"},
+		},
+		dishes: map[int]model.Dish{
+			1: {Id: 1, Name: "米饭", CategoryId: 1, CategoryName: "主食", Status: model.DishStatusEnabled},
+			2: {Id: 2, Name: "红烧肉", CategoryId: 2, CategoryName: "荤菜", Status: model.DishStatusEnabled},
+			3: {Id: 3, Name: "清炒时蔬", Status: model.DishStatusEnabled},
+			4: {Id: 4, Name: "糖醋排骨", Status: model.DishStatusDisabled},
+		},
+		nextId: 100,
+	}
+}
+
+func (r *fakeSetmealRepo) FindById(id int) (*model.Setmeal, error) {
+	s, ok := r.setmeals[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *s
+	return &copied, nil
+}
+
+func (r *fakeSetmealRepo) FindIdByCode(code string) (int, error) {
+	for id, s := range r.setmeals {
+		if s.Code == code {
+			return id, nil
+		}
+	}
+	return 0, nil
+}
+
+func (r *fakeSetmealRepo) FindSlot(id int) (*model.WeeklySlot, error) {
+	slot, ok := r.slots[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return slot, nil
+}
+
+func (r *fakeSetmealRepo) FindDishes(ids []int) (map[int]model.Dish, error) {
+	result := map[int]model.Dish{}
+	for _, id := range ids {
+		if d, ok := r.dishes[id]; ok {
+			result[id] = d
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeSetmealRepo) Create(s *model.Setmeal, weeklySetmealId int) (int64, error) {
+	r.nextId++
+	copied := *s
+	copied.Id = r.nextId
+	r.setmeals[r.nextId] = &copied
+	r.slots[weeklySetmealId].SetmealId = r.nextId
+	return int64(r.nextId), nil
+}
+
+func (r *fakeSetmealRepo) Update(s *model.Setmeal) error {
+	copied := *s
+	r.setmeals[s.Id] = &copied
+	return nil
+}
+
+// fakeDishRepo 内存菜品标签
+type fakeDishRepo struct {
+	dish.DishRepository
+	tags map[int]model.DishTags
+}
+
+func (r *fakeDishRepo) FindTags(dishIds []int) (map[int]model.DishTags, error) {
+	result := map[int]model.DishTags{}
+	for _, id := range dishIds {
+		result[id] = r.tags[id]
+	}
+	return result, nil
+}
+
+func newFakeDishRepo() *fakeDishRepo {
+	return &fakeDishRepo{tags: map[int]model.DishTags{
+		1: {Diets: []string{"素食", "清真"}, Nutrition: &model.Nutrition{EnergyKcal: 200, CarbohydrateG: 45}},
+		2: {Allergens: []string{"大豆", "猪肉"}, Diets: []string{"辣"}, Nutrition: &model.Nutrition{EnergyKcal: 450, ProteinG: 20}},
+		3: {Allergens: []string{"大豆"}, Diets: []string{"素食", "清真"}},
+	}}
+}
+
+func TestCreateSetmealForSlot(t *testing.T) {
+	repo := newFakeSetmealRepo()
+	s := NewSetmealService(repo, newFakeDishRepo())
+
+	meal, err := s.CreateSetmeal(model.SetmealRequest{WeeklySetmealId: 1, DishIds: []int{2, 1, 3}})
+	if err != nil {
+		t.Fatalf("CreateSetmeal() err = %v", err)
+	}
+	if meal.Code != "M20250609-午餐-A" || meal.Name != "20250609 午餐 套餐A" || meal.Description != "红烧肉+米饭+清炒时蔬" {
+		t.Errorf("套餐 = %+v", meal)
+	}
+	if len(meal.Dishes) != 3 || meal.Dishes[0].CategoryName != "荤菜" || meal.Dishes[2].Sort != 3 {
+		t.Errorf("套餐菜品 = %+v", meal.Dishes)
+	}
+	if repo.slots[1].SetmealId != meal.Id {
+		t.Errorf("周套餐未关联新套餐")
+	}
+	// 过敏原取并集，素食、清真须全部菜品满足，辣为任一菜品；营养成分仅合计已填写的菜品
+	tags := meal.Tags
+	if strings.Join(tags.Allergens, ",") != "大豆,猪肉" || strings.Join(tags.Diets, ",") != "辣" {
+		t.Errorf("套餐标签 = %+v", tags)
+	}
+	if tags.Nutrition == nil || tags.Nutrition.EnergyKcal != 650 || tags.Nutrition.ProteinG != 20 || tags.NutritionComplete {
+		t.Errorf("套餐营养成分 = %+v, 完整 = %v", tags.Nutrition, tags.NutritionComplete)
+	}
+	if len(meal.Dishes[0].Allergens) != 2 {
+		t.Errorf("套餐菜品应附带标签: %+v", meal.Dishes[0])
+	}
+
+	if _, err := s.CreateSetmeal(model.SetmealRequest{WeeklySetmealId: 1, DishIds: []int{1}}); err == nil || !strings.Contains(err.Error(), "请直接修改") {
+		t.Errorf("同一周套餐重复新建应拒绝，实际 %v", err)
+	}
+
+	updated, err := s.UpdateSetmeal(meal.Id, model.SetmealRequest{DishIds: []int{1, 2}})
+	if err != nil {
+		t.Fatalf("UpdateSetmeal() err = %v", err)
+	}
+	if updated.Code != meal.Code || updated.Name != meal.Name || updated.Description != "米饭+红烧肉" {
+		t.Errorf("修改后套餐 = %+v", updated)
+	}
+	if !updated.Tags.NutritionComplete || updated.Tags.Nutrition.CarbohydrateG != 45 {
+		t.Errorf("修改后营养成分 = %+v", updated.Tags)
+	}
+
+	cloned, err := s.CloneSetmeal(meal.Id, 2)
+	if err != nil {
+		t.Fatalf("CloneSetmeal() err = %v", err)
+	}
+	if cloned.Id == meal.Id || cloned.Code != "M20250610-午餐-C" || cloned.Description != "米饭+红烧肉" || repo.slots[2].SetmealId != cloned.Id {
+		t.Errorf("复制的套餐 = %+v", cloned)
+	}
+}
+
+func TestCreateSetmealValidatesDishes(t *testing.T) {
+	repo := newFakeSetmealRepo()
+	s := NewSetmealService(repo, newFakeDishRepo())
+
+	tests := []struct {
+		req  model.SetmealRequest
+		want string
+	}{
+		{model.SetmealRequest{DishIds: []int{1}}, "周套餐ID不能为空"},
+		{model.SetmealRequest{WeeklySetmealId: 9, DishIds: []int{1}}, "周套餐不存在"},
+		{model.SetmealRequest{WeeklySetmealId: 1}, "菜品列表不能为空"},
+		{model.SetmealRequest{WeeklySetmealId: 1, DishIds: []int{1, 9}}, "菜品不存在: 9"},
+		{model.SetmealRequest{WeeklySetmealId: 1, DishIds: []int{1, 2, 1}}, "菜品重复: 米饭"},
+		{model.SetmealRequest{WeeklySetmealId: 1, DishIds: []int{4}}, "菜品已停用: 糖醋排骨"},
+	}
+	for _, tt := range tests {
+		if _, err := s.CreateSetmeal(tt.req); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("CreateSetmeal(%+v) err = %v, 期望包含 %s", tt.req, err, tt.want)
+		}
+	}
+	if len(repo.setmeals) != 0 {
+		t.Errorf("校验失败时不应新建套餐")
+	}
+}
@@ -0,0 +1,113 @@
+package setmeal_template
+
+import (
+	"canteen/internal/model"
+	"canteen/internal/repository/meal_period"
+	"canteen/internal/repository/setmeal_template"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type SetmealTemplateService interface {
+	// ListTemplates 查询套餐模板，mealPeriodId 为 0 时返回全部
+	ListTemplates(mealPeriodId int) ([]model.SetmealTemplate, error)
+	GetTemplate(id int) (*model.SetmealTemplate, error)
+	CreateTemplate(template *model.SetmealTemplate) error
+	UpdateTemplate(template *model.SetmealTemplate) error
+	DeleteTemplate(id int) error
+}
+
+type setmealTemplateService struct {
+	templateRepo setmeal_template.SetmealTemplateRepository
+	periodRepo   meal_period.MealPeriodRepository
+}
+
+func NewSetmealTemplateService(templateRepo setmeal_template.SetmealTemplateRepository, periodRepo meal_period.MealPeriodRepository) SetmealTemplateService {
+	return &setmealTemplateService{templateRepo: templateRepo, periodRepo: periodRepo}
+}
+
+func (s *setmealTemplateService) ListTemplates(mealPeriodId int) ([]model.SetmealTemplate, error) {
+	return s.templateRepo.FindAll(mealPeriodId)
+}
+
+func (s *setmealTemplateService) GetTemplate(id int) (*model.SetmealTemplate, error) {
+	if id <= 0 {
+		return nil, errors.New("无效的模板ID")
+	}
+	return s.templateRepo.FindById(id)
+}
+
+func (s *setmealTemplateService) CreateTemplate(t *model.SetmealTemplate) error {
+	if err := s.validate(t); err != nil {
+		return err
+	}
+
+	id, err := s.templateRepo.Create(t)
+	if err != nil {
+		return err
+	}
+	t.Id = int(id)
+	return nil
+}
+
+func (s *setmealTemplateService) UpdateTemplate(t *model.SetmealTemplate) error {
+	if t.Id <= 0 {
+		return errors.New("无效的模板ID")
+	}
+	if _, err := s.templateRepo.FindById(t.Id); err != nil {
+		return err
+	}
+	if err := s.validate(t); err != nil {
+		return err
+	}
+	return s.templateRepo.Update(t)
+}
+
+func (s *setmealTemplateService) DeleteTemplate(id int) error {
+	if id <= 0 {
+		return errors.New("无效的模板ID")
+	}
+	return s.templateRepo.Delete(id)
+}
+
+// validate 校验餐次存在且适用于该星期，同一餐次同一星期的窗口不重复
+func (s *setmealTemplateService) validate(t *model.SetmealTemplate) error {
+	t.Window = strings.ToUpper(strings.TrimSpace(t.Window))
+	t.Remark = strings.TrimSpace(t.Remark)
+	if t.Window == "" {
+		return errors.New("窗口不能为空")
+	}
+	if utf8.RuneCountInString(t.Window) > 10 {
+		return fmt.Errorf("窗口过长: %s", t.Window)
+	}
+	if t.Weekday < 1 || t.Weekday > 7 {
+		return fmt.Errorf("无效的星期: %d", t.Weekday)
+	}
+
+	period, err := s.periodRepo.FindById(t.MealPeriodId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("餐次不存在: %d", t.MealPeriodId)
+	}
+	if err != nil {
+		return err
+	}
+	if !period.AppliesOn(time.Weekday(t.Weekday % 7)) {
+		return fmt.Errorf("餐次 %s 不适用于星期%d", period.Name, t.Weekday)
+	}
+	t.Canteen, t.MealType = period.Canteen, period.Name
+
+	templates, err := s.templateRepo.FindAll(t.MealPeriodId)
+	if err != nil {
+		return err
+	}
+	for _, other := range templates {
+		if other.Id != t.Id && other.Weekday == t.Weekday && other.Window == t.Window {
+			return fmt.Errorf("餐次 %s 星期%d 已配置窗口 %s", period.Name, t.Weekday, t.Window)
+		}
+	}
+	return nil
+}
@@ -1,16 +1,26 @@
 package user
 
 import (
+	"canteen/internal/infrastructure/auth"
 	"canteen/internal/model"
 	"canteen/internal/repository/user"
+	"database/sql"
 	"errors"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
+// ErrBadCredentials 账号或密码错误
+var ErrBadCredentials = errors.New("账号或密码错误")
+
 type UserService interface {
 	FindByCardNo(cardNo string) (*model.UserVo, error)
 	FindById(userId int) (*model.UserVo, error)
 	FindByNickName(nickName string) (*model.UserVo, error)
-	DecreaseUserCount(userId int) error
+	// Login 校验账号密码（sys_user.password 为 bcrypt 哈希）并签发登录令牌
+	Login(userName string, password string) (*model.LoginResult, error)
 }
 
 type userService struct {
@@ -42,9 +52,35 @@ func (s *userService) FindByNickName(nickName string) (*model.UserVo, error) {
 	return s.userRepo.FindByNickName(nickName)
 }
 
-func (s *userService) DecreaseUserCount(userId int) error {
-	if userId <= 0 {
-		return errors.New("无效的用户ID")
+func (s *userService) Login(userName string, password string) (*model.LoginResult, error) {
+	userName = strings.TrimSpace(userName)
+	if userName == "" || password == "" {
+		return nil, errors.New("账号和密码不能为空")
+	}
+
+	userId, hash, err := s.userRepo.FindCredential(userName)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrBadCredentials
+	}
+	if err != nil {
+		return nil, err
 	}
-	return s.userRepo.DecreaseCountByUserId(userId)
-}
\ No newline at end of file
+	if hash == "" || bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return nil, ErrBadCredentials
+	}
+
+	u, err := s.userRepo.FindById(userId)
+	if err != nil {
+		return nil, err
+	}
+	token, expiry, err := auth.IssueToken(userId, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.LoginResult{
+		Token:      token,
+		ExpireTime: expiry.Format("2006-01-02 15:04:05"),
+		User:       u,
+	}, nil
+}
@@ -0,0 +1,72 @@
+// Package testutil 提供依赖 MySQL 的集成测试辅助函数
+package testutil
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// TestDSNEnv 集成测试数据库连接串所在的环境变量，未设置时跳过相关测试
+const TestDSNEnv = "CANTEEN_TEST_DSN"
+
+// OpenTestDB 连接测试库并执行 scripts/init_database.sql 建表
+func OpenTestDB(t testing.TB) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv(TestDSNEnv)
+	if dsn == "" {
+		t.Skipf("未设置 %s，跳过 MySQL 集成测试", TestDSNEnv)
+	}
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatalf("打开测试库失败: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := db.Ping(); err != nil {
+		t.Fatalf("连接测试库失败: %v", err)
+	}
+
+	for _, stmt := range schemaStatements(t) {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("初始化表结构失败: %v\n%s", err, stmt)
+		}
+	}
+
+	return db
+}
+
+// schemaStatements 读取初始化脚本并拆分为单条语句（忽略注释行）
+func schemaStatements(t testing.TB) []string {
+	t.Helper()
+
+	_, file, _, _ := runtime.Caller(0)
+	path := filepath.Join(filepath.Dir(file), "..", "..", "scripts", "init_database.sql")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("读取初始化脚本失败: %v", err)
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "--") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	var statements []string
+	for _, stmt := range strings.Split(strings.Join(lines, "\n"), ";") {
+		if stmt = strings.TrimSpace(stmt); stmt != "" {
+			statements = append(statements, stmt)
+		}
+	}
+	return statements
+}
@@ -14,9 +14,26 @@ import (
 	"log"
 	"os"
 	"path/filepath"
-	"strconv"
 	"time"
 
+	"canteen/internal/infrastructure/clock"
+	calendarRepo "canteen/internal/repository/calendar"
+	deviceRepo "canteen/internal/repository/device"
+	ledgerRepo "canteen/internal/repository/ledger"
+	mealRepo "canteen/internal/repository/meal"
+	periodRepo "canteen/internal/repository/meal_period"
+	noShowRepo "canteen/internal/repository/noshow"
+	orderRepo "canteen/internal/repository/order"
+	rechargeRepo "canteen/internal/repository/recharge"
+	templateRepo "canteen/internal/repository/setmeal_template"
+	userRepo "canteen/internal/repository/user"
+	"canteen/internal/service/ledger"
+	"canteen/internal/service/meal"
+	"canteen/internal/service/meal_period"
+	"canteen/internal/service/noshow"
+	"canteen/internal/service/order"
+	"canteen/internal/service/recharge"
+
 	"github.com/go-redis/redis/v8"
 )
 
@@ -118,9 +135,9 @@ func DailyLicenseCheck() {
 }
 
 // 2.DailyExpireOrderRecords 定时任务：每日处理过期订单
-func DailyExpireOrderRecords(db *sql.DB) {
+func DailyExpireOrderRecords(db *sql.DB, clk clock.Clock) {
 	for {
-		now := time.Now()
+		now := clk.Now()
 		var next time.Time
 		if now.Hour() >= 23 {
 			next = time.Date(now.Year(), now.Month(), now.Day()+1, 23, 0, 0, 0, now.Location())
@@ -131,67 +148,71 @@ func DailyExpireOrderRecords(db *sql.DB) {
 		log.Printf("Next expire task scheduled at: %v", next)
 		time.Sleep(sleepDuration)
 
-		todayStr := time.Now().Format("20060102")
-		todayInt, _ := strconv.Atoi(todayStr)
-
-		tx, err := db.Begin()
-		if err != nil {
-			log.Printf("Failed to begin transaction: %v", err)
+		// 过期订单扣减次数并记录次数流水
+		if err := newOrderService(db, clk).ProcessExpiredOrders(); err != nil {
+			log.Printf("Failed to process expired orders: %v", err)
 			continue
 		}
+		// 按爽约规则暂停多次报餐未就餐人员的报餐
+		if blocked, err := noshow.NewNoShowService(noShowRepo.NewNoShowRepository(db), clk).BlockRepeatOffenders(); err != nil {
+			log.Printf("Failed to block repeat no-show users: %v", err)
+		} else if blocked > 0 {
+			log.Printf("Blocked %d repeat no-show users from booking", blocked)
+		}
+	}
+}
 
-		// Step 1: 更新订单记录并获取受影响的 user_id 列表
-		queryUpdateOrders := `
-				UPDATE order_record 
-				SET status = '已过期', update_time = NOW() 
-				WHERE week_number = ? AND status = '已报餐'
-			`
-		result, err := tx.Exec(queryUpdateOrders, todayInt)
-		if err != nil {
-			tx.Rollback()
-			log.Printf("Failed to update expired orders: %v", err)
-			continue
+// SeedCountLedger 启动时为尚无期初余额的用户补写期初余额，避免首次变动次数后流水合计与次数永久不一致
+func SeedCountLedger(db *sql.DB) error {
+	_, err := ledger.NewLedgerService(ledgerRepo.NewLedgerRepository(db), userRepo.NewUserRepository(db)).SeedOpeningBalances()
+	return err
+}
+
+// DailyCountLedgerCheck 定时任务：每日核对次数流水合计与 sys_user.count
+func DailyCountLedgerCheck(db *sql.DB, clk clock.Clock) {
+	for {
+		now := clk.Now()
+		next := time.Date(now.Year(), now.Month(), now.Day(), 23, 30, 0, 0, now.Location())
+		if !now.Before(next) {
+			next = next.AddDate(0, 0, 1)
 		}
+		log.Printf("Next count ledger check scheduled at: %v", next)
+		time.Sleep(next.Sub(now))
 
-		rowsAffected, _ := result.RowsAffected()
-		log.Printf("Marked %d orders as expired for day %s", rowsAffected, todayStr)
-
-		// Step 2: 将对应的 sys_user.count -1
-		if rowsAffected > 0 {
-			queryDecrementUserCount := `
-					UPDATE sys_user u
-					JOIN (
-						SELECT DISTINCT user_id
-						FROM order_record
-						WHERE week_number = ? AND status = '已过期'
-					) AS o USING (user_id)
-					SET u.count = GREATEST(u.count - 1, 0)
-				`
-
-			_, err = tx.Exec(queryDecrementUserCount, todayInt)
-			if err != nil {
-				tx.Rollback()
-				log.Printf("Failed to decrement user count: %v", err)
-			} else {
-				tx.Commit()
-				log.Printf("Successfully decremented count for users with expired orders.")
-			}
-		} else {
-			tx.Commit()
+		if _, err := ledger.NewLedgerService(ledgerRepo.NewLedgerRepository(db), userRepo.NewUserRepository(db)).CheckDrifts(); err != nil {
+			log.Printf("Count ledger check failed: %v", err)
+		}
+	}
+}
+
+// DailyAllotmentRun 定时任务：每日执行已到执行日的月度配额规则
+func DailyAllotmentRun(db *sql.DB, clk clock.Clock) {
+	for {
+		now := clk.Now()
+		next := time.Date(now.Year(), now.Month(), now.Day(), 0, 10, 0, 0, now.Location())
+		if !now.Before(next) {
+			next = next.AddDate(0, 0, 1)
+		}
+		log.Printf("Next allotment run scheduled at: %v", next)
+		time.Sleep(next.Sub(now))
+
+		service := recharge.NewRechargeService(rechargeRepo.NewRechargeRepository(db), userRepo.NewUserRepository(db), clk)
+		if err := service.RunDueRules(); err != nil {
+			log.Printf("Allotment run failed: %v", err)
 		}
 	}
 }
 
 // 3.WeeklyGenerateSetmeal 定时任务：每周生成套餐
-func WeeklyGenerateSetmeal(db *sql.DB) {
+func WeeklyGenerateSetmeal(db *sql.DB, clk clock.Clock) {
 	for {
-		now := time.Now()
+		now := clk.Now()
 		nextThursday := FindNextThursday(now)
 		log.Printf("Next scheduled generation at: %v", nextThursday)
 		time.Sleep(nextThursday.Sub(now))
 
 		// 生成下周套餐
-		if err := GenerateNextWeekSetmeals(db); err != nil {
+		if err := GenerateNextWeekSetmeals(db, clk); err != nil {
 			log.Printf("Failed to generate setmeals: %v", err)
 		}
 	}
@@ -210,69 +231,9 @@ func FindNextThursday(t time.Time) time.Time {
 	return nextThursday
 }
 
-// GenerateNextWeekSetmeals 生成下周的套餐记录
-func GenerateNextWeekSetmeals(db *sql.DB) error {
-	// 获取下周一的日期
-	nextMonday := GetNextMonday(time.Now())
-	dates := make([]time.Time, 6)
-	for i := 0; i < 6; i++ {
-		dates[i] = nextMonday.AddDate(0, 0, i)
-	}
-
-	// 开启事务
-	tx, err := db.Begin()
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
-
-	// 删除旧记录
-	startWeek := dates[0].Format("20060102")
-	endWeek := dates[5].Format("20060102")
-	if _, err := tx.Exec("DELETE FROM weekly_setmeal WHERE week_number BETWEEN ? AND ?", startWeek, endWeek); err != nil {
-		return err
-	}
-
-	// 插入新记录
-	for _, date := range dates {
-		weekNumber := date.Format("20060102")
-		weekday := GetWeekdayZh(date)
-
-		// 定义午餐和晚餐的备注
-		lunchRemarks := []string{"套餐A", "套餐B", "套餐C"}
-		//dinnerRemark := []string{"套餐A", "套餐C"}
-		dinnerRemark := []string{"套餐A", "套餐C"}
-
-		// 插入3个午餐
-		for _, remark := range lunchRemarks {
-			_, err := tx.Exec(`
-                INSERT INTO weekly_setmeal 
-                    (week_number, weekday, meal_type, setmeal_id, create_time, create_user, remark)
-                VALUES (?, ?, ?, NULL, NOW(), 263, ?)`,
-				weekNumber, weekday, "午餐", remark)
-			if err != nil {
-				return err
-			}
-		}
-
-		// 插入晚餐
-		for _, remark := range dinnerRemark {
-			_, err := tx.Exec(`
-                INSERT INTO weekly_setmeal 
-                    (week_number, weekday, meal_type, setmeal_id, create_time, create_user, remark)
-                VALUES (?, ?, ?, NULL, NOW(), 263, ?)`,
-				weekNumber, weekday, "晚餐", remark)
-			if err != nil {
-				return err
-			}
-		}
-	}
-	// 提交事务
-	if err := tx.Commit(); err != nil {
-		return err
-	}
-	log.Printf("Generated setmeals for week starting %s", dates[0].Format("2006-01-02"))
-	return nil
+// GenerateNextWeekSetmeals 按套餐模板生成下周的套餐记录，已生成时跳过
+func GenerateNextWeekSetmeals(db *sql.DB, clk clock.Clock) error {
+	return newMealService(db, nil, clk).GenerateNextWeekSetmeals()
 }
 
 // GetNextMonday 获取下周一的日期
@@ -303,115 +264,40 @@ func GetWeekdayZh(t time.Time) string {
 }
 
 // CheckIfWeeklySetmealGenerated 检查下一周的套餐是否已生成
-func CheckIfWeeklySetmealGenerated(db *sql.DB) bool {
-	// 获取下周一的日期
-	nextMonday := GetNextMonday(time.Now())
-	dates := make([]time.Time, 6)
-	for i := 0; i < 6; i++ {
-		dates[i] = nextMonday.AddDate(0, 0, i)
-	}
-
-	// 查询是否已有记录
-	for _, date := range dates {
-		weekNumber := date.Format("20060102")
-		query := `SELECT COUNT(*) FROM weekly_setmeal WHERE week_number = ?`
-		var count int
-		err := db.QueryRow(query, weekNumber).Scan(&count)
-		if err != nil {
-			log.Printf("Failed to check weekly setmeal: %v", err)
-			return false
-		}
-		if count == 0 {
-			// 如果有任何一天没有生成套餐，则返回 false
-			return false
-		}
-	}
-	return true
+func CheckIfWeeklySetmealGenerated(db *sql.DB, clk clock.Clock) bool {
+	return newMealService(db, nil, clk).CheckIfWeeklySetmealGenerated()
 }
-func DailyMealCacheUpdate(db *sql.DB, redisClient *redis.Client) {
+func DailyMealCacheUpdate(db *sql.DB, redisClient *redis.Client, clk clock.Clock) {
 	ctx := context.Background()
 
 	for {
-		now := time.Now()
+		now := clk.Now()
 		// 计算下一个凌晨5点的时间点
 		next := time.Date(now.Year(), now.Month(), now.Day(), 5, 0, 0, 0, now.Location())
 		if !now.Before(next) {
 			next = next.Add(24 * time.Hour)
 		}
 		log.Printf("meal cache update scheduled at: %v", next)
-		time.Sleep(time.Until(next))
+		time.Sleep(next.Sub(now))
 
-		if err := UpdateDailyMealCache(ctx, db, redisClient); err != nil {
+		if err := UpdateDailyMealCache(ctx, db, redisClient, clk); err != nil {
 			log.Printf("Daily meal cache update failed: %v", err)
 		}
 	}
 }
 
-func UpdateDailyMealCache(ctx context.Context, db *sql.DB, redisClient *redis.Client) error {
-	dateStr := time.Now().Format("20060102")
-
-	query := `
-		SELECT id, meal_type, remark FROM weekly_setmeal
-		WHERE week_number = ? AND meal_type IN ('午餐', '晚餐')
-	`
-
-	rows, err := db.Query(query, dateStr)
-	if err != nil {
-		return fmt.Errorf("db query failed: %w", err)
-	}
-	defer rows.Close()
-
-	mealTypeMap := map[string]string{"午餐": "lunch", "晚餐": "dinner"}
-	remarkMap := map[string]string{"套餐A": "A", "套餐B": "B", "套餐C": "C"}
-
-	found := make(map[string]bool)
-
-	for rows.Next() {
-		var id int
-		var mealType, remark string
-		if err := rows.Scan(&id, &mealType, &remark); err != nil {
-			log.Printf("Row scan failed: %v", err)
-			continue
-		}
-
-		mealTypeEn, ok1 := mealTypeMap[mealType]
-		remarkEn, ok2 := remarkMap[remark]
-
-		if !ok1 || !ok2 {
-			log.Printf("Unknown mealType or remark: %s, %s", mealType, remark)
-			continue
-		}
-
-		key := fmt.Sprintf("%s-%s-%s", dateStr, mealTypeEn, remarkEn)
-		if err := redisClient.Set(ctx, key, id, 24*time.Hour).Err(); err != nil {
-			log.Printf("Redis SET failed for key %s: %v", key, err)
-		} else {
-			log.Printf("Set Redis: %s => %d", key, id)
-			found[key] = true
-		}
-	}
-
-	if err := rows.Err(); err != nil {
-		return fmt.Errorf("rows iteration error: %w", err)
-	}
-
-	defaultKeys := []string{
-		fmt.Sprintf("%s-lunch-A", dateStr),
-		fmt.Sprintf("%s-lunch-B", dateStr),
-		fmt.Sprintf("%s-lunch-C", dateStr),
-		fmt.Sprintf("%s-dinner-A", dateStr),
-		fmt.Sprintf("%s-dinner-C", dateStr),
-	}
+// UpdateDailyMealCache 按餐次配置刷新当日窗口套餐缓存
+func UpdateDailyMealCache(ctx context.Context, db *sql.DB, redisClient *redis.Client, clk clock.Clock) error {
+	return newMealService(db, redisClient, clk).UpdateDailyMealCache()
+}
 
-	for _, key := range defaultKeys {
-		if !found[key] {
-			if err := redisClient.Set(ctx, key, 1, 24*time.Hour).Err(); err != nil {
-				log.Printf("Redis SET default failed for %s: %v", key, err)
-			} else {
-				log.Printf("Set default Redis: %s => 1", key)
-			}
-		}
-	}
+// newOrderService 构造定时任务使用的订单服务
+func newOrderService(db *sql.DB, clk clock.Clock) order.OrderService {
+	return order.NewOrderService(orderRepo.NewOrderRepository(db), userRepo.NewUserRepository(db), calendarRepo.NewCalendarRepository(db), nil, clk)
+}
 
-	return nil
-}
\ No newline at end of file
+// newMealService 构造定时任务使用的套餐服务
+func newMealService(db *sql.DB, redisClient *redis.Client, clk clock.Clock) meal.MealService {
+	periodService := meal_period.NewMealPeriodService(periodRepo.NewMealPeriodRepository(db), calendarRepo.NewCalendarRepository(db))
+	return meal.NewMealService(mealRepo.NewMealRepository(db), deviceRepo.NewDeviceRepository(db), templateRepo.NewSetmealTemplateRepository(db), periodService, redisClient, clk)
+}
@@ -4,7 +4,9 @@
 CREATE TABLE IF NOT EXISTS `sys_user` (
   `user_id` int(11) NOT NULL AUTO_INCREMENT,
   `dept_id` int(11) DEFAULT NULL,
+  `user_name` varchar(30) DEFAULT NULL,
   `nick_name` varchar(50) DEFAULT NULL,
+  `password` varchar(100) DEFAULT NULL,
   `count` int(11) DEFAULT '0',
   `card_no` varchar(50) DEFAULT NULL,
   `create_time` datetime DEFAULT CURRENT_TIMESTAMP,
@@ -115,6 +117,279 @@ CREATE TABLE IF NOT EXISTS `canteen_config` (
   UNIQUE KEY `idx_config_key` (`config_key`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 
+-- 刷卡终端设备表
+CREATE TABLE IF NOT EXISTS `device` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `serial_no` varchar(50) NOT NULL,
+  `window_code` varchar(10) NOT NULL,
+  `canteen` varchar(50) DEFAULT 'main',
+  `meal_types` varchar(100) DEFAULT '午餐,晚餐',
+  `enabled` tinyint(1) DEFAULT '1',
+  `last_seen_time` datetime DEFAULT NULL,
+  `remark` varchar(200) DEFAULT NULL,
+  `create_time` datetime DEFAULT CURRENT_TIMESTAMP,
+  `update_time` datetime DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_serial_no` (`serial_no`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 现有终端（原先硬编码在代码中的设备映射）
+INSERT IGNORE INTO `device` (`serial_no`, `window_code`, `canteen`, `meal_types`, `remark`) VALUES
+('0180800116', 'A', 'main', '午餐,晚餐', 'A窗口'),
+('0127448632', 'B', 'main', '午餐', 'B窗口'),
+('0158577664', 'C', 'main', '午餐,晚餐', 'C窗口');
+
+-- 核销交易流水表（按设备+终端交易号去重）
+CREATE TABLE IF NOT EXISTS `consum_transaction_log` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `device_no` varchar(50) NOT NULL,
+  `order_no` varchar(64) NOT NULL,
+  `card_no` varchar(50) DEFAULT NULL,
+  `user_id` int(11) DEFAULT NULL,
+  `order_record_id` int(11) DEFAULT NULL,
+  `meal_type` varchar(20) DEFAULT NULL,
+  `response` text,
+  `create_time` datetime DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_device_order` (`device_no`, `order_no`),
+  KEY `idx_order_record_id` (`order_record_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 脱机消费记录表（待对账记录供管理员处理）
+CREATE TABLE IF NOT EXISTS `offline_transaction` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `device_no` varchar(50) NOT NULL,
+  `order_no` varchar(64) NOT NULL,
+  `card_no` varchar(50) DEFAULT NULL,
+  `trans_time` datetime NOT NULL,
+  `money` varchar(20) DEFAULT NULL,
+  `pay_type` int(11) DEFAULT NULL,
+  `card_mode` int(11) DEFAULT NULL,
+  `status` varchar(20) DEFAULT '待处理',
+  `reason` varchar(200) DEFAULT NULL,
+  `operator` varchar(50) DEFAULT NULL,
+  `handle_remark` varchar(200) DEFAULT NULL,
+  `handle_time` datetime DEFAULT NULL,
+  `create_time` datetime DEFAULT CURRENT_TIMESTAMP,
+  `update_time` datetime DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_device_order` (`device_no`, `order_no`),
+  KEY `idx_status` (`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 餐次时段表（按食堂配置，刷卡、缓存预热、周套餐生成均据此判断餐别）
+CREATE TABLE IF NOT EXISTS `meal_period` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `canteen` varchar(50) NOT NULL DEFAULT 'main',
+  `name` varchar(20) NOT NULL,
+  `code` varchar(20) NOT NULL,
+  `start_time` char(5) NOT NULL,
+  `end_time` char(5) NOT NULL,
+  `weekdays` varchar(20) NOT NULL DEFAULT '1,2,3,4,5,6',
+  `sort` int(11) DEFAULT '0',
+  `enabled` tinyint(1) DEFAULT '1',
+  `create_time` datetime DEFAULT CURRENT_TIMESTAMP,
+  `update_time` datetime DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_canteen_name` (`canteen`, `name`),
+  UNIQUE KEY `idx_canteen_code` (`canteen`, `code`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 现有餐次（晚餐开始时间仍按部门配置 flexible/fixed_dinner_start_time 校验）
+-- 早餐、夜宵可按需新增，例如：('main', '早餐', 'breakfast', '06:30', '09:00', '1,2,3,4,5', 1)
+INSERT IGNORE INTO `meal_period` (`canteen`, `name`, `code`, `start_time`, `end_time`, `weekdays`, `sort`) VALUES
+('main', '午餐', 'lunch', '11:00', '14:00', '1,2,3,4,5,6', 2),
+('main', '晚餐', 'dinner', '14:00', '21:00', '1,2,3,4,5,6', 3);
+
+-- 撤销核销审计表
+CREATE TABLE IF NOT EXISTS `consum_refund` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `order_record_id` int(11) NOT NULL,
+  `user_id` int(11) NOT NULL,
+  `device_no` varchar(50) DEFAULT NULL,
+  `order_no` varchar(64) DEFAULT NULL,
+  `meal_type` varchar(20) DEFAULT NULL,
+  `from_status` varchar(20) NOT NULL,
+  `to_status` varchar(20) NOT NULL,
+  `operator` varchar(50) NOT NULL,
+  `reason` varchar(200) NOT NULL,
+  `create_time` datetime DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_order_record_id` (`order_record_id`),
+  KEY `idx_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 用餐次数流水表（sys_user.count 的每次变动均记录于此）
+CREATE TABLE IF NOT EXISTS `count_ledger` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `user_id` int(11) NOT NULL,
+  `change_amount` int(11) NOT NULL,
+  `balance` int(11) NOT NULL,
+  `change_type` varchar(20) NOT NULL,
+  `ref_type` varchar(30) DEFAULT NULL,
+  `ref_id` varchar(64) DEFAULT NULL,
+  `operator` varchar(50) DEFAULT NULL,
+  `remark` varchar(200) DEFAULT NULL,
+  `create_time` datetime DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_user_id` (`user_id`, `create_time`),
+  KEY `idx_ref` (`ref_type`, `ref_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 已有用户的期初余额，服务启动时同样会补齐；已有流水的用户取首条流水变动前的余额
+INSERT INTO `count_ledger` (`user_id`, `change_amount`, `balance`, `change_type`, `create_time`)
+SELECT u.`user_id`, IFNULL(f.`balance` - f.`change_amount`, u.`count`), IFNULL(f.`balance` - f.`change_amount`, u.`count`), '期初余额',
+  IFNULL(f.`create_time`, NOW())
+FROM `sys_user` u
+LEFT JOIN `count_ledger` f ON f.`id` = (SELECT MIN(`id`) FROM `count_ledger` WHERE `user_id` = u.`user_id`)
+WHERE NOT EXISTS (SELECT 1 FROM `count_ledger` l WHERE l.`user_id` = u.`user_id` AND l.`change_type` = '期初余额');
+
+-- 次数对账差异表（每日对账任务写入）
+CREATE TABLE IF NOT EXISTS `count_drift` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `user_id` int(11) NOT NULL,
+  `ledger_sum` int(11) NOT NULL,
+  `count` int(11) NOT NULL,
+  `drift` int(11) NOT NULL,
+  `check_time` datetime DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_check_time` (`check_time`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 月度配额规则表（每月执行日为部门用户充值或重置次数）
+CREATE TABLE IF NOT EXISTS `count_allotment_rule` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `name` varchar(50) NOT NULL,
+  `dept_id` int(11) NOT NULL DEFAULT '0' COMMENT '0 表示全部用户',
+  `mode` varchar(10) NOT NULL DEFAULT 'add' COMMENT 'add 增加 / set 重置',
+  `amount` int(11) NOT NULL,
+  `day_of_month` tinyint(4) NOT NULL DEFAULT '1',
+  `enabled` tinyint(1) NOT NULL DEFAULT '1',
+  `last_run_month` varchar(6) DEFAULT NULL COMMENT '最近执行月份 yyyyMM',
+  `remark` varchar(200) DEFAULT NULL,
+  `create_time` datetime DEFAULT CURRENT_TIMESTAMP,
+  `update_time` datetime DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 卡片表（状态：正常/挂失/冻结/注销），sys_user.card_no 为用户当前卡号
+CREATE TABLE IF NOT EXISTS `sys_card` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `card_no` varchar(50) NOT NULL,
+  `user_id` int(11) NOT NULL,
+  `status` varchar(10) NOT NULL DEFAULT '正常',
+  `replaced_by` varchar(50) DEFAULT NULL COMMENT '补办的新卡号',
+  `remark` varchar(200) DEFAULT NULL,
+  `status_time` datetime DEFAULT CURRENT_TIMESTAMP,
+  `create_time` datetime DEFAULT CURRENT_TIMESTAMP,
+  `update_time` datetime DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_card_no` (`card_no`),
+  KEY `idx_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 已有用户卡号登记为正常卡
+INSERT IGNORE INTO `sys_card` (`card_no`, `user_id`, `status`)
+SELECT `card_no`, `user_id`, '正常' FROM `sys_user` WHERE `card_no` IS NOT NULL AND `card_no` <> '';
+
+-- 卡片操作记录表
+CREATE TABLE IF NOT EXISTS `sys_card_log` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `card_no` varchar(50) NOT NULL,
+  `user_id` int(11) NOT NULL,
+  `action` varchar(10) NOT NULL,
+  `from_status` varchar(10) DEFAULT NULL,
+  `to_status` varchar(10) NOT NULL,
+  `operator` varchar(50) NOT NULL,
+  `remark` varchar(200) DEFAULT NULL,
+  `create_time` datetime DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_card_no` (`card_no`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 员工报餐截止规则，取值为“提前天数 HH:mm”，如 '1 16:00' 表示用餐前一天 16:00 截止
+-- 按 <规则>.<餐次编码>.dept_<部门ID>、<规则>.<餐次编码>、<规则>.dept_<部门ID>、<规则> 的顺序取第一个已配置的值
+-- cancel_cutoff 均未配置时取消截止与报餐截止相同，例如：
+-- ('booking_cutoff.lunch', '1 16:00', '午餐报餐截止'),
+-- ('cancel_cutoff.lunch', '0 09:00', '午餐取消截止'),
+-- ('booking_cutoff.lunch.dept_12', '0 10:00', '12 部门午餐报餐截止')
+INSERT IGNORE INTO `canteen_config` (`config_key`, `config_value`, `description`) VALUES
+('booking_cutoff', '1 17:00', '默认报餐截止');
+
+-- 节假日及调休上班日历，未登记的日期按餐次配置的适用星期供餐
+CREATE TABLE IF NOT EXISTS `canteen_calendar` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `calendar_date` date NOT NULL,
+  `day_type` varchar(10) NOT NULL COMMENT '节假日 / 调休',
+  `name` varchar(50) DEFAULT NULL,
+  `weekday` tinyint(4) DEFAULT NULL COMMENT '调休上班按星期几供餐，1-7',
+  `remark` varchar(200) DEFAULT NULL,
+  `create_time` datetime DEFAULT CURRENT_TIMESTAMP,
+  `update_time` datetime DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_calendar_date` (`calendar_date`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 套餐模板，按餐次、星期配置生成周套餐的窗口；餐次未配置任何模板时按供餐设备的窗口生成
+CREATE TABLE IF NOT EXISTS `setmeal_template` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `meal_period_id` int(11) NOT NULL,
+  `weekday` tinyint(4) NOT NULL COMMENT '1-7 表示周一至周日',
+  `window_code` varchar(10) NOT NULL COMMENT '窗口，生成的周套餐备注为 套餐+窗口',
+  `sort` int(11) NOT NULL DEFAULT 0,
+  `enabled` tinyint(1) NOT NULL DEFAULT 1,
+  `remark` varchar(200) DEFAULT NULL,
+  `create_time` datetime DEFAULT CURRENT_TIMESTAMP,
+  `update_time` datetime DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_period_weekday_window` (`meal_period_id`, `weekday`, `window_code`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 菜品过敏原及饮食标签表
+CREATE TABLE IF NOT EXISTS `dish_tag` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `dish_id` int(11) NOT NULL,
+  `tag_type` varchar(20) NOT NULL COMMENT 'allergen 过敏原 / diet 饮食标签',
+  `tag` varchar(20) NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_dish_tag` (`dish_id`, `tag_type`, `tag`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 菜品营养成分表（每份），未填写的菜品无记录
+CREATE TABLE IF NOT EXISTS `dish_nutrition` (
+  `dish_id` int(11) NOT NULL,
+  `energy_kcal` decimal(8,1) NOT NULL DEFAULT '0.0',
+  `protein_g` decimal(8,1) NOT NULL DEFAULT '0.0',
+  `fat_g` decimal(8,1) NOT NULL DEFAULT '0.0',
+  `carbohydrate_g` decimal(8,1) NOT NULL DEFAULT '0.0',
+  `sodium_mg` decimal(8,1) NOT NULL DEFAULT '0.0',
+  `update_time` datetime DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`dish_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 爽约暂停报餐记录，每日过期任务按 no_show 规则生成，管理员可提前解除
+CREATE TABLE IF NOT EXISTS `booking_block` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `user_id` int(11) NOT NULL,
+  `start_date` date NOT NULL,
+  `end_date` date NOT NULL COMMENT '含当天',
+  `no_show_count` int(11) NOT NULL DEFAULT 0 COMMENT '触发暂停的爽约次数',
+  `reason` varchar(200) DEFAULT NULL,
+  `revoked` tinyint(1) NOT NULL DEFAULT 0 COMMENT '1 表示已提前解除',
+  `create_time` datetime DEFAULT CURRENT_TIMESTAMP,
+  `update_time` datetime DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_user_end` (`user_id`, `end_date`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 爽约规则：爽约次数不少于 min_count 且爽约率（0-1）不低于 min_rate 时标记，
+-- block_days 大于 0 时每日按最近 window_days 天的订单暂停标记人员报餐 block_days 天
+INSERT IGNORE INTO `canteen_config` (`config_key`, `config_value`, `description`) VALUES
+('no_show.min_count', '3', '爽约标记最少次数'),
+('no_show.min_rate', '0.3', '爽约标记最低爽约率'),
+('no_show.block_days', '0', '爽约暂停报餐天数，0 表示不暂停'),
+('no_show.window_days', '30', '自动暂停统计天数');
+
 
 ----------------- TEST ---------------
 -- -- 插入一些基础配置数据